@@ -0,0 +1,95 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"localeyes/internal/models"
+)
+
+func deletePost(c *cli, args []string) error {
+	flags := flag.NewFlagSet("content delete-post", flag.ContinueOnError)
+	reason := flags.String("reason", "", "reason recorded in the audit log")
+	rest, err := parse(flags, args, 2)
+	if err != nil {
+		return err
+	}
+	uId, pId := rest[0], rest[1]
+	post, err := c.posts.GetUserPost(c.ctx, uId, pId)
+	if err != nil {
+		return err
+	}
+	err = c.admin.DeletePost(c.ctx, uId, pId, &models.DeletePost{Type: post.Type, CreatedAt: post.CreatedAt}, *reason)
+	if err != nil {
+		return err
+	}
+	return c.printDone("deleted post " + pId)
+}
+
+func deleteQuestion(c *cli, args []string) error {
+	flags := flag.NewFlagSet("content delete-question", flag.ContinueOnError)
+	reason := flags.String("reason", "", "reason recorded in the audit log")
+	rest, err := parse(flags, args, 3)
+	if err != nil {
+		return err
+	}
+	if err := c.admin.DeleteQuestion(c.ctx, rest[0], rest[1], rest[2], *reason); err != nil {
+		return err
+	}
+	return c.printDone("deleted question " + rest[1])
+}
+
+func deleteAnswer(c *cli, args []string) error {
+	flags := flag.NewFlagSet("content delete-answer", flag.ContinueOnError)
+	reason := flags.String("reason", "", "reason recorded in the audit log")
+	rest, err := parse(flags, args, 3)
+	if err != nil {
+		return err
+	}
+	if err := c.admin.DeleteAnswer(c.ctx, rest[1], rest[0], rest[2], *reason); err != nil {
+		return err
+	}
+	return c.printDone("deleted answer " + rest[1])
+}
+
+func deleteUserContent(c *cli, args []string) error {
+	flags := flag.NewFlagSet("content delete-user", flag.ContinueOnError)
+	reason := flags.String("reason", "", "reason recorded in the audit log")
+	dryRun := flags.Bool("dry-run", false, "only list what would be deleted")
+	rest, err := parse(flags, args, 1)
+	if err != nil {
+		return err
+	}
+	job, err := c.admin.BulkDeleteUserContent(c.ctx, rest[0], *dryRun, *reason)
+	if err != nil {
+		return err
+	}
+	job, err = c.waitJob(job)
+	if err != nil {
+		return err
+	}
+	return c.printJob(job)
+}
+
+// rotateOTP revokes the outstanding OTPs of an email and issues a new one, for users who
+// cannot receive the email with their code
+func rotateOTP(c *cli, args []string) error {
+	rest, err := parse(flag.NewFlagSet("otp rotate", flag.ContinueOnError), args, 1)
+	if err != nil {
+		return err
+	}
+	email := rest[0]
+	if _, err := c.users.FetchUserByEmail(c.ctx, email); err != nil {
+		return fmt.Errorf("%s: %w", email, err)
+	}
+	if err := c.otps.DeleteOTPs(c.ctx, email); err != nil {
+		return err
+	}
+	otp, err := c.otps.GenerateOTP()
+	if err != nil {
+		return err
+	}
+	if err := c.otps.SaveOTP(c.ctx, email, otp); err != nil {
+		return err
+	}
+	return c.print(map[string]string{"email": email, "otp": otp}, []string{"EMAIL", "OTP"}, [][]string{{email, otp}})
+}
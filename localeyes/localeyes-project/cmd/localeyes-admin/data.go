@@ -0,0 +1,275 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Items are dumped one per line in the DynamoDB JSON format used by the AWS CLI, so a dump can
+// be restored here or loaded with the usual tools.
+
+func encodeItem(item map[string]types.AttributeValue) map[string]any {
+	encoded := make(map[string]any, len(item))
+	for name, value := range item {
+		encoded[name] = encodeValue(value)
+	}
+	return encoded
+}
+
+func encodeValue(value types.AttributeValue) any {
+	switch v := value.(type) {
+	case *types.AttributeValueMemberS:
+		return map[string]any{"S": v.Value}
+	case *types.AttributeValueMemberN:
+		return map[string]any{"N": v.Value}
+	case *types.AttributeValueMemberB:
+		return map[string]any{"B": v.Value}
+	case *types.AttributeValueMemberBOOL:
+		return map[string]any{"BOOL": v.Value}
+	case *types.AttributeValueMemberNULL:
+		return map[string]any{"NULL": v.Value}
+	case *types.AttributeValueMemberSS:
+		return map[string]any{"SS": v.Value}
+	case *types.AttributeValueMemberNS:
+		return map[string]any{"NS": v.Value}
+	case *types.AttributeValueMemberBS:
+		return map[string]any{"BS": v.Value}
+	case *types.AttributeValueMemberL:
+		list := make([]any, 0, len(v.Value))
+		for _, element := range v.Value {
+			list = append(list, encodeValue(element))
+		}
+		return map[string]any{"L": list}
+	case *types.AttributeValueMemberM:
+		return map[string]any{"M": encodeItem(v.Value)}
+	}
+	return nil
+}
+
+type jsonValue struct {
+	S    *string               `json:"S"`
+	N    *string               `json:"N"`
+	B    []byte                `json:"B"`
+	BOOL *bool                 `json:"BOOL"`
+	NULL *bool                 `json:"NULL"`
+	SS   []string              `json:"SS"`
+	NS   []string              `json:"NS"`
+	BS   [][]byte              `json:"BS"`
+	L    []*jsonValue          `json:"L"`
+	M    map[string]*jsonValue `json:"M"`
+}
+
+func decodeItem(encoded map[string]*jsonValue) (map[string]types.AttributeValue, error) {
+	item := make(map[string]types.AttributeValue, len(encoded))
+	for name, value := range encoded {
+		decoded, err := decodeValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		item[name] = decoded
+	}
+	return item, nil
+}
+
+func decodeValue(value *jsonValue) (types.AttributeValue, error) {
+	switch {
+	case value == nil:
+		return nil, errors.New("missing value")
+	case value.S != nil:
+		return &types.AttributeValueMemberS{Value: *value.S}, nil
+	case value.N != nil:
+		return &types.AttributeValueMemberN{Value: *value.N}, nil
+	case value.B != nil:
+		return &types.AttributeValueMemberB{Value: value.B}, nil
+	case value.BOOL != nil:
+		return &types.AttributeValueMemberBOOL{Value: *value.BOOL}, nil
+	case value.NULL != nil:
+		return &types.AttributeValueMemberNULL{Value: *value.NULL}, nil
+	case value.SS != nil:
+		return &types.AttributeValueMemberSS{Value: value.SS}, nil
+	case value.NS != nil:
+		return &types.AttributeValueMemberNS{Value: value.NS}, nil
+	case value.BS != nil:
+		return &types.AttributeValueMemberBS{Value: value.BS}, nil
+	case value.L != nil:
+		list := make([]types.AttributeValue, 0, len(value.L))
+		for _, element := range value.L {
+			decoded, err := decodeValue(element)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, decoded)
+		}
+		return &types.AttributeValueMemberL{Value: list}, nil
+	case value.M != nil:
+		item, err := decodeItem(value.M)
+		if err != nil {
+			return nil, err
+		}
+		return &types.AttributeValueMemberM{Value: item}, nil
+	}
+	return nil, errors.New("unknown attribute type")
+}
+
+func keyOf(item map[string]types.AttributeValue, name string) string {
+	if value, ok := item[name].(*types.AttributeValueMemberS); ok {
+		return value.Value
+	}
+	return ""
+}
+
+// printItems lists items by key, the table shows the remaining attributes in short
+func (c *cli) printItems(items []map[string]types.AttributeValue) error {
+	sort.Slice(items, func(i, j int) bool {
+		if keyOf(items[i], "pk") != keyOf(items[j], "pk") {
+			return keyOf(items[i], "pk") < keyOf(items[j], "pk")
+		}
+		return keyOf(items[i], "sk") < keyOf(items[j], "sk")
+	})
+	encoded := make([]map[string]any, 0, len(items))
+	rows := make([][]string, 0, len(items))
+	for _, item := range items {
+		encoded = append(encoded, encodeItem(item))
+		var attributes []string
+		for name, value := range item {
+			if name == "pk" || name == "sk" {
+				continue
+			}
+			text, _ := json.Marshal(encodeValue(value))
+			attributes = append(attributes, name+"="+string(text))
+		}
+		sort.Strings(attributes)
+		summary := strings.Join(attributes, " ")
+		if len(summary) > 120 {
+			summary = summary[:117] + "..."
+		}
+		rows = append(rows, []string{keyOf(item, "pk"), keyOf(item, "sk"), summary})
+	}
+	return c.print(encoded, []string{"PK", "SK", "ATTRIBUTES"}, rows)
+}
+
+func inspectPost(c *cli, args []string) error {
+	rest, err := parse(flag.NewFlagSet("post inspect", flag.ContinueOnError), args, 1)
+	if err != nil {
+		return err
+	}
+	items, err := c.table.PostItems(c.ctx, rest[0])
+	if err != nil {
+		return err
+	}
+	return c.printItems(items)
+}
+
+type partitions []string
+
+func (p *partitions) String() string { return strings.Join(*p, ",") }
+
+func (p *partitions) Set(value string) error {
+	*p = append(*p, value)
+	return nil
+}
+
+func dumpData(c *cli, args []string) error {
+	flags := flag.NewFlagSet("data dump", flag.ContinueOnError)
+	var pks partitions
+	flags.Var(&pks, "pk", "partition to dump, may be repeated")
+	prefix := flags.String("prefix", "", "dump every partition whose key starts with this, scans the table")
+	file := flags.String("file", "", "file to write, standard output when empty")
+	if _, err := parse(flags, args, 0); err != nil {
+		return err
+	}
+	if len(pks) == 0 && *prefix == "" {
+		return errors.New("choose partitions with -pk or -prefix")
+	}
+	var items []map[string]types.AttributeValue
+	for _, pk := range pks {
+		partition, err := c.table.Partition(c.ctx, pk)
+		if err != nil {
+			return err
+		}
+		items = append(items, partition...)
+	}
+	if *prefix != "" {
+		scanned, err := c.table.ScanPrefix(c.ctx, *prefix)
+		if err != nil {
+			return err
+		}
+		items = append(items, scanned...)
+	}
+	var out io.Writer = os.Stdout
+	if *file != "" {
+		f, err := os.Create(*file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	w := bufio.NewWriter(out)
+	encoder := json.NewEncoder(w)
+	for _, item := range items {
+		if err := encoder.Encode(encodeItem(item)); err != nil {
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "dumped %d items\n", len(items))
+	return nil
+}
+
+func restoreData(c *cli, args []string) error {
+	flags := flag.NewFlagSet("data restore", flag.ContinueOnError)
+	file := flags.String("file", "", "file to read, standard input when empty")
+	dryRun := flags.Bool("dry-run", false, "only list the items that would be written")
+	if _, err := parse(flags, args, 0); err != nil {
+		return err
+	}
+	var in io.Reader = os.Stdin
+	if *file != "" {
+		f, err := os.Open(*file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	var items []map[string]types.AttributeValue
+	scanner := bufio.NewScanner(in)
+	// an item is at most 400KB, base64 grows binary attributes by a third
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var encoded map[string]*jsonValue
+		if err := json.Unmarshal(scanner.Bytes(), &encoded); err != nil {
+			return errors.New("line " + strconv.Itoa(line) + ": " + err.Error())
+		}
+		item, err := decodeItem(encoded)
+		if err != nil {
+			return errors.New("line " + strconv.Itoa(line) + ": " + err.Error())
+		}
+		items = append(items, item)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if *dryRun {
+		return c.printItems(items)
+	}
+	if err := c.table.PutItems(c.ctx, items); err != nil {
+		return err
+	}
+	return c.printDone("restored " + strconv.Itoa(len(items)) + " items")
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"localeyes/internal/repositories"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestItemRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		value types.AttributeValue
+	}{
+		{"string", &types.AttributeValueMemberS{Value: "user:a%3Ab"}},
+		{"empty string", &types.AttributeValueMemberS{Value: ""}},
+		{"number", &types.AttributeValueMemberN{Value: "-12.5"}},
+		{"binary", &types.AttributeValueMemberB{Value: []byte{0, 1, 255}}},
+		{"false", &types.AttributeValueMemberBOOL{Value: false}},
+		{"null", &types.AttributeValueMemberNULL{Value: true}},
+		{"string set", &types.AttributeValueMemberSS{Value: []string{"a", "b"}}},
+		{"number set", &types.AttributeValueMemberNS{Value: []string{"1", "2"}}},
+		{"binary set", &types.AttributeValueMemberBS{Value: [][]byte{{1}, {2}}}},
+		{"empty list", &types.AttributeValueMemberL{Value: []types.AttributeValue{}}},
+		{"nested", &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+			"items": &types.AttributeValueMemberL{Value: []types.AttributeValue{
+				&types.AttributeValueMemberS{Value: "x"},
+				&types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+					"n": &types.AttributeValueMemberN{Value: "1"},
+				}},
+			}},
+		}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			item := map[string]types.AttributeValue{
+				"pk":    &types.AttributeValueMemberS{Value: "post:p1"},
+				"value": test.value,
+			}
+			line, err := json.Marshal(encodeItem(item))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var encoded map[string]*jsonValue
+			if err := json.Unmarshal(line, &encoded); err != nil {
+				t.Fatal(err)
+			}
+			decoded, err := decodeItem(encoded)
+			if err != nil {
+				t.Fatalf("decodeItem(%s): %v", line, err)
+			}
+			if !reflect.DeepEqual(decoded, item) {
+				t.Fatalf("%s decodes to %#v, want %#v", line, decoded, item)
+			}
+		})
+	}
+}
+
+func TestDecodeItemRejectsUnknownValues(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+	}{
+		{"missing value", `{"pk":null}`},
+		{"unknown type", `{"pk":{"X":"1"}}`},
+		{"unknown type in list", `{"pk":{"L":[{"X":"1"}]}}`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var encoded map[string]*jsonValue
+			if err := json.Unmarshal([]byte(test.line), &encoded); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := decodeItem(encoded); err == nil {
+				t.Fatalf("decodeItem(%s) succeeded", test.line)
+			}
+		})
+	}
+}
+
+// memoryTable is a DynamoDB endpoint keeping items in the wire format, it answers the
+// partition queries and batch writes the data commands send
+type memoryTable struct {
+	items []map[string]any
+}
+
+func (m *memoryTable) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		ExpressionAttributeValues map[string]map[string]any
+		RequestItems              map[string][]struct {
+			PutRequest struct {
+				Item map[string]any
+			}
+		}
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	output := map[string]any{}
+	switch strings.TrimPrefix(r.Header.Get("X-Amz-Target"), "DynamoDB_20120810.") {
+	case "Query":
+		items := []map[string]any{}
+		for _, item := range m.items {
+			if reflect.DeepEqual(item["pk"], input.ExpressionAttributeValues[":pk"]) {
+				items = append(items, item)
+			}
+		}
+		output["Items"] = items
+		output["Count"] = len(items)
+	case "BatchWriteItem":
+		for _, requests := range input.RequestItems {
+			for _, request := range requests {
+				m.items = append(m.items, request.PutRequest.Item)
+			}
+		}
+	default:
+		http.Error(w, "unexpected operation", http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+	_ = json.NewEncoder(w).Encode(output)
+}
+
+func TestDumpRestoreRoundTrip(t *testing.T) {
+	post := []map[string]any{
+		{"pk": map[string]any{"S": "post:p1"}, "sk": map[string]any{"S": "details"}, "title": map[string]any{"S": "Lakes"}, "likes": map[string]any{"N": "2.50"}},
+		{"pk": map[string]any{"S": "post:p1"}, "sk": map[string]any{"S": "question:q1"}, "photo": map[string]any{"B": "AAH/"}, "tags": map[string]any{"SS": []any{"b", "a"}}},
+		{"pk": map[string]any{"S": "post:p1"}, "sk": map[string]any{"S": "revision:1"}, "hidden": map[string]any{"BOOL": false}, "history": map[string]any{"L": []any{
+			map[string]any{"M": map[string]any{"text": map[string]any{"S": "first"}, "edited_at": map[string]any{"NULL": true}}},
+		}}},
+	}
+	other := map[string]any{"pk": map[string]any{"S": "post:p2"}, "sk": map[string]any{"S": "details"}}
+	table := &memoryTable{items: append([]map[string]any{other}, post...)}
+	server := httptest.NewServer(table)
+	defer server.Close()
+	c := &cli{
+		ctx:    context.Background(),
+		format: "json",
+		table: &repositories.TableRepository{
+			Db: dynamodb.New(dynamodb.Options{
+				Region:       "local",
+				BaseEndpoint: aws.String(server.URL),
+				Credentials:  aws.AnonymousCredentials{},
+				Retryer:      aws.NopRetryer{},
+			}),
+			TableName: "localeyes",
+		},
+	}
+	file := filepath.Join(t.TempDir(), "dump.jsonl")
+	if err := dumpData(c, []string{"-pk", "post:p1", "-file", file}); err != nil {
+		t.Fatal(err)
+	}
+	table.items = nil
+	if err := restoreData(c, []string{"-file", file}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(table.items, post) {
+		t.Fatalf("restored %v, want %v", table.items, post)
+	}
+}
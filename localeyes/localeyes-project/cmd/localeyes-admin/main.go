@@ -0,0 +1,179 @@
+// Command localeyes-admin operates the localeyes table from a terminal. It goes through the same
+// repositories and services as the API, so changes are audited and keep the denormalized items
+// in step. The table is chosen with TABLE_NAME and DYNAMO_REGION, like the Lambda, and
+// DYNAMO_ENDPOINT points it at DynamoDB Local.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"localeyes/config"
+	"localeyes/internal/jobqueue"
+	"localeyes/internal/migrations"
+	"localeyes/internal/repositories"
+	"localeyes/internal/services"
+	"os"
+	"sort"
+	"strings"
+)
+
+type command struct {
+	usage string
+	run   func(c *cli, args []string) error
+}
+
+var commands = map[string]command{
+	"users list":              {"[-email e] [-username prefix] [-city c] [-tag t] [-status s] [-sort created_at|username] [-order asc|desc] [-from YYYY-MM-DD] [-to YYYY-MM-DD] [-limit n] [-cursor c]", listUsers},
+	"users get":               {"<user_id>", getUser},
+	"users reactivate":        {"<user_id> -reason r", reactivateUser},
+	"users suspend":           {"<user_id> -until RFC3339 -reason r", suspendUser},
+	"users delete":            {"<user_id> -reason r", deleteUser},
+	"content delete-post":     {"<user_id> <post_id> -reason r", deletePost},
+	"content delete-question": {"<post_id> <question_id> <user_id> -reason r", deleteQuestion},
+	"content delete-answer":   {"<question_id> <answer_id> <user_id> -reason r", deleteAnswer},
+	"content delete-user":     {"<user_id> -reason r [-dry-run]", deleteUserContent},
+	"otp rotate":              {"<email>", rotateOTP},
+	"post inspect":            {"<post_id>", inspectPost},
+	"data dump":               {"[-pk partition]... [-prefix pk_prefix] [-file out.jsonl]", dumpData},
+	"data restore":            {"[-file in.jsonl] [-dry-run]", restoreData},
+	"table create":            {"", createTable},
+	"migrate status":          {"", migrationStatus},
+	"migrate up":              {"[-to version]", migrateUp},
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: localeyes-admin [-o table|json] [-actor name] <group> <command> [arguments]")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %s %s\n", name, commands[name].usage)
+	}
+}
+
+func main() {
+	flags := flag.NewFlagSet("localeyes-admin", flag.ExitOnError)
+	format := flags.String("o", "table", "output format, table or json")
+	actor := flags.String("actor", os.Getenv("USER"), "operator recorded as the actor of audited changes")
+	flags.Usage = usage
+	_ = flags.Parse(os.Args[1:])
+	args := flags.Args()
+	if len(args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	cmd, ok := commands[args[0]+" "+args[1]]
+	if !ok {
+		usage()
+		os.Exit(2)
+	}
+	if *format != "table" && *format != "json" {
+		fmt.Fprintln(os.Stderr, "output format must be table or json")
+		os.Exit(2)
+	}
+	if os.Getenv("TABLE_NAME") == "" {
+		fmt.Fprintln(os.Stderr, "TABLE_NAME is not set")
+		os.Exit(2)
+	}
+	c := newCLI(*format, *actor)
+	if err := cmd.run(c, args[2:]); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+type cli struct {
+	ctx    context.Context
+	format string
+	admin  *services.AdminService
+	users  *repositories.UserRepository
+	posts  *repositories.PostRepository
+	otps   *repositories.OtpRepository
+	jobs   *repositories.JobRepository
+	table  *repositories.TableRepository
+	db     *dynamodb.Client
+
+	migrations *migrations.Runner
+}
+
+func newCLI(format, actor string) *cli {
+	client := config.GetDBClient()
+	userRepo := repositories.NewNoSQLUserRepository(client)
+	postRepo := repositories.NewPostRepository(client)
+	quesRepo := repositories.NewQuestionRepository(client)
+	auditRepo := repositories.NewAuditRepository(client)
+	jobRepo := repositories.NewJobRepository(client)
+	// jobs run in this process, the command waits for them to finish
+	jobQueue := jobqueue.NewLocalQueue()
+	exportBuilder := services.NewExportBuilder(
+		userRepo,
+		postRepo,
+		quesRepo,
+		repositories.NewAnswerRepository(client),
+		repositories.NewExportRepository(client),
+		jobRepo,
+		jobQueue,
+	)
+	adminService := services.NewAdminService(
+		userRepo,
+		postRepo,
+		quesRepo,
+		repositories.NewAnswerRepository(client),
+		repositories.NewCommentRepository(client),
+		repositories.NewReactionRepository(client),
+		repositories.NewBookmarkRepository(client),
+		repositories.NewFollowRepository(client),
+		repositories.NewRelationRepository(client),
+		repositories.NewReportRepository(client),
+		auditRepo,
+		jobRepo,
+		jobQueue,
+		repositories.NewStatsRepository(client),
+		exportBuilder,
+	)
+	jobQueue.Run = services.NewJobWorker(jobRepo, jobQueue, adminService).Run
+	// changes made from the terminal are audited like those of an admin on the API
+	ctx := context.WithValue(context.Background(), "Id", "cli:"+actor)
+	ctx = context.WithValue(ctx, "Role", "admin")
+	return &cli{
+		ctx:    ctx,
+		format: format,
+		admin:  adminService,
+		users:  userRepo,
+		posts:  postRepo,
+		otps:   repositories.NewOtpRepository(client),
+		jobs:   jobRepo,
+		table:  repositories.NewTableRepository(client),
+		db:     client,
+
+		migrations: migrations.NewRunner(
+			repositories.NewMigrationRepository(client),
+			migrations.Registered(userRepo, postRepo, quesRepo, auditRepo),
+		),
+	}
+}
+
+// parse reads the flags of a command, which may come before or after its positional arguments,
+// and checks that exactly positional arguments remain
+func parse(flags *flag.FlagSet, args []string, positional int) ([]string, error) {
+	var rest []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+		args = flags.Args()
+		if len(args) == 0 {
+			break
+		}
+		rest = append(rest, args[0])
+		args = args[1:]
+	}
+	if len(rest) != positional {
+		return nil, fmt.Errorf("expected %d arguments, got %q", positional, strings.Join(rest, " "))
+	}
+	return rest, nil
+}
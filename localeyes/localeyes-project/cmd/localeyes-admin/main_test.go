@@ -0,0 +1,47 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		positional int
+		rest       []string
+		reason     string
+		fails      bool
+	}{
+		{"flags first", []string{"-reason", "spam", "p1"}, 1, []string{"p1"}, "spam", false},
+		{"flags last", []string{"p1", "-reason", "spam"}, 1, []string{"p1"}, "spam", false},
+		{"flags between", []string{"u1", "-reason=spam", "p1"}, 2, []string{"u1", "p1"}, "spam", false},
+		{"no flags", []string{"p1"}, 1, []string{"p1"}, "", false},
+		{"missing argument", []string{"-reason", "spam"}, 1, nil, "", true},
+		{"extra argument", []string{"p1", "p2"}, 1, nil, "", true},
+		{"unknown flag", []string{"-force", "p1"}, 1, nil, "", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			flags := flag.NewFlagSet("test", flag.ContinueOnError)
+			flags.SetOutput(io.Discard)
+			reason := flags.String("reason", "", "")
+			rest, err := parse(flags, test.args, test.positional)
+			if test.fails {
+				if err == nil {
+					t.Fatalf("parse(%q) = %q, want an error", test.args, rest)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parse(%q): %v", test.args, err)
+			}
+			if !reflect.DeepEqual(rest, test.rest) || *reason != test.reason {
+				t.Fatalf("parse(%q) = %q with reason %q, want %q with reason %q", test.args, rest, *reason, test.rest, test.reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"localeyes/internal/migrations"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// createTable bootstraps the table named by TABLE_NAME, point DYNAMO_ENDPOINT at DynamoDB Local
+// to set up a development table
+func createTable(c *cli, args []string) error {
+	if _, err := parse(flag.NewFlagSet("table create", flag.ContinueOnError), args, 0); err != nil {
+		return err
+	}
+	created, err := migrations.EnsureTable(c.ctx, c.db, os.Getenv("TABLE_NAME"), os.Getenv("INDEX_NAME"))
+	if err != nil {
+		return err
+	}
+	if len(created) == 0 {
+		return c.printDone("table is up to date")
+	}
+	return c.printDone("created " + strings.Join(created, ", "))
+}
+
+func migrationStatus(c *cli, args []string) error {
+	if _, err := parse(flag.NewFlagSet("migrate status", flag.ContinueOnError), args, 0); err != nil {
+		return err
+	}
+	statuses, err := c.migrations.Status(c.ctx)
+	if err != nil {
+		return err
+	}
+	rows := make([][]string, 0, len(statuses))
+	for _, status := range statuses {
+		row := []string{strconv.Itoa(status.Version), status.Name, "pending", "", "", ""}
+		if status.Applied != nil {
+			row[2] = "applied"
+			row[3] = status.Applied.AppliedAt.Format(time.RFC3339)
+			row[4] = status.Applied.AppliedBy
+			row[5] = strconv.Itoa(status.Applied.Items)
+		}
+		rows = append(rows, row)
+	}
+	return c.print(statuses, []string{"VERSION", "NAME", "STATUS", "APPLIED", "BY", "ITEMS"}, rows)
+}
+
+func migrateUp(c *cli, args []string) error {
+	flags := flag.NewFlagSet("migrate up", flag.ContinueOnError)
+	target := flags.Int("to", 0, "last version to apply, every pending one when 0")
+	if _, err := parse(flags, args, 0); err != nil {
+		return err
+	}
+	actor := c.ctx.Value("Id").(string)
+	records, err := c.migrations.Up(c.ctx, *target, actor, func(migration migrations.Migration) {
+		fmt.Fprintf(os.Stderr, "applying %d %s\n", migration.Version, migration.Name)
+	})
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return c.printDone("no pending migrations")
+	}
+	rows := make([][]string, 0, len(records))
+	for _, record := range records {
+		rows = append(rows, []string{strconv.Itoa(record.Version), record.Name, strconv.Itoa(record.Items)})
+	}
+	return c.print(records, []string{"VERSION", "NAME", "ITEMS"}, rows)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"localeyes/config"
+	"localeyes/internal/models"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// print writes v as JSON, or as a table of headers and rows
+func (c *cli) print(v any, headers []string, rows [][]string) error {
+	if c.format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(v)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(headers, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	return w.Flush()
+}
+
+func (c *cli) printDone(message string) error {
+	return c.print(map[string]string{"message": message}, []string{"RESULT"}, [][]string{{message}})
+}
+
+func (c *cli) printUsers(v any, users []*models.ResponseUser) error {
+	rows := make([][]string, 0, len(users))
+	for _, user := range users {
+		createdAt := ""
+		if user.CreatedAt != nil {
+			createdAt = user.CreatedAt.Format(time.RFC3339)
+		}
+		rows = append(rows, []string{user.UId, user.Username, user.Email, user.City, user.Tag, string(user.Status), createdAt})
+	}
+	return c.print(v, []string{"ID", "USERNAME", "EMAIL", "CITY", "TAG", "STATUS", "CREATED"}, rows)
+}
+
+func (c *cli) printJob(job *models.Job) error {
+	rows := [][]string{{job.Id, string(job.Kind), string(job.Status), job.Stage, strconv.Itoa(job.Processed) + "/" + strconv.Itoa(job.Total), strconv.Itoa(job.Failed), job.Error}}
+	if err := c.print(job, []string{"JOB", "KIND", "STATUS", "STAGE", "PROCESSED", "FAILED", "ERROR"}, rows); err != nil {
+		return err
+	}
+	if c.format == "json" || len(job.Items) == 0 {
+		return nil
+	}
+	fmt.Println()
+	items := make([][]string, 0, len(job.Items))
+	for _, item := range job.Items {
+		items = append(items, []string{string(item.Type), item.Id, item.ParentId, item.OwnerId, string(item.Status), item.Error})
+	}
+	return c.print(nil, []string{"TYPE", "ID", "PARENT", "USER", "STATUS", "ERROR"}, items)
+}
+
+// waitJob follows a background job until it finishes. The job runs inside this process, so
+// the command has to stay alive until then.
+func (c *cli) waitJob(job *models.Job) (*models.Job, error) {
+	for job.Status != config.JobSucceeded && job.Status != config.JobFailed {
+		time.Sleep(500 * time.Millisecond)
+		current, err := c.jobs.GetJob(c.ctx, job.Id)
+		if err != nil {
+			return nil, err
+		}
+		if current.Processed != job.Processed || current.Stage != job.Stage {
+			fmt.Fprintf(os.Stderr, "%s: %d/%d processed\n", current.Stage, current.Processed, current.Total)
+		}
+		job = current
+	}
+	return job, nil
+}
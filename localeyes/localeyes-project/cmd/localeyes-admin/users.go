@@ -0,0 +1,157 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"localeyes/config"
+	"localeyes/internal/models"
+	"localeyes/utils"
+	"os"
+	"strings"
+	"time"
+)
+
+func listUsers(c *cli, args []string) error {
+	flags := flag.NewFlagSet("users list", flag.ContinueOnError)
+	search := &models.UserSearch{}
+	flags.StringVar(&search.Email, "email", "", "exact email")
+	flags.StringVar(&search.UsernamePrefix, "username", "", "username prefix")
+	flags.StringVar(&search.City, "city", "", "city")
+	flags.StringVar(&search.Tag, "tag", "", "tag")
+	status := flags.String("status", "", "account status")
+	sortBy := flags.String("sort", "", "created_at or username")
+	order := flags.String("order", "asc", "asc or desc")
+	from := flags.String("from", "", "signed up on or after, YYYY-MM-DD")
+	to := flags.String("to", "", "signed up on or before, YYYY-MM-DD")
+	flags.IntVar(&search.Limit, "limit", config.DefaultPageSize, "page size")
+	flags.StringVar(&search.Cursor, "cursor", "", "cursor of the next page")
+	if _, err := parse(flags, args, 0); err != nil {
+		return err
+	}
+	search.Status = config.AccountStatus(strings.ToUpper(*status))
+	search.Sort = config.UserSort(*sortBy)
+	if search.Sort == "" {
+		search.Sort = config.SortBySignup
+		if search.UsernamePrefix != "" {
+			search.Sort = config.SortByUsername
+		}
+	}
+	search.Desc = *order == "desc"
+	search.Limit = min(max(search.Limit, 1), config.MaxPageSize)
+	var err error
+	if *from != "" {
+		if search.From, err = time.Parse(config.StatsDayLayout, *from); err != nil {
+			return err
+		}
+	}
+	if *to != "" {
+		if search.To, err = time.Parse(config.StatsDayLayout, *to); err != nil {
+			return err
+		}
+		search.To = search.To.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+	result, err := c.admin.SearchUsers(c.ctx, search)
+	if err != nil {
+		return err
+	}
+	if err := c.printUsers(result, result.Users); err != nil {
+		return err
+	}
+	if c.format == "table" && result.NextCursor != "" {
+		fmt.Fprintln(os.Stderr, "next cursor:", result.NextCursor)
+	}
+	return nil
+}
+
+func getUser(c *cli, args []string) error {
+	rest, err := parse(flag.NewFlagSet("users get", flag.ContinueOnError), args, 1)
+	if err != nil {
+		return err
+	}
+	users, err := c.users.FetchUsersByIds(c.ctx, rest)
+	if err != nil {
+		return err
+	}
+	user, ok := users[rest[0]]
+	if !ok {
+		return utils.NoUser
+	}
+	response := &models.ResponseUser{
+		UId:            user.UId,
+		Username:       user.Username,
+		Email:          user.Email,
+		City:           user.City,
+		LivingSince:    user.DwellingAge,
+		Tag:            user.Tag,
+		ActiveStatus:   user.IsActive,
+		Status:         user.CurrentStatus(user.IsActive),
+		StatusReason:   user.StatusReason,
+		SuspendedUntil: user.SuspendedUntil,
+		CreatedAt:      user.CreatedAt,
+	}
+	return c.printUsers(response, []*models.ResponseUser{response})
+}
+
+func reactivateUser(c *cli, args []string) error {
+	flags := flag.NewFlagSet("users reactivate", flag.ContinueOnError)
+	reason := flags.String("reason", "", "reason recorded in the audit log")
+	rest, err := parse(flags, args, 1)
+	if err != nil {
+		return err
+	}
+	if err := c.admin.ReactivateUser(c.ctx, rest[0], *reason); err != nil {
+		return err
+	}
+	return c.printDone("reactivated " + rest[0])
+}
+
+func suspendUser(c *cli, args []string) error {
+	flags := flag.NewFlagSet("users suspend", flag.ContinueOnError)
+	reason := flags.String("reason", "", "reason recorded in the audit log")
+	until := flags.String("until", "", "end of the suspension, RFC3339")
+	rest, err := parse(flags, args, 1)
+	if err != nil {
+		return err
+	}
+	end, err := time.Parse(time.RFC3339, *until)
+	if err != nil {
+		return err
+	}
+	if !end.After(time.Now()) {
+		return errors.New("the suspension has to end in the future")
+	}
+	if *reason == "" {
+		return errors.New("a reason is required")
+	}
+	if err := c.admin.SuspendUser(c.ctx, rest[0], end, *reason); err != nil {
+		return err
+	}
+	return c.printDone("suspended " + rest[0] + " until " + end.Format(time.RFC3339))
+}
+
+func deleteUser(c *cli, args []string) error {
+	flags := flag.NewFlagSet("users delete", flag.ContinueOnError)
+	reason := flags.String("reason", "", "reason recorded in the audit log")
+	rest, err := parse(flags, args, 1)
+	if err != nil {
+		return err
+	}
+	users, err := c.users.FetchUsersByIds(c.ctx, rest)
+	if err != nil {
+		return err
+	}
+	user, ok := users[rest[0]]
+	if !ok {
+		return utils.NoUser
+	}
+	job, err := c.admin.DeleteUser(c.ctx, &models.DeleteUser{UId: user.UId, Username: user.Username, Email: user.Email}, *reason)
+	if err != nil {
+		return err
+	}
+	job, err = c.waitJob(job)
+	if err != nil {
+		return err
+	}
+	return c.printJob(job)
+}
@@ -1,5 +1,7 @@
 package config
 
+import "time"
+
 const (
 	UserTable            = "users"
 	PostTable            = "posts"
@@ -15,3 +17,90 @@ const (
 	DeleteWith2Condition = "DELETE FROM %s WHERE %s= ? AND %s= ?"
 	Count                = "SELECT COUNT(*) AS total_records FROM %s"
 )
+
+// MaxCommentDepth bounds how deeply comment replies can nest, top level comments have depth 0
+const MaxCommentDepth = 5
+
+// SortableTime is a fixed width UTC layout so timestamps used in sort keys order lexically
+const SortableTime = "2006-01-02T15:04:05.000000000Z"
+
+// DefaultPageSize and MaxPageSize bound cursor paginated listings
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// AutoHideReports is the number of distinct reporters after which content is hidden
+// until an admin resolves its report
+const AutoHideReports = 3
+
+// FeedFetchConcurrency bounds how many followed users have their posts loaded at once
+const FeedFetchConcurrency = 10
+
+// AccountCheckTTL is how long an account that was allowed to use the API is not read again,
+// it bounds how long a token outlives a suspension, ban or deactivation
+const AccountCheckTTL = 30 * time.Second
+
+// JobProgressInterval is how many processed items a background job handles between saving its progress
+const JobProgressInterval = 25
+
+// JobLease is how long a job worker without a deadline owns a job, and JobSliceMargin how long
+// before its deadline a worker stops, saves where it got to and queues the rest of the job
+const (
+	JobLease       = 15 * time.Minute
+	JobSliceMargin = 30 * time.Second
+)
+
+// MaxBulkItems bounds how many items one bulk admin job touches, its per-item results are kept
+// on the job item and have to stay below DynamoDB's 400KB item limit
+const MaxBulkItems = 500
+
+// ExportLinkTTL is how long the download token of a personal data export stays valid
+const ExportLinkTTL = 24 * time.Hour
+
+// ExportChunkSize keeps every stored piece of an export archive below DynamoDB's 400KB item limit
+const ExportChunkSize = 350 * 1024
+
+// StatsDayLayout and StatsWeekLayout name the buckets of the admin statistics, both sort lexically
+const (
+	StatsDayLayout  = "2006-01-02"
+	StatsWeekLayout = "%d-W%02d"
+)
+
+// StatsTotalShards is how many items the all time counters are spread over, every write adds to
+// one of them at random so no single item takes the writes of the whole site
+const StatsTotalShards = 10
+
+// AuditDayLayout names the day partitions of the audit log, it sorts lexically
+const AuditDayLayout = "2006-01-02"
+
+// AuditLookbackDays is how far back the whole audit log is read when no range is given
+const AuditLookbackDays = 90
+
+// ActiveMarkerTTL is how long the marker that a user was active in a bucket is kept, it only
+// has to outlive the longest bucket
+const ActiveMarkerTTL = 8 * 24 * time.Hour
+
+// DefaultStatsDays is how far back the statistics go when no range is given
+const DefaultStatsDays = 30
+
+// AnyVersion skips the version check of a conditional write, the version is still bumped
+const AnyVersion = -1
+
+// IdempotencyTTL is how long the response to a request carrying an Idempotency-Key is kept
+// for replay, and MaxIdempotencyKey bounds the length of the key
+const (
+	IdempotencyTTL    = 24 * time.Hour
+	MaxIdempotencyKey = 255
+)
+
+// AlertWindow is how long an alert group is sent at most once for, and AlertQueueSize how many
+// distinct groups can wait for a flush before new ones are dropped
+const (
+	AlertWindow    = time.Minute
+	AlertQueueSize = 256
+)
+
+// AlertFlushMargin is left of the invocation deadline when alerts are flushed after a response,
+// so a slow alert channel cannot time the invocation out and lose the response
+const AlertFlushMargin = 500 * time.Millisecond
@@ -2,11 +2,14 @@ package config
 
 import (
 	"context"
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"os"
 )
 
+// GetDBClient connects to DynamoDB in DYNAMO_REGION, or to the endpoint in DYNAMO_ENDPOINT
+// when it is set, such as a DynamoDB Local at http://localhost:8000
 func GetDBClient() *dynamodb.Client {
 	cfg, err := config.LoadDefaultConfig(context.TODO(),
 		config.WithRegion(os.Getenv("DYNAMO_REGION")),
@@ -14,5 +17,9 @@ func GetDBClient() *dynamodb.Client {
 	if err != nil {
 		panic("Failed to load AWS configuration: " + err.Error())
 	}
-	return dynamodb.NewFromConfig(cfg)
+	return dynamodb.NewFromConfig(cfg, func(o *dynamodb.Options) {
+		if endpoint := os.Getenv("DYNAMO_ENDPOINT"); endpoint != "" {
+			o.BaseEndpoint = aws.String(endpoint)
+		}
+	})
 }
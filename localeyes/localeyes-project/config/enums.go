@@ -2,6 +2,23 @@ package config
 
 type Filter string
 type LikeStatus string
+type VoteType string
+type ContentType string
+type ReactionType string
+type ReportReason string
+type ReportStatus string
+type AuditAction string
+type AccountStatus string
+type JobKind string
+type JobItemStatus string
+type BulkAction string
+type JobStatus string
+type ExportFormat string
+type StatsMetric string
+type StatsGranularity string
+type UserSort string
+type IdempotencyStatus string
+type RateLimitClass string
 
 // Use constants for string-based enums
 const (
@@ -14,3 +31,142 @@ const (
 	Liked    LikeStatus = "LIKED"
 	NotLiked LikeStatus = "NOT_LIKED"
 )
+
+const (
+	Upvote   VoteType = "UP"
+	Downvote VoteType = "DOWN"
+	NoVote   VoteType = "NONE"
+)
+
+const (
+	PostContent     ContentType = "post"
+	QuestionContent ContentType = "question"
+	AnswerContent   ContentType = "reply"
+	UserContent     ContentType = "user"
+	CommentContent  ContentType = "comment"
+)
+
+const (
+	Like       ReactionType = "LIKE"
+	Helpful    ReactionType = "HELPFUL"
+	Love       ReactionType = "LOVE"
+	Funny      ReactionType = "FUNNY"
+	Disagree   ReactionType = "DISAGREE"
+	NoReaction ReactionType = "NONE"
+)
+
+var ReactionTypes = []ReactionType{Like, Helpful, Love, Funny, Disagree}
+
+const (
+	Spam           ReportReason = "SPAM"
+	Harassment     ReportReason = "HARASSMENT"
+	HateSpeech     ReportReason = "HATE_SPEECH"
+	Misinformation ReportReason = "MISINFORMATION"
+	Inappropriate  ReportReason = "INAPPROPRIATE"
+	OtherReason    ReportReason = "OTHER"
+)
+
+var ReportReasons = []ReportReason{Spam, Harassment, HateSpeech, Misinformation, Inappropriate, OtherReason}
+
+const (
+	ReportOpen      ReportStatus = "OPEN"
+	ReportActioned  ReportStatus = "ACTIONED"
+	ReportDismissed ReportStatus = "DISMISSED"
+)
+
+const (
+	AuditDeleteUser     AuditAction = "DELETE_USER"
+	AuditReactivateUser AuditAction = "REACTIVATE_USER"
+	AuditDeletePost     AuditAction = "DELETE_POST"
+	AuditDeleteQuestion AuditAction = "DELETE_QUESTION"
+	AuditDeleteAnswer   AuditAction = "DELETE_ANSWER"
+	AuditResolveReport  AuditAction = "RESOLVE_REPORT"
+	AuditLogin          AuditAction = "LOGIN"
+	AuditLoginFailed    AuditAction = "LOGIN_FAILED"
+	AuditPasswordReset  AuditAction = "PASSWORD_RESET"
+	AuditDeactivate     AuditAction = "DEACTIVATE"
+	AuditSuspendUser    AuditAction = "SUSPEND_USER"
+	AuditBanUser        AuditAction = "BAN_USER"
+	AuditUnbanUser      AuditAction = "UNBAN_USER"
+	AuditReinstateUser  AuditAction = "REINSTATE_USER"
+)
+
+const (
+	AccountActive              AccountStatus = "ACTIVE"
+	AccountDeactivated         AccountStatus = "DEACTIVATED"
+	AccountSuspended           AccountStatus = "SUSPENDED"
+	AccountBanned              AccountStatus = "BANNED"
+	AccountPendingVerification AccountStatus = "PENDING_VERIFICATION"
+)
+
+const (
+	DeleteAccountJob     JobKind = "DELETE_ACCOUNT"
+	ExportJob            JobKind = "EXPORT"
+	BulkDeleteContentJob JobKind = "BULK_DELETE_CONTENT"
+	BulkDeletePostsJob   JobKind = "BULK_DELETE_POSTS"
+	BulkAccountJob       JobKind = "BULK_ACCOUNT"
+)
+
+const (
+	ItemPlanned JobItemStatus = "PLANNED"
+	ItemDone    JobItemStatus = "DONE"
+	ItemFailed  JobItemStatus = "FAILED"
+)
+
+const (
+	BulkReactivate BulkAction = "reactivate"
+	BulkSuspend    BulkAction = "suspend"
+)
+
+const (
+	JobPending   JobStatus = "PENDING"
+	JobRunning   JobStatus = "RUNNING"
+	JobSucceeded JobStatus = "SUCCEEDED"
+	JobFailed    JobStatus = "FAILED"
+)
+
+const (
+	JSONExport ExportFormat = "json"
+	ZipExport  ExportFormat = "zip"
+)
+
+const (
+	SignupsMetric       StatsMetric = "signups"
+	ActiveUsersMetric   StatsMetric = "active_users"
+	FoodPostsMetric     StatsMetric = "posts_food"
+	TravelPostsMetric   StatsMetric = "posts_travel"
+	ShoppingPostsMetric StatsMetric = "posts_shopping"
+	QuestionsMetric     StatsMetric = "questions"
+	AnswersMetric       StatsMetric = "answers"
+	LikesMetric         StatsMetric = "likes"
+	ReportsMetric       StatsMetric = "reports"
+	DeactivationsMetric StatsMetric = "deactivations"
+)
+
+// PostMetrics counts new posts per category
+var PostMetrics = map[Filter]StatsMetric{
+	Food:     FoodPostsMetric,
+	Travel:   TravelPostsMetric,
+	Shopping: ShoppingPostsMetric,
+}
+
+const (
+	StatsDay  StatsGranularity = "day"
+	StatsWeek StatsGranularity = "week"
+)
+
+const (
+	SortBySignup   UserSort = "created_at"
+	SortByUsername UserSort = "username"
+)
+
+const (
+	IdempotencyInProgress IdempotencyStatus = "IN_PROGRESS"
+	IdempotencyCompleted  IdempotencyStatus = "COMPLETED"
+)
+
+const (
+	AuthRateLimit  RateLimitClass = "auth"
+	WriteRateLimit RateLimitClass = "write"
+	ReadRateLimit  RateLimitClass = "read"
+)
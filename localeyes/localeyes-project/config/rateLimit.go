@@ -0,0 +1,48 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RateLimitPolicy is a token bucket holding up to Limit requests that refills completely over Window
+type RateLimitPolicy struct {
+	Class  RateLimitClass
+	Limit  int
+	Window time.Duration
+}
+
+// defaultRateLimits are strict for the unauthenticated account routes that can be used to guess
+// passwords or send mail, and looser for everything behind a token
+var defaultRateLimits = map[RateLimitClass]RateLimitPolicy{
+	AuthRateLimit:  {AuthRateLimit, 10, 10 * time.Minute},
+	WriteRateLimit: {WriteRateLimit, 60, time.Minute},
+	ReadRateLimit:  {ReadRateLimit, 300, time.Minute},
+}
+
+// RateLimitPolicies returns the policy of every class. A class is configured through
+// RATE_LIMIT_<CLASS> holding "<limit>/<window>", e.g. RATE_LIMIT_AUTH="5/1m".
+func RateLimitPolicies() (map[RateLimitClass]RateLimitPolicy, error) {
+	policies := make(map[RateLimitClass]RateLimitPolicy, len(defaultRateLimits))
+	for class, policy := range defaultRateLimits {
+		name := "RATE_LIMIT_" + strings.ToUpper(string(class))
+		if value := os.Getenv(name); value != "" {
+			limit, window, ok := strings.Cut(value, "/")
+			if !ok {
+				return nil, fmt.Errorf("%s must be <limit>/<window>, got %q", name, value)
+			}
+			var err error
+			if policy.Limit, err = strconv.Atoi(limit); err != nil || policy.Limit <= 0 {
+				return nil, fmt.Errorf("%s has an invalid limit %q", name, limit)
+			}
+			if policy.Window, err = time.ParseDuration(window); err != nil || policy.Window <= 0 {
+				return nil, fmt.Errorf("%s has an invalid window %q", name, window)
+			}
+		}
+		policies[class] = policy
+	}
+	return policies, nil
+}
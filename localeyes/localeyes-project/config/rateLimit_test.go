@@ -0,0 +1,44 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimitPolicies(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  string
+		policy RateLimitPolicy
+		fails  bool
+	}{
+		{"default", "", defaultRateLimits[AuthRateLimit], false},
+		{"configured", "5/1m", RateLimitPolicy{AuthRateLimit, 5, time.Minute}, false},
+		{"no window", "5", RateLimitPolicy{}, true},
+		{"zero limit", "0/1m", RateLimitPolicy{}, true},
+		{"bad limit", "five/1m", RateLimitPolicy{}, true},
+		{"bad window", "5/minute", RateLimitPolicy{}, true},
+		{"negative window", "5/-1m", RateLimitPolicy{}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv("RATE_LIMIT_AUTH", test.value)
+			policies, err := RateLimitPolicies()
+			if test.fails {
+				if err == nil {
+					t.Fatalf("RATE_LIMIT_AUTH=%q was accepted", test.value)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RATE_LIMIT_AUTH=%q: %v", test.value, err)
+			}
+			if policies[AuthRateLimit] != test.policy {
+				t.Fatalf("policy = %+v, want %+v", policies[AuthRateLimit], test.policy)
+			}
+			if policies[ReadRateLimit] != defaultRateLimits[ReadRateLimit] {
+				t.Fatalf("read policy = %+v, want the default", policies[ReadRateLimit])
+			}
+		})
+	}
+}
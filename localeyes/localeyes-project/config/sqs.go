@@ -0,0 +1,21 @@
+package config
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"os"
+)
+
+func InitSQS() (*sqs.Client, string, error) {
+	cfg, err := config.LoadDefaultConfig(context.Background(),
+		config.WithRegion(os.Getenv("SQS_REGION")),
+	)
+	if err != nil {
+		return nil, "", err
+	}
+
+	SQSClient := sqs.NewFromConfig(cfg)
+	QueueUrl := os.Getenv("JOB_QUEUE_URL")
+	return SQSClient, QueueUrl, nil
+}
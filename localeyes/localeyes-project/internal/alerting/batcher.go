@@ -0,0 +1,113 @@
+package alerting
+
+import (
+	"context"
+	"go.uber.org/zap"
+	"localeyes/config"
+	"localeyes/internal/interfaces"
+	"localeyes/internal/models"
+	"localeyes/utils"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// maxSampleIds bounds how many request ids are kept per group
+const maxSampleIds = 5
+
+// sendTimeout bounds how long sending the groups of one flush may take when the flushing
+// context has no deadline of its own
+const sendTimeout = 10 * time.Second
+
+// Fingerprint groups alerts caused by the same fault, the same error on the same route. The
+// message is left out because it often carries ids that differ between requests.
+func Fingerprint(alert *models.Alert) string {
+	return alert.Code + "|" + strconv.Itoa(alert.Status) + "|" + alert.Route
+}
+
+// batcher collects the alerts raised while a request is served and sends them grouped by
+// fingerprint when it is flushed, which the Lambda handler does once the response is ready so
+// nothing is left behind in an execution environment that is frozen afterwards. Raising an
+// alert never waits on the network. Every group is claimed for its fingerprint and window in
+// the table first, so an outage failing requests in many execution environments is sent once
+// per window instead of once per environment or request.
+type batcher struct {
+	mu      sync.Mutex
+	groups  map[string]*models.AlertGroup
+	order   []*models.AlertGroup
+	window  time.Duration
+	windows interfaces.AlertRepoInterface
+	send    func(ctx context.Context, groups []*models.AlertGroup) error
+}
+
+func newBatcher(window time.Duration, windows interfaces.AlertRepoInterface, send func(ctx context.Context, groups []*models.AlertGroup) error) *batcher {
+	return &batcher{
+		groups:  make(map[string]*models.AlertGroup),
+		window:  window,
+		windows: windows,
+		send:    send,
+	}
+}
+
+func (b *batcher) Alert(alert *models.Alert) {
+	fingerprint := Fingerprint(alert)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	group, ok := b.groups[fingerprint]
+	if !ok {
+		if len(b.order) >= config.AlertQueueSize {
+			utils.Logger.Warn("Alert queue is full, dropping alert", zap.String("code", alert.Code), zap.String("route", alert.Route))
+			return
+		}
+		group = &models.AlertGroup{Fingerprint: fingerprint, First: alert}
+		b.groups[fingerprint] = group
+		b.order = append(b.order, group)
+	}
+	group.Count++
+	group.LastSeen = alert.Time
+	if alert.RequestId != "" && len(group.RequestIds) < maxSampleIds {
+		group.RequestIds = append(group.RequestIds, alert.RequestId)
+	}
+}
+
+// Flush sends the groups collected since the last flush, leaving out those already sent for
+// their window. A group whose window cannot be claimed is sent anyway, a duplicate alert is
+// better than a lost one. Sending stops AlertFlushMargin before the deadline of ctx, groups
+// that are not sent by then are logged instead.
+func (b *batcher) Flush(ctx context.Context) {
+	b.mu.Lock()
+	pending := b.order
+	b.groups = make(map[string]*models.AlertGroup)
+	b.order = nil
+	b.mu.Unlock()
+	if len(pending) == 0 {
+		return
+	}
+	deadline := time.Now().Add(sendTimeout)
+	if end, ok := ctx.Deadline(); ok {
+		deadline = end.Add(-config.AlertFlushMargin)
+	}
+	if time.Until(deadline) <= 0 {
+		utils.Logger.Error("No time left to send alerts", zap.Int("groups", len(pending)))
+		return
+	}
+	ctx, cancel := context.WithDeadline(ctx, deadline)
+	defer cancel()
+	due := make([]*models.AlertGroup, 0, len(pending))
+	for _, group := range pending {
+		claimed, err := b.windows.ClaimWindow(ctx, group.Fingerprint, group.First.Time.Truncate(b.window))
+		if err != nil {
+			utils.Logger.Error("Error claiming alert window", zap.String("fingerprint", group.Fingerprint), zap.Error(err))
+			claimed = true
+		}
+		if claimed {
+			due = append(due, group)
+		}
+	}
+	if len(due) == 0 {
+		return
+	}
+	if err := b.send(ctx, due); err != nil {
+		utils.Logger.Error("Error sending alerts", zap.Int("groups", len(due)), zap.Error(err))
+	}
+}
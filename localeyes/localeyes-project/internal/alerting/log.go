@@ -0,0 +1,37 @@
+package alerting
+
+import (
+	"context"
+	"go.uber.org/zap"
+	"localeyes/internal/interfaces"
+	"localeyes/internal/models"
+	"localeyes/utils"
+	"time"
+)
+
+// LogAlerter writes the alert groups to the log, for running without an alerting topic
+type LogAlerter struct {
+	*batcher
+}
+
+func NewLogAlerter(window time.Duration, windows interfaces.AlertRepoInterface) *LogAlerter {
+	return &LogAlerter{newBatcher(window, windows, logGroups)}
+}
+
+func logGroups(ctx context.Context, groups []*models.AlertGroup) error {
+	for _, group := range groups {
+		utils.Logger.Error("Alert",
+			zap.String("fingerprint", group.Fingerprint),
+			zap.String("code", group.First.Code),
+			zap.Int("status", group.First.Status),
+			zap.String("message", group.First.Message),
+			zap.String("route", group.First.Route),
+			zap.String("user_id", group.First.UserId),
+			zap.Strings("request_ids", group.RequestIds),
+			zap.Int("count", group.Count),
+			zap.Time("first_seen", group.First.Time),
+			zap.Time("last_seen", group.LastSeen),
+		)
+	}
+	return nil
+}
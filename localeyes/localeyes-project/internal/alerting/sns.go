@@ -0,0 +1,63 @@
+package alerting
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sns/types"
+	"localeyes/internal/interfaces"
+	"localeyes/internal/models"
+	"strconv"
+	"time"
+)
+
+// SNSAlerter publishes one message per alert group to the alerting topic
+type SNSAlerter struct {
+	*batcher
+	client   *sns.Client
+	topicArn string
+}
+
+func NewSNSAlerter(client *sns.Client, topicArn string, window time.Duration, windows interfaces.AlertRepoInterface) *SNSAlerter {
+	alerter := &SNSAlerter{
+		client:   client,
+		topicArn: topicArn,
+	}
+	alerter.batcher = newBatcher(window, windows, alerter.publish)
+	return alerter
+}
+
+func (alerter *SNSAlerter) publish(ctx context.Context, groups []*models.AlertGroup) error {
+	var errs []error
+	for _, group := range groups {
+		messageBody, err := json.Marshal(group)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		_, err = alerter.client.Publish(ctx, &sns.PublishInput{
+			Message:  aws.String(string(messageBody)),
+			TopicArn: aws.String(alerter.topicArn),
+			MessageAttributes: map[string]types.MessageAttributeValue{
+				"ErrorCode": {
+					DataType:    aws.String("String"),
+					StringValue: aws.String(group.First.Code),
+				},
+				"StatusCode": {
+					DataType:    aws.String("Number"),
+					StringValue: aws.String(strconv.Itoa(group.First.Status)),
+				},
+				"Count": {
+					DataType:    aws.String("Number"),
+					StringValue: aws.String(strconv.Itoa(group.Count)),
+				},
+			},
+		})
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
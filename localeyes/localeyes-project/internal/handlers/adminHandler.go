@@ -4,11 +4,14 @@ import (
 	"encoding/json"
 	"github.com/go-playground/validator"
 	"github.com/gorilla/mux"
+	"localeyes/config"
 	"localeyes/internal/interfaces"
 	"localeyes/internal/models"
 	"localeyes/utils"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type AdminHandler struct {
@@ -34,8 +37,7 @@ func (handler *AdminHandler) GetAllUsers(w http.ResponseWriter, r *http.Request)
 
 	users, err := handler.service.GetAllUsers(r.Context(), *params)
 	if err != nil {
-		response := utils.NewInternalServerError("Error fetching all users" + err.Error())
-		response.ToJson(w, http.StatusInternalServerError)
+		utils.WriteError(w, r, err)
 		return
 	}
 	response := &models.Response{
@@ -47,46 +49,283 @@ func (handler *AdminHandler) GetAllUsers(w http.ResponseWriter, r *http.Request)
 	return
 }
 
+func (handler *AdminHandler) SearchUsers(w http.ResponseWriter, r *http.Request) {
+	queryParams := r.URL.Query()
+	search := &models.UserSearch{
+		Email:          queryParams.Get("email"),
+		UsernamePrefix: queryParams.Get("username"),
+		City:           queryParams.Get("city"),
+		Tag:            queryParams.Get("tag"),
+		Status:         config.AccountStatus(strings.ToUpper(queryParams.Get("status"))),
+		Sort:           config.UserSort(queryParams.Get("sort")),
+		Desc:           queryParams.Get("order") == "desc",
+	}
+	search.Limit, search.Cursor = pageParams(r)
+	switch search.Status {
+	case "", config.AccountActive, config.AccountDeactivated, config.AccountSuspended, config.AccountBanned, config.AccountPendingVerification:
+	default:
+		utils.WriteError(w, r, utils.InvalidInput("unknown account status %q", search.Status))
+		return
+	}
+	if search.Sort == "" {
+		// a username prefix is answered from the username index, everything else by signup time
+		search.Sort = config.SortBySignup
+		if search.UsernamePrefix != "" {
+			search.Sort = config.SortByUsername
+		}
+	}
+	if search.Sort != config.SortBySignup && search.Sort != config.SortByUsername {
+		utils.WriteError(w, r, utils.InvalidInput("sort must be %q or %q", config.SortBySignup, config.SortByUsername))
+		return
+	}
+	if order := queryParams.Get("order"); order != "" && order != "asc" && order != "desc" {
+		utils.WriteError(w, r, utils.InvalidInput("order must be \"asc\" or \"desc\""))
+		return
+	}
+	var err error
+	if from := queryParams.Get("from"); from != "" {
+		search.From, err = time.Parse(config.StatsDayLayout, from)
+		if err != nil {
+			utils.WriteError(w, r, utils.InvalidInput("from must be a date like %s", config.StatsDayLayout))
+			return
+		}
+	}
+	if to := queryParams.Get("to"); to != "" {
+		search.To, err = time.Parse(config.StatsDayLayout, to)
+		if err != nil {
+			utils.WriteError(w, r, utils.InvalidInput("to must be a date like %s", config.StatsDayLayout))
+			return
+		}
+		// the to date is inclusive
+		search.To = search.To.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+	if !search.From.IsZero() && !search.To.IsZero() && search.To.Before(search.From) {
+		utils.WriteError(w, r, utils.InvalidInput("from must not be after to"))
+		return
+	}
+	result, err := handler.service.SearchUsers(r.Context(), search)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+	response := &models.Response{
+		Message: "Successfully searched users",
+		Data:    result,
+		Code:    http.StatusOK,
+	}
+	response.ToJson(w, http.StatusOK)
+	return
+}
+
 func (handler *AdminHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 	userId := mux.Vars(r)["user_id"]
 	var user models.DeleteUser
 	err := json.NewDecoder(r.Body).Decode(&user)
 	if err != nil {
-		response := utils.NewBadRequestError("Invalid JSON body")
-		response.ToJson(w, http.StatusBadRequest)
+		utils.WriteError(w, r, utils.InvalidBody)
 		return
 	}
 	err = handler.validator.Struct(user)
 	if err != nil {
-		response := utils.NewBadRequestError("Invalid Input")
-		response.ToJson(w, http.StatusBadRequest)
+		utils.WriteError(w, r, utils.ValidationError(err))
 		return
 	}
 	user.UId = userId
-	err = handler.service.DeleteUser(r.Context(), &user)
+	job, err := handler.service.DeleteUser(r.Context(), &user, auditReason(r))
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+	response := models.Response{
+		Message: "Started deleting user",
+		Code:    http.StatusAccepted,
+		Data:    job,
+	}
+	response.ToJson(w, http.StatusAccepted)
+	return
+}
+
+func (handler *AdminHandler) ExportUser(w http.ResponseWriter, r *http.Request) {
+	userId := mux.Vars(r)["user_id"]
+	format, ok := exportFormat(r)
+	if !ok {
+		utils.WriteError(w, r, utils.InvalidInput("format must be %q or %q", config.JSONExport, config.ZipExport))
+		return
+	}
+	job, err := handler.service.ExportUser(r.Context(), userId, format)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+	response := models.Response{
+		Message: "Started export",
+		Code:    http.StatusAccepted,
+		Data:    job,
+	}
+	response.ToJson(w, http.StatusAccepted)
+	utils.LoggerFrom(r.Context()).Info("Started export for user " + userId)
+}
+
+// dryRun reads the dry_run query parameter, false when it is absent
+func dryRun(r *http.Request) (bool, bool) {
+	value := r.URL.Query().Get("dry_run")
+	if value == "" {
+		return false, true
+	}
+	parsed, err := strconv.ParseBool(value)
+	return parsed, err == nil
+}
+
+func (handler *AdminHandler) BulkDeleteUserContent(w http.ResponseWriter, r *http.Request) {
+	userId := mux.Vars(r)["user_id"]
+	preview, ok := dryRun(r)
+	if !ok {
+		utils.WriteError(w, r, utils.InvalidInput("dry_run must be a boolean"))
+		return
+	}
+	job, err := handler.service.BulkDeleteUserContent(r.Context(), userId, preview, auditReason(r))
+	handler.writeBulkJob(w, r, job, err)
+}
+
+func (handler *AdminHandler) BulkDeletePosts(w http.ResponseWriter, r *http.Request) {
+	preview, ok := dryRun(r)
+	if !ok {
+		utils.WriteError(w, r, utils.InvalidInput("dry_run must be a boolean"))
+		return
+	}
+	var request models.BulkDeletePosts
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		utils.WriteError(w, r, utils.InvalidBody)
+		return
+	}
+	err = handler.validator.Struct(request)
+	if err != nil {
+		utils.WriteError(w, r, utils.ValidationError(err))
+		return
+	}
+	job, err := handler.service.BulkDeletePosts(r.Context(), &request, preview)
+	handler.writeBulkJob(w, r, job, err)
+}
+
+func (handler *AdminHandler) BulkChangeAccounts(w http.ResponseWriter, r *http.Request) {
+	action := config.BulkAction(mux.Vars(r)["action"])
+	if action != config.BulkReactivate && action != config.BulkSuspend {
+		utils.WriteError(w, r, utils.InvalidInput("action must be %q or %q", config.BulkReactivate, config.BulkSuspend))
+		return
+	}
+	preview, ok := dryRun(r)
+	if !ok {
+		utils.WriteError(w, r, utils.InvalidInput("dry_run must be a boolean"))
+		return
+	}
+	var request models.BulkAccountChange
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		utils.WriteError(w, r, utils.InvalidBody)
+		return
+	}
+	err = handler.validator.Struct(request)
 	if err != nil {
-		response := utils.NewInternalServerError("Error deleting user" + err.Error())
-		response.ToJson(w, http.StatusInternalServerError)
+		utils.WriteError(w, r, utils.ValidationError(err))
+		return
+	}
+	if action == config.BulkSuspend && (request.Until == nil || !request.Until.After(time.Now())) {
+		utils.WriteError(w, r, utils.InvalidInput("until must be in the future"))
+		return
+	}
+	job, err := handler.service.BulkChangeAccounts(r.Context(), action, &request, preview)
+	handler.writeBulkJob(w, r, job, err)
+}
+
+func (handler *AdminHandler) writeBulkJob(w http.ResponseWriter, r *http.Request, job *models.Job, err error) {
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+	response := models.Response{
+		Message: "Started bulk job",
+		Code:    http.StatusAccepted,
+		Data:    job,
+	}
+	response.ToJson(w, http.StatusAccepted)
+	utils.LoggerFrom(r.Context()).Info("Started bulk job " + job.Id)
+}
+
+func (handler *AdminHandler) GetJob(w http.ResponseWriter, r *http.Request) {
+	jobId := mux.Vars(r)["job_id"]
+	job, err := handler.service.GetJob(r.Context(), jobId)
+	if err != nil {
+		utils.WriteError(w, r, err)
 		return
 	}
 	response := models.Response{
-		Message: "Successfully deleted user",
+		Message: "Fetched job",
 		Code:    http.StatusOK,
+		Data:    job,
 	}
 	response.ToJson(w, http.StatusOK)
-	return
+	utils.LoggerFrom(r.Context()).Info("Fetched job " + jobId)
 }
 
 func (handler *AdminHandler) ReActivateUser(w http.ResponseWriter, r *http.Request) {
 	userId := mux.Vars(r)["user_id"]
-	err := handler.service.ReactivateUser(r.Context(), userId)
+	err := handler.service.ReactivateUser(r.Context(), userId, auditReason(r))
+	handler.writeAccountChange(w, r, err, "Successfully re-activated user")
+}
+
+func (handler *AdminHandler) SuspendUser(w http.ResponseWriter, r *http.Request) {
+	userId := mux.Vars(r)["user_id"]
+	var request models.SuspendUser
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		utils.WriteError(w, r, utils.InvalidBody)
+		return
+	}
+	err = handler.validator.Struct(request)
 	if err != nil {
-		response := utils.NewInternalServerError("Error reactivating user" + err.Error())
-		response.ToJson(w, http.StatusInternalServerError)
+		utils.WriteError(w, r, utils.ValidationError(err))
+		return
+	}
+	if !request.Until.After(time.Now()) {
+		utils.WriteError(w, r, utils.InvalidInput("until must be in the future"))
 		return
 	}
+	err = handler.service.SuspendUser(r.Context(), userId, request.Until, request.Reason)
+	handler.writeAccountChange(w, r, err, "Successfully suspended user")
+}
+
+func (handler *AdminHandler) BanUser(w http.ResponseWriter, r *http.Request) {
+	userId := mux.Vars(r)["user_id"]
+	var request models.BanUser
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		utils.WriteError(w, r, utils.InvalidBody)
+		return
+	}
+	err = handler.validator.Struct(request)
+	if err != nil {
+		utils.WriteError(w, r, utils.ValidationError(err))
+		return
+	}
+	err = handler.service.BanUser(r.Context(), userId, request.Reason)
+	handler.writeAccountChange(w, r, err, "Successfully banned user")
+}
+
+func (handler *AdminHandler) UnbanUser(w http.ResponseWriter, r *http.Request) {
+	userId := mux.Vars(r)["user_id"]
+	err := handler.service.UnbanUser(r.Context(), userId, auditReason(r))
+	handler.writeAccountChange(w, r, err, "Successfully unbanned user")
+}
+
+func (handler *AdminHandler) writeAccountChange(w http.ResponseWriter, r *http.Request, err error, message string) {
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+	utils.LoggerFrom(r.Context()).Info(message)
 	response := models.Response{
-		Message: "Successfully re-activated user",
+		Message: message,
 		Code:    http.StatusOK,
 	}
 	response.ToJson(w, http.StatusOK)
@@ -99,20 +338,17 @@ func (handler *AdminHandler) DeletePost(w http.ResponseWriter, r *http.Request)
 	var post models.DeletePost
 	err := json.NewDecoder(r.Body).Decode(&post)
 	if err != nil {
-		response := utils.NewBadRequestError("Invalid JSON body")
-		response.ToJson(w, http.StatusBadRequest)
+		utils.WriteError(w, r, utils.InvalidBody)
 		return
 	}
 	err = handler.validator.Struct(post)
 	if err != nil {
-		response := utils.NewBadRequestError("Invalid Input")
-		response.ToJson(w, http.StatusBadRequest)
+		utils.WriteError(w, r, utils.ValidationError(err))
 		return
 	}
-	err = handler.service.DeletePost(r.Context(), userId, postId, &post)
+	err = handler.service.DeletePost(r.Context(), userId, postId, &post, auditReason(r))
 	if err != nil {
-		response := utils.NewInternalServerError("Error deleting post" + err.Error())
-		response.ToJson(w, http.StatusInternalServerError)
+		utils.WriteError(w, r, err)
 		return
 	}
 	response := models.Response{
@@ -127,10 +363,9 @@ func (handler *AdminHandler) DeleteQuestion(w http.ResponseWriter, r *http.Reque
 	questionId := mux.Vars(r)["ques_id"]
 	postId := mux.Vars(r)["post_id"]
 	userId := mux.Vars(r)["user_id"]
-	err := handler.service.DeleteQuestion(r.Context(), postId, questionId, userId)
+	err := handler.service.DeleteQuestion(r.Context(), postId, questionId, userId, auditReason(r))
 	if err != nil {
-		response := utils.NewInternalServerError("Error deleting question" + err.Error())
-		response.ToJson(w, http.StatusInternalServerError)
+		utils.WriteError(w, r, err)
 		return
 	}
 	response := models.Response{
@@ -145,10 +380,9 @@ func (handler *AdminHandler) DeleteAnswer(w http.ResponseWriter, r *http.Request
 	questionId := mux.Vars(r)["ques_id"]
 	ansId := mux.Vars(r)["answer_id"]
 	userId := mux.Vars(r)["user_id"]
-	err := handler.service.DeleteAnswer(r.Context(), ansId, questionId, userId)
+	err := handler.service.DeleteAnswer(r.Context(), ansId, questionId, userId, auditReason(r))
 	if err != nil {
-		response := utils.NewInternalServerError("Error deleting reply" + err.Error())
-		response.ToJson(w, http.StatusInternalServerError)
+		utils.WriteError(w, r, err)
 		return
 	}
 	response := models.Response{
@@ -158,3 +392,186 @@ func (handler *AdminHandler) DeleteAnswer(w http.ResponseWriter, r *http.Request
 	response.ToJson(w, http.StatusOK)
 	return
 }
+
+func (handler *AdminHandler) ListReports(w http.ResponseWriter, r *http.Request) {
+	status := config.ReportStatus(r.URL.Query().Get("status"))
+	if status != "" && status != config.ReportOpen && status != config.ReportActioned && status != config.ReportDismissed {
+		utils.WriteError(w, r, utils.InvalidInput("unknown report status %q", status))
+		return
+	}
+	limit, cursor := pageParams(r)
+	cases, next, err := handler.service.ListReports(r.Context(), status, limit, cursor)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+	response := &models.Response{
+		Message: "Successfully got reports",
+		Data:    &models.ResponseReportCases{Cases: cases, NextCursor: next},
+		Code:    http.StatusOK,
+	}
+	response.ToJson(w, http.StatusOK)
+	return
+}
+
+func (handler *AdminHandler) GetReport(w http.ResponseWriter, r *http.Request) {
+	targetType, targetId, ok := reportTarget(w, r)
+	if !ok {
+		return
+	}
+	reportCase, err := handler.service.GetReport(r.Context(), targetType, targetId)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+	response := &models.Response{
+		Message: "Successfully got report",
+		Data:    reportCase,
+		Code:    http.StatusOK,
+	}
+	response.ToJson(w, http.StatusOK)
+	return
+}
+
+func (handler *AdminHandler) ResolveReport(w http.ResponseWriter, r *http.Request) {
+	adminId := r.Context().Value("Id").(string)
+	targetType, targetId, ok := reportTarget(w, r)
+	if !ok {
+		return
+	}
+	var request models.ResolveReport
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		utils.WriteError(w, r, utils.InvalidBody)
+		return
+	}
+	err = handler.validator.Struct(request)
+	if err != nil {
+		utils.WriteError(w, r, utils.ValidationError(err))
+		return
+	}
+	err = handler.service.ResolveReport(r.Context(), adminId, targetType, targetId, request.Status, auditReason(r))
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+	utils.LoggerFrom(r.Context()).Info("Report resolved")
+	response := &models.Response{
+		Message: "Successfully resolved report",
+		Data:    request.Status,
+		Code:    http.StatusOK,
+	}
+	response.ToJson(w, http.StatusOK)
+	return
+}
+
+func reportTarget(w http.ResponseWriter, r *http.Request) (config.ContentType, string, bool) {
+	targetType := config.ContentType(mux.Vars(r)["target_type"])
+	switch targetType {
+	case config.PostContent, config.QuestionContent, config.AnswerContent, config.UserContent:
+		return targetType, mux.Vars(r)["target_id"], true
+	}
+	utils.WriteError(w, r, utils.InvalidInput("unknown target type %q", targetType))
+	return "", "", false
+}
+
+// auditReason is the optional "reason" query parameter an admin can attach to any action
+func auditReason(r *http.Request) string {
+	reason := r.URL.Query().Get("reason")
+	if len(reason) > 500 {
+		reason = reason[:500]
+	}
+	return reason
+}
+
+// GetStats serves /admin/stats?granularity=day|week&from=YYYY-MM-DD&to=YYYY-MM-DD, by default
+// the daily buckets of the last DefaultStatsDays days
+func (handler *AdminHandler) GetStats(w http.ResponseWriter, r *http.Request) {
+	queryParams := r.URL.Query()
+	granularity := config.StatsGranularity(queryParams.Get("granularity"))
+	if granularity == "" {
+		granularity = config.StatsDay
+	}
+	if granularity != config.StatsDay && granularity != config.StatsWeek {
+		utils.WriteError(w, r, utils.InvalidInput("granularity must be %q or %q", config.StatsDay, config.StatsWeek))
+		return
+	}
+	to := time.Now()
+	from := to.AddDate(0, 0, -config.DefaultStatsDays)
+	var err error
+	if value := queryParams.Get("from"); value != "" {
+		from, err = time.Parse(config.StatsDayLayout, value)
+		if err != nil {
+			utils.WriteError(w, r, utils.InvalidInput("from must be a date like %s", config.StatsDayLayout))
+			return
+		}
+	}
+	if value := queryParams.Get("to"); value != "" {
+		to, err = time.Parse(config.StatsDayLayout, value)
+		if err != nil {
+			utils.WriteError(w, r, utils.InvalidInput("to must be a date like %s", config.StatsDayLayout))
+			return
+		}
+	}
+	if to.Before(from) {
+		utils.WriteError(w, r, utils.InvalidInput("from must not be after to"))
+		return
+	}
+	stats, err := handler.service.GetStats(r.Context(), granularity, from, to)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+	response := &models.Response{
+		Message: "Successfully got stats",
+		Data:    stats,
+		Code:    http.StatusOK,
+	}
+	response.ToJson(w, http.StatusOK)
+	return
+}
+
+func (handler *AdminHandler) GetAuditLog(w http.ResponseWriter, r *http.Request) {
+	queryParams := r.URL.Query()
+	query := &models.AuditQuery{
+		ActorId:    queryParams.Get("actor_id"),
+		TargetType: config.ContentType(queryParams.Get("target_type")),
+		TargetId:   queryParams.Get("target_id"),
+	}
+	query.Limit, query.Cursor = pageParams(r)
+	if query.TargetId != "" {
+		switch query.TargetType {
+		case config.PostContent, config.QuestionContent, config.AnswerContent, config.UserContent:
+		default:
+			utils.WriteError(w, r, utils.InvalidInput("unknown target type %q", query.TargetType))
+			return
+		}
+	}
+	var err error
+	if from := queryParams.Get("from"); from != "" {
+		query.From, err = time.Parse(time.RFC3339, from)
+		if err != nil {
+			utils.WriteError(w, r, utils.InvalidInput("from must be an RFC 3339 time"))
+			return
+		}
+	}
+	if to := queryParams.Get("to"); to != "" {
+		query.To, err = time.Parse(time.RFC3339, to)
+		if err != nil {
+			utils.WriteError(w, r, utils.InvalidInput("to must be an RFC 3339 time"))
+			return
+		}
+	}
+	entries, next, err := handler.service.GetAuditLog(r.Context(), query)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+	response := &models.Response{
+		Message: "Successfully got audit log",
+		Data:    &models.ResponseAuditEntries{Entries: entries, NextCursor: next},
+		Code:    http.StatusOK,
+	}
+	response.ToJson(w, http.StatusOK)
+	return
+}
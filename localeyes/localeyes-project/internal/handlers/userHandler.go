@@ -1,23 +1,16 @@
 package handlers
 
 import (
-	"context"
 	_ "database/sql"
 	"encoding/json"
-	"errors"
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/service/sns"
-	"github.com/aws/aws-sdk-go-v2/service/sns/types"
 	"github.com/go-playground/validator"
 	"github.com/gorilla/mux"
 	"localeyes/config"
 	"localeyes/internal/interfaces"
 	"localeyes/internal/models"
 	"localeyes/utils"
-	"log"
 	"net/http"
 	"strconv"
-	"time"
 )
 
 type UserHandler struct {
@@ -37,25 +30,17 @@ func (handler *UserHandler) SendOtp(w http.ResponseWriter, r *http.Request) {
 	var userEmail models.UserEmail
 	err := json.NewDecoder(r.Body).Decode(&userEmail)
 	if err != nil {
-		response := utils.NewBadRequestError("Invalid JSON body")
-		response.ToJson(w, http.StatusBadRequest)
+		utils.WriteError(w, r, utils.InvalidBody)
 		return
 	}
 	err = handler.validator.Struct(userEmail)
 	if err != nil {
-		response := utils.NewBadRequestError("Invalid Input")
-		response.ToJson(w, http.StatusBadRequest)
+		utils.WriteError(w, r, utils.ValidationError(err))
 		return
 	}
 	err = handler.service.SendOtp(r.Context(), userEmail.Email)
 	if err != nil {
-		if errors.Is(err, utils.NoUser) {
-			response := utils.NewBadRequestError("No User")
-			response.ToJson(w, http.StatusBadRequest)
-			return
-		}
-		response := utils.NewInternalServerError("Internal server error" + err.Error())
-		response.ToJson(w, http.StatusInternalServerError)
+		utils.WriteError(w, r, err)
 		return
 	}
 	response := &models.Response{
@@ -71,25 +56,17 @@ func (handler *UserHandler) ResetPassword(w http.ResponseWriter, r *http.Request
 	var resetUser models.ResetPasswordUser
 	err := json.NewDecoder(r.Body).Decode(&resetUser)
 	if err != nil {
-		response := utils.NewBadRequestError("Invalid JSON body")
-		response.ToJson(w, http.StatusBadRequest)
+		utils.WriteError(w, r, utils.InvalidBody)
 		return
 	}
 	err = handler.validator.Struct(resetUser)
 	if err != nil {
-		response := utils.NewBadRequestError("Invalid Input")
-		response.ToJson(w, http.StatusBadRequest)
+		utils.WriteError(w, r, utils.ValidationError(err))
 		return
 	}
 	err = handler.service.PasswordReset(r.Context(), resetUser)
 	if err != nil {
-		if errors.Is(err, utils.WrongOTP) {
-			response := utils.NewInternalServerError(err.Error())
-			response.ToJson(w, http.StatusBadRequest)
-			return
-		}
-		response := utils.NewInternalServerError("Internal server error")
-		response.ToJson(w, http.StatusInternalServerError)
+		utils.WriteError(w, r, err)
 		return
 	}
 	response := &models.Response{
@@ -102,51 +79,7 @@ func (handler *UserHandler) ResetPassword(w http.ResponseWriter, r *http.Request
 
 func (handler *UserHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
 	// On Hold
-	w.WriteHeader(http.StatusNotImplemented)
-	response := &models.Response{
-		Message: "This service is not available yet",
-		Code:    http.StatusNotImplemented,
-	}
-	if response.Code == http.StatusNotImplemented {
-		snsClient, topicArn, err := config.InitSNS()
-		if err != nil {
-			log.Printf("Failed to initialize SNS client: %v", err)
-		} else {
-			messageBody, _ := json.Marshal(map[string]string{
-				"error_code":  "5500",
-				"status_code": strconv.Itoa(response.Code),
-				"message":     response.Message,
-				"timestamp":   time.Now().Format(time.RFC3339),
-			})
-
-			message := &sns.PublishInput{
-				Message:  aws.String(string(messageBody)),
-				TopicArn: aws.String(topicArn),
-				MessageAttributes: map[string]types.MessageAttributeValue{
-					"ErrorCode": {
-						DataType:    aws.String("String"),
-						StringValue: aws.String("5500"),
-					},
-					"StatusCode": {
-						DataType:    aws.String("Number"),
-						StringValue: aws.String(strconv.Itoa(response.Code)),
-					},
-				},
-			}
-
-			_, err = snsClient.Publish(context.Background(), message)
-			if err != nil {
-				log.Printf("Failed to send message to SNS: %v", err)
-			} else {
-				log.Println("Error message successfully sent to SNS.")
-			}
-		}
-	}
-	err := json.NewEncoder(w).Encode(response)
-	if err != nil {
-		log.Printf("Error encoding response: %v", err)
-	}
-	return
+	utils.WriteError(w, r, utils.NotAvailable)
 }
 
 func (handler *UserHandler) SignUp(w http.ResponseWriter, r *http.Request) {
@@ -154,35 +87,23 @@ func (handler *UserHandler) SignUp(w http.ResponseWriter, r *http.Request) {
 	var client models.Client
 	err := json.NewDecoder(r.Body).Decode(&client)
 	if err != nil {
-		response := utils.NewBadRequestError("Invalid Json")
-		response.ToJson(w, http.StatusBadRequest)
+		utils.WriteError(w, r, utils.InvalidBody)
 		return
 	}
 	// validate userInput
 	err = handler.validator.Struct(client)
 	if err != nil {
-		response := utils.NewBadRequestError(err.Error())
-		response.ToJson(w, http.StatusBadRequest)
+		utils.WriteError(w, r, utils.ValidationError(err))
 		return
 	}
 	var livingSinceInYears = client.LivingSince.Days/365.0 + client.LivingSince.Months/12.0 + client.LivingSince.Years
 
 	err = handler.service.Signup(r.Context(), client.Username, client.Password, client.Email, livingSinceInYears)
 	if err != nil {
-		if errors.Is(err, utils.UserExistsName) {
-			response := utils.NewBadRequestError("Username already registered")
-			response.ToJson(w, http.StatusBadRequest)
-			return
-		} else if errors.Is(err, utils.UserExistsEmail) {
-			response := utils.NewBadRequestError("Email already registered")
-			response.ToJson(w, http.StatusBadRequest)
-			return
-		}
-		response := utils.NewInternalServerError("Error signing up" + err.Error())
-		response.ToJson(w, http.StatusInternalServerError)
+		utils.WriteError(w, r, err)
 		return
 	}
-	utils.Logger.Info("User signed up successfully")
+	utils.LoggerFrom(r.Context()).Info("User signed up successfully")
 	response := &models.Response{
 		Message: "User created successfully",
 		Code:    http.StatusOK,
@@ -196,32 +117,28 @@ func (handler *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 	var client models.ClientLogin
 	err := json.NewDecoder(r.Body).Decode(&client)
 	if err != nil {
-		response := utils.NewBadRequestError("Invalid Input")
-		response.ToJson(w, http.StatusBadRequest)
+		utils.WriteError(w, r, utils.InvalidBody)
 		return
 	}
 
 	// validate userInput
 	err = handler.validator.Struct(client)
 	if err != nil {
-		response := utils.NewBadRequestError("Invalid Input")
-		response.ToJson(w, http.StatusBadRequest)
+		utils.WriteError(w, r, utils.ValidationError(err))
 		return
 	}
 
 	user, err := handler.service.Login(r.Context(), client.Username, client.Password)
 	if err != nil {
-		response := utils.NewUnauthorizedError(err.Error())
-		response.ToJson(w, http.StatusUnauthorized)
+		utils.WriteError(w, r, err)
 		return
 	}
 	generatedToken, err := utils.GenerateTokenFunc(user.Username, user.UId)
 	if err != nil {
-		response := utils.NewInternalServerError("Error generating token ")
-		response.ToJson(w, http.StatusInternalServerError)
+		utils.WriteError(w, r, err)
 		return
 	}
-	utils.Logger.Info("User logged in successfully")
+	utils.LoggerFrom(r.Context()).Info("User logged in successfully")
 	response := models.Response{
 		Data:    generatedToken,
 		Code:    http.StatusOK,
@@ -236,11 +153,10 @@ func (handler *UserHandler) DeActivate(w http.ResponseWriter, r *http.Request) {
 	id := r.Context().Value("Id").(string)
 	err := handler.service.DeActivate(r.Context(), id)
 	if err != nil {
-		response := utils.NewInternalServerError(err.Error())
-		response.ToJson(w, http.StatusInternalServerError)
+		utils.WriteError(w, r, err)
 		return
 	}
-	utils.Logger.Info("User deactivated successfully")
+	utils.LoggerFrom(r.Context()).Info("User deactivated successfully")
 	response := &models.Response{
 		Message: "User Deactivated successfully",
 		Code:    http.StatusOK,
@@ -249,13 +165,78 @@ func (handler *UserHandler) DeActivate(w http.ResponseWriter, r *http.Request) {
 	return
 }
 
+// exportFormat reads the format query parameter of an export request, it defaults to JSON
+func exportFormat(r *http.Request) (config.ExportFormat, bool) {
+	format := config.ExportFormat(r.URL.Query().Get("format"))
+	if format == "" {
+		return config.JSONExport, true
+	}
+	return format, format == config.JSONExport || format == config.ZipExport
+}
+
+func (handler *UserHandler) ExportData(w http.ResponseWriter, r *http.Request) {
+	id := r.Context().Value("Id").(string)
+	format, ok := exportFormat(r)
+	if !ok {
+		utils.WriteError(w, r, utils.InvalidInput("format must be %q or %q", config.JSONExport, config.ZipExport))
+		return
+	}
+	job, err := handler.service.StartExport(r.Context(), id, format)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+	response := &models.Response{
+		Message: "Started export",
+		Code:    http.StatusAccepted,
+		Data:    job,
+	}
+	response.ToJson(w, http.StatusAccepted)
+	utils.LoggerFrom(r.Context()).Info("Started export for user " + id)
+}
+
+func (handler *UserHandler) GetExport(w http.ResponseWriter, r *http.Request) {
+	id := r.Context().Value("Id").(string)
+	jobId := mux.Vars(r)["job_id"]
+	job, err := handler.service.GetExport(r.Context(), id, jobId)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+	response := &models.Response{
+		Message: "Fetched export",
+		Code:    http.StatusOK,
+		Data:    job,
+	}
+	response.ToJson(w, http.StatusOK)
+}
+
+// DownloadExport serves a finished export archive. The download token is the only credential
+// so the link can be opened without logging in until it expires.
+func (handler *UserHandler) DownloadExport(w http.ResponseWriter, r *http.Request) {
+	token := mux.Vars(r)["token"]
+	export, archive, err := handler.service.DownloadExport(r.Context(), token)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", export.ContentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+export.FileName+`"`)
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(archive)
+	if err != nil {
+		utils.LoggerFrom(r.Context()).Error("ERROR: Error writing export archive")
+		return
+	}
+	utils.LoggerFrom(r.Context()).Info("Downloaded export of user " + export.UId)
+}
+
 func (handler *UserHandler) ViewProfile(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	id := r.Context().Value("Id").(string)
 	user, err := handler.service.FetchProfile(r.Context(), id)
 	if err != nil {
-		response := utils.NewUnauthorizedError("Invalid token")
-		response.ToJson(w, http.StatusUnauthorized)
+		utils.WriteError(w, r, err)
 		return
 	}
 	responseUser := &models.ResponseUser{
@@ -266,13 +247,16 @@ func (handler *UserHandler) ViewProfile(w http.ResponseWriter, r *http.Request)
 		LivingSince:  user.DwellingAge,
 		Tag:          user.Tag,
 		ActiveStatus: user.IsActive,
+		Followers:    user.Followers,
+		Following:    user.Following,
 	}
+	w.Header().Set("ETag", utils.ETag(user.Version))
 	response := models.Response{
 		Data:    responseUser,
 		Code:    http.StatusOK,
 		Message: "User viewed successfully",
 	}
-	utils.Logger.Info("User viewed successfully")
+	utils.LoggerFrom(r.Context()).Info("User viewed successfully")
 	response.ToJson(w, http.StatusOK)
 	return
 }
@@ -283,8 +267,7 @@ func (handler *UserHandler) ViewNotifications(w http.ResponseWriter, r *http.Req
 
 	notifications, err := handler.service.GetNotifications(r.Context(), id)
 	if err != nil {
-		response := utils.NewInternalServerError("Error getting notifications")
-		response.ToJson(w, http.StatusInternalServerError)
+		utils.WriteError(w, r, err)
 		return
 	}
 	var dtoNotifications []*models.Notification
@@ -305,7 +288,7 @@ func (handler *UserHandler) ViewNotifications(w http.ResponseWriter, r *http.Req
 		Code:    http.StatusOK,
 		Data:    dtoNotifications,
 	}
-	utils.Logger.Info("User viewed successfully")
+	utils.LoggerFrom(r.Context()).Info("User viewed successfully")
 	response.ToJson(w, http.StatusOK)
 	return
 }
@@ -315,8 +298,7 @@ func (handler *UserHandler) GetUserById(w http.ResponseWriter, r *http.Request)
 	id := mux.Vars(r)["user_id"]
 	user, err := handler.service.FetchProfile(r.Context(), id)
 	if err != nil {
-		response := utils.NewInternalServerError("Error getting user")
-		response.ToJson(w, http.StatusInternalServerError)
+		utils.WriteError(w, r, err)
 		return
 	}
 	responseUser := models.ResponseUser{
@@ -326,7 +308,10 @@ func (handler *UserHandler) GetUserById(w http.ResponseWriter, r *http.Request)
 		City:        user.City,
 		LivingSince: user.DwellingAge,
 		Tag:         user.Tag,
+		Followers:   user.Followers,
+		Following:   user.Following,
 	}
+	w.Header().Set("ETag", utils.ETag(user.Version))
 	response := models.Response{
 		Data:    responseUser,
 		Code:    http.StatusOK,
@@ -337,29 +322,183 @@ func (handler *UserHandler) GetUserById(w http.ResponseWriter, r *http.Request)
 	return
 }
 
+func (handler *UserHandler) FollowUser(w http.ResponseWriter, r *http.Request) {
+	followeeId := mux.Vars(r)["user_id"]
+	userId := r.Context().Value("Id").(string)
+	err := handler.service.Follow(r.Context(), userId, followeeId)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+	utils.LoggerFrom(r.Context()).Info("User followed")
+	response := &models.Response{
+		Code:    http.StatusOK,
+		Message: "User followed",
+		Data:    true,
+	}
+	response.ToJson(w, http.StatusOK)
+	return
+}
+
+func (handler *UserHandler) UnfollowUser(w http.ResponseWriter, r *http.Request) {
+	followeeId := mux.Vars(r)["user_id"]
+	userId := r.Context().Value("Id").(string)
+	err := handler.service.Unfollow(r.Context(), userId, followeeId)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+	utils.LoggerFrom(r.Context()).Info("User unfollowed")
+	response := &models.Response{
+		Code:    http.StatusOK,
+		Message: "User unfollowed",
+		Data:    false,
+	}
+	response.ToJson(w, http.StatusOK)
+	return
+}
+
+func (handler *UserHandler) GetFollowers(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["user_id"]
+	limit, cursor := pageParams(r)
+	follows, next, err := handler.service.GetFollowers(r.Context(), id, limit, cursor)
+	handler.writeFollows(w, r, follows, next, err)
+}
+
+func (handler *UserHandler) GetFollowing(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["user_id"]
+	limit, cursor := pageParams(r)
+	follows, next, err := handler.service.GetFollowing(r.Context(), id, limit, cursor)
+	handler.writeFollows(w, r, follows, next, err)
+}
+
+func (handler *UserHandler) writeFollows(w http.ResponseWriter, r *http.Request, follows []*models.ResponseFollow, next string, err error) {
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+	response := models.Response{
+		Data: models.ResponseFollows{
+			Users:      follows,
+			NextCursor: next,
+		},
+		Code:    http.StatusOK,
+		Message: "Success",
+	}
+	utils.LoggerFrom(r.Context()).Info("Successfully listed follows")
+	response.ToJson(w, http.StatusOK)
+	return
+}
+
+func (handler *UserHandler) BlockUser(w http.ResponseWriter, r *http.Request) {
+	blockedId := mux.Vars(r)["user_id"]
+	userId := r.Context().Value("Id").(string)
+	err := handler.service.Block(r.Context(), userId, blockedId)
+	handler.writeRelationChange(w, r, err, "User blocked", true)
+}
+
+func (handler *UserHandler) UnblockUser(w http.ResponseWriter, r *http.Request) {
+	blockedId := mux.Vars(r)["user_id"]
+	userId := r.Context().Value("Id").(string)
+	err := handler.service.Unblock(r.Context(), userId, blockedId)
+	handler.writeRelationChange(w, r, err, "User unblocked", false)
+}
+
+func (handler *UserHandler) MuteUser(w http.ResponseWriter, r *http.Request) {
+	mutedId := mux.Vars(r)["user_id"]
+	userId := r.Context().Value("Id").(string)
+	err := handler.service.Mute(r.Context(), userId, mutedId)
+	handler.writeRelationChange(w, r, err, "User muted", true)
+}
+
+func (handler *UserHandler) UnmuteUser(w http.ResponseWriter, r *http.Request) {
+	mutedId := mux.Vars(r)["user_id"]
+	userId := r.Context().Value("Id").(string)
+	err := handler.service.Unmute(r.Context(), userId, mutedId)
+	handler.writeRelationChange(w, r, err, "User unmuted", false)
+}
+
+func (handler *UserHandler) writeRelationChange(w http.ResponseWriter, r *http.Request, err error, message string, state bool) {
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+	utils.LoggerFrom(r.Context()).Info(message)
+	response := &models.Response{
+		Code:    http.StatusOK,
+		Message: message,
+		Data:    state,
+	}
+	response.ToJson(w, http.StatusOK)
+	return
+}
+
+func (handler *UserHandler) GetBlockedUsers(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value("Id").(string)
+	users, err := handler.service.GetBlockedUsers(r.Context(), userId)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+	response := models.Response{
+		Data:    users,
+		Code:    http.StatusOK,
+		Message: "Success",
+	}
+	utils.LoggerFrom(r.Context()).Info("Successfully listed blocked users")
+	response.ToJson(w, http.StatusOK)
+	return
+}
+
+func (handler *UserHandler) GetMutedUsers(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value("Id").(string)
+	users, err := handler.service.GetMutedUsers(r.Context(), userId)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+	response := models.Response{
+		Data:    users,
+		Code:    http.StatusOK,
+		Message: "Success",
+	}
+	utils.LoggerFrom(r.Context()).Info("Successfully listed muted users")
+	response.ToJson(w, http.StatusOK)
+	return
+}
+
+// UpdateUserById updates the profile of the caller, only admins may update someone else's
 func (handler *UserHandler) UpdateUserById(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	id := mux.Vars(r)["user_id"]
+	callerId, _ := r.Context().Value("Id").(string)
+	role, _ := r.Context().Value("Role").(string)
+	if id != callerId && role != "admin" {
+		utils.WriteError(w, r, utils.NotYourProfile)
+		return
+	}
+	version, ok := ifMatch(w, r)
+	if !ok {
+		return
+	}
 	var newUser models.UpdateClient
 	err := json.NewDecoder(r.Body).Decode(&newUser)
 	if err != nil {
-		response := utils.NewBadRequestError("Invalid JSON body")
-		response.ToJson(w, http.StatusBadRequest)
+		utils.WriteError(w, r, utils.InvalidBody)
 		return
 	}
 	// validate userInput
 	err = handler.validator.Struct(newUser)
 	if err != nil {
-		response := utils.NewBadRequestError("Invalid Input")
-		response.ToJson(w, http.StatusBadRequest)
+		utils.WriteError(w, r, utils.ValidationError(err))
 		return
 	}
-	err = handler.service.UpdateUser(r.Context(), id, &newUser)
+	version, err = handler.service.UpdateUser(r.Context(), id, &newUser, version)
 	if err != nil {
-		response := utils.NewInternalServerError("Error updating user")
-		response.ToJson(w, http.StatusInternalServerError)
+		utils.WriteError(w, r, err)
 		return
 	}
+	w.Header().Set("ETag", utils.ETag(version))
 	response := &models.Response{
 		Message: "Success",
 		Code:    http.StatusOK,
@@ -368,6 +507,17 @@ func (handler *UserHandler) UpdateUserById(w http.ResponseWriter, r *http.Reques
 	return
 }
 
+// ifMatch reads the version the client last saw from the If-Match header of a conditional
+// write, answering the request itself when the header is missing or malformed
+func ifMatch(w http.ResponseWriter, r *http.Request) (int, bool) {
+	version, err := utils.ParseIfMatch(r.Header.Get("If-Match"))
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return 0, false
+	}
+	return version, true
+}
+
 //Post related handlers
 
 func (handler *UserHandler) DisplayPosts(w http.ResponseWriter, r *http.Request) {
@@ -398,30 +548,19 @@ func (handler *UserHandler) DisplayPosts(w http.ResponseWriter, r *http.Request)
 	} else {
 		searchPointer = &search
 	}
-	posts, err := handler.service.GiveAllPosts(r.Context(), limitPointer, offsetPointer, searchPointer, filterPointer)
+	id := r.Context().Value("Id").(string)
+	posts, err := handler.service.GiveAllPosts(r.Context(), id, limitPointer, offsetPointer, searchPointer, filterPointer)
 	if err != nil {
-		response := utils.NewInternalServerError(err.Error())
-		response.ToJson(w, http.StatusInternalServerError)
+		utils.WriteError(w, r, err)
 		return
 	}
-	var responseData []models.ResponsePost
-	for _, post := range posts {
-		responseData = append(responseData, models.ResponsePost{
-			PostId:    post.PostId,
-			UId:       post.UId,
-			Title:     post.Title,
-			Type:      post.Type,
-			Content:   post.Content,
-			Likes:     post.Likes,
-			CreatedAt: post.CreatedAt,
-		})
-	}
+	responseData := toResponsePosts(posts)
 	response := models.Response{
 		Data:    responseData,
 		Code:    http.StatusOK,
 		Message: "Success",
 	}
-	utils.Logger.Info("Successfully displayed posts")
+	utils.LoggerFrom(r.Context()).Info("Successfully displayed posts")
 	response.ToJson(w, http.StatusOK)
 	return
 }
@@ -430,10 +569,41 @@ func (handler *UserHandler) DisplayUserPosts(w http.ResponseWriter, r *http.Requ
 	id := r.Context().Value("Id").(string)
 	posts, err := handler.service.GiveUserPosts(r.Context(), id)
 	if err != nil {
-		response := utils.NewInternalServerError("Error displaying posts")
-		response.ToJson(w, http.StatusInternalServerError)
+		utils.WriteError(w, r, err)
+		return
+	}
+	responseData := toResponsePosts(posts)
+	response := models.Response{
+		Data:    responseData,
+		Code:    http.StatusOK,
+		Message: "Success",
+	}
+	utils.LoggerFrom(r.Context()).Info("Successfully displayed user posts")
+	response.ToJson(w, http.StatusOK)
+	return
+}
+
+// GetPost returns one post with its version as the ETag, which an edit sends back in If-Match
+func (handler *UserHandler) GetPost(w http.ResponseWriter, r *http.Request) {
+	postId := mux.Vars(r)["post_id"]
+	userId := r.Context().Value("Id").(string)
+	post, err := handler.service.GetPost(r.Context(), userId, postId)
+	if err != nil {
+		utils.WriteError(w, r, err)
 		return
 	}
+	w.Header().Set("ETag", utils.ETag(post.Version))
+	response := models.Response{
+		Data:    toResponsePosts([]*models.Post{post})[0],
+		Code:    http.StatusOK,
+		Message: "Success",
+	}
+	utils.LoggerFrom(r.Context()).Info("Successfully displayed the post")
+	response.ToJson(w, http.StatusOK)
+	return
+}
+
+func toResponsePosts(posts []*models.Post) []models.ResponsePost {
 	var responseData []models.ResponsePost
 	for _, post := range posts {
 		responseData = append(responseData, models.ResponsePost{
@@ -443,15 +613,116 @@ func (handler *UserHandler) DisplayUserPosts(w http.ResponseWriter, r *http.Requ
 			Type:      post.Type,
 			Content:   post.Content,
 			Likes:     post.Likes,
+			Questions: post.Questions,
+			Comments:  post.Comments,
+			Reactions: post.Reactions,
+			Saved:     post.Saved,
 			CreatedAt: post.CreatedAt,
+			EditedAt:  post.EditedAt,
+			Version:   post.Version,
 		})
 	}
+	return responseData
+}
+
+// pageParams reads the limit and cursor query parameters of a cursor paginated listing
+func pageParams(r *http.Request) (int, string) {
+	queryParams := r.URL.Query()
+	limit, err := strconv.Atoi(queryParams.Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = config.DefaultPageSize
+	}
+	if limit > config.MaxPageSize {
+		limit = config.MaxPageSize
+	}
+	return limit, queryParams.Get("cursor")
+}
+
+func (handler *UserHandler) GetFollowingFeed(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value("Id").(string)
+	limit, cursor := pageParams(r)
+	posts, next, err := handler.service.GetFollowingFeed(r.Context(), userId, limit, cursor)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
 	response := models.Response{
-		Data:    responseData,
+		Data: models.ResponsePostPage{
+			Posts:      toResponsePosts(posts),
+			NextCursor: next,
+		},
 		Code:    http.StatusOK,
 		Message: "Success",
 	}
-	utils.Logger.Info("Successfully displayed user posts")
+	utils.LoggerFrom(r.Context()).Info("Successfully displayed following feed")
+	response.ToJson(w, http.StatusOK)
+	return
+}
+
+func (handler *UserHandler) SavePost(w http.ResponseWriter, r *http.Request) {
+	postId := mux.Vars(r)["post_id"]
+	userId := r.Context().Value("Id").(string)
+	var request models.SavePost
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		utils.WriteError(w, r, utils.InvalidBody)
+		return
+	}
+	err = handler.validator.Struct(request)
+	if err != nil {
+		utils.WriteError(w, r, utils.ValidationError(err))
+		return
+	}
+	err = handler.service.SavePost(r.Context(), userId, postId, &request)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+	utils.LoggerFrom(r.Context()).Info("Post saved")
+	response := &models.Response{
+		Code:    http.StatusOK,
+		Message: "Post saved",
+		Data:    true,
+	}
+	response.ToJson(w, http.StatusOK)
+	return
+}
+
+func (handler *UserHandler) UnsavePost(w http.ResponseWriter, r *http.Request) {
+	postId := mux.Vars(r)["post_id"]
+	userId := r.Context().Value("Id").(string)
+	err := handler.service.UnsavePost(r.Context(), userId, postId)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+	utils.LoggerFrom(r.Context()).Info("Post unsaved")
+	response := &models.Response{
+		Code:    http.StatusOK,
+		Message: "Post removed from saved",
+		Data:    false,
+	}
+	response.ToJson(w, http.StatusOK)
+	return
+}
+
+func (handler *UserHandler) GetSavedPosts(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value("Id").(string)
+	limit, cursor := pageParams(r)
+	posts, next, err := handler.service.GetSavedPosts(r.Context(), userId, limit, cursor)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+	response := models.Response{
+		Data: models.ResponsePostPage{
+			Posts:      toResponsePosts(posts),
+			NextCursor: next,
+		},
+		Code:    http.StatusOK,
+		Message: "Success",
+	}
+	utils.LoggerFrom(r.Context()).Info("Successfully displayed saved posts")
 	response.ToJson(w, http.StatusOK)
 	return
 }
@@ -461,24 +732,21 @@ func (handler *UserHandler) CreatePost(w http.ResponseWriter, r *http.Request) {
 	id := r.Context().Value("Id").(string)
 	err := json.NewDecoder(r.Body).Decode(&requestPost)
 	if err != nil {
-		response := utils.NewBadRequestError("Missing Request body")
-		response.ToJson(w, http.StatusBadRequest)
+		utils.WriteError(w, r, utils.InvalidBody)
 		return
 	}
 	// validate userInput
 	err = handler.validator.Struct(requestPost)
 	if err != nil {
-		response := utils.NewBadRequestError("Invalid Input")
-		response.ToJson(w, http.StatusBadRequest)
+		utils.WriteError(w, r, utils.ValidationError(err))
 		return
 	}
 	err = handler.service.CreatePost(r.Context(), id, requestPost.Title, requestPost.Content, config.Filter(requestPost.Type))
 	if err != nil {
-		response := utils.NewInternalServerError("Error creating post")
-		response.ToJson(w, http.StatusInternalServerError)
+		utils.WriteError(w, r, err)
 		return
 	}
-	utils.Logger.Info("Successfully created post")
+	utils.LoggerFrom(r.Context()).Info("Successfully created post")
 	response := &models.Response{
 		Message: "Post created successfully",
 		Code:    http.StatusOK,
@@ -490,28 +758,31 @@ func (handler *UserHandler) CreatePost(w http.ResponseWriter, r *http.Request) {
 func (handler *UserHandler) UpdatePost(w http.ResponseWriter, r *http.Request) {
 	postId := mux.Vars(r)["post_id"]
 	id := r.Context().Value("Id").(string)
+	version, ok := ifMatch(w, r)
+	if !ok {
+		return
+	}
 	var post models.UpdatePost
 	post.PostId = postId
 	err := json.NewDecoder(r.Body).Decode(&post)
 	if err != nil {
-		response := utils.NewBadRequestError("Invalid/Missing Request body")
-		response.ToJson(w, http.StatusBadRequest)
+		utils.WriteError(w, r, utils.InvalidBody)
 		return
 	}
 	err = handler.validator.Struct(post)
 	if err != nil {
-		response := utils.NewBadRequestError(err.Error())
-		response.ToJson(w, http.StatusBadRequest)
+		utils.WriteError(w, r, utils.ValidationError(err))
 		return
 	}
 	post.UId = id
-	err = handler.service.UpdatePost(r.Context(), &post)
+	post.Version = version
+	version, err = handler.service.UpdatePost(r.Context(), &post)
 	if err != nil {
-		response := utils.NewInternalServerError(err.Error())
-		response.ToJson(w, http.StatusInternalServerError)
+		utils.WriteError(w, r, err)
 		return
 	}
-	utils.Logger.Info("Successfully updated post")
+	utils.LoggerFrom(r.Context()).Info("Successfully updated post")
+	w.Header().Set("ETag", utils.ETag(version))
 	response := &models.Response{
 		Message: "Post updated successfully",
 		Code:    http.StatusOK,
@@ -525,24 +796,21 @@ func (handler *UserHandler) DeletePost(w http.ResponseWriter, r *http.Request) {
 	var post models.DeletePost
 	err := json.NewDecoder(r.Body).Decode(&post)
 	if err != nil {
-		response := utils.NewUnauthorizedError("Invalid Json")
-		response.ToJson(w, http.StatusBadRequest)
+		utils.WriteError(w, r, utils.InvalidBody)
 		return
 	}
 	err = handler.validator.Struct(post)
 	if err != nil {
-		response := utils.NewBadRequestError(err.Error())
-		response.ToJson(w, http.StatusBadRequest)
+		utils.WriteError(w, r, utils.ValidationError(err))
 		return
 	}
 
 	err = handler.service.DeleteUserPost(r.Context(), id, postId, &post)
 	if err != nil {
-		response := utils.NewInternalServerError(err.Error())
-		response.ToJson(w, http.StatusInternalServerError)
+		utils.WriteError(w, r, err)
 		return
 	}
-	utils.Logger.Info("Successfully deleted post")
+	utils.LoggerFrom(r.Context()).Info("Successfully deleted post")
 	response := &models.Response{
 		Message: "Post deleted successfully",
 		Code:    http.StatusOK,
@@ -557,23 +825,20 @@ func (handler *UserHandler) LikePost(w http.ResponseWriter, r *http.Request) {
 	var post models.LikePost
 	err := json.NewDecoder(r.Body).Decode(&post)
 	if err != nil {
-		response := utils.NewUnauthorizedError("Invalid Json")
-		response.ToJson(w, http.StatusBadRequest)
+		utils.WriteError(w, r, utils.InvalidBody)
 		return
 	}
 	err = handler.validator.Struct(post)
 	if err != nil {
-		response := utils.NewBadRequestError(err.Error())
-		response.ToJson(w, http.StatusBadRequest)
+		utils.WriteError(w, r, utils.ValidationError(err))
 		return
 	}
 	status, err := handler.service.Like(r.Context(), userId, postId, &post)
 	if err != nil {
-		response := utils.NewInternalServerError(err.Error())
-		response.ToJson(w, http.StatusInternalServerError)
+		utils.WriteError(w, r, err)
 		return
 	}
-	utils.Logger.Info("Successfully liked post")
+	utils.LoggerFrom(r.Context()).Info("Successfully liked post")
 	response := &models.Response{
 		Message: "Post liked successfully",
 		Code:    http.StatusOK,
@@ -589,11 +854,10 @@ func (handler *UserHandler) GetLikeStatus(w http.ResponseWriter, r *http.Request
 
 	status, err := handler.service.GetLikeStatus(r.Context(), userId, postId)
 	if err != nil {
-		response := utils.NewInternalServerError(err.Error())
-		response.ToJson(w, http.StatusInternalServerError)
+		utils.WriteError(w, r, err)
 		return
 	}
-	utils.Logger.Info("Successfully viewed the like status")
+	utils.LoggerFrom(r.Context()).Info("Successfully viewed the like status")
 	response := &models.Response{
 		Message: "Successfully viewed the like status",
 		Code:    http.StatusOK,
@@ -603,20 +867,47 @@ func (handler *UserHandler) GetLikeStatus(w http.ResponseWriter, r *http.Request
 	return
 }
 
+func (handler *UserHandler) ReactToPost(w http.ResponseWriter, r *http.Request) {
+	postId := mux.Vars(r)["post_id"]
+	userId := r.Context().Value("Id").(string)
+	var request models.ReactPost
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		utils.WriteError(w, r, utils.InvalidBody)
+		return
+	}
+	err = handler.validator.Struct(request)
+	if err != nil {
+		utils.WriteError(w, r, utils.ValidationError(err))
+		return
+	}
+	err = handler.service.ReactToPost(r.Context(), userId, postId, &request)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+	utils.LoggerFrom(r.Context()).Info("Reacted to post")
+	response := &models.Response{
+		Code:    http.StatusOK,
+		Message: "Reaction recorded",
+		Data:    request.Reaction,
+	}
+	response.ToJson(w, http.StatusOK)
+	return
+}
+
 // question related handlers
 
 func (handler *UserHandler) CreateQuestion(w http.ResponseWriter, r *http.Request) {
 	var question models.RequestQuestion
 	err := json.NewDecoder(r.Body).Decode(&question)
 	if err != nil {
-		response := utils.NewBadRequestError("Invalid Request Body")
-		response.ToJson(w, http.StatusBadRequest)
+		utils.WriteError(w, r, utils.InvalidBody)
 		return
 	}
 	err = handler.validator.Struct(question)
 	if err != nil {
-		response := utils.NewBadRequestError("Invalid Input")
-		response.ToJson(w, http.StatusBadRequest)
+		utils.WriteError(w, r, utils.ValidationError(err))
 		return
 	}
 	postId := mux.Vars(r)["post_id"]
@@ -625,11 +916,10 @@ func (handler *UserHandler) CreateQuestion(w http.ResponseWriter, r *http.Reques
 	question.PostId = postId
 	err = handler.service.AddQuestion(r.Context(), &question)
 	if err != nil {
-		response := utils.NewInternalServerError("error while asking question")
-		response.ToJson(w, http.StatusInternalServerError)
+		utils.WriteError(w, r, err)
 		return
 	}
-	utils.Logger.Info("Question created")
+	utils.LoggerFrom(r.Context()).Info("Question created")
 	response := &models.Response{
 		Code:    http.StatusOK,
 		Message: "Question Created",
@@ -640,13 +930,13 @@ func (handler *UserHandler) CreateQuestion(w http.ResponseWriter, r *http.Reques
 
 func (handler *UserHandler) GetAllQuestions(w http.ResponseWriter, r *http.Request) {
 	postId := mux.Vars(r)["post_id"]
-	questions, err := handler.service.GetQuestionByPId(r.Context(), postId)
+	userId := r.Context().Value("Id").(string)
+	questions, err := handler.service.GetQuestionByPId(r.Context(), postId, userId)
 	if err != nil {
-		response := utils.NewInternalServerError("Error getting questions")
-		response.ToJson(w, http.StatusInternalServerError)
+		utils.WriteError(w, r, err)
 		return
 	}
-	utils.Logger.Info("Successfully retrieved all questions")
+	utils.LoggerFrom(r.Context()).Info("Successfully retrieved all questions")
 	response := &models.Response{
 		Message: "Successfully retrieved all questions",
 		Code:    http.StatusOK,
@@ -661,25 +951,22 @@ func (handler *UserHandler) AddAnswer(w http.ResponseWriter, r *http.Request) {
 	err := json.NewDecoder(r.Body).Decode(&request)
 	userId := r.Context().Value("Id").(string)
 	if err != nil {
-		response := utils.NewBadRequestError("Invalid Request Body")
-		response.ToJson(w, http.StatusBadRequest)
+		utils.WriteError(w, r, utils.InvalidBody)
 		return
 	}
 	err = handler.validator.Struct(request)
 	if err != nil {
-		response := utils.NewBadRequestError("Invalid Input")
-		response.ToJson(w, http.StatusBadRequest)
+		utils.WriteError(w, r, utils.ValidationError(err))
 		return
 	}
 	request.UserId = userId
 	request.QId = quesId
 	err = handler.service.AddAnswer(r.Context(), &request)
 	if err != nil {
-		response := utils.NewInternalServerError("error while adding answer")
-		response.ToJson(w, http.StatusInternalServerError)
+		utils.WriteError(w, r, err)
 		return
 	}
-	utils.Logger.Info("Answer added")
+	utils.LoggerFrom(r.Context()).Info("Answer added")
 	response := &models.Response{
 		Code:    http.StatusOK,
 		Message: "Answer Added",
@@ -695,11 +982,10 @@ func (handler *UserHandler) DeleteQuestion(w http.ResponseWriter, r *http.Reques
 
 	err := handler.service.DeleteQuestion(r.Context(), postId, quesId, userId)
 	if err != nil {
-		response := utils.NewInternalServerError("error while deleting question")
-		response.ToJson(w, http.StatusInternalServerError)
+		utils.WriteError(w, r, err)
 		return
 	}
-	utils.Logger.Info("Question deleted")
+	utils.LoggerFrom(r.Context()).Info("Question deleted")
 	response := &models.Response{
 		Code:    http.StatusOK,
 		Message: "Question Deleted",
@@ -714,11 +1000,10 @@ func (handler *UserHandler) DeleteAnswer(w http.ResponseWriter, r *http.Request)
 	userId := r.Context().Value("Id").(string)
 	err := handler.service.DeleteAnswer(r.Context(), quesId, answerId, userId)
 	if err != nil {
-		response := utils.NewInternalServerError("error while deleting answer")
-		response.ToJson(w, http.StatusInternalServerError)
+		utils.WriteError(w, r, err)
 		return
 	}
-	utils.Logger.Info("Answer deleted")
+	utils.LoggerFrom(r.Context()).Info("Answer deleted")
 	response := &models.Response{
 		Code:    http.StatusOK,
 		Message: "Answer Deleted",
@@ -729,13 +1014,13 @@ func (handler *UserHandler) DeleteAnswer(w http.ResponseWriter, r *http.Request)
 
 func (handler *UserHandler) GetAllAnswers(w http.ResponseWriter, r *http.Request) {
 	quesId := mux.Vars(r)["ques_id"]
-	answers, err := handler.service.GetAllAnswers(r.Context(), quesId)
+	userId := r.Context().Value("Id").(string)
+	answers, err := handler.service.GetAllAnswers(r.Context(), quesId, userId)
 	if err != nil {
-		response := utils.NewInternalServerError("error while getting answers")
-		response.ToJson(w, http.StatusInternalServerError)
+		utils.WriteError(w, r, err)
 		return
 	}
-	utils.Logger.Info("Successfully retrieved all answers")
+	utils.LoggerFrom(r.Context()).Info("Successfully retrieved all answers")
 	response := &models.Response{
 		Message: "Successfully retrieved all answers",
 		Code:    http.StatusOK,
@@ -744,3 +1029,359 @@ func (handler *UserHandler) GetAllAnswers(w http.ResponseWriter, r *http.Request
 	response.ToJson(w, http.StatusOK)
 	return
 }
+
+func (handler *UserHandler) VoteAnswer(w http.ResponseWriter, r *http.Request) {
+	quesId := mux.Vars(r)["ques_id"]
+	answerId := mux.Vars(r)["answer_id"]
+	userId := r.Context().Value("Id").(string)
+	var request models.VoteAnswer
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		utils.WriteError(w, r, utils.InvalidBody)
+		return
+	}
+	err = handler.validator.Struct(request)
+	if err != nil {
+		utils.WriteError(w, r, utils.ValidationError(err))
+		return
+	}
+	err = handler.service.VoteAnswer(r.Context(), quesId, answerId, userId, request.Vote)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+	utils.LoggerFrom(r.Context()).Info("Answer voted")
+	response := &models.Response{
+		Code:    http.StatusOK,
+		Message: "Vote recorded",
+		Data:    request.Vote,
+	}
+	response.ToJson(w, http.StatusOK)
+	return
+}
+
+func (handler *UserHandler) AcceptAnswer(w http.ResponseWriter, r *http.Request) {
+	quesId := mux.Vars(r)["ques_id"]
+	answerId := mux.Vars(r)["answer_id"]
+	userId := r.Context().Value("Id").(string)
+	var request models.AcceptAnswer
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		utils.WriteError(w, r, utils.InvalidBody)
+		return
+	}
+	err = handler.validator.Struct(request)
+	if err != nil {
+		utils.WriteError(w, r, utils.ValidationError(err))
+		return
+	}
+	accepted, err := handler.service.AcceptAnswer(r.Context(), request.PostId, quesId, answerId, userId)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+	message := "Answer accepted"
+	if !accepted {
+		message = "Answer unaccepted"
+	}
+	utils.LoggerFrom(r.Context()).Info(message)
+	response := &models.Response{
+		Code:    http.StatusOK,
+		Message: message,
+		Data:    accepted,
+	}
+	response.ToJson(w, http.StatusOK)
+	return
+}
+
+func (handler *UserHandler) ReactToQuestion(w http.ResponseWriter, r *http.Request) {
+	postId := mux.Vars(r)["post_id"]
+	quesId := mux.Vars(r)["ques_id"]
+	userId := r.Context().Value("Id").(string)
+	var request models.React
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		utils.WriteError(w, r, utils.InvalidBody)
+		return
+	}
+	err = handler.validator.Struct(request)
+	if err != nil {
+		utils.WriteError(w, r, utils.ValidationError(err))
+		return
+	}
+	err = handler.service.ReactToQuestion(r.Context(), postId, quesId, userId, request.Reaction)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+	utils.LoggerFrom(r.Context()).Info("Reacted to question")
+	response := &models.Response{
+		Code:    http.StatusOK,
+		Message: "Reaction recorded",
+		Data:    request.Reaction,
+	}
+	response.ToJson(w, http.StatusOK)
+	return
+}
+
+func (handler *UserHandler) ReactToAnswer(w http.ResponseWriter, r *http.Request) {
+	quesId := mux.Vars(r)["ques_id"]
+	answerId := mux.Vars(r)["answer_id"]
+	userId := r.Context().Value("Id").(string)
+	var request models.React
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		utils.WriteError(w, r, utils.InvalidBody)
+		return
+	}
+	err = handler.validator.Struct(request)
+	if err != nil {
+		utils.WriteError(w, r, utils.ValidationError(err))
+		return
+	}
+	err = handler.service.ReactToAnswer(r.Context(), quesId, answerId, userId, request.Reaction)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+	utils.LoggerFrom(r.Context()).Info("Reacted to answer")
+	response := &models.Response{
+		Code:    http.StatusOK,
+		Message: "Reaction recorded",
+		Data:    request.Reaction,
+	}
+	response.ToJson(w, http.StatusOK)
+	return
+}
+
+func (handler *UserHandler) UpdateQuestion(w http.ResponseWriter, r *http.Request) {
+	quesId := mux.Vars(r)["ques_id"]
+	postId := mux.Vars(r)["post_id"]
+	userId := r.Context().Value("Id").(string)
+	var request models.UpdateQuestion
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		utils.WriteError(w, r, utils.InvalidBody)
+		return
+	}
+	err = handler.validator.Struct(request)
+	if err != nil {
+		utils.WriteError(w, r, utils.ValidationError(err))
+		return
+	}
+	err = handler.service.UpdateQuestion(r.Context(), postId, quesId, userId, request.Text)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+	utils.LoggerFrom(r.Context()).Info("Question updated")
+	response := &models.Response{
+		Code:    http.StatusOK,
+		Message: "Question Updated",
+	}
+	response.ToJson(w, http.StatusOK)
+	return
+}
+
+func (handler *UserHandler) UpdateAnswer(w http.ResponseWriter, r *http.Request) {
+	quesId := mux.Vars(r)["ques_id"]
+	answerId := mux.Vars(r)["answer_id"]
+	userId := r.Context().Value("Id").(string)
+	var request models.UpdateAnswer
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		utils.WriteError(w, r, utils.InvalidBody)
+		return
+	}
+	err = handler.validator.Struct(request)
+	if err != nil {
+		utils.WriteError(w, r, utils.ValidationError(err))
+		return
+	}
+	err = handler.service.UpdateAnswer(r.Context(), quesId, answerId, userId, request.Answer)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+	utils.LoggerFrom(r.Context()).Info("Answer updated")
+	response := &models.Response{
+		Code:    http.StatusOK,
+		Message: "Answer Updated",
+	}
+	response.ToJson(w, http.StatusOK)
+	return
+}
+
+// revision history handlers
+
+func (handler *UserHandler) GetPostRevisions(w http.ResponseWriter, r *http.Request) {
+	postId := mux.Vars(r)["post_id"]
+	handler.writeRevisions(w, r, config.PostContent, postId)
+}
+
+func (handler *UserHandler) GetQuestionRevisions(w http.ResponseWriter, r *http.Request) {
+	quesId := mux.Vars(r)["ques_id"]
+	handler.writeRevisions(w, r, config.QuestionContent, quesId)
+}
+
+func (handler *UserHandler) GetAnswerRevisions(w http.ResponseWriter, r *http.Request) {
+	answerId := mux.Vars(r)["answer_id"]
+	handler.writeRevisions(w, r, config.AnswerContent, answerId)
+}
+
+func (handler *UserHandler) writeRevisions(w http.ResponseWriter, r *http.Request, contentType config.ContentType, id string) {
+	revisions, err := handler.service.GetRevisions(r.Context(), contentType, id)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+	utils.LoggerFrom(r.Context()).Info("Successfully retrieved revisions")
+	response := &models.Response{
+		Message: "Successfully retrieved revisions",
+		Code:    http.StatusOK,
+		Data:    revisions,
+	}
+	response.ToJson(w, http.StatusOK)
+}
+
+// comment related handlers
+
+func (handler *UserHandler) CreateComment(w http.ResponseWriter, r *http.Request) {
+	postId := mux.Vars(r)["post_id"]
+	userId := r.Context().Value("Id").(string)
+	var request models.RequestComment
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		utils.WriteError(w, r, utils.InvalidBody)
+		return
+	}
+	err = handler.validator.Struct(request)
+	if err != nil {
+		utils.WriteError(w, r, utils.ValidationError(err))
+		return
+	}
+	comment, err := handler.service.AddComment(r.Context(), userId, postId, &request)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+	utils.LoggerFrom(r.Context()).Info("Comment added")
+	response := &models.Response{
+		Code:    http.StatusOK,
+		Message: "Comment Added",
+		Data:    comment,
+	}
+	response.ToJson(w, http.StatusCreated)
+	return
+}
+
+func (handler *UserHandler) DeleteComment(w http.ResponseWriter, r *http.Request) {
+	postId := mux.Vars(r)["post_id"]
+	commentId := mux.Vars(r)["comment_id"]
+	userId := r.Context().Value("Id").(string)
+	err := handler.service.DeleteComment(r.Context(), userId, postId, commentId)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+	utils.LoggerFrom(r.Context()).Info("Comment deleted")
+	response := &models.Response{
+		Code:    http.StatusOK,
+		Message: "Comment Deleted",
+	}
+	response.ToJson(w, http.StatusOK)
+	return
+}
+
+func (handler *UserHandler) GetAllComments(w http.ResponseWriter, r *http.Request) {
+	postId := mux.Vars(r)["post_id"]
+	userId := r.Context().Value("Id").(string)
+	comments, err := handler.service.GetComments(r.Context(), postId, userId)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+	utils.LoggerFrom(r.Context()).Info("Successfully retrieved all comments")
+	response := &models.Response{
+		Message: "Successfully retrieved all comments",
+		Code:    http.StatusOK,
+		Data:    comments,
+	}
+	response.ToJson(w, http.StatusOK)
+	return
+}
+
+func (handler *UserHandler) ReportPost(w http.ResponseWriter, r *http.Request) {
+	postId := mux.Vars(r)["post_id"]
+	userId := r.Context().Value("Id").(string)
+	request, ok := handler.decodeReport(w, r)
+	if !ok {
+		return
+	}
+	err := handler.service.ReportPost(r.Context(), userId, postId, request)
+	handler.writeReportResult(w, r, err)
+}
+
+func (handler *UserHandler) ReportQuestion(w http.ResponseWriter, r *http.Request) {
+	postId := mux.Vars(r)["post_id"]
+	quesId := mux.Vars(r)["ques_id"]
+	userId := r.Context().Value("Id").(string)
+	request, ok := handler.decodeReport(w, r)
+	if !ok {
+		return
+	}
+	err := handler.service.ReportQuestion(r.Context(), userId, postId, quesId, request)
+	handler.writeReportResult(w, r, err)
+}
+
+func (handler *UserHandler) ReportAnswer(w http.ResponseWriter, r *http.Request) {
+	quesId := mux.Vars(r)["ques_id"]
+	answerId := mux.Vars(r)["answer_id"]
+	userId := r.Context().Value("Id").(string)
+	request, ok := handler.decodeReport(w, r)
+	if !ok {
+		return
+	}
+	err := handler.service.ReportAnswer(r.Context(), userId, quesId, answerId, request)
+	handler.writeReportResult(w, r, err)
+}
+
+func (handler *UserHandler) ReportUser(w http.ResponseWriter, r *http.Request) {
+	reportedId := mux.Vars(r)["user_id"]
+	userId := r.Context().Value("Id").(string)
+	request, ok := handler.decodeReport(w, r)
+	if !ok {
+		return
+	}
+	err := handler.service.ReportUser(r.Context(), userId, reportedId, request)
+	handler.writeReportResult(w, r, err)
+}
+
+func (handler *UserHandler) decodeReport(w http.ResponseWriter, r *http.Request) (*models.RequestReport, bool) {
+	var request models.RequestReport
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		utils.WriteError(w, r, utils.InvalidBody)
+		return nil, false
+	}
+	err = handler.validator.Struct(request)
+	if err != nil {
+		utils.WriteError(w, r, utils.ValidationError(err))
+		return nil, false
+	}
+	return &request, true
+}
+
+func (handler *UserHandler) writeReportResult(w http.ResponseWriter, r *http.Request, err error) {
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+	utils.LoggerFrom(r.Context()).Info("Report filed")
+	response := &models.Response{
+		Code:    http.StatusCreated,
+		Message: "Report submitted",
+	}
+	response.ToJson(w, http.StatusCreated)
+	return
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"context"
+	"github.com/go-playground/validator"
+	"github.com/gorilla/mux"
+	"localeyes/internal/interfaces"
+	"localeyes/internal/models"
+	"localeyes/utils"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fakeUserService answers the calls a test makes, the rest are left to the embedded interface
+// and panic when called
+type fakeUserService struct {
+	interfaces.UserServiceInterface
+	updateUser func(uId string, version int) (int, error)
+	getPost    func(uId, pId string) (*models.Post, error)
+	updated    []string
+}
+
+func (s *fakeUserService) UpdateUser(ctx context.Context, uId string, requestUser *models.UpdateClient, version int) (int, error) {
+	s.updated = append(s.updated, uId)
+	return s.updateUser(uId, version)
+}
+
+func (s *fakeUserService) GetPost(ctx context.Context, uId string, pId string) (*models.Post, error) {
+	return s.getPost(uId, pId)
+}
+
+// newValidator registers the validations main registers
+func newValidator() *validator.Validate {
+	validate := validator.New()
+	_ = validate.RegisterValidation("isValidFilter", utils.ValidateFilter)
+	_ = validate.RegisterValidation("isValidPassword", utils.ValidatePassword)
+	_ = validate.RegisterValidation("isValidTime", utils.ValidateTime)
+	_ = validate.RegisterValidation("isValidVote", utils.ValidateVote)
+	_ = validate.RegisterValidation("isValidReaction", utils.ValidateReaction)
+	_ = validate.RegisterValidation("isValidReportReason", utils.ValidateReportReason)
+	_ = validate.RegisterValidation("isValidResolution", utils.ValidateResolution)
+	return validate
+}
+
+// serve routes one request to handle under route, as the caller and role the authentication
+// middleware would have set
+func serve(handle http.HandlerFunc, method, route, path, callerId, role string, header http.Header, body string) *httptest.ResponseRecorder {
+	router := mux.NewRouter()
+	router.HandleFunc(route, handle).Methods(method)
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	for name, values := range header {
+		r.Header[name] = values
+	}
+	ctx := context.WithValue(r.Context(), "Id", callerId)
+	ctx = context.WithValue(ctx, "Role", role)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, r.WithContext(ctx))
+	return recorder
+}
+
+func TestUpdateUserById(t *testing.T) {
+	const profile = `{"password":"Secret#123","city":"Pune","living_since":{"days":0,"months":0,"years":2}}`
+	tests := []struct {
+		name     string
+		callerId string
+		role     string
+		ifMatch  string
+		result   error
+		status   int
+		etag     string
+		updated  bool
+	}{
+		{"own profile", "u1", "user", `"3"`, nil, http.StatusOK, `"4"`, true},
+		{"someone else's profile", "u2", "user", `"3"`, nil, http.StatusForbidden, "", false},
+		{"someone else's profile without If-Match", "u2", "user", "", nil, http.StatusForbidden, "", false},
+		{"admin", "admin1", "admin", `"3"`, nil, http.StatusOK, `"4"`, true},
+		{"missing If-Match", "u1", "user", "", nil, http.StatusPreconditionRequired, "", false},
+		{"malformed If-Match", "u1", "user", "3", nil, http.StatusBadRequest, "", false},
+		{"outdated version", "u1", "user", `"2"`, utils.VersionMismatch, http.StatusPreconditionFailed, "", true},
+		{"deleted user", "u1", "user", `"3"`, utils.NoUser, http.StatusNotFound, "", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			service := &fakeUserService{updateUser: func(uId string, version int) (int, error) {
+				if test.result != nil {
+					return 0, test.result
+				}
+				return version + 1, nil
+			}}
+			handler := NewUserHandler(service, newValidator())
+			header := http.Header{}
+			if test.ifMatch != "" {
+				header.Set("If-Match", test.ifMatch)
+			}
+			recorder := serve(handler.UpdateUserById, http.MethodPut, "/user/{user_id}", "/user/u1", test.callerId, test.role, header, profile)
+			if recorder.Code != test.status {
+				t.Fatalf("status %d, want %d: %s", recorder.Code, test.status, recorder.Body.String())
+			}
+			if etag := recorder.Header().Get("ETag"); etag != test.etag {
+				t.Fatalf("ETag %q, want %q", etag, test.etag)
+			}
+			if updated := len(service.updated) > 0; updated != test.updated {
+				t.Fatalf("updated = %v, want %v", updated, test.updated)
+			}
+			if test.updated && service.updated[0] != "u1" {
+				t.Fatalf("updated user %q, want u1", service.updated[0])
+			}
+		})
+	}
+}
+
+func TestGetPost(t *testing.T) {
+	tests := []struct {
+		name   string
+		post   *models.Post
+		err    error
+		status int
+		etag   string
+	}{
+		{"post", &models.Post{PostId: "p1", UId: "u2", Version: 5}, nil, http.StatusOK, `"5"`},
+		{"missing post", nil, utils.NoPost, http.StatusNotFound, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			service := &fakeUserService{getPost: func(uId, pId string) (*models.Post, error) {
+				return test.post, test.err
+			}}
+			handler := NewUserHandler(service, newValidator())
+			recorder := serve(handler.GetPost, http.MethodGet, "/post/{post_id}", "/post/p1", "u1", "user", nil, "")
+			if recorder.Code != test.status {
+				t.Fatalf("status %d, want %d: %s", recorder.Code, test.status, recorder.Body.String())
+			}
+			if etag := recorder.Header().Get("ETag"); etag != test.etag {
+				t.Fatalf("ETag %q, want %q", etag, test.etag)
+			}
+		})
+	}
+}
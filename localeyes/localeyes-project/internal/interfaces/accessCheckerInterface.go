@@ -0,0 +1,8 @@
+package interfaces
+
+import "context"
+
+type AccessCheckerInterface interface {
+	// CheckAccess fails unless the account of uId may still use the API
+	CheckAccess(ctx context.Context, uId string) error
+}
@@ -2,14 +2,30 @@ package interfaces
 
 import (
 	"context"
+	"localeyes/config"
 	"localeyes/internal/models"
+	"time"
 )
 
 type AdminServiceInterface interface {
 	GetAllUsers(ctx context.Context, params models.GetUsersParams) ([]*models.ResponseUser, error)
-	ReactivateUser(ctx context.Context, uId string) error
-	DeleteUser(ctx context.Context, user *models.DeleteUser) error
-	DeletePost(ctx context.Context, uId string, pId string, post *models.DeletePost) error
-	DeleteQuestion(ctx context.Context, pId string, qId string, uId string) error
-	DeleteAnswer(ctx context.Context, rId string, qId string, uId string) error
+	SearchUsers(ctx context.Context, search *models.UserSearch) (*models.ResponseUserSearch, error)
+	ReactivateUser(ctx context.Context, uId string, reason string) error
+	SuspendUser(ctx context.Context, uId string, until time.Time, reason string) error
+	BanUser(ctx context.Context, uId string, reason string) error
+	UnbanUser(ctx context.Context, uId string, reason string) error
+	DeleteUser(ctx context.Context, user *models.DeleteUser, reason string) (*models.Job, error)
+	GetJob(ctx context.Context, id string) (*models.Job, error)
+	BulkDeleteUserContent(ctx context.Context, uId string, dryRun bool, reason string) (*models.Job, error)
+	BulkDeletePosts(ctx context.Context, request *models.BulkDeletePosts, dryRun bool) (*models.Job, error)
+	BulkChangeAccounts(ctx context.Context, action config.BulkAction, request *models.BulkAccountChange, dryRun bool) (*models.Job, error)
+	ExportUser(ctx context.Context, uId string, format config.ExportFormat) (*models.Job, error)
+	DeletePost(ctx context.Context, uId string, pId string, post *models.DeletePost, reason string) error
+	DeleteQuestion(ctx context.Context, pId string, qId string, uId string, reason string) error
+	DeleteAnswer(ctx context.Context, rId string, qId string, uId string, reason string) error
+	GetStats(ctx context.Context, granularity config.StatsGranularity, from time.Time, to time.Time) (*models.ResponseStats, error)
+	GetAuditLog(ctx context.Context, query *models.AuditQuery) ([]*models.AuditEntry, string, error)
+	ListReports(ctx context.Context, status config.ReportStatus, limit int, cursor string) ([]*models.ReportCase, string, error)
+	GetReport(ctx context.Context, targetType config.ContentType, targetId string) (*models.ResponseReportCase, error)
+	ResolveReport(ctx context.Context, adminId string, targetType config.ContentType, targetId string, status config.ReportStatus, reason string) error
 }
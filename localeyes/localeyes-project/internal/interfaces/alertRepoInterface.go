@@ -0,0 +1,12 @@
+package interfaces
+
+import (
+	"context"
+	"time"
+)
+
+type AlertRepoInterface interface {
+	// ClaimWindow reports whether this process is the first to send the alert group with the
+	// fingerprint for the window starting at window
+	ClaimWindow(ctx context.Context, fingerprint string, window time.Time) (bool, error)
+}
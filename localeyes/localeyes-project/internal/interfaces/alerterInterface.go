@@ -0,0 +1,13 @@
+package interfaces
+
+import (
+	"context"
+	"localeyes/internal/models"
+)
+
+type AlerterInterface interface {
+	// Alert queues the alert and returns straight away, it never waits on the network
+	Alert(alert *models.Alert)
+	// Flush sends the queued alerts and returns once they are sent
+	Flush(ctx context.Context)
+}
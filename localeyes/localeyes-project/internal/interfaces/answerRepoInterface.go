@@ -2,11 +2,20 @@ package interfaces
 
 import (
 	"context"
+	"localeyes/config"
 	"localeyes/internal/models"
+	"time"
 )
 
 type AnswerRepoInterface interface {
 	AddAnswer(ctx context.Context, answer *models.Reply) error
 	DeleteAnswer(ctx context.Context, qId string, rId string, uId string) error
 	GetAllAnswersByQId(ctx context.Context, qId string) ([]*models.Reply, error)
+	GetAnswer(ctx context.Context, qId string, rId string) (*models.Reply, error)
+	VoteAnswer(ctx context.Context, qId string, rId string, uId string, vote config.VoteType) error
+	GetUserVotes(ctx context.Context, uId string, rIds []string) (map[string]config.VoteType, error)
+	AcceptAnswer(ctx context.Context, pId string, qId string, rId string, previousRId string) error
+	UpdateAnswer(ctx context.Context, qId string, rId string, uId string, text string, editedAt time.Time) error
+	GetAnswerCounts(ctx context.Context, qIds []string) (map[string]int, error)
+	SetAnswerHidden(ctx context.Context, qId string, rId string, hidden bool) error
 }
@@ -0,0 +1,11 @@
+package interfaces
+
+import (
+	"context"
+	"localeyes/internal/models"
+)
+
+type AuditRepoInterface interface {
+	Record(ctx context.Context, entry *models.AuditEntry) error
+	Query(ctx context.Context, query *models.AuditQuery) ([]*models.AuditEntry, string, error)
+}
@@ -0,0 +1,14 @@
+package interfaces
+
+import (
+	"context"
+	"localeyes/internal/models"
+	"time"
+)
+
+type BookmarkRepoInterface interface {
+	SavePost(ctx context.Context, uId string, pId string, postUId string, savedAt time.Time) error
+	UnsavePost(ctx context.Context, uId string, pId string) error
+	GetSavedPosts(ctx context.Context, uId string, limit int, cursor string) ([]*models.Bookmark, string, error)
+	GetSavedPostIds(ctx context.Context, uId string, pIds []string) (map[string]bool, error)
+}
@@ -0,0 +1,14 @@
+package interfaces
+
+import (
+	"context"
+	"localeyes/internal/models"
+	"time"
+)
+
+type CommentRepoInterface interface {
+	CreateComment(ctx context.Context, comment *models.Comment) error
+	GetCommentRef(ctx context.Context, cId string) (*models.CommentRef, error)
+	GetCommentsByPId(ctx context.Context, pId string) ([]*models.Comment, error)
+	SoftDeleteComment(ctx context.Context, pId string, path string, uId string, deletedAt time.Time) error
+}
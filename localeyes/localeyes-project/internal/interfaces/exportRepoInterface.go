@@ -0,0 +1,11 @@
+package interfaces
+
+import (
+	"context"
+	"localeyes/internal/models"
+)
+
+type ExportRepoInterface interface {
+	SaveArchive(ctx context.Context, token string, export *models.Export, archive []byte) error
+	GetArchive(ctx context.Context, token string) (*models.Export, []byte, error)
+}
@@ -0,0 +1,17 @@
+package interfaces
+
+import (
+	"context"
+	"localeyes/internal/models"
+	"time"
+)
+
+type FollowRepoInterface interface {
+	Follow(ctx context.Context, uId string, followeeId string, followedAt time.Time) error
+	Unfollow(ctx context.Context, uId string, followeeId string) error
+	IsFollowing(ctx context.Context, uId string, followeeId string) (bool, error)
+	GetFollowers(ctx context.Context, uId string, limit int, cursor string) ([]*models.Follow, string, error)
+	GetFollowing(ctx context.Context, uId string, limit int, cursor string) ([]*models.Follow, string, error)
+	GetAllFollowing(ctx context.Context, uId string) ([]string, error)
+	GetFollowCounts(ctx context.Context, uId string) (*models.Counts, error)
+}
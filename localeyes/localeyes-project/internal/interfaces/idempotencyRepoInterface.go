@@ -0,0 +1,12 @@
+package interfaces
+
+import (
+	"context"
+	"localeyes/internal/models"
+)
+
+type IdempotencyRepoInterface interface {
+	Claim(ctx context.Context, scope, key string, record *models.IdempotencyRecord) (*models.IdempotencyRecord, error)
+	Complete(ctx context.Context, scope, key string, record *models.IdempotencyRecord) error
+	Release(ctx context.Context, scope, key string) error
+}
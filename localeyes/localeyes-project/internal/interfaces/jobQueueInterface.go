@@ -0,0 +1,9 @@
+package interfaces
+
+import "context"
+
+type JobQueueInterface interface {
+	// Enqueue hands a saved job to a worker. A job may be handed out more than once, only the
+	// worker holding its lease runs it.
+	Enqueue(ctx context.Context, jobId string) error
+}
@@ -0,0 +1,14 @@
+package interfaces
+
+import (
+	"context"
+	"localeyes/internal/models"
+	"time"
+)
+
+type JobRepoInterface interface {
+	SaveJob(ctx context.Context, job *models.Job) error
+	SaveJobItem(ctx context.Context, job *models.Job, index int) error
+	GetJob(ctx context.Context, id string) (*models.Job, error)
+	ClaimJob(ctx context.Context, id string, until time.Time) (*models.Job, error)
+}
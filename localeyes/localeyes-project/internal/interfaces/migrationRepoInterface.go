@@ -0,0 +1,14 @@
+package interfaces
+
+import (
+	"context"
+	"localeyes/internal/models"
+	"time"
+)
+
+type MigrationRepoInterface interface {
+	GetApplied(ctx context.Context) (map[int]*models.MigrationRecord, error)
+	RecordApplied(ctx context.Context, record *models.MigrationRecord) error
+	AcquireLock(ctx context.Context, owner string, ttl time.Duration) error
+	ReleaseLock(ctx context.Context, owner string) error
+}
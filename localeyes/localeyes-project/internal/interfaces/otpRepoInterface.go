@@ -6,4 +6,5 @@ type OTPRepoInterface interface {
 	GenerateOTP() (string, error)
 	SaveOTP(ctx context.Context, email string, otp string) error
 	ValidateOTP(ctx context.Context, email string, otp string) bool
+	DeleteOTPs(ctx context.Context, email string) error
 }
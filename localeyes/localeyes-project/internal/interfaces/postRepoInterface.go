@@ -9,10 +9,18 @@ import (
 
 type PostRepository interface {
 	Create(ctx context.Context, post *models.Post) error
-	GetAllPostsWithFilter(ctx context.Context, limit *int, offset *int, search *string, filter *string) ([]*models.Post, error)
+	GetAllPostsWithFilter(ctx context.Context, limit *int, offset *int, search *string, filter *string, excluded map[string]bool) ([]*models.Post, error)
 	DeletePost(ctx context.Context, filter config.Filter, createdAt time.Time, uId string, pId string) error
 	GetPostsByUId(ctx context.Context, uId string) ([]*models.Post, error)
-	UpdatePost(ctx context.Context, uId string, post *models.Post) error
-	ToggleLike(ctx context.Context, postUId, uId, filter, pId string, createdAt time.Time) (config.LikeStatus, error)
+	GetUserPostsBefore(ctx context.Context, uId string, before time.Time, limit int) ([]*models.Post, error)
+	UpdatePost(ctx context.Context, uId string, post *models.Post) (int, error)
+	UpdateLikeCount(ctx context.Context, postUId, filter, pId string, createdAt time.Time, delta int) error
+	DeleteLikeEntry(ctx context.Context, uId string, pId string) error
 	HasUserLikedAPost(ctx context.Context, uId string, pId string) (bool, error)
+	IsPostOwner(ctx context.Context, uId string, pId string) (bool, error)
+	GetUserPost(ctx context.Context, uId string, pId string) (*models.Post, error)
+	GetPostOwner(ctx context.Context, pId string) (string, error)
+	GetPostsByOwners(ctx context.Context, owners map[string]string) (map[string]*models.Post, error)
+	GetPostCounts(ctx context.Context, pIds []string) (map[string]*models.Counts, error)
+	SetPostHidden(ctx context.Context, uId string, pId string, hidden bool) error
 }
@@ -3,10 +3,15 @@ package interfaces
 import (
 	"context"
 	"localeyes/internal/models"
+	"time"
 )
 
 type QuestionRepoInterface interface {
 	Create(ctx context.Context, question *models.Question) error
 	DeleteByQId(ctx context.Context, qId string, pId string, uId string) error
 	GetAllQuestionsByPId(ctx context.Context, pId string) ([]*models.Question, error)
+	GetQuestionRef(ctx context.Context, qId string) (*models.QuestionRef, error)
+	GetQuestion(ctx context.Context, pId string, qId string) (*models.Question, error)
+	UpdateQuestion(ctx context.Context, pId string, qId string, uId string, text string, editedAt time.Time) error
+	SetQuestionHidden(ctx context.Context, pId string, qId string, hidden bool) error
 }
@@ -0,0 +1,11 @@
+package interfaces
+
+import (
+	"context"
+	"localeyes/config"
+	"localeyes/internal/models"
+)
+
+type RateLimiterInterface interface {
+	Allow(ctx context.Context, subject string, policy config.RateLimitPolicy) (*models.RateLimitDecision, error)
+}
@@ -0,0 +1,13 @@
+package interfaces
+
+import (
+	"context"
+	"localeyes/config"
+	"localeyes/internal/models"
+)
+
+type ReactionRepoInterface interface {
+	GetUserReaction(ctx context.Context, contentType config.ContentType, id string, uId string) (config.ReactionType, error)
+	React(ctx context.Context, contentType config.ContentType, id string, uId string, reaction config.ReactionType) (config.ReactionType, error)
+	GetReactionSummaries(ctx context.Context, contentType config.ContentType, ids []string) (map[string]models.ReactionSummary, error)
+}
@@ -0,0 +1,18 @@
+package interfaces
+
+import (
+	"context"
+	"localeyes/internal/models"
+	"time"
+)
+
+type RelationRepoInterface interface {
+	Block(ctx context.Context, uId string, blockedId string, createdAt time.Time) error
+	Unblock(ctx context.Context, uId string, blockedId string) error
+	Mute(ctx context.Context, uId string, mutedId string, createdAt time.Time) error
+	Unmute(ctx context.Context, uId string, mutedId string) error
+	GetBlocked(ctx context.Context, uId string) ([]*models.Relation, error)
+	GetMuted(ctx context.Context, uId string) ([]*models.Relation, error)
+	GetHiddenUsers(ctx context.Context, uId string, includeMuted bool) (map[string]bool, error)
+	IsBlocked(ctx context.Context, uId string, otherId string) (bool, error)
+}
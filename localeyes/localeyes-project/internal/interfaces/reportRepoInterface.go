@@ -0,0 +1,17 @@
+package interfaces
+
+import (
+	"context"
+	"localeyes/config"
+	"localeyes/internal/models"
+	"time"
+)
+
+type ReportRepoInterface interface {
+	Report(ctx context.Context, reportCase *models.ReportCase, report *models.Report) (*models.ReportCase, error)
+	GetCase(ctx context.Context, targetType config.ContentType, targetId string) (*models.ReportCase, error)
+	GetReports(ctx context.Context, targetType config.ContentType, targetId string) ([]*models.Report, error)
+	ListCases(ctx context.Context, status config.ReportStatus, limit int, cursor string) ([]*models.ReportCase, string, error)
+	SetCaseHidden(ctx context.Context, targetType config.ContentType, targetId string, hidden bool) error
+	Resolve(ctx context.Context, targetType config.ContentType, targetId string, status config.ReportStatus, adminId string, resolvedAt time.Time) error
+}
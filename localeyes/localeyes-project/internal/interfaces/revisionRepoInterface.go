@@ -0,0 +1,11 @@
+package interfaces
+
+import (
+	"context"
+	"localeyes/config"
+	"localeyes/internal/models"
+)
+
+type RevisionRepoInterface interface {
+	GetRevisions(ctx context.Context, contentType config.ContentType, id string) ([]*models.Revision, error)
+}
@@ -0,0 +1,15 @@
+package interfaces
+
+import (
+	"context"
+	"localeyes/config"
+	"localeyes/internal/models"
+	"time"
+)
+
+type StatsRepoInterface interface {
+	Increment(ctx context.Context, at time.Time, counts map[config.StatsMetric]int) error
+	MarkActive(ctx context.Context, uId string, at time.Time) error
+	GetSeries(ctx context.Context, granularity config.StatsGranularity, from, to time.Time) ([]*models.StatsBucket, error)
+	GetTotals(ctx context.Context) (map[config.StatsMetric]int, error)
+}
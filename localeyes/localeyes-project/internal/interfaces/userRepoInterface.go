@@ -10,9 +10,13 @@ type UserRepository interface {
 	FetchUserByEmail(ctx context.Context, email string) (*models.UserSKEmail, error)
 	FetchUserByUsername(ctx context.Context, username string) (*models.UserSKUsername, error)
 	FetchUserById(ctx context.Context, uid string, isUserActive bool) (*models.User, error)
-	UpdateUserById(ctx context.Context, user *models.User) error
-	ToggleUserActiveStatus(ctx context.Context, user *models.User) error
+	UpdateUserById(ctx context.Context, user *models.User, expected int) (int, error)
+	SetAccountState(ctx context.Context, user *models.User, state *models.AccountState) error
 	FetchNotifications(ctx context.Context, uId string) ([]*models.Notification, error)
 	GetAllUsers(ctx context.Context, params models.GetUsersParams) ([]*models.User, error)
+	SearchUsers(ctx context.Context, search *models.UserSearch) ([]*models.UserIndexEntry, string, *int, error)
 	DeleteUser(ctx context.Context, uId, username, email string) error
+	FetchUsersByIds(ctx context.Context, uIds []string) (map[string]*models.User, error)
+	FindOwnedContent(ctx context.Context, uId string) (*models.OwnedContent, error)
+	DeleteNotifications(ctx context.Context, pIds []string) error
 }
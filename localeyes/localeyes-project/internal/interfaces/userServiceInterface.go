@@ -11,21 +11,54 @@ type UserServiceInterface interface {
 	Login(ctx context.Context, username string, password string) (*models.User, error)
 	FetchProfile(ctx context.Context, uid string) (*models.User, error)
 	DeActivate(ctx context.Context, uid string) error
+	StartExport(ctx context.Context, uId string, format config.ExportFormat) (*models.Job, error)
+	GetExport(ctx context.Context, uId string, jobId string) (*models.Job, error)
+	DownloadExport(ctx context.Context, token string) (*models.Export, []byte, error)
 	GetNotifications(ctx context.Context, uid string) ([]*models.Notification, error)
-	UpdateUser(ctx context.Context, uId string, requestUser *models.UpdateClient) error
+	UpdateUser(ctx context.Context, uId string, requestUser *models.UpdateClient, version int) (int, error)
 	CreatePost(ctx context.Context, userId string, title string, content string, postType config.Filter) error
-	UpdatePost(ctx context.Context, post *models.UpdatePost) error
-	GiveAllPosts(ctx context.Context, limit *int, offset *int, search *string, filter *string) ([]*models.Post, error)
+	UpdatePost(ctx context.Context, post *models.UpdatePost) (int, error)
+	GiveAllPosts(ctx context.Context, uId string, limit *int, offset *int, search *string, filter *string) ([]*models.Post, error)
 	GiveUserPosts(ctx context.Context, uId string) ([]*models.Post, error)
+	GetPost(ctx context.Context, uId string, pId string) (*models.Post, error)
+	SavePost(ctx context.Context, uId string, pId string, post *models.SavePost) error
+	UnsavePost(ctx context.Context, uId string, pId string) error
+	GetSavedPosts(ctx context.Context, uId string, limit int, cursor string) ([]*models.Post, string, error)
+	Follow(ctx context.Context, uId string, followeeId string) error
+	Unfollow(ctx context.Context, uId string, followeeId string) error
+	GetFollowers(ctx context.Context, uId string, limit int, cursor string) ([]*models.ResponseFollow, string, error)
+	GetFollowing(ctx context.Context, uId string, limit int, cursor string) ([]*models.ResponseFollow, string, error)
+	GetFollowingFeed(ctx context.Context, uId string, limit int, cursor string) ([]*models.Post, string, error)
+	Block(ctx context.Context, uId string, blockedId string) error
+	Unblock(ctx context.Context, uId string, blockedId string) error
+	Mute(ctx context.Context, uId string, mutedId string) error
+	Unmute(ctx context.Context, uId string, mutedId string) error
+	GetBlockedUsers(ctx context.Context, uId string) ([]*models.ResponseRelation, error)
+	GetMutedUsers(ctx context.Context, uId string) ([]*models.ResponseRelation, error)
 	DeleteUserPost(ctx context.Context, uId string, pId string, post *models.DeletePost) error
 	Like(ctx context.Context, uId string, pId string, post *models.LikePost) (config.LikeStatus, error)
 	GetLikeStatus(ctx context.Context, uId string, pId string) (config.LikeStatus, error)
+	ReactToPost(ctx context.Context, uId string, pId string, post *models.ReactPost) error
+	ReactToQuestion(ctx context.Context, pId string, qId string, uId string, reaction config.ReactionType) error
+	ReactToAnswer(ctx context.Context, qId string, rId string, uId string, reaction config.ReactionType) error
 	SendOtp(ctx context.Context, email string) error
 	PasswordReset(ctx context.Context, resetUser models.ResetPasswordUser) error
 	AddQuestion(ctx context.Context, ques *models.RequestQuestion) error
 	DeleteQuestion(ctx context.Context, pId string, qId string, uId string) error
-	GetQuestionByPId(ctx context.Context, pId string) ([]*models.Question, error)
+	GetQuestionByPId(ctx context.Context, pId string, uId string) ([]*models.ResponseQuestion, error)
 	AddAnswer(ctx context.Context, ans *models.RequestAnswer) error
 	DeleteAnswer(ctx context.Context, qId string, rId string, uId string) error
-	GetAllAnswers(ctx context.Context, qId string) ([]*models.Reply, error)
+	GetAllAnswers(ctx context.Context, qId string, uId string) ([]*models.ResponseAnswer, error)
+	VoteAnswer(ctx context.Context, qId string, rId string, uId string, vote config.VoteType) error
+	AcceptAnswer(ctx context.Context, pId string, qId string, rId string, uId string) (bool, error)
+	UpdateQuestion(ctx context.Context, pId string, qId string, uId string, text string) error
+	UpdateAnswer(ctx context.Context, qId string, rId string, uId string, text string) error
+	GetRevisions(ctx context.Context, contentType config.ContentType, id string) ([]*models.Revision, error)
+	AddComment(ctx context.Context, uId string, pId string, request *models.RequestComment) (*models.Comment, error)
+	DeleteComment(ctx context.Context, uId string, pId string, cId string) error
+	GetComments(ctx context.Context, pId, uId string) ([]*models.ResponseComment, error)
+	ReportPost(ctx context.Context, uId string, pId string, request *models.RequestReport) error
+	ReportQuestion(ctx context.Context, uId string, pId string, qId string, request *models.RequestReport) error
+	ReportAnswer(ctx context.Context, uId string, qId string, rId string, request *models.RequestReport) error
+	ReportUser(ctx context.Context, uId string, reportedId string, request *models.RequestReport) error
 }
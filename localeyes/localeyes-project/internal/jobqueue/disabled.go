@@ -0,0 +1,18 @@
+package jobqueue
+
+import (
+	"context"
+	"localeyes/utils"
+)
+
+// DisabledQueue stands in when no job queue is configured. The API still serves everything that
+// runs within a request and only the endpoints starting jobs fail.
+type DisabledQueue struct{}
+
+func NewDisabledQueue() *DisabledQueue {
+	return &DisabledQueue{}
+}
+
+func (queue *DisabledQueue) Enqueue(ctx context.Context, jobId string) error {
+	return utils.JobsUnavailable
+}
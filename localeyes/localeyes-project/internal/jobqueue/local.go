@@ -0,0 +1,27 @@
+package jobqueue
+
+import (
+	"context"
+	"go.uber.org/zap"
+	"localeyes/utils"
+)
+
+// LocalQueue runs jobs in the background of the process itself. It is only meant for running
+// the API as a plain HTTP server, where the process outlives the request that started a job.
+type LocalQueue struct {
+	// Run is the worker the queued jobs are handed to
+	Run func(ctx context.Context, jobId string) error
+}
+
+func NewLocalQueue() *LocalQueue {
+	return &LocalQueue{}
+}
+
+func (queue *LocalQueue) Enqueue(ctx context.Context, jobId string) error {
+	go func() {
+		if err := queue.Run(context.Background(), jobId); err != nil {
+			utils.Logger.Error("Error running job", zap.String("job_id", jobId), zap.Error(err))
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,30 @@
+package jobqueue
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+)
+
+// SQSQueue queues jobs on the SQS queue the job worker function consumes. A message that is not
+// deleted, because its worker failed or was killed, is handed out again once its visibility
+// timeout has passed.
+type SQSQueue struct {
+	client   *sqs.Client
+	queueUrl string
+}
+
+func NewSQSQueue(client *sqs.Client, queueUrl string) *SQSQueue {
+	return &SQSQueue{
+		client:   client,
+		queueUrl: queueUrl,
+	}
+}
+
+func (queue *SQSQueue) Enqueue(ctx context.Context, jobId string) error {
+	_, err := queue.client.SendMessage(ctx, &sqs.SendMessageInput{
+		QueueUrl:    aws.String(queue.queueUrl),
+		MessageBody: aws.String(jobId),
+	})
+	return err
+}
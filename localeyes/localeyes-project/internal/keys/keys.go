@@ -0,0 +1,239 @@
+// Package keys builds and parses the partition and sort keys of the single table. A key is a
+// kind followed by its parts, all joined with ":". Parts are escaped so ids, emails and usernames
+// may contain ":" without changing how the key splits. Parts without ":" or "%" are written as
+// they are, so keys written before this package kept their format.
+package keys
+
+import (
+	"fmt"
+	"localeyes/config"
+	"localeyes/utils"
+	"strings"
+	"time"
+)
+
+const separator = ":"
+
+// Kind names the entity a key belongs to and is the first part of its keys
+type Kind string
+
+const (
+	User      Kind = "user"
+	Email     Kind = "email"
+	Username  Kind = "username"
+	OTP       Kind = "otp:email"
+	Post      Kind = "post"
+	Question  Kind = "question"
+	Reply     Kind = "reply"
+	Comment   Kind = "comment"
+	Like      Kind = "like"
+	Vote      Kind = "vote"
+	Bookmark  Kind = "bookmark"
+	Saved     Kind = "saved"
+	Follow    Kind = "follow"
+	Following Kind = "following"
+	Followers Kind = "followers"
+	Block     Kind = "block"
+	BlockedBy Kind = "blockedby"
+	Mute      Kind = "mute"
+	Reaction  Kind = "reaction"
+	Revision  Kind = "revision"
+	Report    Kind = "report"
+	Case      Kind = "case"
+	Audit     Kind = "audit"
+	Stats     Kind = "stats"
+	Active    Kind = "active"
+	Job       Kind = "job"
+	Export    Kind = "export"
+	Chunk     Kind = "chunk"
+	Index     Kind = "uindex"
+	Count     Kind = "count"
+	Version   Kind = "version"
+	Idem      Kind = "idem"
+	RateLimit Kind = "ratelimit"
+	Alert     Kind = "alert"
+)
+
+// Partitions that hold one item per entity, under keys of its kind
+const (
+	Users         = "users"
+	Posts         = "posts"
+	Notifications = "notifications"
+)
+
+var escaper = strings.NewReplacer("%", "%25", ":", "%3A")
+
+// Escape encodes a part so it holds no separator
+func Escape(part string) string {
+	return escaper.Replace(part)
+}
+
+// Unescape decodes a part written by Escape. A "%" that starts no escape is kept as it is,
+// emails and usernames holding one were stored unescaped before this package existed and have
+// to be readable until RekeyLookups has moved them.
+func Unescape(part string) (string, error) {
+	if !strings.Contains(part, "%") {
+		return part, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(part); i++ {
+		switch {
+		case strings.HasPrefix(part[i:], "%25"):
+			b.WriteByte('%')
+			i += 2
+		case strings.HasPrefix(part[i:], "%3A"):
+			b.WriteByte(':')
+			i += 2
+		default:
+			b.WriteByte(part[i])
+		}
+	}
+	return b.String(), nil
+}
+
+// Key builds the key of the kind from its parts
+func (k Kind) Key(parts ...string) string {
+	var b strings.Builder
+	b.WriteString(string(k))
+	for _, part := range parts {
+		b.WriteString(separator)
+		b.WriteString(Escape(part))
+	}
+	return b.String()
+}
+
+// Prefix is what every key of the kind starting with parts begins with, for begins_with
+// conditions
+func (k Kind) Prefix(parts ...string) string {
+	return k.Key(parts...) + separator
+}
+
+// Is reports whether key is of the kind
+func (k Kind) Is(key string) bool {
+	return strings.HasPrefix(key, k.Prefix())
+}
+
+// Parse returns the id of a key made of the kind and a single part
+func (k Kind) Parse(key string) (string, error) {
+	parts, err := k.ParseParts(key, 1)
+	if err != nil {
+		return "", err
+	}
+	return parts[0], nil
+}
+
+// ParseParts returns the n parts of a key of the kind
+func (k Kind) ParseParts(key string, n int) ([]string, error) {
+	if !k.Is(key) {
+		return nil, fmt.Errorf("%w: %q is not a %s key", utils.MalformedKey, key, k)
+	}
+	parts := strings.Split(strings.TrimPrefix(key, k.Prefix()), separator)
+	if len(parts) != n {
+		return nil, fmt.Errorf("%w: %q has %d parts, %s keys have %d", utils.MalformedKey, key, len(parts), k, n)
+	}
+	for i, part := range parts {
+		unescaped, err := Unescape(part)
+		if err != nil {
+			return nil, err
+		}
+		if unescaped == "" {
+			return nil, fmt.Errorf("%w: %q has an empty part", utils.MalformedKey, key)
+		}
+		parts[i] = unescaped
+	}
+	return parts, nil
+}
+
+// Split separates a key of any kind into its kind and parts, for code reading keys of several
+// kinds at once. A kind made of more than one part, like OTP, comes back as its first part.
+func Split(key string) (Kind, []string, error) {
+	parts := strings.Split(key, separator)
+	for i, part := range parts[1:] {
+		unescaped, err := Unescape(part)
+		if err != nil {
+			return "", nil, err
+		}
+		parts[i+1] = unescaped
+	}
+	return Kind(parts[0]), parts[1:], nil
+}
+
+// Listing is a post in the "posts" partition, sorted by type then creation time
+type Listing struct {
+	Type      config.Filter
+	CreatedAt time.Time
+	PostId    string
+}
+
+// ListingKey is "post:<type>:<created at>:<post id>". The creation time is kept in RFC3339 so
+// it holds separators itself, the key is split from both ends around it.
+func ListingKey(postType config.Filter, createdAt time.Time, pId string) string {
+	return Post.Key(string(postType)) + separator + createdAt.Format(time.RFC3339) + separator + Escape(pId)
+}
+
+func ParseListing(key string) (*Listing, error) {
+	if !Post.Is(key) {
+		return nil, fmt.Errorf("%w: %q is not a listing key", utils.MalformedKey, key)
+	}
+	rest := strings.TrimPrefix(key, Post.Prefix())
+	postType, rest, ok := strings.Cut(rest, separator)
+	last := strings.LastIndex(rest, separator)
+	if !ok || last < 0 {
+		return nil, fmt.Errorf("%w: %q is not a listing key", utils.MalformedKey, key)
+	}
+	createdAt, err := time.Parse(time.RFC3339, rest[:last])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q: %s", utils.MalformedKey, key, err.Error())
+	}
+	pId, err := Unescape(rest[last+1:])
+	if err != nil {
+		return nil, err
+	}
+	if postType == "" || pId == "" {
+		return nil, fmt.Errorf("%w: %q has an empty part", utils.MalformedKey, key)
+	}
+	return &Listing{Type: config.Filter(postType), CreatedAt: createdAt, PostId: pId}, nil
+}
+
+// CountKey is the partition holding the counters of the partition key, which is kept as it is
+func CountKey(key string) string {
+	return Count.Prefix() + key
+}
+
+// Sorted is a sort key ordering items by time, with id keeping keys of the same instant apart
+func Sorted(at time.Time, id string) string {
+	return SortedBy(at.UTC().Format(config.SortableTime), id)
+}
+
+// SortedBy is a sort key ordering items by value, with id keeping equal values apart
+func SortedBy(value, id string) string {
+	return value + "#" + id
+}
+
+// SortedUntil is the upper bound of the sort keys written by Sorted up to and including at
+func SortedUntil(at time.Time) string {
+	return SortedBy(at.UTC().Format(config.SortableTime), "~")
+}
+
+// ParseSorted returns the id of a sort key written by Sorted or SortedBy
+func ParseSorted(key string) (string, error) {
+	i := strings.LastIndex(key, "#")
+	if i < 0 || i == len(key)-1 {
+		return "", fmt.Errorf("%w: %q is not an ordered key", utils.MalformedKey, key)
+	}
+	return key[i+1:], nil
+}
+
+// CommentKey is "comment:<path>". The path is the list of ordered segments of the comment and its
+// ancestors, written as is so the thread sorts in display order.
+func CommentKey(path string) string {
+	return Comment.Prefix() + path
+}
+
+func ParseComment(key string) (string, error) {
+	path := strings.TrimPrefix(key, Comment.Prefix())
+	if !Comment.Is(key) || path == "" {
+		return "", fmt.Errorf("%w: %q is not a comment key", utils.MalformedKey, key)
+	}
+	return path, nil
+}
@@ -0,0 +1,148 @@
+package keys
+
+import (
+	"errors"
+	"localeyes/config"
+	"localeyes/utils"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestKeyRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		kind  Kind
+		parts []string
+		key   string
+	}{
+		{"plain id", User, []string{"42"}, "user:42"},
+		{"separator in id", User, []string{"a:b"}, "user:a%3Ab"},
+		{"escape in id", Email, []string{"50%off@example.com"}, "email:50%25off@example.com"},
+		{"escaped escape", Username, []string{"%3A"}, "username:%253A"},
+		{"several parts", Audit, []string{"target", "post", "p:1"}, "audit:target:post:p%3A1"},
+		{"kind of two parts", OTP, []string{"me:you@example.com"}, "otp:email:me%3Ayou@example.com"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			key := test.kind.Key(test.parts...)
+			if key != test.key {
+				t.Fatalf("Key(%q) = %q, want %q", test.parts, key, test.key)
+			}
+			parts, err := test.kind.ParseParts(key, len(test.parts))
+			if err != nil {
+				t.Fatalf("ParseParts(%q): %v", key, err)
+			}
+			if !reflect.DeepEqual(parts, test.parts) {
+				t.Fatalf("ParseParts(%q) = %q, want %q", key, parts, test.parts)
+			}
+		})
+	}
+}
+
+func TestParsePartsRejectsMalformedKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		kind Kind
+		key  string
+		n    int
+	}{
+		{"other kind", User, "post:1", 1},
+		{"kind prefix only", User, "users:1", 1},
+		{"too many parts", User, "user:1:2", 1},
+		{"too few parts", Audit, "audit:actor", 2},
+		{"empty part", User, "user:", 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := test.kind.ParseParts(test.key, test.n); !errors.Is(err, utils.MalformedKey) {
+				t.Fatalf("ParseParts(%q) error = %v, want MalformedKey", test.key, err)
+			}
+		})
+	}
+}
+
+func TestParsePartsReadsLegacyKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		kind Kind
+		key  string
+		part string
+	}{
+		{"percent sign", Email, "email:50%off@x.com", "50%off@x.com"},
+		{"trailing percent sign", Username, "username:100%", "100%"},
+		{"percent sign before an escape", Username, "username:a%%3A", "a%:"},
+		{"lone escape prefix", Email, "email:a%3@x.com", "a%3@x.com"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			part, err := test.kind.Parse(test.key)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", test.key, err)
+			}
+			if part != test.part {
+				t.Fatalf("Parse(%q) = %q, want %q", test.key, part, test.part)
+			}
+		})
+	}
+}
+
+func TestSplit(t *testing.T) {
+	kind, parts, err := Split(Reaction.Key("post", "p:1", "u%2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kind != Reaction || !reflect.DeepEqual(parts, []string{"post", "p:1", "u%2"}) {
+		t.Fatalf("Split = %q %q", kind, parts)
+	}
+}
+
+func TestListingRoundTrip(t *testing.T) {
+	createdAt := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		postId string
+	}{
+		{"plain id", "p1"},
+		{"separator in id", "p:1"},
+		{"escape in id", "p%1"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			key := ListingKey(config.Filter("food"), createdAt, test.postId)
+			listing, err := ParseListing(key)
+			if err != nil {
+				t.Fatalf("ParseListing(%q): %v", key, err)
+			}
+			if listing.Type != "food" || !listing.CreatedAt.Equal(createdAt) || listing.PostId != test.postId {
+				t.Fatalf("ParseListing(%q) = %+v", key, listing)
+			}
+		})
+	}
+}
+
+func TestSortedRoundTrip(t *testing.T) {
+	at := time.Date(2024, 3, 1, 12, 30, 0, 5, time.UTC)
+	tests := []struct {
+		name string
+		id   string
+	}{
+		{"plain id", "u1"},
+		{"separator in id", "u:1"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			key := Sorted(at, test.id)
+			id, err := ParseSorted(key)
+			if err != nil {
+				t.Fatalf("ParseSorted(%q): %v", key, err)
+			}
+			if id != test.id {
+				t.Fatalf("ParseSorted(%q) = %q, want %q", key, id, test.id)
+			}
+			if key > SortedUntil(at) || key < Sorted(at.Add(-time.Nanosecond), "~") {
+				t.Fatalf("%q sorts outside the keys of its instant", key)
+			}
+		})
+	}
+}
@@ -2,15 +2,32 @@ package middlewares
 
 import (
 	"context"
-	"encoding/json"
+	"go.uber.org/zap"
+	"localeyes/config"
+	"localeyes/internal/interfaces"
 	"localeyes/utils"
 	"net/http"
 	"strings"
+	"sync"
+	"time"
 )
 
-func AuthenticationMiddleware(next http.Handler) http.Handler {
+// allowedAccounts holds until when an account is known to pass CheckAccess, so a warm execution
+// environment reads an account at most once per AccountCheckTTL. A suspension, ban or
+// deactivation stops tokens issued before it within that time.
+var allowedAccounts sync.Map
+
+// AuthenticationMiddleware lets requests through that carry a valid token of an account that
+// may still use the API
+func AuthenticationMiddleware(accounts interfaces.AccessCheckerInterface) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return authenticate(accounts, next)
+	}
+}
+
+func authenticate(accounts interfaces.AccessCheckerInterface, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		excludedPaths := []string{"/login", "/signup", "/sns", "/otp", "/password/reset"}
+		excludedPaths := []string{"/login", "/signup", "/sns", "/otp", "/password/reset", "/export/download/"}
 		for _, path := range excludedPaths {
 			if strings.Contains(r.URL.Path, path) {
 				next.ServeHTTP(w, r)
@@ -20,76 +37,68 @@ func AuthenticationMiddleware(next http.Handler) http.Handler {
 		w.Header().Set("Content-Type", "application/json")
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
-			w.WriteHeader(http.StatusUnauthorized)
-			response := utils.NewUnauthorizedError("Missing authentication token")
-			err := json.NewEncoder(w).Encode(response)
-			if err != nil {
-				utils.Logger.Error("ERROR: Error encoding response")
-			}
+			utils.WriteError(w, r, utils.MissingToken)
 			return
 		}
 		if !utils.ValidateTokenFunc(authHeader) {
-			w.WriteHeader(http.StatusUnauthorized)
-			response := utils.NewUnauthorizedError("Invalid token")
-			err := json.NewEncoder(w).Encode(response)
-			if err != nil {
-				utils.Logger.Error("ERROR: Error encoding response")
-			}
+			utils.WriteError(w, r, utils.InvalidToken)
 			return
 		}
 		claims, err := utils.ExtractClaimsFunc(authHeader)
 		if err != nil {
-			w.WriteHeader(http.StatusUnauthorized)
-			response := utils.NewUnauthorizedError("Invalid token")
-			err := json.NewEncoder(w).Encode(response)
-			if err != nil {
-				utils.Logger.Error("ERROR: Error encoding response")
-			}
+			utils.WriteError(w, r, utils.InvalidToken)
 			return
 		}
 		id := claims["id"]
 		ctx := context.WithValue(r.Context(), "Id", id)
 		ctx = context.WithValue(ctx, "Role", "user")
+		utils.AddLogFields(ctx, zap.Any("user_id", id))
+		uId, _ := id.(string)
+		if err := checkAccess(ctx, accounts, uId); err != nil {
+			utils.WriteError(w, r, err)
+			return
+		}
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// checkAccess asks accounts whether uId may use the API unless it was allowed recently, only
+// allowed accounts are remembered so a blocked one is read again on every request
+func checkAccess(ctx context.Context, accounts interfaces.AccessCheckerInterface, uId string) error {
+	now := time.Now()
+	if until, ok := allowedAccounts.Load(uId); ok && now.Before(until.(time.Time)) {
+		return nil
+	}
+	if err := accounts.CheckAccess(ctx, uId); err != nil {
+		allowedAccounts.Delete(uId)
+		return err
+	}
+	allowedAccounts.Store(uId, now.Add(config.AccountCheckTTL))
+	return nil
+}
+
 func AdminAuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
-			w.WriteHeader(http.StatusUnauthorized)
-			response := utils.NewUnauthorizedError("Missing authentication token")
-			err := json.NewEncoder(w).Encode(response)
-			if err != nil {
-				utils.Logger.Error("ERROR: Error encoding response")
-			}
+			utils.WriteError(w, r, utils.MissingToken)
 			return
 		}
 		if !utils.ValidateAdminTokenFunc(authHeader) {
-			w.WriteHeader(http.StatusUnauthorized)
-			response := utils.NewUnauthorizedError("Not an admin")
-			err := json.NewEncoder(w).Encode(response)
-			if err != nil {
-				utils.Logger.Error("ERROR: Error encoding response")
-			}
+			utils.WriteError(w, r, utils.NotAdmin)
 			return
 		}
 		claims, err := utils.ExtractClaimsFunc(authHeader)
 		if err != nil {
-			w.WriteHeader(http.StatusUnauthorized)
-			response := utils.NewUnauthorizedError("Invalid token")
-			err := json.NewEncoder(w).Encode(response)
-			if err != nil {
-				utils.Logger.Error("ERROR: Error encoding response")
-			}
+			utils.WriteError(w, r, utils.InvalidToken)
 			return
 		}
 		role := claims["sub"]
 		id := claims["id"]
 		ctx := context.WithValue(r.Context(), "Role", role)
 		ctx = context.WithValue(ctx, "Id", id)
+		utils.AddLogFields(ctx, zap.Any("user_id", id), zap.Any("role", role))
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
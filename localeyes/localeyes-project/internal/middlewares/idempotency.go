@@ -0,0 +1,145 @@
+package middlewares
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"localeyes/config"
+	"localeyes/internal/interfaces"
+	"localeyes/internal/models"
+	"localeyes/utils"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+)
+
+// Logging in is left out, its response carries a fresh token that must not be stored and
+// logging in twice does no harm
+var idempotencyExcludedPaths = []string{"/login"}
+
+// replayedHeaders are the response headers kept with a stored response
+var replayedHeaders = []string{"Content-Type", "Content-Disposition", "ETag", "Location"}
+
+// IdempotencyMiddleware makes POST requests carrying an Idempotency-Key safe to retry. The first
+// request with a key runs and its response is stored, retries with the same key and body get
+// that response replayed and a different request under the same key is rejected. Keys are scoped
+// to the caller. Requests made before logging in are scoped to their source address, so an
+// anonymous client can neither read another's stored response nor block another's key.
+func IdempotencyMiddleware(repo interfaces.IdempotencyRepoInterface) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get("Idempotency-Key")
+			if r.Method != http.MethodPost || key == "" || isIdempotencyExcluded(r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > config.MaxIdempotencyKey {
+				utils.WriteError(w, r, utils.InvalidIdempotencyKey)
+				return
+			}
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				utils.WriteError(w, r, utils.InvalidBody)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			hash := requestHash(r, body)
+			scope := "public:" + clientAddress(r)
+			if id, ok := r.Context().Value("Id").(string); ok && id != "" {
+				scope = id
+			}
+			now := time.Now()
+			existing, err := repo.Claim(r.Context(), scope, key, &models.IdempotencyRecord{
+				RequestHash: hash,
+				Status:      config.IdempotencyInProgress,
+				CreatedAt:   now,
+				TTl:         now.Add(config.IdempotencyTTL).Unix(),
+			})
+			if err != nil {
+				utils.WriteError(w, r, err)
+				return
+			}
+			if existing != nil {
+				replay(w, r, existing, hash)
+				return
+			}
+
+			recorder := httptest.NewRecorder()
+			next.ServeHTTP(recorder, r)
+			// failed requests are not kept so retrying them runs them again
+			if recorder.Code >= http.StatusInternalServerError {
+				if err := repo.Release(r.Context(), scope, key); err != nil {
+					utils.LoggerFrom(r.Context()).Error("Error releasing idempotency key: " + err.Error())
+				}
+			} else {
+				headers := make(map[string]string)
+				for _, header := range replayedHeaders {
+					if value := recorder.Header().Get(header); value != "" {
+						headers[header] = value
+					}
+				}
+				err := repo.Complete(r.Context(), scope, key, &models.IdempotencyRecord{
+					RequestHash: hash,
+					Status:      config.IdempotencyCompleted,
+					StatusCode:  recorder.Code,
+					Headers:     headers,
+					Body:        recorder.Body.Bytes(),
+					CreatedAt:   now,
+					TTl:         now.Add(config.IdempotencyTTL).Unix(),
+				})
+				if err != nil {
+					// without the stored response a retry would find the key in progress until it expires
+					utils.LoggerFrom(r.Context()).Error("Error storing idempotent response: " + err.Error())
+					if err := repo.Release(r.Context(), scope, key); err != nil {
+						utils.LoggerFrom(r.Context()).Error("Error releasing idempotency key: " + err.Error())
+					}
+				}
+			}
+			for header, values := range recorder.Header() {
+				w.Header()[header] = values
+			}
+			w.WriteHeader(recorder.Code)
+			_, _ = w.Write(recorder.Body.Bytes())
+		})
+	}
+}
+
+// replay answers a retried request from the record stored for its key
+func replay(w http.ResponseWriter, r *http.Request, record *models.IdempotencyRecord, hash string) {
+	if record.RequestHash != hash {
+		utils.WriteError(w, r, utils.IdempotencyKeyReused)
+		return
+	}
+	if record.Status != config.IdempotencyCompleted {
+		w.Header().Set("Retry-After", "1")
+		utils.WriteError(w, r, utils.IdempotencyInProgress)
+		return
+	}
+	for header, value := range record.Headers {
+		w.Header().Set(header, value)
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(record.StatusCode)
+	_, _ = w.Write(record.Body)
+}
+
+// requestHash fingerprints what a key was first used for, the same key on another route
+// counts as a different request
+func requestHash(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func isIdempotencyExcluded(path string) bool {
+	for _, excluded := range idempotencyExcludedPaths {
+		if strings.HasPrefix(path, excluded) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,132 @@
+package middlewares
+
+import (
+	"context"
+	"localeyes/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// memoryIdempotency keeps idempotency records in memory
+type memoryIdempotency struct {
+	mu      sync.Mutex
+	records map[string]*models.IdempotencyRecord
+}
+
+func newMemoryIdempotency() *memoryIdempotency {
+	return &memoryIdempotency{records: make(map[string]*models.IdempotencyRecord)}
+}
+
+func (repo *memoryIdempotency) Claim(ctx context.Context, scope, key string, record *models.IdempotencyRecord) (*models.IdempotencyRecord, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	if existing, ok := repo.records[scope+"/"+key]; ok {
+		return existing, nil
+	}
+	repo.records[scope+"/"+key] = record
+	return nil, nil
+}
+
+func (repo *memoryIdempotency) Complete(ctx context.Context, scope, key string, record *models.IdempotencyRecord) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	repo.records[scope+"/"+key] = record
+	return nil
+}
+
+func (repo *memoryIdempotency) Release(ctx context.Context, scope, key string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	delete(repo.records, scope+"/"+key)
+	return nil
+}
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	type request struct {
+		remoteAddr string
+		userId     string
+		key        string
+		body       string
+	}
+	tests := []struct {
+		name     string
+		first    request
+		retry    request
+		status   int
+		runs     int
+		replayed bool
+	}{
+		{
+			name:     "anonymous retry is replayed",
+			first:    request{remoteAddr: "10.0.0.1:1000", key: "k1", body: `{"email":"a@x"}`},
+			retry:    request{remoteAddr: "10.0.0.1:2000", key: "k1", body: `{"email":"a@x"}`},
+			status:   http.StatusCreated,
+			runs:     1,
+			replayed: true,
+		},
+		{
+			name:   "anonymous key reused with another body is rejected",
+			first:  request{remoteAddr: "10.0.0.1:1000", key: "k1", body: `{"email":"a@x"}`},
+			retry:  request{remoteAddr: "10.0.0.1:1000", key: "k1", body: `{"email":"b@x"}`},
+			status: http.StatusUnprocessableEntity,
+			runs:   1,
+		},
+		{
+			name:   "anonymous clients do not share keys",
+			first:  request{remoteAddr: "10.0.0.1:1000", key: "k1", body: `{"email":"a@x"}`},
+			retry:  request{remoteAddr: "10.0.0.2:1000", key: "k1", body: `{"email":"a@x"}`},
+			status: http.StatusCreated,
+			runs:   2,
+		},
+		{
+			name:   "users do not share keys",
+			first:  request{remoteAddr: "10.0.0.1:1000", userId: "u1", key: "k1", body: `{}`},
+			retry:  request{remoteAddr: "10.0.0.1:1000", userId: "u2", key: "k1", body: `{}`},
+			status: http.StatusCreated,
+			runs:   2,
+		},
+		{
+			name:   "user key reused with another body is rejected",
+			first:  request{remoteAddr: "10.0.0.1:1000", userId: "u1", key: "k1", body: `{"a":1}`},
+			retry:  request{remoteAddr: "10.0.0.2:1000", userId: "u1", key: "k1", body: `{"a":2}`},
+			status: http.StatusUnprocessableEntity,
+			runs:   1,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			runs := 0
+			handler := IdempotencyMiddleware(newMemoryIdempotency())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				runs++
+				w.WriteHeader(http.StatusCreated)
+			}))
+			serve := func(req request) *httptest.ResponseRecorder {
+				r := httptest.NewRequest(http.MethodPost, "/signup", strings.NewReader(req.body))
+				r.RemoteAddr = req.remoteAddr
+				r.Header.Set("Idempotency-Key", req.key)
+				if req.userId != "" {
+					r = r.WithContext(context.WithValue(r.Context(), "Id", req.userId))
+				}
+				recorder := httptest.NewRecorder()
+				handler.ServeHTTP(recorder, r)
+				return recorder
+			}
+			if first := serve(test.first); first.Code != http.StatusCreated {
+				t.Fatalf("first request answered %d", first.Code)
+			}
+			retry := serve(test.retry)
+			if retry.Code != test.status {
+				t.Fatalf("retry answered %d, want %d", retry.Code, test.status)
+			}
+			if runs != test.runs {
+				t.Fatalf("handler ran %d times, want %d", runs, test.runs)
+			}
+			if replayed := retry.Header().Get("Idempotent-Replayed") == "true"; replayed != test.replayed {
+				t.Fatalf("retry replayed = %v, want %v", replayed, test.replayed)
+			}
+		})
+	}
+}
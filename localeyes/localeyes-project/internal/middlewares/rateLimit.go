@@ -0,0 +1,73 @@
+package middlewares
+
+import (
+	"localeyes/config"
+	"localeyes/internal/interfaces"
+	"localeyes/utils"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// authRateLimitedPaths are reachable without a token and can be used to guess passwords and
+// OTPs or to send mail, so they get the strict policy
+var authRateLimitedPaths = []string{"/signup", "/login", "/otp", "/password/reset", "/sns"}
+
+// RateLimitMiddleware throttles requests by route class. Requests carrying a token are counted
+// against the user, the others against the client address. When the limiter fails the request
+// goes through, an outage of the bucket store should not take the API down with it.
+func RateLimitMiddleware(limiter interfaces.RateLimiterInterface, policies map[config.RateLimitClass]config.RateLimitPolicy) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			policy := policies[rateLimitClass(r)]
+			subject := "ip:" + clientAddress(r)
+			if id, ok := r.Context().Value("Id").(string); ok && id != "" && policy.Class != config.AuthRateLimit {
+				subject = "user:" + id
+			}
+			decision, err := limiter.Allow(r.Context(), subject, policy)
+			if err != nil {
+				utils.LoggerFrom(r.Context()).Error("Error checking rate limit: " + err.Error())
+				next.ServeHTTP(w, r)
+				return
+			}
+			w.Header().Set("RateLimit-Policy", strconv.Itoa(policy.Limit)+";w="+strconv.Itoa(ceilSeconds(policy.Window)))
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(decision.Limit))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+			w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(decision.Reset)))
+			if !decision.Allowed {
+				w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(decision.RetryAfter)))
+				utils.WriteError(w, r, utils.TooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func rateLimitClass(r *http.Request) config.RateLimitClass {
+	for _, path := range authRateLimitedPaths {
+		if strings.HasPrefix(r.URL.Path, path) {
+			return config.AuthRateLimit
+		}
+	}
+	if r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+		return config.ReadRateLimit
+	}
+	return config.WriteRateLimit
+}
+
+// clientAddress is the address the request came from without its port. On Lambda it is the
+// source address API Gateway saw, forwarded headers are not trusted as clients can set them.
+func clientAddress(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,130 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"localeyes/config"
+	"localeyes/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// scriptedLimiter answers every request with the same decision and records who was counted
+type scriptedLimiter struct {
+	decision *models.RateLimitDecision
+	err      error
+	subjects []string
+	classes  []config.RateLimitClass
+}
+
+func (l *scriptedLimiter) Allow(ctx context.Context, subject string, policy config.RateLimitPolicy) (*models.RateLimitDecision, error) {
+	l.subjects = append(l.subjects, subject)
+	l.classes = append(l.classes, policy.Class)
+	return l.decision, l.err
+}
+
+var testPolicies = map[config.RateLimitClass]config.RateLimitPolicy{
+	config.AuthRateLimit:  {Class: config.AuthRateLimit, Limit: 5, Window: 10 * time.Minute},
+	config.ReadRateLimit:  {Class: config.ReadRateLimit, Limit: 120, Window: time.Minute},
+	config.WriteRateLimit: {Class: config.WriteRateLimit, Limit: 30, Window: time.Minute},
+}
+
+func TestRateLimitMiddlewareSubjects(t *testing.T) {
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		userId  string
+		subject string
+		class   config.RateLimitClass
+	}{
+		{"anonymous read", http.MethodGet, "/posts/all", "", "ip:192.0.2.1", config.ReadRateLimit},
+		{"user read", http.MethodGet, "/posts/all", "u1", "user:u1", config.ReadRateLimit},
+		{"user write", http.MethodPost, "/post", "u1", "user:u1", config.WriteRateLimit},
+		{"login", http.MethodPost, "/login", "", "ip:192.0.2.1", config.AuthRateLimit},
+		{"otp with a token", http.MethodPost, "/otp", "u1", "ip:192.0.2.1", config.AuthRateLimit},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			limiter := &scriptedLimiter{decision: &models.RateLimitDecision{Allowed: true, Limit: 5, Remaining: 4}}
+			r := httptest.NewRequest(test.method, test.path, nil)
+			r.RemoteAddr = "192.0.2.1:4321"
+			if test.userId != "" {
+				r = r.WithContext(context.WithValue(r.Context(), "Id", test.userId))
+			}
+			handler := RateLimitMiddleware(limiter, testPolicies)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+			handler.ServeHTTP(httptest.NewRecorder(), r)
+			if len(limiter.subjects) != 1 || limiter.subjects[0] != test.subject || limiter.classes[0] != test.class {
+				t.Fatalf("counted %q under %q, want %q under %q", limiter.subjects, limiter.classes, test.subject, test.class)
+			}
+		})
+	}
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	tests := []struct {
+		name     string
+		decision *models.RateLimitDecision
+		err      error
+		status   int
+		served   bool
+		headers  map[string]string
+	}{
+		{
+			name:     "allowed",
+			decision: &models.RateLimitDecision{Allowed: true, Limit: 30, Remaining: 29, Reset: 1500 * time.Millisecond},
+			status:   http.StatusOK,
+			served:   true,
+			headers: map[string]string{
+				"RateLimit-Policy":    "30;w=60",
+				"RateLimit-Limit":     "30",
+				"RateLimit-Remaining": "29",
+				"RateLimit-Reset":     "2",
+				"Retry-After":         "",
+			},
+		},
+		{
+			name:     "refused",
+			decision: &models.RateLimitDecision{Limit: 30, Reset: time.Minute, RetryAfter: 2500 * time.Millisecond},
+			status:   http.StatusTooManyRequests,
+			headers: map[string]string{
+				"RateLimit-Limit":     "30",
+				"RateLimit-Remaining": "0",
+				"RateLimit-Reset":     "60",
+				"Retry-After":         "3",
+			},
+		},
+		{
+			name:   "limiter down",
+			err:    errors.New("throttled"),
+			status: http.StatusOK,
+			served: true,
+			headers: map[string]string{
+				"RateLimit-Limit": "",
+				"Retry-After":     "",
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			limiter := &scriptedLimiter{decision: test.decision, err: test.err}
+			served := false
+			handler := RateLimitMiddleware(limiter, testPolicies)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				served = true
+			}))
+			recorder := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/post", nil)
+			handler.ServeHTTP(recorder, r.WithContext(context.WithValue(r.Context(), "Id", "u1")))
+			if recorder.Code != test.status || served != test.served {
+				t.Fatalf("status %d served %v, want %d served %v", recorder.Code, served, test.status, test.served)
+			}
+			for name, value := range test.headers {
+				if got := recorder.Header().Get(name); got != value {
+					t.Fatalf("%s = %q, want %q", name, got, value)
+				}
+			}
+		})
+	}
+}
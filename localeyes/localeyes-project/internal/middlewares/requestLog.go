@@ -0,0 +1,91 @@
+package middlewares
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	"localeyes/utils"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// requestIdPattern is what a request id sent by a client has to look like to be used as is
+var requestIdPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,128}$`)
+
+// statusRecorder remembers the status a request was answered with
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (recorder *statusRecorder) WriteHeader(status int) {
+	if recorder.status == 0 {
+		recorder.status = status
+	}
+	recorder.ResponseWriter.WriteHeader(status)
+}
+
+func (recorder *statusRecorder) Write(body []byte) (int, error) {
+	if recorder.status == 0 {
+		recorder.status = http.StatusOK
+	}
+	return recorder.ResponseWriter.Write(body)
+}
+
+// RequestLogMiddleware gives every request an id and a logger carrying it and the route, and
+// writes an access log line once the request has been answered. It wraps the router instead
+// of being added with Use so requests that match no route are logged too.
+func RequestLogMiddleware(router *mux.Router) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		requestId := requestID(r)
+		var match mux.RouteMatch
+		route := "unmatched"
+		if router.Match(r, &match) && match.Route != nil {
+			if template, err := match.Route.GetPathTemplate(); err == nil {
+				route = template
+			}
+		}
+		ctx := context.WithValue(r.Context(), "RequestId", requestId)
+		ctx = utils.WithLogger(ctx, utils.Logger.With(zap.String("request_id", requestId), zap.String("route", route)))
+		w.Header().Set("X-Request-Id", requestId)
+		recorder := &statusRecorder{ResponseWriter: w}
+		router.ServeHTTP(recorder, r.WithContext(ctx))
+		status := recorder.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		utils.LoggerFrom(ctx).Info("Request",
+			zap.String("method", r.Method),
+			zap.String("path", redactedPath(r.URL.Path, match.Vars)),
+			zap.String("query", utils.RedactQuery(r.URL.Query())),
+			zap.Int("status", status),
+			zap.Duration("latency", time.Since(start)),
+		)
+	})
+}
+
+// requestID is the id API Gateway gave the request, else the X-Request-Id the client sent
+// when it looks like an id, else a new one
+func requestID(r *http.Request) string {
+	if requestId, ok := r.Context().Value("RequestId").(string); ok && requestId != "" {
+		return requestId
+	}
+	if requestId := r.Header.Get("X-Request-Id"); requestIdPattern.MatchString(requestId) {
+		return requestId
+	}
+	return uuid.NewString()
+}
+
+// redactedPath hides path variables that are credentials, like the token of an export download
+func redactedPath(path string, vars map[string]string) string {
+	for name, value := range vars {
+		if value != "" && utils.IsSensitive(name) {
+			path = strings.Replace(path, value, utils.Redacted, 1)
+		}
+	}
+	return path
+}
@@ -0,0 +1,110 @@
+package middlewares
+
+import (
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"localeyes/utils"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRedactedPath(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		vars map[string]string
+		want string
+	}{
+		{"no variables", "/posts/all", nil, "/posts/all"},
+		{"plain variable", "/post/p1", map[string]string{"post_id": "p1"}, "/post/p1"},
+		{"token", "/export/abc123", map[string]string{"token": "abc123"}, "/export/" + utils.Redacted},
+		{"token and id", "/export/u1/abc123", map[string]string{"user_id": "u1", "token": "abc123"}, "/export/u1/" + utils.Redacted},
+		{"empty token", "/export/", map[string]string{"token": ""}, "/export/"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := redactedPath(test.path, test.vars); got != test.want {
+				t.Fatalf("redactedPath(%q) = %q, want %q", test.path, got, test.want)
+			}
+		})
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		kept   bool
+	}{
+		{"client id", "abc-123_x.y", true},
+		{"no id", "", false},
+		{"spaces", "abc 123", false},
+		{"log injection", "abc\ninjected", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/posts/all", nil)
+			r.Header.Set("X-Request-Id", test.header)
+			got := requestID(r)
+			if got == "" || (got == test.header) != test.kept {
+				t.Fatalf("requestID with X-Request-Id %q = %q", test.header, got)
+			}
+		})
+	}
+}
+
+func TestRequestLogMiddleware(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	logger := utils.Logger
+	utils.Logger = zap.New(core)
+	t.Cleanup(func() { utils.Logger = logger })
+	router := mux.NewRouter()
+	router.HandleFunc("/export/{token}", func(w http.ResponseWriter, r *http.Request) {
+		utils.AddLogFields(r.Context(), zap.String("user_id", "u1"))
+		utils.LoggerFrom(r.Context()).Info("Downloading export")
+		w.WriteHeader(http.StatusNotFound)
+	})
+	tests := []struct {
+		name      string
+		path      string
+		requestId string
+		route     string
+		loggedAs  string
+		status    int64
+		lines     int
+	}{
+		{"route", "/export/abc123?page=2", "req-1", "/export/{token}", "/export/" + utils.Redacted, http.StatusNotFound, 2},
+		{"no route", "/missing", "req-2", "unmatched", "/missing", http.StatusNotFound, 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			logs.TakeAll()
+			r := httptest.NewRequest(http.MethodGet, test.path, nil)
+			r.Header.Set("X-Request-Id", test.requestId)
+			recorder := httptest.NewRecorder()
+			RequestLogMiddleware(router).ServeHTTP(recorder, r)
+			if id := recorder.Header().Get("X-Request-Id"); id != test.requestId {
+				t.Fatalf("answered with request id %q, want %q", id, test.requestId)
+			}
+			entries := logs.TakeAll()
+			if len(entries) != test.lines {
+				t.Fatalf("logged %d lines, want %d", len(entries), test.lines)
+			}
+			for _, entry := range entries {
+				fields := entry.ContextMap()
+				if fields["request_id"] != test.requestId || fields["route"] != test.route {
+					t.Fatalf("%q logged with %v, want request id %q on route %q", entry.Message, fields, test.requestId, test.route)
+				}
+			}
+			access := entries[len(entries)-1].ContextMap()
+			if access["path"] != test.loggedAs || access["status"] != test.status {
+				t.Fatalf("access log %v, want path %q and status %d", access, test.loggedAs, test.status)
+			}
+			if test.lines > 1 && access["user_id"] != "u1" {
+				t.Fatalf("access log %v does not carry the fields added while handling the request", access)
+			}
+		})
+	}
+}
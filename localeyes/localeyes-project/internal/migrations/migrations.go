@@ -0,0 +1,108 @@
+// Package migrations bootstraps the table and applies versioned data migrations to it. Applied
+// migrations are recorded in the table itself, so every environment knows where it stands.
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"localeyes/internal/interfaces"
+	"localeyes/internal/models"
+	"localeyes/internal/repositories"
+	"sort"
+	"time"
+)
+
+// lockTTL is how long a run may hold the lock before another run can take it over
+const lockTTL = 30 * time.Minute
+
+// Migration changes existing data to the layout the code expects. Up has to be safe to run
+// again, a run that fails halfway is simply started again. It returns how many items it wrote.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(ctx context.Context) (int, error)
+}
+
+// Registered lists every migration in the order they are applied. Versions are never reused
+// or reordered once released.
+func Registered(userRepo *repositories.UserRepository, postRepo *repositories.PostRepository, quesRepo *repositories.QuestionRepository, auditRepo *repositories.AuditRepository) []Migration {
+	return []Migration{
+		{1, "post_owner_items", postRepo.BackfillOwners},
+		{2, "question_refs", quesRepo.BackfillRefs},
+		{3, "user_search_index", userRepo.BackfillSearchIndex},
+		{4, "escaped_user_lookups", userRepo.RekeyLookups},
+		{5, "audit_day_partitions", auditRepo.PartitionByDay},
+	}
+}
+
+type Runner struct {
+	Repo       interfaces.MigrationRepoInterface
+	Migrations []Migration
+}
+
+func NewRunner(repo interfaces.MigrationRepoInterface, migrations []Migration) *Runner {
+	sorted := append([]Migration(nil), migrations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+	return &Runner{
+		Repo:       repo,
+		Migrations: sorted,
+	}
+}
+
+// Status lists every known migration with its record when it has been applied
+func (r *Runner) Status(ctx context.Context) ([]*models.MigrationStatus, error) {
+	applied, err := r.Repo.GetApplied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]*models.MigrationStatus, 0, len(r.Migrations))
+	for _, migration := range r.Migrations {
+		statuses = append(statuses, &models.MigrationStatus{
+			Version: migration.Version,
+			Name:    migration.Name,
+			Applied: applied[migration.Version],
+		})
+	}
+	return statuses, nil
+}
+
+// Up applies the pending migrations up to and including target, every one when target is 0.
+// Migrations run one at a time under a lock and stop at the first that fails.
+func (r *Runner) Up(ctx context.Context, target int, actor string, progress func(migration Migration)) ([]*models.MigrationRecord, error) {
+	if err := r.Repo.AcquireLock(ctx, actor, lockTTL); err != nil {
+		return nil, err
+	}
+	defer r.Repo.ReleaseLock(context.WithoutCancel(ctx), actor)
+	applied, err := r.Repo.GetApplied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var records []*models.MigrationRecord
+	for _, migration := range r.Migrations {
+		if target > 0 && migration.Version > target {
+			break
+		}
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+		if progress != nil {
+			progress(migration)
+		}
+		items, err := migration.Up(ctx)
+		if err != nil {
+			return records, fmt.Errorf("migration %d %s: %w", migration.Version, migration.Name, err)
+		}
+		record := &models.MigrationRecord{
+			Version:   migration.Version,
+			Name:      migration.Name,
+			Items:     items,
+			AppliedBy: actor,
+			AppliedAt: time.Now(),
+		}
+		if err := r.Repo.RecordApplied(ctx, record); err != nil {
+			return records, err
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
@@ -0,0 +1,137 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"time"
+)
+
+// tableWait bounds how long creating the table or an index may take before giving up
+const tableWait = 10 * time.Minute
+
+// CreatedAtIndexKeys are the keys of the secondary index named by INDEX_NAME
+var CreatedAtIndexKeys = []types.KeySchemaElement{
+	{AttributeName: aws.String("pk"), KeyType: types.KeyTypeHash},
+	{AttributeName: aws.String("created_at"), KeyType: types.KeyTypeRange},
+}
+
+// EnsureTable creates the table with its index and item expiry on the "ttl" attribute. Whatever
+// already exists is left as it is, so it is safe to run against a table in use. It returns
+// what it had to create.
+func EnsureTable(ctx context.Context, db *dynamodb.Client, tableName, indexName string) ([]string, error) {
+	var created []string
+	described, err := db.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(tableName)})
+	var notFound *types.ResourceNotFoundException
+	switch {
+	case errors.As(err, &notFound):
+		input := &dynamodb.CreateTableInput{
+			TableName: aws.String(tableName),
+			AttributeDefinitions: []types.AttributeDefinition{
+				{AttributeName: aws.String("pk"), AttributeType: types.ScalarAttributeTypeS},
+				{AttributeName: aws.String("sk"), AttributeType: types.ScalarAttributeTypeS},
+			},
+			KeySchema: []types.KeySchemaElement{
+				{AttributeName: aws.String("pk"), KeyType: types.KeyTypeHash},
+				{AttributeName: aws.String("sk"), KeyType: types.KeyTypeRange},
+			},
+			BillingMode: types.BillingModePayPerRequest,
+		}
+		if indexName != "" {
+			input.AttributeDefinitions = append(input.AttributeDefinitions, types.AttributeDefinition{
+				AttributeName: aws.String("created_at"), AttributeType: types.ScalarAttributeTypeS,
+			})
+			input.GlobalSecondaryIndexes = []types.GlobalSecondaryIndex{{
+				IndexName:  aws.String(indexName),
+				KeySchema:  CreatedAtIndexKeys,
+				Projection: &types.Projection{ProjectionType: types.ProjectionTypeAll},
+			}}
+		}
+		if _, err := db.CreateTable(ctx, input); err != nil {
+			return created, err
+		}
+		created = append(created, "table "+tableName)
+		if indexName != "" {
+			created = append(created, "index "+indexName)
+		}
+		if err := waitActive(ctx, db, tableName, indexName); err != nil {
+			return created, err
+		}
+	case err != nil:
+		return created, err
+	case indexName != "" && !hasIndex(described.Table, indexName):
+		_, err := db.UpdateTable(ctx, &dynamodb.UpdateTableInput{
+			TableName: aws.String(tableName),
+			AttributeDefinitions: []types.AttributeDefinition{
+				{AttributeName: aws.String("pk"), AttributeType: types.ScalarAttributeTypeS},
+				{AttributeName: aws.String("created_at"), AttributeType: types.ScalarAttributeTypeS},
+			},
+			GlobalSecondaryIndexUpdates: []types.GlobalSecondaryIndexUpdate{{
+				Create: &types.CreateGlobalSecondaryIndexAction{
+					IndexName:  aws.String(indexName),
+					KeySchema:  CreatedAtIndexKeys,
+					Projection: &types.Projection{ProjectionType: types.ProjectionTypeAll},
+				},
+			}},
+		})
+		if err != nil {
+			return created, err
+		}
+		created = append(created, "index "+indexName)
+		if err := waitActive(ctx, db, tableName, indexName); err != nil {
+			return created, err
+		}
+	}
+
+	ttl, err := db.DescribeTimeToLive(ctx, &dynamodb.DescribeTimeToLiveInput{TableName: aws.String(tableName)})
+	if err != nil {
+		return created, err
+	}
+	if status := ttl.TimeToLiveDescription; status == nil || status.TimeToLiveStatus == types.TimeToLiveStatusDisabled {
+		_, err = db.UpdateTimeToLive(ctx, &dynamodb.UpdateTimeToLiveInput{
+			TableName: aws.String(tableName),
+			TimeToLiveSpecification: &types.TimeToLiveSpecification{
+				AttributeName: aws.String("ttl"),
+				Enabled:       aws.Bool(true),
+			},
+		})
+		if err != nil {
+			return created, err
+		}
+		created = append(created, "ttl on ttl")
+	}
+	return created, nil
+}
+
+func hasIndex(table *types.TableDescription, indexName string) bool {
+	for _, index := range table.GlobalSecondaryIndexes {
+		if aws.ToString(index.IndexName) == indexName {
+			return true
+		}
+	}
+	return false
+}
+
+// waitActive waits for the table and, when named, its index to become usable
+func waitActive(ctx context.Context, db *dynamodb.Client, tableName, indexName string) error {
+	err := dynamodb.NewTableExistsWaiter(db).Wait(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(tableName)}, tableWait)
+	if err != nil || indexName == "" {
+		return err
+	}
+	deadline := time.Now().Add(tableWait)
+	for time.Now().Before(deadline) {
+		described, err := db.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(tableName)})
+		if err != nil {
+			return err
+		}
+		for _, index := range described.Table.GlobalSecondaryIndexes {
+			if aws.ToString(index.IndexName) == indexName && index.IndexStatus == types.IndexStatusActive {
+				return nil
+			}
+		}
+		time.Sleep(5 * time.Second)
+	}
+	return errors.New("index " + indexName + " did not become active in time")
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Alert is a server error the operators are told about
+type Alert struct {
+	Code      string    `json:"error_code"`
+	Status    int       `json:"status_code"`
+	Message   string    `json:"message"`
+	RequestId string    `json:"request_id,omitempty"`
+	Route     string    `json:"route,omitempty"`
+	UserId    string    `json:"user_id,omitempty"`
+	Time      time.Time `json:"timestamp"`
+}
+
+// AlertGroup is every alert with the same fingerprint raised during one window, First is the
+// first of them and RequestIds a sample of the requests that failed
+type AlertGroup struct {
+	Fingerprint string    `json:"fingerprint"`
+	First       *Alert    `json:"first"`
+	Count       int       `json:"count"`
+	LastSeen    time.Time `json:"last_seen"`
+	RequestIds  []string  `json:"request_ids,omitempty"`
+}
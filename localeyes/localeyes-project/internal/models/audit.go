@@ -0,0 +1,37 @@
+package models
+
+import (
+	"encoding/json"
+	"localeyes/config"
+	"time"
+)
+
+// AuditEntry records one admin or security relevant action. Entries are only ever written,
+// each one is stored under the global log and copied under its actor and its target.
+type AuditEntry struct {
+	PK         string             `json:"-" dynamodbav:"pk"`
+	SK         string             `json:"-" dynamodbav:"sk"`
+	Id         string             `json:"id" dynamodbav:"id"`
+	ActorId    string             `json:"actor_id" dynamodbav:"actor_id"`
+	ActorRole  string             `json:"actor_role,omitempty" dynamodbav:"actor_role,omitempty"`
+	Action     config.AuditAction `json:"action" dynamodbav:"action"`
+	TargetType config.ContentType `json:"target_type" dynamodbav:"target_type"`
+	TargetId   string             `json:"target_id" dynamodbav:"target_id"`
+	TargetKeys map[string]string  `json:"target_keys,omitempty" dynamodbav:"target_keys,omitempty"`
+	Reason     string             `json:"reason,omitempty" dynamodbav:"reason,omitempty"`
+	RequestId  string             `json:"request_id,omitempty" dynamodbav:"request_id,omitempty"`
+	Before     json.RawMessage    `json:"before,omitempty" dynamodbav:"before,omitempty"`
+	CreatedAt  time.Time          `json:"created_at" dynamodbav:"created_at"`
+}
+
+// AuditQuery selects entries of one actor, one target, one actor on one target or, when neither
+// is set, the whole log
+type AuditQuery struct {
+	ActorId    string
+	TargetType config.ContentType
+	TargetId   string
+	From       time.Time
+	To         time.Time
+	Limit      int
+	Cursor     string
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// Bookmark is the entry in the user's saved list, ordered by when the post was saved
+type Bookmark struct {
+	UId        string    `json:"user_id" dynamodbav:"pk"`
+	SK         string    `json:"-" dynamodbav:"sk"`
+	PostId     string    `json:"post_id" dynamodbav:"post_id"`
+	PostUserId string    `json:"post_user_id" dynamodbav:"post_user_id"`
+	SavedAt    time.Time `json:"saved_at" dynamodbav:"saved_at"`
+}
+
+// BookmarkRef resolves a saved post back to the saved list entry of one user
+type BookmarkRef struct {
+	PostId  string `json:"post_id" dynamodbav:"pk"`
+	UId     string `json:"user_id" dynamodbav:"sk"`
+	SavedSK string `json:"-" dynamodbav:"saved_sk"`
+}
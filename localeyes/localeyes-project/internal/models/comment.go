@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+type Comment struct {
+	PostId    string    `json:"post_id" dynamodbav:"pk"`
+	Path      string    `json:"path" dynamodbav:"sk"`
+	CId       string    `json:"comment_id" dynamodbav:"comment_id"`
+	ParentId  string    `json:"parent_id,omitempty" dynamodbav:"parent_id,omitempty"`
+	UserId    string    `json:"user_id" dynamodbav:"user_id"`
+	Content   string    `json:"content" dynamodbav:"content"`
+	Depth     int       `json:"depth" dynamodbav:"depth"`
+	Deleted   bool      `json:"deleted" dynamodbav:"deleted"`
+	CreatedAt time.Time `json:"created_at" dynamodbav:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" dynamodbav:"updated_at"`
+}
+
+// CommentRef resolves a comment id to its place in the thread
+type CommentRef struct {
+	CId    string `json:"comment_id" dynamodbav:"pk"`
+	SK     string `json:"-" dynamodbav:"sk"`
+	PostId string `json:"post_id" dynamodbav:"post_id"`
+	Path   string `json:"path" dynamodbav:"path"`
+	Depth  int    `json:"depth" dynamodbav:"depth"`
+}
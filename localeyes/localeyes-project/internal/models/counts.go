@@ -0,0 +1,12 @@
+package models
+
+type Counts struct {
+	PK             string `json:"-" dynamodbav:"pk"`
+	SK             string `json:"-" dynamodbav:"sk"`
+	QuestionCount  int    `json:"question_count" dynamodbav:"question_count"`
+	AnswerCount    int    `json:"answer_count" dynamodbav:"answer_count"`
+	CommentCount   int    `json:"comment_count" dynamodbav:"comment_count"`
+	FollowerCount  int    `json:"follower_count" dynamodbav:"follower_count"`
+	FollowingCount int    `json:"following_count" dynamodbav:"following_count"`
+	UserCount      int    `json:"user_count" dynamodbav:"user_count"`
+}
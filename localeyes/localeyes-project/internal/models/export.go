@@ -0,0 +1,33 @@
+package models
+
+import (
+	"localeyes/config"
+	"time"
+)
+
+// UserExport is the personal data of one user as it is handed out in an export archive
+type UserExport struct {
+	GeneratedAt   time.Time       `json:"generated_at"`
+	Profile       *ResponseUser   `json:"profile"`
+	Posts         []*Post         `json:"posts"`
+	Questions     []*Question     `json:"questions"`
+	Answers       []*Reply        `json:"answers"`
+	Likes         []string        `json:"liked_post_ids"`
+	Notifications []*Notification `json:"notifications"`
+}
+
+// Export describes a stored archive, the archive itself is kept in chunks under the same
+// partition and everything expires with the download token
+type Export struct {
+	PK          string              `json:"-" dynamodbav:"pk"`
+	SK          string              `json:"-" dynamodbav:"sk"`
+	UId         string              `json:"user_id" dynamodbav:"user_id"`
+	JobId       string              `json:"job_id" dynamodbav:"job_id"`
+	Format      config.ExportFormat `json:"format" dynamodbav:"format"`
+	FileName    string              `json:"file_name" dynamodbav:"file_name"`
+	ContentType string              `json:"content_type" dynamodbav:"content_type"`
+	Size        int                 `json:"size" dynamodbav:"size"`
+	Chunks      int                 `json:"-" dynamodbav:"chunks"`
+	ExpiresAt   time.Time           `json:"expires_at" dynamodbav:"expires_at"`
+	TTl         int64               `json:"-" dynamodbav:"ttl"`
+}
@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// Follow is one side of a follow edge, kept under both "following:<follower>" and
+// "followers:<followee>" so either list can be read with a single query
+type Follow struct {
+	PK         string    `json:"-" dynamodbav:"pk"`
+	UId        string    `json:"user_id" dynamodbav:"sk"`
+	FollowedAt time.Time `json:"followed_at" dynamodbav:"followed_at"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"localeyes/config"
+	"time"
+)
+
+// IdempotencyRecord is kept under "idem:<scope>:<key>" for a request carrying an Idempotency-Key.
+// It is claimed before the request runs and holds the response once it has finished.
+type IdempotencyRecord struct {
+	PK          string                   `json:"-" dynamodbav:"pk"`
+	SK          string                   `json:"-" dynamodbav:"sk"`
+	RequestHash string                   `json:"request_hash" dynamodbav:"request_hash"`
+	Status      config.IdempotencyStatus `json:"status" dynamodbav:"status"`
+	StatusCode  int                      `json:"status_code,omitempty" dynamodbav:"status_code,omitempty"`
+	Headers     map[string]string        `json:"headers,omitempty" dynamodbav:"headers,omitempty"`
+	Body        []byte                   `json:"-" dynamodbav:"body,omitempty"`
+	CreatedAt   time.Time                `json:"created_at" dynamodbav:"created_at"`
+	TTl         int64                    `json:"-" dynamodbav:"ttl"`
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"localeyes/config"
+	"time"
+)
+
+// Job tracks work that outlives the request which started it, such as deleting an account
+type Job struct {
+	PK         string            `json:"-" dynamodbav:"pk"`
+	SK         string            `json:"-" dynamodbav:"sk"`
+	Id         string            `json:"job_id" dynamodbav:"job_id"`
+	Kind       config.JobKind    `json:"kind" dynamodbav:"kind"`
+	Status     config.JobStatus  `json:"status" dynamodbav:"status"`
+	ActorId    string            `json:"actor_id" dynamodbav:"actor_id"`
+	TargetId   string            `json:"target_id" dynamodbav:"target_id"`
+	Stage      string            `json:"stage,omitempty" dynamodbav:"stage,omitempty"`
+	Total      int               `json:"total" dynamodbav:"total"`
+	Processed  int               `json:"processed" dynamodbav:"processed"`
+	Failed     int               `json:"failed" dynamodbav:"failed"`
+	Error      string            `json:"error,omitempty" dynamodbav:"error,omitempty"`
+	CreatedAt  time.Time         `json:"created_at" dynamodbav:"created_at"`
+	UpdatedAt  time.Time         `json:"updated_at" dynamodbav:"updated_at"`
+	FinishedAt *time.Time        `json:"finished_at,omitempty" dynamodbav:"finished_at,omitempty"`
+	Result     map[string]string `json:"result,omitempty" dynamodbav:"result,omitempty"`
+	DryRun     bool              `json:"dry_run,omitempty" dynamodbav:"dry_run,omitempty"`
+	Items      []*JobItem        `json:"items,omitempty" dynamodbav:"items,omitempty"`
+	// Params are the inputs the worker needs to run the job, like the reason an admin gave
+	Params map[string]string `json:"-" dynamodbav:"params,omitempty"`
+	// LeaseUntil is the unix time until which a worker owns the job, 0 when no worker does
+	LeaseUntil int64 `json:"-" dynamodbav:"lease_until,omitempty"`
+}
+
+// JobItem is the outcome of a bulk job for one item, a dry run leaves every item PLANNED and a
+// resumed job applies the items still PLANNED
+type JobItem struct {
+	Type     config.ContentType   `json:"type" dynamodbav:"type"`
+	Id       string               `json:"id" dynamodbav:"id"`
+	ParentId string               `json:"parent_id,omitempty" dynamodbav:"parent_id,omitempty"`
+	OwnerId  string               `json:"user_id,omitempty" dynamodbav:"user_id,omitempty"`
+	Status   config.JobItemStatus `json:"status" dynamodbav:"status"`
+	Error    string               `json:"error,omitempty" dynamodbav:"error,omitempty"`
+	// Path is where a comment item sits in its thread, it is needed to blank the comment
+	Path string `json:"-" dynamodbav:"path,omitempty"`
+}
+
+// ContentRef names one piece of content and, when it is the target of a reaction, the reaction
+type ContentRef struct {
+	Type     config.ContentType
+	Id       string
+	Reaction config.ReactionType
+}
+
+// OwnedContent is everything a user left behind in other partitions of the table. Ids are
+// plain, without their key prefixes.
+type OwnedContent struct {
+	Posts         []*Post
+	Questions     []*QuestionRef
+	Answers       []*Reply
+	Comments      []*Comment
+	Reactions     []*ContentRef
+	Likes         []string
+	Saved         []string
+	Following     []string
+	Followers     []string
+	Blocking      []string
+	BlockedBy     []string
+	MutedBy       []string
+	Notifications []string
+}
+
+func (content *OwnedContent) Count() int {
+	return len(content.Posts) + len(content.Questions) + len(content.Answers) + len(content.Comments) +
+		len(content.Reactions) + len(content.Likes) + len(content.Saved) + len(content.Following) +
+		len(content.Followers) + len(content.Blocking) + len(content.BlockedBy) + len(content.MutedBy) +
+		len(content.Notifications)
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// MigrationRecord marks a data migration as applied to the table
+type MigrationRecord struct {
+	PK        string    `json:"-" dynamodbav:"pk"`
+	SK        string    `json:"-" dynamodbav:"sk"`
+	Version   int       `json:"version" dynamodbav:"version"`
+	Name      string    `json:"name" dynamodbav:"name"`
+	Items     int       `json:"items" dynamodbav:"items"`
+	AppliedBy string    `json:"applied_by" dynamodbav:"applied_by"`
+	AppliedAt time.Time `json:"applied_at" dynamodbav:"applied_at"`
+}
+
+// MigrationStatus is a known migration and when it was applied, nil while it is pending
+type MigrationStatus struct {
+	Version int              `json:"version"`
+	Name    string           `json:"name"`
+	Applied *MigrationRecord `json:"applied,omitempty"`
+}
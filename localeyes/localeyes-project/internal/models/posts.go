@@ -6,21 +6,38 @@ import (
 )
 
 type Post struct {
-	PostId    string        `json:"post_id" dynamodbav:"sk"`
-	UId       string        `json:"user_id" dynamodbav:"pk"`
-	Title     string        `json:"title" dynamodbav:"title"`
-	Type      config.Filter `json:"type" dynamodbav:"type"`
-	Content   string        `json:"content" dynamodbav:"content"`
-	Likes     int           `json:"likes" dynamodbav:"likes"`
-	CreatedAt time.Time     `json:"created_at" dynamodbav:"created_at"`
+	PostId    string          `json:"post_id" dynamodbav:"sk"`
+	UId       string          `json:"user_id" dynamodbav:"pk"`
+	Title     string          `json:"title" dynamodbav:"title"`
+	Type      config.Filter   `json:"type" dynamodbav:"type"`
+	Content   string          `json:"content" dynamodbav:"content"`
+	Likes     int             `json:"likes" dynamodbav:"likes"`
+	CreatedAt time.Time       `json:"created_at" dynamodbav:"created_at"`
+	EditedAt  *time.Time      `json:"edited_at,omitempty" dynamodbav:"edited_at,omitempty"`
+	Questions int             `json:"question_count" dynamodbav:"-"`
+	Comments  int             `json:"comment_count" dynamodbav:"-"`
+	Reactions ReactionSummary `json:"reactions" dynamodbav:"-"`
+	Saved     bool            `json:"saved" dynamodbav:"-"`
+	Hidden    bool            `json:"-" dynamodbav:"hidden,omitempty"`
+	Version   int             `json:"version" dynamodbav:"version"`
 }
 
 type PostSKFilter struct {
-	PK        string    `json:"pk" dynamodbav:"pk"`
-	SK        string    `json:"sk" dynamodbav:"sk"`
-	CreatedAt time.Time `json:"created_at" dynamodbav:"created_at"`
-	UId       string    `json:"user_id" dynamodbav:"user_id"`
-	Title     string    `json:"title" dynamodbav:"title"`
-	Content   string    `json:"content" dynamodbav:"content"`
-	Likes     int       `json:"likes" dynamodbav:"likes"`
+	PK        string     `json:"pk" dynamodbav:"pk"`
+	SK        string     `json:"sk" dynamodbav:"sk"`
+	CreatedAt time.Time  `json:"created_at" dynamodbav:"created_at"`
+	UId       string     `json:"user_id" dynamodbav:"user_id"`
+	Title     string     `json:"title" dynamodbav:"title"`
+	Content   string     `json:"content" dynamodbav:"content"`
+	Likes     int        `json:"likes" dynamodbav:"likes"`
+	EditedAt  *time.Time `json:"edited_at,omitempty" dynamodbav:"edited_at,omitempty"`
+	Hidden    bool       `json:"-" dynamodbav:"hidden,omitempty"`
+	Version   int        `json:"version" dynamodbav:"version"`
+}
+
+// PostOwner resolves a post id to its author
+type PostOwner struct {
+	PostId string `json:"post_id" dynamodbav:"pk"`
+	SK     string `json:"-" dynamodbav:"sk"`
+	UId    string `json:"user_id" dynamodbav:"user_id"`
 }
@@ -1,15 +1,57 @@
 package models
 
+import (
+	"localeyes/config"
+	"time"
+)
+
 type Question struct {
-	QId    string `json:"question_id" dynamodbav:"sk"`
-	PostId string `json:"post_id" dynamodbav:"pk"`
+	QId              string     `json:"question_id" dynamodbav:"sk"`
+	PostId           string     `json:"post_id" dynamodbav:"pk"`
+	UserId           string     `json:"q_user_id" dynamodbav:"q_user_id"`
+	Text             string     `json:"text" dynamodbav:"text"`
+	AcceptedAnswerId string     `json:"accepted_answer_id" dynamodbav:"accepted_answer_id,omitempty"`
+	CreatedAt        time.Time  `json:"created_at" dynamodbav:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at" dynamodbav:"updated_at"`
+	EditedAt         *time.Time `json:"edited_at,omitempty" dynamodbav:"edited_at,omitempty"`
+	Hidden           bool       `json:"-" dynamodbav:"hidden,omitempty"`
+}
+
+// QuestionRef resolves a question id to its post and author
+type QuestionRef struct {
+	QId    string `json:"question_id" dynamodbav:"pk"`
+	SK     string `json:"-" dynamodbav:"sk"`
+	PostId string `json:"post_id" dynamodbav:"post_id"`
 	UserId string `json:"q_user_id" dynamodbav:"q_user_id"`
-	Text   string `json:"text" dynamodbav:"text"`
 }
 
 type Reply struct {
-	RId    string `json:"r_id" dynamodbav:"sk"`
-	QId    string `json:"q_id" dynamodbav:"pk"`
-	Answer string `json:"answer" dynamodbav:"answer"`
-	UserId string `json:"r_user_id" dynamodbav:"r_user_id"`
+	RId       string     `json:"r_id" dynamodbav:"sk"`
+	QId       string     `json:"q_id" dynamodbav:"pk"`
+	Answer    string     `json:"answer" dynamodbav:"answer"`
+	UserId    string     `json:"r_user_id" dynamodbav:"r_user_id"`
+	Upvotes   int        `json:"upvotes" dynamodbav:"upvotes"`
+	Downvotes int        `json:"downvotes" dynamodbav:"downvotes"`
+	Score     int        `json:"score" dynamodbav:"score"`
+	Accepted  bool       `json:"accepted" dynamodbav:"accepted"`
+	CreatedAt time.Time  `json:"created_at" dynamodbav:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at" dynamodbav:"updated_at"`
+	EditedAt  *time.Time `json:"edited_at,omitempty" dynamodbav:"edited_at,omitempty"`
+	Hidden    bool       `json:"-" dynamodbav:"hidden,omitempty"`
+}
+
+type AnswerVote struct {
+	RId  string          `json:"r_id" dynamodbav:"pk"`
+	UId  string          `json:"user_id" dynamodbav:"sk"`
+	Vote config.VoteType `json:"vote" dynamodbav:"vote"`
+}
+
+type Revision struct {
+	PK       string             `json:"-" dynamodbav:"pk"`
+	SK       string             `json:"-" dynamodbav:"sk"`
+	Type     config.ContentType `json:"type" dynamodbav:"type"`
+	EditorId string             `json:"editor_id" dynamodbav:"editor_id"`
+	Title    string             `json:"title,omitempty" dynamodbav:"title,omitempty"`
+	Text     string             `json:"text" dynamodbav:"text"`
+	EditedAt time.Time          `json:"edited_at" dynamodbav:"edited_at"`
 }
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// RateLimitDecision is the outcome of taking a request from a token bucket
+type RateLimitDecision struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is how long until the bucket is full again and RetryAfter how long until the
+	// next request is allowed, zero when this one was
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+// RateLimitBucket is the state of one token bucket
+type RateLimitBucket struct {
+	Tokens    float64
+	UpdatedAt time.Time
+}
@@ -0,0 +1,11 @@
+package models
+
+import "localeyes/config"
+
+type Reaction struct {
+	Target   string              `json:"target" dynamodbav:"pk"`
+	UId      string              `json:"user_id" dynamodbav:"sk"`
+	Reaction config.ReactionType `json:"reaction" dynamodbav:"reaction"`
+}
+
+type ReactionSummary map[config.ReactionType]int
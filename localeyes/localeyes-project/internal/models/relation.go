@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+// Relation is a one way block or mute of another user
+type Relation struct {
+	PK        string    `json:"-" dynamodbav:"pk"`
+	UId       string    `json:"user_id" dynamodbav:"sk"`
+	CreatedAt time.Time `json:"created_at" dynamodbav:"created_at"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"localeyes/config"
+	"time"
+)
+
+// Report is one user's report of a target, kept under "report:<type>:<id>" so each
+// user can report a target only once
+type Report struct {
+	Target    string              `json:"-" dynamodbav:"pk"`
+	UId       string              `json:"user_id" dynamodbav:"sk"`
+	Reason    config.ReportReason `json:"reason" dynamodbav:"reason"`
+	Text      string              `json:"text,omitempty" dynamodbav:"text,omitempty"`
+	CreatedAt time.Time           `json:"created_at" dynamodbav:"created_at"`
+}
+
+// ReportCase aggregates every report of one target and carries what an admin needs to act on it
+type ReportCase struct {
+	PK              string              `json:"-" dynamodbav:"pk"`
+	SK              string              `json:"-" dynamodbav:"sk"`
+	TargetType      config.ContentType  `json:"target_type" dynamodbav:"target_type"`
+	TargetId        string              `json:"target_id" dynamodbav:"target_id"`
+	OwnerId         string              `json:"owner_id" dynamodbav:"owner_id"`
+	PostId          string              `json:"post_id,omitempty" dynamodbav:"post_id,omitempty"`
+	QuestionId      string              `json:"question_id,omitempty" dynamodbav:"question_id,omitempty"`
+	Status          config.ReportStatus `json:"status" dynamodbav:"status"`
+	ReportCount     int                 `json:"report_count" dynamodbav:"report_count"`
+	Hidden          bool                `json:"hidden" dynamodbav:"hidden"`
+	FirstReportedAt time.Time           `json:"first_reported_at" dynamodbav:"first_reported_at"`
+	LastReportedAt  time.Time           `json:"last_reported_at" dynamodbav:"last_reported_at"`
+	ResolvedBy      string              `json:"resolved_by,omitempty" dynamodbav:"resolved_by,omitempty"`
+	ResolvedAt      *time.Time          `json:"resolved_at,omitempty" dynamodbav:"resolved_at,omitempty"`
+}
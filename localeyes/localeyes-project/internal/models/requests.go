@@ -43,6 +43,7 @@ type UpdatePost struct {
 	Type      config.Filter `json:"type" validate:"required,isValidFilter"`
 	Content   string        `json:"content" validate:"required"`
 	CreatedAt time.Time     `json:"created_at" validate:"required,isValidTime"`
+	Version   int           `json:"-"`
 }
 
 type DeletePost struct {
@@ -56,6 +57,10 @@ type LikePost struct {
 	Type      config.Filter `json:"type" validate:"required,isValidFilter"`
 }
 
+type SavePost struct {
+	UId string `json:"user_id" validate:"required"`
+}
+
 type RequestQuestion struct {
 	PostId string `json:"post_id"`
 	UserId string `json:"q_user_id"`
@@ -68,8 +73,71 @@ type RequestAnswer struct {
 	UserId string `json:"r_user_id"`
 }
 
+type SuspendUser struct {
+	Until  time.Time `json:"until" validate:"required"`
+	Reason string    `json:"reason" validate:"required,max=500"`
+}
+
+type BulkDeletePosts struct {
+	Search string        `json:"search" validate:"required,max=100"`
+	Type   config.Filter `json:"type" validate:"omitempty,isValidFilter"`
+	UserId string        `json:"user_id"`
+	Reason string        `json:"reason" validate:"required,max=500"`
+}
+
+type BulkAccountChange struct {
+	UserIds []string   `json:"user_ids" validate:"required,min=1,max=500,dive,required"`
+	Until   *time.Time `json:"until"`
+	Reason  string     `json:"reason" validate:"required,max=500"`
+}
+
+type BanUser struct {
+	Reason string `json:"reason" validate:"required,max=500"`
+}
+
 type DeleteUser struct {
 	Username string `json:"username" validate:"required"`
 	Email    string `json:"email" validate:"required,email"`
 	UId      string `json:"user_id"`
 }
+
+type UpdateQuestion struct {
+	Text string `json:"text" validate:"required"`
+}
+
+type UpdateAnswer struct {
+	Answer string `json:"answer" validate:"required"`
+}
+
+type RequestComment struct {
+	Content  string `json:"content" validate:"required"`
+	ParentId string `json:"parent_id"`
+}
+
+type React struct {
+	Reaction config.ReactionType `json:"reaction" validate:"required,isValidReaction"`
+}
+
+type ReactPost struct {
+	Reaction  config.ReactionType `json:"reaction" validate:"required,isValidReaction"`
+	UId       string              `json:"user_id" validate:"required"`
+	CreatedAt time.Time           `json:"created_at" validate:"required,isValidTime"`
+	Type      config.Filter       `json:"type" validate:"required,isValidFilter"`
+}
+
+type RequestReport struct {
+	Reason config.ReportReason `json:"reason" validate:"required,isValidReportReason"`
+	Text   string              `json:"text" validate:"max=1000"`
+}
+
+type ResolveReport struct {
+	Status config.ReportStatus `json:"status" validate:"required,isValidResolution"`
+}
+
+type VoteAnswer struct {
+	Vote config.VoteType `json:"vote" validate:"required,isValidVote"`
+}
+
+type AcceptAnswer struct {
+	PostId string `json:"post_id" validate:"required"`
+}
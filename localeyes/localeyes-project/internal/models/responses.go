@@ -1,15 +1,10 @@
 package models
 
 import (
-	"context"
 	"encoding/json"
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/service/sns"
-	"github.com/aws/aws-sdk-go-v2/service/sns/types"
+	"go.uber.org/zap"
 	"localeyes/config"
-	"log"
 	"net/http"
-	"strconv"
 	"time"
 )
 
@@ -20,77 +15,151 @@ type Response struct {
 }
 
 type ResponseUser struct {
-	UId          string  `json:"id"`
-	Username     string  `json:"username"`
-	City         string  `json:"city"`
-	LivingSince  float64 `json:"living_since"`
-	Tag          string  `json:"tag"`
-	ActiveStatus bool    `json:"active_status"`
-	Email        string  `json:"email"`
+	UId            string               `json:"id"`
+	Username       string               `json:"username"`
+	City           string               `json:"city"`
+	LivingSince    float64              `json:"living_since"`
+	Tag            string               `json:"tag"`
+	ActiveStatus   bool                 `json:"active_status"`
+	Email          string               `json:"email"`
+	Followers      int                  `json:"follower_count"`
+	Following      int                  `json:"following_count"`
+	Status         config.AccountStatus `json:"status,omitempty"`
+	StatusReason   string               `json:"status_reason,omitempty"`
+	SuspendedUntil *time.Time           `json:"suspended_until,omitempty"`
+	CreatedAt      *time.Time           `json:"created_at,omitempty"`
+}
+
+// ResponseUserSearch is one page of the admin user search. TotalEstimate is how many users the
+// searched index partition holds, it is left out when filters other than the partition's own
+// apply because the partition count says nothing about how many users match them.
+type ResponseUserSearch struct {
+	Users         []*ResponseUser `json:"users"`
+	NextCursor    string          `json:"next_cursor,omitempty"`
+	TotalEstimate *int            `json:"total_estimate,omitempty"`
+}
+
+type ResponseFollow struct {
+	UId        string    `json:"user_id"`
+	Username   string    `json:"username"`
+	Tag        string    `json:"tag"`
+	FollowedAt time.Time `json:"followed_at"`
+}
+
+type ResponseRelation struct {
+	UId       string    `json:"user_id"`
+	Username  string    `json:"username"`
+	Tag       string    `json:"tag"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ResponseAuditEntries struct {
+	Entries    []*AuditEntry `json:"entries"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+}
+
+type ResponseReportCases struct {
+	Cases      []*ReportCase `json:"cases"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+}
+
+type ResponseReportCase struct {
+	Case    *ReportCase `json:"case"`
+	Reports []*Report   `json:"reports"`
+}
+
+type ResponseFollows struct {
+	Users      []*ResponseFollow `json:"users"`
+	NextCursor string            `json:"next_cursor,omitempty"`
 }
 
 type ResponseQuestion struct {
-	QId       string   `json:"question_id"`
-	PostId    string   `json:"post_id"`
-	UserId    string   `json:"q_user_id"`
-	Text      string   `json:"text"`
-	Replies   []string `json:"replies"`
-	CreatedAt string   `json:"created_at"`
+	QId              string          `json:"question_id"`
+	PostId           string          `json:"post_id"`
+	UserId           string          `json:"q_user_id"`
+	Username         string          `json:"username"`
+	Tag              string          `json:"tag"`
+	Text             string          `json:"text"`
+	Replies          int             `json:"replies"`
+	Reactions        ReactionSummary `json:"reactions"`
+	AcceptedAnswerId string          `json:"accepted_answer_id,omitempty"`
+	CreatedAt        time.Time       `json:"created_at"`
+	UpdatedAt        time.Time       `json:"updated_at"`
+	EditedAt         *time.Time      `json:"edited_at,omitempty"`
 }
 
 type ResponsePost struct {
-	PostId    string        `json:"post_id"`
-	UId       string        `json:"user_id"`
-	Title     string        `json:"title"`
-	Type      config.Filter `json:"type"`
-	Content   string        `json:"content"`
-	Likes     int           `json:"likes"`
-	CreatedAt time.Time     `json:"created_at"`
+	PostId    string          `json:"post_id"`
+	UId       string          `json:"user_id"`
+	Title     string          `json:"title"`
+	Type      config.Filter   `json:"type"`
+	Content   string          `json:"content"`
+	Likes     int             `json:"likes"`
+	Questions int             `json:"question_count"`
+	Comments  int             `json:"comment_count"`
+	Reactions ReactionSummary `json:"reactions"`
+	Saved     bool            `json:"saved"`
+	CreatedAt time.Time       `json:"created_at"`
+	EditedAt  *time.Time      `json:"edited_at,omitempty"`
+	Version   int             `json:"version"`
+}
+
+type ResponsePostPage struct {
+	Posts      []ResponsePost `json:"posts"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
+
+type ResponseAnswer struct {
+	RId       string          `json:"r_id"`
+	QId       string          `json:"q_id"`
+	Answer    string          `json:"answer"`
+	UserId    string          `json:"r_user_id"`
+	Username  string          `json:"username"`
+	Tag       string          `json:"tag"`
+	Upvotes   int             `json:"upvotes"`
+	Downvotes int             `json:"downvotes"`
+	Score     int             `json:"score"`
+	Accepted  bool            `json:"accepted"`
+	MyVote    config.VoteType `json:"my_vote"`
+	Reactions ReactionSummary `json:"reactions"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+	EditedAt  *time.Time      `json:"edited_at,omitempty"`
+}
+
+type ResponseComment struct {
+	CId       string             `json:"comment_id"`
+	PostId    string             `json:"post_id"`
+	ParentId  string             `json:"parent_id,omitempty"`
+	UserId    string             `json:"user_id,omitempty"`
+	Username  string             `json:"username,omitempty"`
+	Tag       string             `json:"tag,omitempty"`
+	Content   string             `json:"content"`
+	Depth     int                `json:"depth"`
+	Deleted   bool               `json:"deleted"`
+	CreatedAt time.Time          `json:"created_at"`
+	Replies   []*ResponseComment `json:"replies"`
+}
+
+// Problem is an RFC 7807 problem details body. Code is the stable name of the error that
+// clients can match on, Detail is meant for people and may change.
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail"`
+	Code      string `json:"code"`
+	Instance  string `json:"instance,omitempty"`
+	RequestId string `json:"request_id,omitempty"`
 }
 
 func (res *Response) ToJson(w http.ResponseWriter, statusCode int) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
 
-	if res.Code == 5500 {
-		snsClient, topicArn, err := config.InitSNS()
-		if err != nil {
-			log.Printf("Failed to initialize SNS client: %v", err)
-		} else {
-			messageBody, _ := json.Marshal(map[string]string{
-				"error_code":  strconv.Itoa(res.Code),
-				"status_code": strconv.Itoa(statusCode),
-				"message":     res.Message,
-				"timestamp":   time.Now().Format(time.RFC3339),
-			})
-
-			message := &sns.PublishInput{
-				Message:  aws.String(string(messageBody)),
-				TopicArn: aws.String(topicArn),
-				MessageAttributes: map[string]types.MessageAttributeValue{
-					"ErrorCode": {
-						DataType:    aws.String("String"),
-						StringValue: aws.String(strconv.Itoa(res.Code)),
-					},
-					"StatusCode": {
-						DataType:    aws.String("Number"),
-						StringValue: aws.String(strconv.Itoa(statusCode)),
-					},
-				},
-			}
-
-			_, err = snsClient.Publish(context.Background(), message)
-			if err != nil {
-				log.Printf("Failed to send message to SNS: %v", err)
-			} else {
-				log.Println("Error message successfully sent to SNS.")
-			}
-		}
-	}
-
 	err := json.NewEncoder(w).Encode(res)
 	if err != nil {
-		log.Printf("Failed to encode error response: %v", err)
+		zap.L().Error("Error encoding response", zap.Error(err))
 		return
 	}
 }
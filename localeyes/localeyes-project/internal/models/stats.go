@@ -0,0 +1,15 @@
+package models
+
+import "localeyes/config"
+
+// StatsBucket holds the counters of one day or one ISO week
+type StatsBucket struct {
+	Period string                     `json:"period"`
+	Counts map[config.StatsMetric]int `json:"counts"`
+}
+
+type ResponseStats struct {
+	Granularity config.StatsGranularity    `json:"granularity"`
+	Totals      map[config.StatsMetric]int `json:"totals"`
+	Series      []*StatsBucket             `json:"series"`
+}
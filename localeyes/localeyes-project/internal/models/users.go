@@ -1,49 +1,90 @@
 package models
 
+import (
+	"localeyes/config"
+	"time"
+)
+
 type User struct {
-	UId         string  `json:"id" dynamodbav:"pk"`
-	Email       string  `json:"email" dynamodbav:"email"`
-	Username    string  `json:"username" dynamodbav:"username"`
-	Password    string  `json:"password" dynamodbav:"password"`
-	City        string  `json:"city" dynamodbav:"city"`
-	DwellingAge float64 `json:"dwelling_age" dynamodbav:"dwelling_age"`
-	IsActive    bool    `json:"is_active" dynamodbav:"sk"`
-	Tag         string  `json:"tag" dynamodbav:"tag"`
+	UId         string     `json:"id" dynamodbav:"pk"`
+	Email       string     `json:"email" dynamodbav:"email"`
+	Username    string     `json:"username" dynamodbav:"username"`
+	Password    string     `json:"password" dynamodbav:"password"`
+	City        string     `json:"city" dynamodbav:"city"`
+	DwellingAge float64    `json:"dwelling_age" dynamodbav:"dwelling_age"`
+	IsActive    bool       `json:"is_active" dynamodbav:"sk"`
+	Tag         string     `json:"tag" dynamodbav:"tag"`
+	CreatedAt   *time.Time `json:"created_at,omitempty" dynamodbav:"created_at,omitempty"`
+	Followers   int        `json:"follower_count" dynamodbav:"-"`
+	Following   int        `json:"following_count" dynamodbav:"-"`
+	Version     int        `json:"version" dynamodbav:"version"`
+	AccountState
 }
 
 type UserWithStringStatus struct {
-	UId         string  `json:"id" dynamodbav:"pk"`
-	Email       string  `json:"email" dynamodbav:"email"`
-	Username    string  `json:"username" dynamodbav:"username"`
-	Password    string  `json:"password" dynamodbav:"password"`
-	City        string  `json:"city" dynamodbav:"city"`
-	DwellingAge float64 `json:"dwelling_age" dynamodbav:"dwelling_age"`
-	IsActive    string  `json:"is_active" dynamodbav:"sk"`
-	Tag         string  `json:"tag" dynamodbav:"tag"`
+	UId         string     `json:"id" dynamodbav:"pk"`
+	Email       string     `json:"email" dynamodbav:"email"`
+	Username    string     `json:"username" dynamodbav:"username"`
+	Password    string     `json:"password" dynamodbav:"password"`
+	City        string     `json:"city" dynamodbav:"city"`
+	DwellingAge float64    `json:"dwelling_age" dynamodbav:"dwelling_age"`
+	IsActive    string     `json:"is_active" dynamodbav:"sk"`
+	Tag         string     `json:"tag" dynamodbav:"tag"`
+	CreatedAt   *time.Time `json:"created_at,omitempty" dynamodbav:"created_at,omitempty"`
+	Version     int        `json:"version" dynamodbav:"version"`
+	AccountState
 }
 
 type UserSKEmail struct {
-	PK          string  `json:"pk" dynamodbav:"pk"`
-	UId         string  `json:"id" dynamodbav:"uid"`
-	Email       string  `json:"email" dynamodbav:"sk"`
-	Username    string  `json:"username" dynamodbav:"username"`
-	Password    string  `json:"password" dynamodbav:"password"`
-	City        string  `json:"city" dynamodbav:"city"`
-	DwellingAge float64 `json:"dwelling_age" dynamodbav:"dwelling_age"`
-	IsActive    bool    `json:"is_active" dynamodbav:"is_active"`
-	Tag         string  `json:"tag" dynamodbav:"tag"`
+	PK          string     `json:"pk" dynamodbav:"pk"`
+	UId         string     `json:"id" dynamodbav:"uid"`
+	Email       string     `json:"email" dynamodbav:"sk"`
+	Username    string     `json:"username" dynamodbav:"username"`
+	Password    string     `json:"password" dynamodbav:"password"`
+	City        string     `json:"city" dynamodbav:"city"`
+	DwellingAge float64    `json:"dwelling_age" dynamodbav:"dwelling_age"`
+	IsActive    bool       `json:"is_active" dynamodbav:"is_active"`
+	Tag         string     `json:"tag" dynamodbav:"tag"`
+	CreatedAt   *time.Time `json:"created_at,omitempty" dynamodbav:"created_at,omitempty"`
+	AccountState
 }
 
 type UserSKUsername struct {
-	PK          string  `json:"pk" dynamodbav:"pk"`
-	UId         string  `json:"id" dynamodbav:"uid"`
-	Email       string  `json:"email" dynamodbav:"email"`
-	Username    string  `json:"username" dynamodbav:"sk"`
-	Password    string  `json:"password" dynamodbav:"password"`
-	City        string  `json:"city" dynamodbav:"city"`
-	DwellingAge float64 `json:"dwelling_age" dynamodbav:"dwelling_age"`
-	IsActive    bool    `json:"is_active" dynamodbav:"is_active"`
-	Tag         string  `json:"tag" dynamodbav:"tag"`
+	PK          string     `json:"pk" dynamodbav:"pk"`
+	UId         string     `json:"id" dynamodbav:"uid"`
+	Email       string     `json:"email" dynamodbav:"email"`
+	Username    string     `json:"username" dynamodbav:"sk"`
+	Password    string     `json:"password" dynamodbav:"password"`
+	City        string     `json:"city" dynamodbav:"city"`
+	DwellingAge float64    `json:"dwelling_age" dynamodbav:"dwelling_age"`
+	IsActive    bool       `json:"is_active" dynamodbav:"is_active"`
+	Tag         string     `json:"tag" dynamodbav:"tag"`
+	CreatedAt   *time.Time `json:"created_at,omitempty" dynamodbav:"created_at,omitempty"`
+	AccountState
+}
+
+// AccountState is the lifecycle and moderation state of an account, copied onto every user item.
+// Accounts written before it was kept have no status and are read from their active flag.
+type AccountState struct {
+	Status          config.AccountStatus `json:"status,omitempty" dynamodbav:"account_status,omitempty"`
+	StatusReason    string               `json:"status_reason,omitempty" dynamodbav:"status_reason,omitempty"`
+	StatusActor     string               `json:"status_actor,omitempty" dynamodbav:"status_actor,omitempty"`
+	SuspendedUntil  *time.Time           `json:"suspended_until,omitempty" dynamodbav:"suspended_until,omitempty"`
+	StatusChangedAt *time.Time           `json:"status_changed_at,omitempty" dynamodbav:"status_changed_at,omitempty"`
+}
+
+func (state AccountState) CurrentStatus(isActive bool) config.AccountStatus {
+	if state.Status != "" {
+		return state.Status
+	}
+	if isActive {
+		return config.AccountActive
+	}
+	return config.AccountDeactivated
+}
+
+func (state AccountState) SuspensionExpired(now time.Time) bool {
+	return state.Status == config.AccountSuspended && state.SuspendedUntil != nil && !now.Before(*state.SuspendedUntil)
 }
 
 type UserEmail struct {
@@ -61,3 +102,31 @@ type GetUsersParams struct {
 	Offset int32
 	Search string
 }
+
+// UserSearch filters the admin user search, every filter that is set has to match
+type UserSearch struct {
+	Email          string
+	UsernamePrefix string
+	City           string
+	Tag            string
+	Status         config.AccountStatus
+	From           time.Time
+	To             time.Time
+	Sort           config.UserSort
+	Desc           bool
+	Limit          int
+	Cursor         string
+}
+
+// UserIndexEntry is the summary of a user kept on every user search index item
+type UserIndexEntry struct {
+	PK        string               `json:"-" dynamodbav:"pk"`
+	SK        string               `json:"-" dynamodbav:"sk"`
+	UId       string               `json:"id" dynamodbav:"uid"`
+	Username  string               `json:"username" dynamodbav:"username"`
+	Email     string               `json:"email" dynamodbav:"email"`
+	City      string               `json:"city" dynamodbav:"city"`
+	Tag       string               `json:"tag" dynamodbav:"tag"`
+	Status    config.AccountStatus `json:"status" dynamodbav:"account_status"`
+	CreatedAt *time.Time           `json:"created_at,omitempty" dynamodbav:"created_at,omitempty"`
+}
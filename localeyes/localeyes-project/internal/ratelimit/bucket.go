@@ -0,0 +1,36 @@
+// Package ratelimit holds the token bucket shared by the rate limiters and the limiter used when
+// the API runs as a single long lived server. On Lambda the buckets live in DynamoDB instead,
+// see repositories.RateLimitRepository.
+package ratelimit
+
+import (
+	"localeyes/config"
+	"localeyes/internal/models"
+	"math"
+	"time"
+)
+
+// Take refills bucket for the time passed since it was last updated and takes one request from
+// it if a whole token is left. A bucket that was never used is passed as nil and starts full.
+func Take(bucket *models.RateLimitBucket, policy config.RateLimitPolicy, now time.Time) (*models.RateLimitBucket, *models.RateLimitDecision) {
+	perSecond := float64(policy.Limit) / policy.Window.Seconds()
+	tokens := float64(policy.Limit)
+	if bucket != nil {
+		elapsed := now.Sub(bucket.UpdatedAt).Seconds()
+		tokens = math.Min(tokens, bucket.Tokens+math.Max(elapsed, 0)*perSecond)
+	}
+	decision := &models.RateLimitDecision{Limit: policy.Limit}
+	if tokens >= 1 {
+		tokens--
+		decision.Allowed = true
+	} else {
+		decision.RetryAfter = seconds((1 - tokens) / perSecond)
+	}
+	decision.Remaining = int(tokens)
+	decision.Reset = seconds((float64(policy.Limit) - tokens) / perSecond)
+	return &models.RateLimitBucket{Tokens: tokens, UpdatedAt: now}, decision
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
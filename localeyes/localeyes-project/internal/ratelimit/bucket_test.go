@@ -0,0 +1,126 @@
+package ratelimit
+
+import (
+	"localeyes/config"
+	"localeyes/internal/models"
+	"testing"
+	"time"
+)
+
+func TestTake(t *testing.T) {
+	// ten requests a minute, a token every six seconds
+	policy := config.RateLimitPolicy{Class: config.WriteRateLimit, Limit: 10, Window: time.Minute}
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		bucket     *models.RateLimitBucket
+		now        time.Time
+		allowed    bool
+		remaining  int
+		retryAfter time.Duration
+		reset      time.Duration
+	}{
+		{
+			name:      "new bucket starts full",
+			now:       start,
+			allowed:   true,
+			remaining: 9,
+			reset:     6 * time.Second,
+		},
+		{
+			name:      "last token",
+			bucket:    &models.RateLimitBucket{Tokens: 1, UpdatedAt: start},
+			now:       start,
+			allowed:   true,
+			remaining: 0,
+			reset:     time.Minute,
+		},
+		{
+			name:       "empty bucket",
+			bucket:     &models.RateLimitBucket{Tokens: 0, UpdatedAt: start},
+			now:        start,
+			remaining:  0,
+			retryAfter: 6 * time.Second,
+			reset:      time.Minute,
+		},
+		{
+			name:       "partly refilled",
+			bucket:     &models.RateLimitBucket{Tokens: 0, UpdatedAt: start},
+			now:        start.Add(3 * time.Second),
+			remaining:  0,
+			retryAfter: 3 * time.Second,
+			reset:      57 * time.Second,
+		},
+		{
+			name:      "refilled a token",
+			bucket:    &models.RateLimitBucket{Tokens: 0, UpdatedAt: start},
+			now:       start.Add(6 * time.Second),
+			allowed:   true,
+			remaining: 0,
+			reset:     time.Minute,
+		},
+		{
+			name:      "refill stops at the limit",
+			bucket:    &models.RateLimitBucket{Tokens: 0, UpdatedAt: start},
+			now:       start.Add(time.Hour),
+			allowed:   true,
+			remaining: 9,
+			reset:     6 * time.Second,
+		},
+		{
+			name:       "clock going back refills nothing",
+			bucket:     &models.RateLimitBucket{Tokens: 0, UpdatedAt: start},
+			now:        start.Add(-time.Minute),
+			remaining:  0,
+			retryAfter: 6 * time.Second,
+			reset:      time.Minute,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bucket, decision := Take(test.bucket, policy, test.now)
+			if decision.Allowed != test.allowed || decision.Remaining != test.remaining || decision.Limit != policy.Limit {
+				t.Fatalf("decision = %+v, want allowed %v with %d remaining", decision, test.allowed, test.remaining)
+			}
+			if !near(decision.RetryAfter, test.retryAfter) || !near(decision.Reset, test.reset) {
+				t.Fatalf("decision = %+v, want retry after %v and reset %v", decision, test.retryAfter, test.reset)
+			}
+			if !bucket.UpdatedAt.Equal(test.now) {
+				t.Fatalf("bucket updated at %v, want %v", bucket.UpdatedAt, test.now)
+			}
+		})
+	}
+}
+
+func TestTakeBurst(t *testing.T) {
+	policy := config.RateLimitPolicy{Class: config.AuthRateLimit, Limit: 5, Window: 10 * time.Minute}
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	var bucket *models.RateLimitBucket
+	var decision *models.RateLimitDecision
+	for i := 0; i < policy.Limit; i++ {
+		bucket, decision = Take(bucket, policy, now)
+		if !decision.Allowed {
+			t.Fatalf("request %d of a burst of %d was refused", i+1, policy.Limit)
+		}
+	}
+	// every further request is refused and does not drain the bucket below empty
+	for i := 0; i < 3; i++ {
+		bucket, decision = Take(bucket, policy, now)
+		if decision.Allowed {
+			t.Fatalf("request %d past the burst was allowed", i+1)
+		}
+		if !near(decision.RetryAfter, 2*time.Minute) {
+			t.Fatalf("retry after %v, want 2m", decision.RetryAfter)
+		}
+	}
+	_, decision = Take(bucket, policy, now.Add(2*time.Minute))
+	if !decision.Allowed {
+		t.Fatal("request after the retry delay was refused")
+	}
+}
+
+// near compares durations computed from floats, which may be off by a rounding error
+func near(got, want time.Duration) bool {
+	diff := got - want
+	return diff > -time.Millisecond && diff < time.Millisecond
+}
@@ -0,0 +1,53 @@
+package ratelimit
+
+import (
+	"context"
+	"localeyes/config"
+	"localeyes/internal/models"
+	"sync"
+	"time"
+)
+
+// sweepEvery is how many requests pass between dropping the buckets that have refilled completely
+const sweepEvery = 1000
+
+// MemoryLimiter keeps the buckets in the process, it only limits correctly while every
+// request goes through the same process
+type MemoryLimiter struct {
+	mu       sync.Mutex
+	buckets  map[string]*memoryBucket
+	requests int
+}
+
+type memoryBucket struct {
+	*models.RateLimitBucket
+	full time.Time
+}
+
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{
+		buckets: make(map[string]*memoryBucket),
+	}
+}
+
+func (limiter *MemoryLimiter) Allow(ctx context.Context, subject string, policy config.RateLimitPolicy) (*models.RateLimitDecision, error) {
+	key := string(policy.Class) + "/" + subject
+	now := time.Now()
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+	limiter.requests++
+	if limiter.requests%sweepEvery == 0 {
+		for key, bucket := range limiter.buckets {
+			if now.After(bucket.full) {
+				delete(limiter.buckets, key)
+			}
+		}
+	}
+	var bucket *models.RateLimitBucket
+	if existing, ok := limiter.buckets[key]; ok {
+		bucket = existing.RateLimitBucket
+	}
+	bucket, decision := Take(bucket, policy, now)
+	limiter.buckets[key] = &memoryBucket{bucket, now.Add(decision.Reset)}
+	return decision, nil
+}
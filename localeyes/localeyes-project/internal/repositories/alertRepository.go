@@ -0,0 +1,47 @@
+package repositories
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"localeyes/config"
+	"localeyes/internal/keys"
+	"localeyes/utils"
+	"os"
+	"strconv"
+	"time"
+)
+
+const alertSK = "window"
+
+type AlertRepository struct {
+	Db        *dynamodb.Client
+	TableName string
+}
+
+func NewAlertRepository(db *dynamodb.Client) *AlertRepository {
+	return &AlertRepository{
+		db,
+		os.Getenv("TABLE_NAME"),
+	}
+}
+
+// ClaimWindow marks the alert group with the fingerprint as sent for the window, every Lambda
+// execution environment raising the same alert in the window tries it and only the first one
+// sends the group. The marker expires a window after the window has ended.
+func (repo *AlertRepository) ClaimWindow(ctx context.Context, fingerprint string, window time.Time) (bool, error) {
+	_, err := repo.Db.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(repo.TableName),
+		Item: map[string]types.AttributeValue{
+			"pk":  &types.AttributeValueMemberS{Value: keys.Alert.Key(fingerprint, strconv.FormatInt(window.Unix(), 10))},
+			"sk":  &types.AttributeValueMemberS{Value: alertSK},
+			"ttl": &types.AttributeValueMemberN{Value: strconv.FormatInt(window.Add(2*config.AlertWindow).Unix(), 10)},
+		},
+		ConditionExpression: aws.String("attribute_not_exists(pk)"),
+	})
+	if utils.IsConditionFailed(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
@@ -6,9 +6,14 @@ import (
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"localeyes/config"
+	"localeyes/internal/keys"
 	"localeyes/internal/models"
+	"localeyes/utils"
 	"os"
-	"strings"
+	"sort"
+	"strconv"
+	"time"
 )
 
 type AnswerRepository struct {
@@ -27,57 +32,406 @@ func NewAnswerRepository(db *dynamodb.Client) *AnswerRepository {
 
 func (repo *AnswerRepository) AddAnswer(ctx context.Context, answer *models.Reply) error {
 	answerNew := &models.Reply{
-		RId:    "reply:" + answer.RId,
-		QId:    "question:" + answer.QId,
-		Answer: answer.Answer,
-		UserId: answer.UserId,
+		RId:       keys.Reply.Key(answer.RId),
+		QId:       keys.Question.Key(answer.QId),
+		Answer:    answer.Answer,
+		UserId:    answer.UserId,
+		CreatedAt: answer.CreatedAt,
+		UpdatedAt: answer.UpdatedAt,
 	}
 	answerAv, err := attributevalue.MarshalMap(answerNew)
 	if err != nil {
 		return err
 	}
-	input := &dynamodb.PutItemInput{
-		Item:      answerAv,
-		TableName: aws.String(repo.TableName),
-	}
-	_, err = repo.Db.PutItem(ctx, input)
+	_, err = repo.Db.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{
+			{
+				Put: &types.Put{
+					Item:      answerAv,
+					TableName: aws.String(repo.TableName),
+				},
+			},
+			countUpdate(repo.TableName, keys.Question.Key(answer.QId), "answer_count", 1),
+		},
+	})
 	return err
 }
 
 func (repo *AnswerRepository) DeleteAnswer(ctx context.Context, qId, rId, uId string) error {
-	_, err := repo.Db.DeleteItem(ctx, &dynamodb.DeleteItemInput{
-		TableName:           aws.String(repo.TableName),
-		ConditionExpression: aws.String("r_user_id = :userId"),
-		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":userId": &types.AttributeValueMemberS{Value: uId},
-		},
-		Key: map[string]types.AttributeValue{
-			"pk": &types.AttributeValueMemberS{Value: "question:" + qId},
-			"sk": &types.AttributeValueMemberS{Value: "reply:" + rId},
+	_, err := repo.Db.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{
+			{
+				Delete: &types.Delete{
+					TableName:           aws.String(repo.TableName),
+					ConditionExpression: aws.String("r_user_id = :userId"),
+					ExpressionAttributeValues: map[string]types.AttributeValue{
+						":userId": &types.AttributeValueMemberS{Value: uId},
+					},
+					Key: map[string]types.AttributeValue{
+						"pk": &types.AttributeValueMemberS{Value: keys.Question.Key(qId)},
+						"sk": &types.AttributeValueMemberS{Value: keys.Reply.Key(rId)},
+					},
+				},
+			},
+			countUpdate(repo.TableName, keys.Question.Key(qId), "answer_count", -1),
 		},
 	})
-	return err
+	if err != nil {
+		if utils.IsConditionFailed(err) {
+			return utils.NotYourAnswer
+		}
+		return err
+	}
+	_, err = deleteByPrefix(ctx, repo.Db, repo.TableName, keys.Vote.Key(rId), keys.User.Prefix())
+	if err != nil {
+		return err
+	}
+	return deleteReactions(ctx, repo.Db, repo.TableName, config.AnswerContent, rId)
 }
 
 func (repo *AnswerRepository) GetAllAnswersByQId(ctx context.Context, qId string) ([]*models.Reply, error) {
-	result, err := repo.Db.Query(ctx, &dynamodb.QueryInput{
+	queryInput := &dynamodb.QueryInput{
 		TableName:              aws.String(repo.TableName),
 		KeyConditionExpression: aws.String("pk = :pk AND begins_with(sk, :sk)"),
 		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":pk": &types.AttributeValueMemberS{Value: "question:" + qId},
-			":sk": &types.AttributeValueMemberS{Value: "reply:"},
+			":pk": &types.AttributeValueMemberS{Value: keys.Question.Key(qId)},
+			":sk": &types.AttributeValueMemberS{Value: keys.Reply.Prefix()},
 		},
+	}
+	var replies []*models.Reply
+	for {
+		result, err := repo.Db.Query(ctx, queryInput)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range result.Items {
+			var reply models.Reply
+			err = attributevalue.UnmarshalMap(v, &reply)
+			if err != nil {
+				return nil, err
+			}
+			if reply.QId, err = keys.Question.Parse(reply.QId); err != nil {
+				return nil, err
+			}
+			if reply.RId, err = keys.Reply.Parse(reply.RId); err != nil {
+				return nil, err
+			}
+			replies = append(replies, &reply)
+		}
+		if result.LastEvaluatedKey == nil {
+			break
+		}
+		queryInput.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+	sort.SliceStable(replies, func(i, j int) bool {
+		return replies[i].CreatedAt.Before(replies[j].CreatedAt)
 	})
+	return replies, nil
+}
+
+func (repo *AnswerRepository) GetAnswerCounts(ctx context.Context, qIds []string) (map[string]int, error) {
+	pks := make([]string, 0, len(qIds))
+	for _, qId := range qIds {
+		pks = append(pks, keys.Question.Key(qId))
+	}
+	counts, err := getCounts(ctx, repo.Db, repo.TableName, pks)
 	if err != nil {
 		return nil, err
 	}
-	var replies []*models.Reply
-	for _, v := range result.Items {
-		var reply models.Reply
-		err = attributevalue.UnmarshalMap(v, &reply)
-		reply.QId = strings.Split(reply.QId, ":")[1]
-		reply.RId = strings.Split(reply.RId, ":")[1]
-		replies = append(replies, &reply)
+	answerCounts := make(map[string]int)
+	for pk, count := range counts {
+		qId, err := keys.Question.Parse(pk)
+		if err != nil {
+			return nil, err
+		}
+		answerCounts[qId] = count.AnswerCount
 	}
-	return replies, nil
+	return answerCounts, nil
+}
+
+func (repo *AnswerRepository) GetAnswer(ctx context.Context, qId, rId string) (*models.Reply, error) {
+	result, err := repo.Db.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(repo.TableName),
+		Key: map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{Value: keys.Question.Key(qId)},
+			"sk": &types.AttributeValueMemberS{Value: keys.Reply.Key(rId)},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.Item == nil {
+		return nil, utils.NoAnswer
+	}
+	var reply models.Reply
+	if err := attributevalue.UnmarshalMap(result.Item, &reply); err != nil {
+		return nil, err
+	}
+	reply.QId = qId
+	reply.RId = rId
+	return &reply, nil
+}
+
+func (repo *AnswerRepository) getVote(ctx context.Context, rId, uId string) (config.VoteType, error) {
+	result, err := repo.Db.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(repo.TableName),
+		Key: map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{Value: keys.Vote.Key(rId)},
+			"sk": &types.AttributeValueMemberS{Value: keys.User.Key(uId)},
+		},
+	})
+	if err != nil {
+		return config.NoVote, err
+	}
+	if result.Item == nil {
+		return config.NoVote, nil
+	}
+	var vote models.AnswerVote
+	if err := attributevalue.UnmarshalMap(result.Item, &vote); err != nil {
+		return config.NoVote, err
+	}
+	return vote.Vote, nil
+}
+
+func (repo *AnswerRepository) VoteAnswer(ctx context.Context, qId, rId, uId string, vote config.VoteType) error {
+	previous, err := repo.getVote(ctx, rId, uId)
+	if err != nil {
+		return err
+	}
+	if previous == vote {
+		return nil
+	}
+	upDelta, downDelta := 0, 0
+	switch previous {
+	case config.Upvote:
+		upDelta--
+	case config.Downvote:
+		downDelta--
+	}
+	switch vote {
+	case config.Upvote:
+		upDelta++
+	case config.Downvote:
+		downDelta++
+	}
+
+	voteKey := map[string]types.AttributeValue{
+		"pk": &types.AttributeValueMemberS{Value: keys.Vote.Key(rId)},
+		"sk": &types.AttributeValueMemberS{Value: keys.User.Key(uId)},
+	}
+	// the vote item is conditioned on the state we read so concurrent votes by the same user cannot double count
+	var voteWrite types.TransactWriteItem
+	switch {
+	case vote == config.NoVote:
+		voteWrite.Delete = &types.Delete{
+			TableName:           aws.String(repo.TableName),
+			Key:                 voteKey,
+			ConditionExpression: aws.String("vote = :previous"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":previous": &types.AttributeValueMemberS{Value: string(previous)},
+			},
+		}
+	case previous == config.NoVote:
+		voteWrite.Put = &types.Put{
+			TableName: aws.String(repo.TableName),
+			Item: map[string]types.AttributeValue{
+				"pk":   voteKey["pk"],
+				"sk":   voteKey["sk"],
+				"vote": &types.AttributeValueMemberS{Value: string(vote)},
+			},
+			ConditionExpression: aws.String("attribute_not_exists(pk)"),
+		}
+	default:
+		voteWrite.Update = &types.Update{
+			TableName:           aws.String(repo.TableName),
+			Key:                 voteKey,
+			UpdateExpression:    aws.String("SET vote = :vote"),
+			ConditionExpression: aws.String("vote = :previous"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":vote":     &types.AttributeValueMemberS{Value: string(vote)},
+				":previous": &types.AttributeValueMemberS{Value: string(previous)},
+			},
+		}
+	}
+	replyWrite := types.TransactWriteItem{
+		Update: &types.Update{
+			TableName: aws.String(repo.TableName),
+			Key: map[string]types.AttributeValue{
+				"pk": &types.AttributeValueMemberS{Value: keys.Question.Key(qId)},
+				"sk": &types.AttributeValueMemberS{Value: keys.Reply.Key(rId)},
+			},
+			UpdateExpression:    aws.String("ADD upvotes :up, downvotes :down, score :score"),
+			ConditionExpression: aws.String("attribute_exists(pk) AND attribute_exists(sk)"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":up":    &types.AttributeValueMemberN{Value: strconv.Itoa(upDelta)},
+				":down":  &types.AttributeValueMemberN{Value: strconv.Itoa(downDelta)},
+				":score": &types.AttributeValueMemberN{Value: strconv.Itoa(upDelta - downDelta)},
+			},
+		},
+	}
+	_, err = repo.Db.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{replyWrite, voteWrite},
+	})
+	if conditionFailedAt(err, 0) {
+		return utils.NoAnswer
+	}
+	if utils.IsConditionFailed(err) {
+		return utils.EditConflict
+	}
+	return err
+}
+
+func (repo *AnswerRepository) GetUserVotes(ctx context.Context, uId string, rIds []string) (map[string]config.VoteType, error) {
+	votes := make(map[string]config.VoteType)
+	// BatchGetItem accepts at most 100 keys per call
+	for start := 0; start < len(rIds); start += 100 {
+		end := start + 100
+		if end > len(rIds) {
+			end = len(rIds)
+		}
+		var itemKeys []map[string]types.AttributeValue
+		for _, rId := range rIds[start:end] {
+			itemKeys = append(itemKeys, map[string]types.AttributeValue{
+				"pk": &types.AttributeValueMemberS{Value: keys.Vote.Key(rId)},
+				"sk": &types.AttributeValueMemberS{Value: keys.User.Key(uId)},
+			})
+		}
+		requestItems := map[string]types.KeysAndAttributes{
+			repo.TableName: {Keys: itemKeys},
+		}
+		for len(requestItems) > 0 {
+			result, err := repo.Db.BatchGetItem(ctx, &dynamodb.BatchGetItemInput{
+				RequestItems: requestItems,
+			})
+			if err != nil {
+				return nil, err
+			}
+			for _, item := range result.Responses[repo.TableName] {
+				var vote models.AnswerVote
+				if err := attributevalue.UnmarshalMap(item, &vote); err != nil {
+					return nil, err
+				}
+				rId, err := keys.Vote.Parse(vote.RId)
+				if err != nil {
+					return nil, err
+				}
+				votes[rId] = vote.Vote
+			}
+			requestItems = result.UnprocessedKeys
+		}
+	}
+	return votes, nil
+}
+
+// AcceptAnswer marks rId as the accepted answer of the question and clears previousRId.
+// An empty rId only clears the current acceptance.
+func (repo *AnswerRepository) AcceptAnswer(ctx context.Context, pId, qId, rId, previousRId string) error {
+	questionUpdate := &types.Update{
+		TableName: aws.String(repo.TableName),
+		Key: map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{Value: keys.Post.Key(pId)},
+			"sk": &types.AttributeValueMemberS{Value: keys.Question.Key(qId)},
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{},
+	}
+	if previousRId == "" {
+		questionUpdate.ConditionExpression = aws.String("attribute_exists(pk) AND attribute_not_exists(accepted_answer_id)")
+	} else {
+		questionUpdate.ConditionExpression = aws.String("accepted_answer_id = :previous")
+		questionUpdate.ExpressionAttributeValues[":previous"] = &types.AttributeValueMemberS{Value: previousRId}
+	}
+	if rId == "" {
+		questionUpdate.UpdateExpression = aws.String("REMOVE accepted_answer_id")
+	} else {
+		questionUpdate.UpdateExpression = aws.String("SET accepted_answer_id = :answer")
+		questionUpdate.ExpressionAttributeValues[":answer"] = &types.AttributeValueMemberS{Value: rId}
+	}
+	if len(questionUpdate.ExpressionAttributeValues) == 0 {
+		questionUpdate.ExpressionAttributeValues = nil
+	}
+	transactItems := []types.TransactWriteItem{{Update: questionUpdate}}
+
+	setAccepted := func(answerId string, accepted bool) types.TransactWriteItem {
+		return types.TransactWriteItem{
+			Update: &types.Update{
+				TableName: aws.String(repo.TableName),
+				Key: map[string]types.AttributeValue{
+					"pk": &types.AttributeValueMemberS{Value: keys.Question.Key(qId)},
+					"sk": &types.AttributeValueMemberS{Value: keys.Reply.Key(answerId)},
+				},
+				UpdateExpression:    aws.String("SET accepted = :accepted"),
+				ConditionExpression: aws.String("attribute_exists(pk) AND attribute_exists(sk)"),
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":accepted": &types.AttributeValueMemberBOOL{Value: accepted},
+				},
+			},
+		}
+	}
+	if rId != "" {
+		transactItems = append(transactItems, setAccepted(rId, true))
+	}
+	if previousRId != "" && previousRId != rId {
+		if _, err := repo.GetAnswer(ctx, qId, previousRId); err == nil {
+			transactItems = append(transactItems, setAccepted(previousRId, false))
+		}
+	}
+	_, err := repo.Db.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: transactItems,
+	})
+	if utils.IsConditionFailed(err) {
+		// the acceptance changed since it was read or an answer is gone
+		return utils.EditConflict
+	}
+	return err
+}
+
+func (repo *AnswerRepository) UpdateAnswer(ctx context.Context, qId, rId, uId, text string, editedAt time.Time) error {
+	answer, err := repo.GetAnswer(ctx, qId, rId)
+	if err != nil {
+		return err
+	}
+	if answer.UserId != uId {
+		return utils.NotYourAnswer
+	}
+	revision, err := revisionPut(repo.TableName, config.AnswerContent, rId, uId, "", answer.Answer, editedAt)
+	if err != nil {
+		return err
+	}
+	answerUpdate := types.TransactWriteItem{
+		Update: &types.Update{
+			TableName: aws.String(repo.TableName),
+			Key: map[string]types.AttributeValue{
+				"pk": &types.AttributeValueMemberS{Value: keys.Question.Key(qId)},
+				"sk": &types.AttributeValueMemberS{Value: keys.Reply.Key(rId)},
+			},
+			UpdateExpression:    aws.String("SET answer = :answer, edited_at = :editedAt, updated_at = :editedAt"),
+			ConditionExpression: aws.String("r_user_id = :userId AND answer = :previous"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":answer":   &types.AttributeValueMemberS{Value: text},
+				":editedAt": &types.AttributeValueMemberS{Value: editedAt.Format(time.RFC3339)},
+				":userId":   &types.AttributeValueMemberS{Value: uId},
+				":previous": &types.AttributeValueMemberS{Value: answer.Answer},
+			},
+		},
+	}
+	_, err = repo.Db.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{answerUpdate, revision},
+	})
+	if utils.IsConditionFailed(err) {
+		return utils.EditConflict
+	}
+	return err
+}
+
+func (repo *AnswerRepository) SetAnswerHidden(ctx context.Context, qId, rId string, hidden bool) error {
+	err := setHidden(ctx, repo.Db, repo.TableName, []map[string]types.AttributeValue{
+		{
+			"pk": &types.AttributeValueMemberS{Value: keys.Question.Key(qId)},
+			"sk": &types.AttributeValueMemberS{Value: keys.Reply.Key(rId)},
+		},
+	}, hidden)
+	if utils.IsConditionFailed(err) {
+		return utils.NoAnswer
+	}
+	return err
 }
@@ -0,0 +1,236 @@
+package repositories
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"localeyes/config"
+	"localeyes/internal/keys"
+	"localeyes/internal/models"
+	"localeyes/utils"
+	"os"
+	"strings"
+	"time"
+)
+
+// Every entry is written to the partition of its day, "audit:<yyyy-mm-dd>", so no single
+// partition takes the writes of the whole site, and to those of its target and actor
+
+type AuditRepository struct {
+	Db        *dynamodb.Client
+	TableName string
+}
+
+func NewAuditRepository(db *dynamodb.Client) *AuditRepository {
+	return &AuditRepository{
+		db,
+		os.Getenv("TABLE_NAME"),
+	}
+}
+
+func auditActorPK(actorId string) string {
+	return keys.Audit.Key("actor", actorId)
+}
+
+func auditDayPK(day time.Time) string {
+	return keys.Audit.Key(day.UTC().Format(config.AuditDayLayout))
+}
+
+func auditTargetPK(targetType config.ContentType, targetId string) string {
+	return keys.Audit.Key("target", string(targetType), targetId)
+}
+
+// Record appends an entry to the log. Every copy is written with a not-exists condition and
+// the repository offers no update or delete, so entries cannot be altered once written.
+func (repo *AuditRepository) Record(ctx context.Context, entry *models.AuditEntry) error {
+	entry.SK = keys.Sorted(entry.CreatedAt, entry.Id)
+	pks := []string{auditDayPK(entry.CreatedAt), auditTargetPK(entry.TargetType, entry.TargetId)}
+	if entry.ActorId != "" {
+		pks = append(pks, auditActorPK(entry.ActorId))
+	}
+	items := make([]types.TransactWriteItem, 0, len(pks))
+	for _, pk := range pks {
+		entry.PK = pk
+		item, err := attributevalue.MarshalMap(entry)
+		if err != nil {
+			return err
+		}
+		items = append(items, types.TransactWriteItem{
+			Put: &types.Put{
+				TableName:           aws.String(repo.TableName),
+				Item:                item,
+				ConditionExpression: aws.String("attribute_not_exists(pk)"),
+			},
+		})
+	}
+	_, err := repo.Db.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: items})
+	return err
+}
+
+// auditDays lists the days from the newest to the oldest, both included
+func auditDays(newest, oldest time.Time) []string {
+	newest = newest.UTC().Truncate(24 * time.Hour)
+	oldest = oldest.UTC().Truncate(24 * time.Hour)
+	var days []string
+	for day := newest; !day.Before(oldest); day = day.AddDate(0, 0, -1) {
+		days = append(days, auditDayPK(day))
+	}
+	return days
+}
+
+// Query returns one page of entries newest first, bounded by the query's time range when set.
+// Entries of an actor are narrowed down to a target when both are given. The whole log is read
+// day by day back to the start of the range, or AuditLookbackDays when it has none, and its
+// cursor names the day it stopped in.
+func (repo *AuditRepository) Query(ctx context.Context, query *models.AuditQuery) ([]*models.AuditEntry, string, error) {
+	// "0" sorts before and "~" after every timestamp prefix
+	from, to := "0", "~"
+	if !query.From.IsZero() {
+		from = query.From.UTC().Format(config.SortableTime)
+	}
+	if !query.To.IsZero() {
+		to = keys.SortedUntil(query.To)
+	}
+	values := map[string]types.AttributeValue{
+		":from": &types.AttributeValueMemberS{Value: from},
+		":to":   &types.AttributeValueMemberS{Value: to},
+	}
+	var filter *string
+	var startSK string
+	var pks []string
+	if query.ActorId != "" || query.TargetId != "" {
+		if query.Cursor != "" {
+			sk, err := utils.DecodeCursor(query.Cursor)
+			if err != nil {
+				return nil, "", err
+			}
+			startSK = sk
+		}
+		if query.ActorId != "" {
+			pks = []string{auditActorPK(query.ActorId)}
+			if query.TargetId != "" {
+				filter = aws.String("target_type = :targetType AND target_id = :targetId")
+				values[":targetType"] = &types.AttributeValueMemberS{Value: string(query.TargetType)}
+				values[":targetId"] = &types.AttributeValueMemberS{Value: query.TargetId}
+			}
+		} else {
+			pks = []string{auditTargetPK(query.TargetType, query.TargetId)}
+		}
+	} else {
+		newest, oldest := time.Now(), query.From
+		if !query.To.IsZero() {
+			newest = query.To
+		}
+		if oldest.IsZero() {
+			oldest = newest.AddDate(0, 0, -config.AuditLookbackDays)
+		}
+		if query.Cursor != "" {
+			position, err := utils.DecodeCursor(query.Cursor)
+			if err != nil {
+				return nil, "", err
+			}
+			day, sk, ok := strings.Cut(position, "|")
+			if !ok {
+				return nil, "", utils.InvalidCursor
+			}
+			if newest, err = time.Parse(config.AuditDayLayout, day); err != nil {
+				return nil, "", utils.InvalidCursor
+			}
+			startSK = sk
+		}
+		pks = auditDays(newest, oldest)
+	}
+
+	entries := make([]*models.AuditEntry, 0, query.Limit)
+	for i, pk := range pks {
+		values[":pk"] = &types.AttributeValueMemberS{Value: pk}
+		input := &dynamodb.QueryInput{
+			TableName:                 aws.String(repo.TableName),
+			KeyConditionExpression:    aws.String("pk = :pk AND sk BETWEEN :from AND :to"),
+			FilterExpression:          filter,
+			ExpressionAttributeValues: values,
+			ScanIndexForward:          aws.Bool(false),
+			Limit:                     aws.Int32(int32(query.Limit)),
+		}
+		if i == 0 && startSK != "" {
+			input.ExclusiveStartKey = map[string]types.AttributeValue{
+				"pk": &types.AttributeValueMemberS{Value: pk},
+				"sk": &types.AttributeValueMemberS{Value: startSK},
+			}
+		}
+		for {
+			result, err := repo.Db.Query(ctx, input)
+			if err != nil {
+				return nil, "", err
+			}
+			for j, item := range result.Items {
+				var entry models.AuditEntry
+				if err := attributevalue.UnmarshalMap(item, &entry); err != nil {
+					return nil, "", err
+				}
+				entries = append(entries, &entry)
+				if len(entries) < query.Limit {
+					continue
+				}
+				if j == len(result.Items)-1 && result.LastEvaluatedKey == nil && i == len(pks)-1 {
+					return entries, "", nil
+				}
+				position := entry.SK
+				if query.ActorId == "" && query.TargetId == "" {
+					position = entry.CreatedAt.UTC().Format(config.AuditDayLayout) + "|" + entry.SK
+				}
+				return entries, utils.EncodeCursor(position), nil
+			}
+			if result.LastEvaluatedKey == nil {
+				break
+			}
+			input.ExclusiveStartKey = result.LastEvaluatedKey
+		}
+	}
+	return entries, "", nil
+}
+
+// PartitionByDay copies the entries of the log written before it was split by day from the
+// single "audit" partition to the partition of their day. The old copies are kept, entries
+// are never deleted, and entries that were already copied are skipped.
+func (repo *AuditRepository) PartitionByDay(ctx context.Context) (int, error) {
+	written := 0
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(repo.TableName),
+		KeyConditionExpression: aws.String("pk = :pk"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": &types.AttributeValueMemberS{Value: string(keys.Audit)},
+		},
+	}
+	for {
+		result, err := repo.Db.Query(ctx, input)
+		if err != nil {
+			return written, err
+		}
+		for _, item := range result.Items {
+			var entry models.AuditEntry
+			if err := attributevalue.UnmarshalMap(item, &entry); err != nil {
+				return written, err
+			}
+			item["pk"] = &types.AttributeValueMemberS{Value: auditDayPK(entry.CreatedAt)}
+			_, err := repo.Db.PutItem(ctx, &dynamodb.PutItemInput{
+				TableName:           aws.String(repo.TableName),
+				Item:                item,
+				ConditionExpression: aws.String("attribute_not_exists(pk)"),
+			})
+			if utils.IsConditionFailed(err) {
+				continue
+			}
+			if err != nil {
+				return written, err
+			}
+			written++
+		}
+		if result.LastEvaluatedKey == nil {
+			return written, nil
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+}
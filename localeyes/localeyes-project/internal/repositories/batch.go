@@ -0,0 +1,88 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// BatchWriteItem accepts at most 25 requests per call
+const maxBatchWrite = 25
+
+func batchWrite(ctx context.Context, db *dynamodb.Client, tableName string, writeRequests []types.WriteRequest) error {
+	for start := 0; start < len(writeRequests); start += maxBatchWrite {
+		end := start + maxBatchWrite
+		if end > len(writeRequests) {
+			end = len(writeRequests)
+		}
+		pending := map[string][]types.WriteRequest{
+			tableName: writeRequests[start:end],
+		}
+		for len(pending) > 0 {
+			output, err := db.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{
+				RequestItems: pending,
+			})
+			if err != nil {
+				return err
+			}
+			pending = output.UnprocessedItems
+		}
+	}
+	return nil
+}
+
+// deleteByPrefix removes every item under pk whose sk starts with skPrefix, an empty
+// prefix empties the whole partition, and returns the sort keys that were deleted
+func deleteByPrefix(ctx context.Context, db *dynamodb.Client, tableName, pk, skPrefix string) ([]string, error) {
+	var deleted []string
+	queryInput := &dynamodb.QueryInput{
+		TableName:              aws.String(tableName),
+		KeyConditionExpression: aws.String("pk = :pk AND begins_with(sk, :sk)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": &types.AttributeValueMemberS{Value: pk},
+			":sk": &types.AttributeValueMemberS{Value: skPrefix},
+		},
+		ProjectionExpression: aws.String("pk, sk"),
+	}
+	if skPrefix == "" {
+		queryInput.KeyConditionExpression = aws.String("pk = :pk")
+		delete(queryInput.ExpressionAttributeValues, ":sk")
+	}
+	for {
+		queryOutput, err := db.Query(ctx, queryInput)
+		if err != nil {
+			return deleted, err
+		}
+		var writeRequests []types.WriteRequest
+		for _, item := range queryOutput.Items {
+			writeRequests = append(writeRequests, types.WriteRequest{
+				DeleteRequest: &types.DeleteRequest{
+					Key: map[string]types.AttributeValue{
+						"pk": item["pk"],
+						"sk": item["sk"],
+					},
+				},
+			})
+			deleted = append(deleted, item["sk"].(*types.AttributeValueMemberS).Value)
+		}
+		if err := batchWrite(ctx, db, tableName, writeRequests); err != nil {
+			return deleted, err
+		}
+		if queryOutput.LastEvaluatedKey == nil {
+			break
+		}
+		queryInput.ExclusiveStartKey = queryOutput.LastEvaluatedKey
+	}
+	return deleted, nil
+}
+
+// conditionFailedAt reports whether the item at index of a cancelled transaction failed its condition
+func conditionFailedAt(err error, index int) bool {
+	var transactionErr *types.TransactionCanceledException
+	if !errors.As(err, &transactionErr) || index >= len(transactionErr.CancellationReasons) {
+		return false
+	}
+	return aws.ToString(transactionErr.CancellationReasons[index].Code) == "ConditionalCheckFailed"
+}
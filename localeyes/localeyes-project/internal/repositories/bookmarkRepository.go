@@ -0,0 +1,270 @@
+package repositories
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"localeyes/internal/keys"
+	"localeyes/internal/models"
+	"localeyes/utils"
+	"os"
+	"time"
+)
+
+type BookmarkRepository struct {
+	Db        *dynamodb.Client
+	TableName string
+}
+
+func NewBookmarkRepository(db *dynamodb.Client) *BookmarkRepository {
+	return &BookmarkRepository{
+		db,
+		os.Getenv("TABLE_NAME"),
+	}
+}
+
+// A saved post is kept twice: "saved:<uid>/<saved at>#<pid>" orders the user's list by
+// save time, and "bookmark:<pid>/user:<uid>" answers saved-state lookups and lets the
+// bookmarks of a deleted post be found.
+func (repo *BookmarkRepository) SavePost(ctx context.Context, uId, pId, postUId string, savedAt time.Time) error {
+	savedSK := keys.Sorted(savedAt, pId)
+	bookmarkAv, err := attributevalue.MarshalMap(&models.Bookmark{
+		UId:        keys.Saved.Key(uId),
+		SK:         savedSK,
+		PostId:     pId,
+		PostUserId: postUId,
+		SavedAt:    savedAt,
+	})
+	if err != nil {
+		return err
+	}
+	refAv, err := attributevalue.MarshalMap(&models.BookmarkRef{
+		PostId:  keys.Bookmark.Key(pId),
+		UId:     keys.User.Key(uId),
+		SavedSK: savedSK,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = repo.Db.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{
+			{
+				Put: &types.Put{
+					TableName:           aws.String(repo.TableName),
+					Item:                refAv,
+					ConditionExpression: aws.String("attribute_not_exists(pk)"),
+				},
+			},
+			{
+				Put: &types.Put{
+					TableName: aws.String(repo.TableName),
+					Item:      bookmarkAv,
+				},
+			},
+		},
+	})
+	// saving an already saved post keeps the original entry
+	if utils.IsConditionFailed(err) {
+		return nil
+	}
+	return err
+}
+
+func (repo *BookmarkRepository) getRef(ctx context.Context, uId, pId string) (*models.BookmarkRef, error) {
+	result, err := repo.Db.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(repo.TableName),
+		Key: map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{Value: keys.Bookmark.Key(pId)},
+			"sk": &types.AttributeValueMemberS{Value: keys.User.Key(uId)},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.Item == nil {
+		return nil, utils.NotSaved
+	}
+	var ref models.BookmarkRef
+	if err := attributevalue.UnmarshalMap(result.Item, &ref); err != nil {
+		return nil, err
+	}
+	return &ref, nil
+}
+
+func (repo *BookmarkRepository) UnsavePost(ctx context.Context, uId, pId string) error {
+	ref, err := repo.getRef(ctx, uId, pId)
+	if err != nil {
+		return err
+	}
+	_, err = repo.Db.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{
+			{
+				Delete: &types.Delete{
+					TableName: aws.String(repo.TableName),
+					Key: map[string]types.AttributeValue{
+						"pk": &types.AttributeValueMemberS{Value: keys.Bookmark.Key(pId)},
+						"sk": &types.AttributeValueMemberS{Value: keys.User.Key(uId)},
+					},
+					ConditionExpression: aws.String("saved_sk = :savedSK"),
+					ExpressionAttributeValues: map[string]types.AttributeValue{
+						":savedSK": &types.AttributeValueMemberS{Value: ref.SavedSK},
+					},
+				},
+			},
+			{
+				Delete: &types.Delete{
+					TableName: aws.String(repo.TableName),
+					Key: map[string]types.AttributeValue{
+						"pk": &types.AttributeValueMemberS{Value: keys.Saved.Key(uId)},
+						"sk": &types.AttributeValueMemberS{Value: ref.SavedSK},
+					},
+				},
+			},
+		},
+	})
+	if utils.IsConditionFailed(err) {
+		return utils.NotSaved
+	}
+	return err
+}
+
+// GetSavedPosts returns up to limit bookmarks, most recently saved first, and the cursor
+// of the next page which is empty on the last page
+func (repo *BookmarkRepository) GetSavedPosts(ctx context.Context, uId string, limit int, cursor string) ([]*models.Bookmark, string, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(repo.TableName),
+		KeyConditionExpression: aws.String("pk = :pk"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": &types.AttributeValueMemberS{Value: keys.Saved.Key(uId)},
+		},
+		ScanIndexForward: aws.Bool(false),
+		Limit:            aws.Int32(int32(limit)),
+	}
+	if cursor != "" {
+		sk, err := utils.DecodeCursor(cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		input.ExclusiveStartKey = map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{Value: keys.Saved.Key(uId)},
+			"sk": &types.AttributeValueMemberS{Value: sk},
+		}
+	}
+	result, err := repo.Db.Query(ctx, input)
+	if err != nil {
+		return nil, "", err
+	}
+	bookmarks := make([]*models.Bookmark, 0, len(result.Items))
+	for _, item := range result.Items {
+		var bookmark models.Bookmark
+		if err := attributevalue.UnmarshalMap(item, &bookmark); err != nil {
+			return nil, "", err
+		}
+		bookmark.UId = uId
+		bookmarks = append(bookmarks, &bookmark)
+	}
+	next := ""
+	if result.LastEvaluatedKey != nil {
+		next = utils.EncodeCursor(result.LastEvaluatedKey["sk"].(*types.AttributeValueMemberS).Value)
+	}
+	return bookmarks, next, nil
+}
+
+// GetSavedPostIds reports which of the given posts the user has saved
+func (repo *BookmarkRepository) GetSavedPostIds(ctx context.Context, uId string, pIds []string) (map[string]bool, error) {
+	saved := make(map[string]bool)
+	var itemKeys []map[string]types.AttributeValue
+	seen := make(map[string]bool)
+	for _, pId := range pIds {
+		if seen[pId] {
+			continue
+		}
+		seen[pId] = true
+		itemKeys = append(itemKeys, map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{Value: keys.Bookmark.Key(pId)},
+			"sk": &types.AttributeValueMemberS{Value: keys.User.Key(uId)},
+		})
+	}
+	// BatchGetItem accepts at most 100 keys per call
+	for start := 0; start < len(itemKeys); start += 100 {
+		end := start + 100
+		if end > len(itemKeys) {
+			end = len(itemKeys)
+		}
+		requestItems := map[string]types.KeysAndAttributes{
+			repo.TableName: {Keys: itemKeys[start:end], ProjectionExpression: aws.String("pk")},
+		}
+		for len(requestItems) > 0 {
+			result, err := repo.Db.BatchGetItem(ctx, &dynamodb.BatchGetItemInput{
+				RequestItems: requestItems,
+			})
+			if err != nil {
+				return nil, err
+			}
+			for _, item := range result.Responses[repo.TableName] {
+				pId, err := keys.Bookmark.Parse(item["pk"].(*types.AttributeValueMemberS).Value)
+				if err != nil {
+					return nil, err
+				}
+				saved[pId] = true
+			}
+			requestItems = result.UnprocessedKeys
+		}
+	}
+	return saved, nil
+}
+
+// deleteBookmarks removes the post from the saved list of every user who saved it
+func deleteBookmarks(ctx context.Context, db *dynamodb.Client, tableName, pId string) error {
+	queryInput := &dynamodb.QueryInput{
+		TableName:              aws.String(tableName),
+		KeyConditionExpression: aws.String("pk = :pk"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": &types.AttributeValueMemberS{Value: keys.Bookmark.Key(pId)},
+		},
+	}
+	for {
+		queryOutput, err := db.Query(ctx, queryInput)
+		if err != nil {
+			return err
+		}
+		var writeRequests []types.WriteRequest
+		for _, item := range queryOutput.Items {
+			var ref models.BookmarkRef
+			if err := attributevalue.UnmarshalMap(item, &ref); err != nil {
+				return err
+			}
+			uId, err := keys.User.Parse(ref.UId)
+			if err != nil {
+				return err
+			}
+			writeRequests = append(writeRequests,
+				types.WriteRequest{
+					DeleteRequest: &types.DeleteRequest{
+						Key: map[string]types.AttributeValue{
+							"pk": &types.AttributeValueMemberS{Value: ref.PostId},
+							"sk": &types.AttributeValueMemberS{Value: ref.UId},
+						},
+					},
+				},
+				types.WriteRequest{
+					DeleteRequest: &types.DeleteRequest{
+						Key: map[string]types.AttributeValue{
+							"pk": &types.AttributeValueMemberS{Value: keys.Saved.Key(uId)},
+							"sk": &types.AttributeValueMemberS{Value: ref.SavedSK},
+						},
+					},
+				},
+			)
+		}
+		if err := batchWrite(ctx, db, tableName, writeRequests); err != nil {
+			return err
+		}
+		if queryOutput.LastEvaluatedKey == nil {
+			return nil
+		}
+		queryInput.ExclusiveStartKey = queryOutput.LastEvaluatedKey
+	}
+}
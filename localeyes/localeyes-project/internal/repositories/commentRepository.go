@@ -0,0 +1,172 @@
+package repositories
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"localeyes/internal/keys"
+	"localeyes/internal/models"
+	"localeyes/utils"
+	"os"
+	"time"
+)
+
+type CommentRepository struct {
+	Db        *dynamodb.Client
+	TableName string
+}
+
+func NewCommentRepository(db *dynamodb.Client) *CommentRepository {
+	return &CommentRepository{
+		db,
+		os.Getenv("TABLE_NAME"),
+	}
+}
+
+// Comments live in the post partition under "comment:<path>" where the path is the
+// materialized list of ancestor segments, so one query returns the whole thread in
+// display order: every reply sorts directly after its parent, siblings by creation time.
+// The comment is only written while the post exists, a post deleted under the request fails
+// it with NoPost instead of leaving the comment behind in an orphaned partition.
+func (repo *CommentRepository) CreateComment(ctx context.Context, comment *models.Comment) error {
+	commentNew := *comment
+	commentNew.PostId = keys.Post.Key(comment.PostId)
+	commentNew.Path = keys.CommentKey(comment.Path)
+	commentAv, err := attributevalue.MarshalMap(commentNew)
+	if err != nil {
+		return err
+	}
+	ref := &models.CommentRef{
+		CId:    keys.Comment.Key(comment.CId),
+		SK:     "ref",
+		PostId: comment.PostId,
+		Path:   comment.Path,
+		Depth:  comment.Depth,
+	}
+	refAv, err := attributevalue.MarshalMap(ref)
+	if err != nil {
+		return err
+	}
+	_, err = repo.Db.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{
+			{
+				ConditionCheck: &types.ConditionCheck{
+					TableName: aws.String(repo.TableName),
+					Key: map[string]types.AttributeValue{
+						"pk": &types.AttributeValueMemberS{Value: keys.Post.Key(comment.PostId)},
+						"sk": &types.AttributeValueMemberS{Value: postOwnerSK},
+					},
+					ConditionExpression: aws.String("attribute_exists(pk)"),
+				},
+			},
+			{
+				Put: &types.Put{
+					TableName:           aws.String(repo.TableName),
+					Item:                commentAv,
+					ConditionExpression: aws.String("attribute_not_exists(pk)"),
+				},
+			},
+			{
+				Put: &types.Put{
+					TableName:           aws.String(repo.TableName),
+					Item:                refAv,
+					ConditionExpression: aws.String("attribute_not_exists(pk)"),
+				},
+			},
+			countUpdate(repo.TableName, keys.Post.Key(comment.PostId), "comment_count", 1),
+		},
+	})
+	if conditionFailedAt(err, 0) {
+		return utils.NoPost
+	}
+	return err
+}
+
+func (repo *CommentRepository) GetCommentRef(ctx context.Context, cId string) (*models.CommentRef, error) {
+	result, err := repo.Db.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(repo.TableName),
+		Key: map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{Value: keys.Comment.Key(cId)},
+			"sk": &types.AttributeValueMemberS{Value: "ref"},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.Item == nil {
+		return nil, utils.NoComment
+	}
+	var ref models.CommentRef
+	if err := attributevalue.UnmarshalMap(result.Item, &ref); err != nil {
+		return nil, err
+	}
+	ref.CId = cId
+	return &ref, nil
+}
+
+func (repo *CommentRepository) GetCommentsByPId(ctx context.Context, pId string) ([]*models.Comment, error) {
+	queryInput := &dynamodb.QueryInput{
+		TableName:              aws.String(repo.TableName),
+		KeyConditionExpression: aws.String("pk = :pk AND begins_with(sk, :sk)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": &types.AttributeValueMemberS{Value: keys.Post.Key(pId)},
+			":sk": &types.AttributeValueMemberS{Value: keys.Comment.Prefix()},
+		},
+	}
+	comments := make([]*models.Comment, 0)
+	for {
+		result, err := repo.Db.Query(ctx, queryInput)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range result.Items {
+			var comment models.Comment
+			if err := attributevalue.UnmarshalMap(item, &comment); err != nil {
+				return nil, err
+			}
+			comment.PostId = pId
+			if comment.Path, err = keys.ParseComment(comment.Path); err != nil {
+				return nil, err
+			}
+			comments = append(comments, &comment)
+		}
+		if result.LastEvaluatedKey == nil {
+			break
+		}
+		queryInput.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+	return comments, nil
+}
+
+// SoftDeleteComment blanks the comment but keeps the item so its replies stay attached to the thread
+func (repo *CommentRepository) SoftDeleteComment(ctx context.Context, pId, path, uId string, deletedAt time.Time) error {
+	_, err := repo.Db.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{
+			{
+				Update: &types.Update{
+					TableName: aws.String(repo.TableName),
+					Key: map[string]types.AttributeValue{
+						"pk": &types.AttributeValueMemberS{Value: keys.Post.Key(pId)},
+						"sk": &types.AttributeValueMemberS{Value: keys.CommentKey(path)},
+					},
+					UpdateExpression:    aws.String("SET deleted = :deleted, content = :content, updated_at = :deletedAt"),
+					ConditionExpression: aws.String("user_id = :userId AND deleted = :notDeleted"),
+					ExpressionAttributeValues: map[string]types.AttributeValue{
+						":deleted":    &types.AttributeValueMemberBOOL{Value: true},
+						":notDeleted": &types.AttributeValueMemberBOOL{Value: false},
+						":content":    &types.AttributeValueMemberS{Value: ""},
+						":deletedAt":  &types.AttributeValueMemberS{Value: deletedAt.Format(time.RFC3339)},
+						":userId":     &types.AttributeValueMemberS{Value: uId},
+					},
+				},
+			},
+			countUpdate(repo.TableName, keys.Post.Key(pId), "comment_count", -1),
+		},
+	})
+	if utils.IsConditionFailed(err) {
+		return utils.NotYourComment
+	}
+	return err
+}
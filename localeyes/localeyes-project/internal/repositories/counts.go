@@ -0,0 +1,89 @@
+package repositories
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"localeyes/internal/models"
+	"strconv"
+)
+
+// countsSK is the sort key of the aggregate counter item kept in the partition of a post or question
+const countsSK = "counts"
+
+func countUpdate(tableName, pk, attribute string, delta int) types.TransactWriteItem {
+	return types.TransactWriteItem{
+		Update: &types.Update{
+			TableName: aws.String(tableName),
+			Key: map[string]types.AttributeValue{
+				"pk": &types.AttributeValueMemberS{Value: pk},
+				"sk": &types.AttributeValueMemberS{Value: countsSK},
+			},
+			UpdateExpression: aws.String("ADD #count :delta"),
+			ExpressionAttributeNames: map[string]string{
+				"#count": attribute,
+			},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":delta": &types.AttributeValueMemberN{Value: strconv.Itoa(delta)},
+			},
+		},
+	}
+}
+
+func countsDelete(pk string) types.WriteRequest {
+	return types.WriteRequest{
+		DeleteRequest: &types.DeleteRequest{
+			Key: map[string]types.AttributeValue{
+				"pk": &types.AttributeValueMemberS{Value: pk},
+				"sk": &types.AttributeValueMemberS{Value: countsSK},
+			},
+		},
+	}
+}
+
+// getCounts loads the counter items of the given partitions, keyed by partition key.
+// Partitions without a counter item are absent from the result.
+func getCounts(ctx context.Context, db *dynamodb.Client, tableName string, pks []string) (map[string]*models.Counts, error) {
+	counts := make(map[string]*models.Counts)
+	// BatchGetItem accepts at most 100 keys per call
+	for start := 0; start < len(pks); start += 100 {
+		end := start + 100
+		if end > len(pks) {
+			end = len(pks)
+		}
+		var keys []map[string]types.AttributeValue
+		seen := make(map[string]bool)
+		for _, pk := range pks[start:end] {
+			if seen[pk] {
+				continue
+			}
+			seen[pk] = true
+			keys = append(keys, map[string]types.AttributeValue{
+				"pk": &types.AttributeValueMemberS{Value: pk},
+				"sk": &types.AttributeValueMemberS{Value: countsSK},
+			})
+		}
+		requestItems := map[string]types.KeysAndAttributes{
+			tableName: {Keys: keys},
+		}
+		for len(requestItems) > 0 {
+			result, err := db.BatchGetItem(ctx, &dynamodb.BatchGetItemInput{
+				RequestItems: requestItems,
+			})
+			if err != nil {
+				return nil, err
+			}
+			for _, item := range result.Responses[tableName] {
+				var count models.Counts
+				if err := attributevalue.UnmarshalMap(item, &count); err != nil {
+					return nil, err
+				}
+				counts[count.PK] = &count
+			}
+			requestItems = result.UnprocessedKeys
+		}
+	}
+	return counts, nil
+}
@@ -0,0 +1,50 @@
+package repositories
+
+import (
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"localeyes/internal/models"
+	"testing"
+)
+
+// TestCountUpdate checks that every counter written with countUpdate is read back by
+// models.Counts, a counter under another name would always read as zero
+func TestCountUpdate(t *testing.T) {
+	tests := []struct {
+		attribute string
+		delta     int
+		read      func(counts *models.Counts) int
+	}{
+		{"question_count", 1, func(counts *models.Counts) int { return counts.QuestionCount }},
+		{"answer_count", 1, func(counts *models.Counts) int { return counts.AnswerCount }},
+		{"comment_count", -1, func(counts *models.Counts) int { return counts.CommentCount }},
+		{"follower_count", 1, func(counts *models.Counts) int { return counts.FollowerCount }},
+		{"following_count", -1, func(counts *models.Counts) int { return counts.FollowingCount }},
+		{"user_count", 1, func(counts *models.Counts) int { return counts.UserCount }},
+	}
+	for _, test := range tests {
+		t.Run(test.attribute, func(t *testing.T) {
+			update := countUpdate("table", "post:p1", test.attribute, test.delta).Update
+			if aws.ToString(update.UpdateExpression) != "ADD #count :delta" {
+				t.Fatalf("update expression %q", aws.ToString(update.UpdateExpression))
+			}
+			if sk := update.Key["sk"].(*types.AttributeValueMemberS).Value; sk != countsSK {
+				t.Fatalf("counter item sort key %q, want %q", sk, countsSK)
+			}
+			// the item as ADD leaves it on a partition that had no counters yet
+			item := map[string]types.AttributeValue{
+				"pk": update.Key["pk"],
+				"sk": update.Key["sk"],
+				update.ExpressionAttributeNames["#count"]: update.ExpressionAttributeValues[":delta"],
+			}
+			var counts models.Counts
+			if err := attributevalue.UnmarshalMap(item, &counts); err != nil {
+				t.Fatal(err)
+			}
+			if got := test.read(&counts); got != test.delta {
+				t.Fatalf("%s reads back as %d, want %d", test.attribute, got, test.delta)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package repositories
+
+import (
+	"encoding/json"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// dynamoCall is one request the repository under test sent to DynamoDB
+type dynamoCall struct {
+	Operation string
+	Input     map[string]any
+}
+
+// dynamoResponse is what the fake answers a call with, an error when Error names its type
+type dynamoResponse struct {
+	Error string
+	Body  map[string]any
+}
+
+// fakeDynamo starts a DynamoDB endpoint that records every call and answers it with respond,
+// and returns a client talking to it
+func fakeDynamo(t *testing.T, respond func(call dynamoCall) dynamoResponse) (*dynamodb.Client, *[]dynamoCall) {
+	t.Helper()
+	var calls []dynamoCall
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		call := dynamoCall{Operation: strings.TrimPrefix(r.Header.Get("X-Amz-Target"), "DynamoDB_20120810.")}
+		if err := json.Unmarshal(body, &call.Input); err != nil {
+			t.Errorf("%s: %v", call.Operation, err)
+		}
+		calls = append(calls, call)
+		response := respond(call)
+		if response.Body == nil {
+			response.Body = map[string]any{}
+		}
+		w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+		if response.Error != "" {
+			response.Body["__type"] = "com.amazonaws.dynamodb.v20120810#" + response.Error
+			w.WriteHeader(http.StatusBadRequest)
+		}
+		_ = json.NewEncoder(w).Encode(response.Body)
+	}))
+	t.Cleanup(server.Close)
+	client := dynamodb.New(dynamodb.Options{
+		Region:       "local",
+		BaseEndpoint: aws.String(server.URL),
+		Credentials:  aws.AnonymousCredentials{},
+		Retryer:      aws.NopRetryer{},
+	})
+	return client, &calls
+}
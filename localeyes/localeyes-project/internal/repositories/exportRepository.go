@@ -0,0 +1,123 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"localeyes/config"
+	"localeyes/internal/keys"
+	"localeyes/internal/models"
+	"localeyes/utils"
+	"os"
+	"strconv"
+	"time"
+)
+
+const exportMetaSK = "meta"
+
+type ExportRepository struct {
+	Db        *dynamodb.Client
+	TableName string
+}
+
+func NewExportRepository(db *dynamodb.Client) *ExportRepository {
+	return &ExportRepository{
+		db,
+		os.Getenv("TABLE_NAME"),
+	}
+}
+
+// SaveArchive stores the archive under "export:<token>" split into chunks that each fit in an
+// item. The chunks are written before the meta item so a token only resolves once it is complete.
+func (repo *ExportRepository) SaveArchive(ctx context.Context, token string, export *models.Export, archive []byte) error {
+	pk := keys.Export.Key(token)
+	ttl := strconv.FormatInt(export.ExpiresAt.Unix(), 10)
+	var writeRequests []types.WriteRequest
+	for start := 0; start < len(archive); start += config.ExportChunkSize {
+		end := min(start+config.ExportChunkSize, len(archive))
+		writeRequests = append(writeRequests, types.WriteRequest{
+			PutRequest: &types.PutRequest{
+				Item: map[string]types.AttributeValue{
+					"pk":   &types.AttributeValueMemberS{Value: pk},
+					"sk":   &types.AttributeValueMemberS{Value: keys.Chunk.Key(fmt.Sprintf("%06d", len(writeRequests)))},
+					"data": &types.AttributeValueMemberB{Value: archive[start:end]},
+					"ttl":  &types.AttributeValueMemberN{Value: ttl},
+				},
+			},
+		})
+	}
+	if err := batchWrite(ctx, repo.Db, repo.TableName, writeRequests); err != nil {
+		return err
+	}
+	export.PK = pk
+	export.SK = exportMetaSK
+	export.Size = len(archive)
+	export.Chunks = len(writeRequests)
+	export.TTl = export.ExpiresAt.Unix()
+	item, err := attributevalue.MarshalMap(export)
+	if err != nil {
+		return err
+	}
+	_, err = repo.Db.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(repo.TableName),
+		Item:      item,
+	})
+	return err
+}
+
+// GetArchive returns the export behind a download token. TTL deletion can lag behind the
+// expiry by hours, so expiry is checked here as well.
+func (repo *ExportRepository) GetArchive(ctx context.Context, token string) (*models.Export, []byte, error) {
+	pk := keys.Export.Key(token)
+	result, err := repo.Db.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(repo.TableName),
+		Key: map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{Value: pk},
+			"sk": &types.AttributeValueMemberS{Value: exportMetaSK},
+		},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if result.Item == nil {
+		return nil, nil, utils.NoExport
+	}
+	var export models.Export
+	if err := attributevalue.UnmarshalMap(result.Item, &export); err != nil {
+		return nil, nil, err
+	}
+	if time.Now().After(export.ExpiresAt) {
+		return nil, nil, utils.NoExport
+	}
+	archive := make([]byte, 0, export.Size)
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(repo.TableName),
+		KeyConditionExpression: aws.String("pk = :pk AND begins_with(sk, :sk)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": &types.AttributeValueMemberS{Value: pk},
+			":sk": &types.AttributeValueMemberS{Value: keys.Chunk.Prefix()},
+		},
+	}
+	for {
+		output, err := repo.Db.Query(ctx, input)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, item := range output.Items {
+			if data, ok := item["data"].(*types.AttributeValueMemberB); ok {
+				archive = append(archive, data.Value...)
+			}
+		}
+		if output.LastEvaluatedKey == nil {
+			break
+		}
+		input.ExclusiveStartKey = output.LastEvaluatedKey
+	}
+	if len(archive) != export.Size {
+		return nil, nil, utils.NoExport
+	}
+	return &export, archive, nil
+}
@@ -0,0 +1,205 @@
+package repositories
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"localeyes/internal/keys"
+	"localeyes/internal/models"
+	"localeyes/utils"
+	"os"
+	"time"
+)
+
+type FollowRepository struct {
+	Db        *dynamodb.Client
+	TableName string
+}
+
+func NewFollowRepository(db *dynamodb.Client) *FollowRepository {
+	return &FollowRepository{
+		db,
+		os.Getenv("TABLE_NAME"),
+	}
+}
+
+// edgeKey addresses one user-to-user edge such as a follow, block or mute
+func edgeKey(pk, uId string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"pk": &types.AttributeValueMemberS{Value: pk},
+		"sk": &types.AttributeValueMemberS{Value: keys.User.Key(uId)},
+	}
+}
+
+// Follow records that uId follows followeeId, following an already followed user is a no-op.
+// Follower and following counts live on the "follow:<uid>/counts" item.
+func (repo *FollowRepository) Follow(ctx context.Context, uId, followeeId string, followedAt time.Time) error {
+	following, err := attributevalue.MarshalMap(&models.Follow{
+		PK:         keys.Following.Key(uId),
+		UId:        keys.User.Key(followeeId),
+		FollowedAt: followedAt,
+	})
+	if err != nil {
+		return err
+	}
+	follower, err := attributevalue.MarshalMap(&models.Follow{
+		PK:         keys.Followers.Key(followeeId),
+		UId:        keys.User.Key(uId),
+		FollowedAt: followedAt,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = repo.Db.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{
+			{
+				Put: &types.Put{
+					TableName:           aws.String(repo.TableName),
+					Item:                following,
+					ConditionExpression: aws.String("attribute_not_exists(pk)"),
+				},
+			},
+			{
+				Put: &types.Put{
+					TableName: aws.String(repo.TableName),
+					Item:      follower,
+				},
+			},
+			countUpdate(repo.TableName, keys.Follow.Key(uId), "following_count", 1),
+			countUpdate(repo.TableName, keys.Follow.Key(followeeId), "follower_count", 1),
+		},
+	})
+	if utils.IsConditionFailed(err) {
+		return nil
+	}
+	return err
+}
+
+func (repo *FollowRepository) Unfollow(ctx context.Context, uId, followeeId string) error {
+	_, err := repo.Db.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{
+			{
+				Delete: &types.Delete{
+					TableName:           aws.String(repo.TableName),
+					Key:                 edgeKey(keys.Following.Key(uId), followeeId),
+					ConditionExpression: aws.String("attribute_exists(pk)"),
+				},
+			},
+			{
+				Delete: &types.Delete{
+					TableName: aws.String(repo.TableName),
+					Key:       edgeKey(keys.Followers.Key(followeeId), uId),
+				},
+			},
+			countUpdate(repo.TableName, keys.Follow.Key(uId), "following_count", -1),
+			countUpdate(repo.TableName, keys.Follow.Key(followeeId), "follower_count", -1),
+		},
+	})
+	if utils.IsConditionFailed(err) {
+		return utils.NotFollowing
+	}
+	return err
+}
+
+func (repo *FollowRepository) IsFollowing(ctx context.Context, uId, followeeId string) (bool, error) {
+	result, err := repo.Db.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(repo.TableName),
+		Key:       edgeKey(keys.Following.Key(uId), followeeId),
+	})
+	if err != nil {
+		return false, err
+	}
+	return result.Item != nil, nil
+}
+
+func (repo *FollowRepository) GetFollowers(ctx context.Context, uId string, limit int, cursor string) ([]*models.Follow, string, error) {
+	return repo.getFollows(ctx, keys.Followers.Key(uId), limit, cursor)
+}
+
+func (repo *FollowRepository) GetFollowing(ctx context.Context, uId string, limit int, cursor string) ([]*models.Follow, string, error) {
+	return repo.getFollows(ctx, keys.Following.Key(uId), limit, cursor)
+}
+
+func (repo *FollowRepository) getFollows(ctx context.Context, pk string, limit int, cursor string) ([]*models.Follow, string, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(repo.TableName),
+		KeyConditionExpression: aws.String("pk = :pk"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": &types.AttributeValueMemberS{Value: pk},
+		},
+		Limit: aws.Int32(int32(limit)),
+	}
+	if cursor != "" {
+		sk, err := utils.DecodeCursor(cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		input.ExclusiveStartKey = map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{Value: pk},
+			"sk": &types.AttributeValueMemberS{Value: sk},
+		}
+	}
+	result, err := repo.Db.Query(ctx, input)
+	if err != nil {
+		return nil, "", err
+	}
+	follows := make([]*models.Follow, 0, len(result.Items))
+	for _, item := range result.Items {
+		var follow models.Follow
+		if err := attributevalue.UnmarshalMap(item, &follow); err != nil {
+			return nil, "", err
+		}
+		if follow.UId, err = keys.User.Parse(follow.UId); err != nil {
+			return nil, "", err
+		}
+		follows = append(follows, &follow)
+	}
+	next := ""
+	if result.LastEvaluatedKey != nil {
+		next = utils.EncodeCursor(result.LastEvaluatedKey["sk"].(*types.AttributeValueMemberS).Value)
+	}
+	return follows, next, nil
+}
+
+// GetAllFollowing returns the ids of every user uId follows
+func (repo *FollowRepository) GetAllFollowing(ctx context.Context, uId string) ([]string, error) {
+	var uIds []string
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(repo.TableName),
+		KeyConditionExpression: aws.String("pk = :pk"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": &types.AttributeValueMemberS{Value: keys.Following.Key(uId)},
+		},
+		ProjectionExpression: aws.String("sk"),
+	}
+	for {
+		result, err := repo.Db.Query(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range result.Items {
+			followed, err := keys.User.Parse(item["sk"].(*types.AttributeValueMemberS).Value)
+			if err != nil {
+				return nil, err
+			}
+			uIds = append(uIds, followed)
+		}
+		if result.LastEvaluatedKey == nil {
+			return uIds, nil
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+}
+
+func (repo *FollowRepository) GetFollowCounts(ctx context.Context, uId string) (*models.Counts, error) {
+	counts, err := getCounts(ctx, repo.Db, repo.TableName, []string{keys.Follow.Key(uId)})
+	if err != nil {
+		return nil, err
+	}
+	if count, ok := counts[keys.Follow.Key(uId)]; ok {
+		return count, nil
+	}
+	return &models.Counts{}, nil
+}
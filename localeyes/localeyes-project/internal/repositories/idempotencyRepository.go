@@ -0,0 +1,108 @@
+package repositories
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"localeyes/internal/keys"
+	"localeyes/internal/models"
+	"localeyes/utils"
+	"os"
+	"strconv"
+	"time"
+)
+
+const idempotencySK = "request"
+
+type IdempotencyRepository struct {
+	Db        *dynamodb.Client
+	TableName string
+}
+
+func NewIdempotencyRepository(db *dynamodb.Client) *IdempotencyRepository {
+	return &IdempotencyRepository{
+		db,
+		os.Getenv("TABLE_NAME"),
+	}
+}
+
+func idempotencyKey(scope, key string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"pk": &types.AttributeValueMemberS{Value: keys.Idem.Key(scope, key)},
+		"sk": &types.AttributeValueMemberS{Value: idempotencySK},
+	}
+}
+
+// Claim stores record for the key unless another request holds it. It returns nil when the
+// claim was taken and the existing record otherwise. TTL deletion can lag behind by hours so
+// expired records are taken over as if they were gone.
+func (repo *IdempotencyRepository) Claim(ctx context.Context, scope, key string, record *models.IdempotencyRecord) (*models.IdempotencyRecord, error) {
+	record.PK = keys.Idem.Key(scope, key)
+	record.SK = idempotencySK
+	item, err := attributevalue.MarshalMap(record)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		_, err = repo.Db.PutItem(ctx, &dynamodb.PutItemInput{
+			TableName:           aws.String(repo.TableName),
+			Item:                item,
+			ConditionExpression: aws.String("attribute_not_exists(pk) OR #ttl < :now"),
+			ExpressionAttributeNames: map[string]string{
+				"#ttl": "ttl",
+			},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":now": &types.AttributeValueMemberN{Value: strconv.FormatInt(time.Now().Unix(), 10)},
+			},
+		})
+		if err == nil {
+			return nil, nil
+		}
+		if !utils.IsConditionFailed(err) {
+			return nil, err
+		}
+		result, err := repo.Db.GetItem(ctx, &dynamodb.GetItemInput{
+			TableName:      aws.String(repo.TableName),
+			Key:            idempotencyKey(scope, key),
+			ConsistentRead: aws.Bool(true),
+		})
+		if err != nil {
+			return nil, err
+		}
+		if result.Item == nil {
+			// released between the two calls, try to claim it again
+			continue
+		}
+		var existing models.IdempotencyRecord
+		if err := attributevalue.UnmarshalMap(result.Item, &existing); err != nil {
+			return nil, err
+		}
+		return &existing, nil
+	}
+}
+
+// Complete replaces the claim with the finished record
+func (repo *IdempotencyRepository) Complete(ctx context.Context, scope, key string, record *models.IdempotencyRecord) error {
+	record.PK = keys.Idem.Key(scope, key)
+	record.SK = idempotencySK
+	item, err := attributevalue.MarshalMap(record)
+	if err != nil {
+		return err
+	}
+	_, err = repo.Db.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(repo.TableName),
+		Item:      item,
+	})
+	return err
+}
+
+// Release drops a claim so the request can be retried with the same key
+func (repo *IdempotencyRepository) Release(ctx context.Context, scope, key string) error {
+	_, err := repo.Db.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(repo.TableName),
+		Key:       idempotencyKey(scope, key),
+	})
+	return err
+}
@@ -0,0 +1,134 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"localeyes/internal/keys"
+	"localeyes/internal/models"
+	"localeyes/utils"
+	"os"
+	"strconv"
+	"time"
+)
+
+const jobSK = "job"
+
+type JobRepository struct {
+	Db        *dynamodb.Client
+	TableName string
+}
+
+func NewJobRepository(db *dynamodb.Client) *JobRepository {
+	return &JobRepository{
+		db,
+		os.Getenv("TABLE_NAME"),
+	}
+}
+
+func jobKey(id string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"pk": &types.AttributeValueMemberS{Value: keys.Job.Key(id)},
+		"sk": &types.AttributeValueMemberS{Value: jobSK},
+	}
+}
+
+// SaveJob writes the whole job, a job is only ever updated by the worker holding its lease
+func (repo *JobRepository) SaveJob(ctx context.Context, job *models.Job) error {
+	job.PK = keys.Job.Key(job.Id)
+	job.SK = jobSK
+	item, err := attributevalue.MarshalMap(job)
+	if err != nil {
+		return err
+	}
+	_, err = repo.Db.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(repo.TableName),
+		Item:      item,
+	})
+	return err
+}
+
+// SaveJobItem writes the outcome of one item of a bulk job and the job's counters, without
+// rewriting the whole job for every item
+func (repo *JobRepository) SaveJobItem(ctx context.Context, job *models.Job, index int) error {
+	item := job.Items[index]
+	updatedAt, err := attributevalue.Marshal(job.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	path := fmt.Sprintf("items[%d]", index)
+	_, err = repo.Db.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:        aws.String(repo.TableName),
+		Key:              jobKey(job.Id),
+		UpdateExpression: aws.String("SET " + path + ".#status = :item_status, " + path + ".#error = :error, processed = :processed, failed = :failed, updated_at = :updated_at"),
+		ExpressionAttributeNames: map[string]string{
+			"#status": "status",
+			"#error":  "error",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":item_status": &types.AttributeValueMemberS{Value: string(item.Status)},
+			":error":       &types.AttributeValueMemberS{Value: item.Error},
+			":processed":   &types.AttributeValueMemberN{Value: strconv.Itoa(job.Processed)},
+			":failed":      &types.AttributeValueMemberN{Value: strconv.Itoa(job.Failed)},
+			":updated_at":  updatedAt,
+		},
+	})
+	return err
+}
+
+func (repo *JobRepository) GetJob(ctx context.Context, id string) (*models.Job, error) {
+	result, err := repo.Db.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(repo.TableName),
+		Key:            jobKey(id),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.Item == nil {
+		return nil, utils.NoJob
+	}
+	var job models.Job
+	if err := attributevalue.UnmarshalMap(result.Item, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// ClaimJob takes the lease on a job for a worker until the given time and returns the job as
+// stored. A lease whose holder neither finished nor released it is taken over once it has run
+// out, so a job is resumed by whichever worker claims it next.
+func (repo *JobRepository) ClaimJob(ctx context.Context, id string, until time.Time) (*models.Job, error) {
+	now := time.Now()
+	result, err := repo.Db.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:           aws.String(repo.TableName),
+		Key:                 jobKey(id),
+		UpdateExpression:    aws.String("SET lease_until = :until"),
+		ConditionExpression: aws.String("attribute_exists(pk) AND (attribute_not_exists(lease_until) OR lease_until < :now)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":until": &types.AttributeValueMemberN{Value: strconv.FormatInt(until.Unix(), 10)},
+			":now":   &types.AttributeValueMemberN{Value: strconv.FormatInt(now.Unix(), 10)},
+		},
+		ReturnValues:                        types.ReturnValueAllNew,
+		ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+	})
+	if err != nil {
+		var conditionErr *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionErr) {
+			if conditionErr.Item == nil {
+				return nil, utils.NoJob
+			}
+			return nil, utils.JobLeased
+		}
+		return nil, err
+	}
+	var job models.Job
+	if err := attributevalue.UnmarshalMap(result.Attributes, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
@@ -0,0 +1,127 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"localeyes/internal/keys"
+	"localeyes/internal/models"
+	"localeyes/utils"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Applied migrations are kept under "migrations" with the zero padded version as sort key,
+// next to the "lock" item that keeps two runs from applying migrations at the same time
+const (
+	migrationsPK  = "migrations"
+	migrationLock = "lock"
+)
+
+type MigrationRepository struct {
+	Db        *dynamodb.Client
+	TableName string
+}
+
+func NewMigrationRepository(db *dynamodb.Client) *MigrationRepository {
+	return &MigrationRepository{
+		db,
+		os.Getenv("TABLE_NAME"),
+	}
+}
+
+func migrationSK(version int) string {
+	return keys.Version.Key(fmt.Sprintf("%06d", version))
+}
+
+func (repo *MigrationRepository) GetApplied(ctx context.Context) (map[int]*models.MigrationRecord, error) {
+	applied := make(map[int]*models.MigrationRecord)
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(repo.TableName),
+		KeyConditionExpression: aws.String("pk = :pk AND begins_with(sk, :sk)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": &types.AttributeValueMemberS{Value: migrationsPK},
+			":sk": &types.AttributeValueMemberS{Value: keys.Version.Prefix()},
+		},
+		ConsistentRead: aws.Bool(true),
+	}
+	for {
+		result, err := repo.Db.Query(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range result.Items {
+			var record models.MigrationRecord
+			if err := attributevalue.UnmarshalMap(item, &record); err != nil {
+				return nil, err
+			}
+			applied[record.Version] = &record
+		}
+		if result.LastEvaluatedKey == nil {
+			return applied, nil
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+}
+
+func (repo *MigrationRepository) RecordApplied(ctx context.Context, record *models.MigrationRecord) error {
+	record.PK = migrationsPK
+	record.SK = migrationSK(record.Version)
+	item, err := attributevalue.MarshalMap(record)
+	if err != nil {
+		return err
+	}
+	_, err = repo.Db.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(repo.TableName),
+		Item:      item,
+	})
+	return err
+}
+
+// AcquireLock takes the migration lock for owner. A lock whose holder did not release it is
+// taken over once ttl has passed.
+func (repo *MigrationRepository) AcquireLock(ctx context.Context, owner string, ttl time.Duration) error {
+	now := time.Now()
+	_, err := repo.Db.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(repo.TableName),
+		Item: map[string]types.AttributeValue{
+			"pk":         &types.AttributeValueMemberS{Value: migrationsPK},
+			"sk":         &types.AttributeValueMemberS{Value: migrationLock},
+			"owner":      &types.AttributeValueMemberS{Value: owner},
+			"expires_at": &types.AttributeValueMemberN{Value: strconv.FormatInt(now.Add(ttl).Unix(), 10)},
+		},
+		ConditionExpression: aws.String("attribute_not_exists(pk) OR expires_at < :now"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":now": &types.AttributeValueMemberN{Value: strconv.FormatInt(now.Unix(), 10)},
+		},
+	})
+	if utils.IsConditionFailed(err) {
+		return utils.MigrationLocked
+	}
+	return err
+}
+
+func (repo *MigrationRepository) ReleaseLock(ctx context.Context, owner string) error {
+	_, err := repo.Db.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(repo.TableName),
+		Key: map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{Value: migrationsPK},
+			"sk": &types.AttributeValueMemberS{Value: migrationLock},
+		},
+		ConditionExpression: aws.String("#owner = :owner"),
+		ExpressionAttributeNames: map[string]string{
+			"#owner": "owner",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":owner": &types.AttributeValueMemberS{Value: owner},
+		},
+	})
+	if utils.IsConditionFailed(err) {
+		return nil
+	}
+	return err
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"localeyes/internal/keys"
 	"localeyes/internal/models"
 	"math/big"
 	"os"
@@ -40,7 +41,7 @@ func (repo *OtpRepository) GenerateOTP() (string, error) {
 
 func (repo *OtpRepository) SaveOTP(ctx context.Context, email, otp string) error {
 	otpModal := &models.OTP{
-		Email: "otp:email:" + email,
+		Email: keys.OTP.Key(email),
 		Otp:   otp,
 		TTl:   time.Now().Add(10 * time.Minute).Unix(),
 	}
@@ -60,9 +61,15 @@ func (repo *OtpRepository) ValidateOTP(ctx context.Context, email, otp string) b
 		TableName:              aws.String(repo.TableName),
 		KeyConditionExpression: aws.String("pk = :pk AND sk = :sk"),
 		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":pk": &types.AttributeValueMemberS{Value: fmt.Sprintf("otp:email:%s", email)},
+			":pk": &types.AttributeValueMemberS{Value: keys.OTP.Key(email)},
 			":sk": &types.AttributeValueMemberS{Value: fmt.Sprintf("%s", otp)},
 		},
 	})
 	return err == nil && len(result.Items) == 1
 }
+
+// DeleteOTPs revokes every outstanding OTP of the email
+func (repo *OtpRepository) DeleteOTPs(ctx context.Context, email string) error {
+	_, err := deleteByPrefix(ctx, repo.Db, repo.TableName, keys.OTP.Key(email), "")
+	return err
+}
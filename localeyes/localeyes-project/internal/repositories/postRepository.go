@@ -8,13 +8,18 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"localeyes/config"
+	"localeyes/internal/keys"
 	"localeyes/internal/models"
+	"localeyes/utils"
 	"os"
+	"strconv"
 	"strings"
-	"sync"
 	"time"
 )
 
+// postOwnerSK is the sort key of the item in a post partition naming the post's author
+const postOwnerSK = "owner"
+
 type PostRepository struct {
 	Db        *dynamodb.Client
 	TableName string
@@ -31,26 +36,26 @@ func NewPostRepository(db *dynamodb.Client) *PostRepository {
 
 func (repo *PostRepository) Create(ctx context.Context, post *models.Post) error {
 	postPKId := &models.Post{
-		PostId:    "post:" + post.PostId,
+		PostId:    keys.Post.Key(post.PostId),
 		Title:     post.Title,
 		Content:   post.Content,
 		Type:      post.Type,
 		CreatedAt: post.CreatedAt,
-		UId:       "user:" + post.UId,
+		UId:       keys.User.Key(post.UId),
 		Likes:     post.Likes,
 	}
 	postSKFilter := &models.PostSKFilter{
 		Title:     post.Title,
 		Content:   post.Content,
-		SK:        fmt.Sprintf("post:%s:%s:%s", post.Type, post.CreatedAt.Format(time.RFC3339), post.PostId),
+		SK:        keys.ListingKey(post.Type, post.CreatedAt, post.PostId),
 		CreatedAt: post.CreatedAt,
 		UId:       post.UId,
 		Likes:     post.Likes,
-		PK:        "posts",
+		PK:        keys.Posts,
 	}
 	notification := &models.Notification{
-		PK:        "notifications",
-		PostId:    "post:" + post.PostId,
+		PK:        keys.Notifications,
+		PostId:    keys.Post.Key(post.PostId),
 		Title:     post.Title,
 		Content:   post.Content,
 		Type:      post.Type,
@@ -73,7 +78,12 @@ func (repo *PostRepository) Create(ctx context.Context, post *models.Post) error
 	notificationAv["created_at"] = &types.AttributeValueMemberS{
 		Value: post.CreatedAt.Format(time.RFC3339),
 	}
-	posts = append(posts, postPKIdAv, postSKFilterAv, notificationAv)
+	ownerAv, err := attributevalue.MarshalMap(&models.PostOwner{
+		PostId: keys.Post.Key(post.PostId),
+		SK:     postOwnerSK,
+		UId:    post.UId,
+	})
+	posts = append(posts, postPKIdAv, postSKFilterAv, notificationAv, ownerAv)
 	writeRequests := make([]types.WriteRequest, len(posts))
 	for i, post := range posts {
 		writeRequests[i] = types.WriteRequest{
@@ -91,7 +101,8 @@ func (repo *PostRepository) Create(ctx context.Context, post *models.Post) error
 	return err
 }
 
-func (repo *PostRepository) GetAllPostsWithFilter(ctx context.Context, limit, offset *int, search, filter *string) ([]*models.Post, error) {
+// GetAllPostsWithFilter lists posts newest first, leaving out hidden posts and posts written by excluded users
+func (repo *PostRepository) GetAllPostsWithFilter(ctx context.Context, limit, offset *int, search, filter *string, excluded map[string]bool) ([]*models.Post, error) {
 	var allItems []map[string]types.AttributeValue
 
 	// Construct the basic query input
@@ -105,14 +116,14 @@ func (repo *PostRepository) GetAllPostsWithFilter(ctx context.Context, limit, of
 		// When filter is provided, query for specific post type
 		queryInput.KeyConditionExpression = aws.String("pk = :pk and begins_with(sk, :prefix)")
 		queryInput.ExpressionAttributeValues = map[string]types.AttributeValue{
-			":pk":     &types.AttributeValueMemberS{Value: "posts"},
-			":prefix": &types.AttributeValueMemberS{Value: fmt.Sprintf("post:%s:", strings.ToUpper(*filter))},
+			":pk":     &types.AttributeValueMemberS{Value: keys.Posts},
+			":prefix": &types.AttributeValueMemberS{Value: keys.Post.Prefix(strings.ToUpper(*filter))},
 		}
 	} else {
 		// When no filter is provided, query all posts
 		queryInput.KeyConditionExpression = aws.String("pk = :pk")
 		queryInput.ExpressionAttributeValues = map[string]types.AttributeValue{
-			":pk": &types.AttributeValueMemberS{Value: "posts"},
+			":pk": &types.AttributeValueMemberS{Value: keys.Posts},
 		}
 	}
 
@@ -129,7 +140,15 @@ func (repo *PostRepository) GetAllPostsWithFilter(ctx context.Context, limit, of
 			return nil, fmt.Errorf("failed to execute query: %w", err)
 		}
 
-		allItems = append(allItems, result.Items...)
+		for _, item := range result.Items {
+			if author, ok := item["user_id"].(*types.AttributeValueMemberS); ok && excluded[author.Value] {
+				continue
+			}
+			if hidden, ok := item["hidden"].(*types.AttributeValueMemberBOOL); ok && hidden.Value {
+				continue
+			}
+			allItems = append(allItems, item)
+		}
 
 		// Check if we need to continue pagination
 		if result.LastEvaluatedKey == nil {
@@ -153,7 +172,10 @@ func (repo *PostRepository) GetAllPostsWithFilter(ctx context.Context, limit, of
 			return nil, fmt.Errorf("failed to unmarshal post: %w", err)
 		}
 
-		sk := strings.Split(postWithSK.SK, ":")
+		listing, err := keys.ParseListing(postWithSK.SK)
+		if err != nil {
+			return nil, err
+		}
 
 		post := &models.Post{
 			Title:     postWithSK.Title,
@@ -161,8 +183,10 @@ func (repo *PostRepository) GetAllPostsWithFilter(ctx context.Context, limit, of
 			Likes:     postWithSK.Likes,
 			CreatedAt: postWithSK.CreatedAt,
 			UId:       postWithSK.UId,
-			PostId:    sk[len(sk)-1],
-			Type:      config.Filter(sk[1]),
+			PostId:    listing.PostId,
+			Type:      listing.Type,
+			EditedAt:  postWithSK.EditedAt,
+			Version:   postWithSK.Version,
 		}
 		posts = append(posts, post)
 	}
@@ -187,8 +211,8 @@ func (repo *PostRepository) DeletePost(ctx context.Context, filter config.Filter
 	input1 := &dynamodb.DeleteItemInput{
 		TableName: aws.String(repo.TableName),
 		Key: map[string]types.AttributeValue{
-			"pk": &types.AttributeValueMemberS{Value: "posts"},
-			"sk": &types.AttributeValueMemberS{Value: fmt.Sprintf("post:%s:%s:%s", filter, createdAt.Format(time.RFC3339), pId)},
+			"pk": &types.AttributeValueMemberS{Value: keys.Posts},
+			"sk": &types.AttributeValueMemberS{Value: keys.ListingKey(config.Filter(filter), createdAt, pId)},
 		},
 		ExpressionAttributeValues: map[string]types.AttributeValue{
 			":userId": &types.AttributeValueMemberS{Value: uId},
@@ -199,17 +223,21 @@ func (repo *PostRepository) DeletePost(ctx context.Context, filter config.Filter
 	input2 := &dynamodb.DeleteItemInput{
 		TableName: aws.String(repo.TableName),
 		Key: map[string]types.AttributeValue{
-			"pk": &types.AttributeValueMemberS{Value: "user:" + uId},
-			"sk": &types.AttributeValueMemberS{Value: "post:" + pId},
+			"pk": &types.AttributeValueMemberS{Value: keys.User.Key(uId)},
+			"sk": &types.AttributeValueMemberS{Value: keys.Post.Key(pId)},
 		},
 	}
 	result, deleteErr := repo.Db.DeleteItem(ctx, input1)
+	if utils.IsConditionFailed(deleteErr) {
+		return utils.NotYourPost
+	}
 	if deleteErr != nil {
 		return deleteErr
 	}
 	if result.Attributes != nil {
 		var writeRequests []types.WriteRequest
 		var pks []string
+		var replies []string
 		var queryOutput *dynamodb.QueryOutput
 		var err error
 		//queryInputLike := &dynamodb.QueryInput{
@@ -254,8 +282,8 @@ func (repo *PostRepository) DeletePost(ctx context.Context, filter config.Filter
 			TableName:              aws.String(repo.TableName),
 			KeyConditionExpression: aws.String("pk = :pk AND begins_with(sk, :sk)"),
 			ExpressionAttributeValues: map[string]types.AttributeValue{
-				":pk": &types.AttributeValueMemberS{Value: "post:" + pId},
-				":sk": &types.AttributeValueMemberS{Value: "question:"},
+				":pk": &types.AttributeValueMemberS{Value: keys.Post.Key(pId)},
+				":sk": &types.AttributeValueMemberS{Value: keys.Question.Prefix()},
 			},
 		}
 		for {
@@ -300,7 +328,7 @@ func (repo *PostRepository) DeletePost(ctx context.Context, filter config.Filter
 				KeyConditionExpression: aws.String("pk = :pk AND begins_with(sk, :sk)"),
 				ExpressionAttributeValues: map[string]types.AttributeValue{
 					":pk": &types.AttributeValueMemberS{Value: pk},
-					":sk": &types.AttributeValueMemberS{Value: "reply:"},
+					":sk": &types.AttributeValueMemberS{Value: keys.Reply.Prefix()},
 				},
 			}
 			for {
@@ -320,6 +348,7 @@ func (repo *PostRepository) DeletePost(ctx context.Context, filter config.Filter
 							},
 						},
 					})
+					replies = append(replies, item["sk"].(*types.AttributeValueMemberS).Value)
 				}
 				_, err := repo.Db.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{
 					RequestItems: map[string][]types.WriteRequest{
@@ -336,6 +365,72 @@ func (repo *PostRepository) DeletePost(ctx context.Context, filter config.Filter
 				writeRequests = nil
 			}
 		}
+		comments, err := deleteByPrefix(ctx, repo.Db, repo.TableName, keys.Post.Key(pId), keys.Comment.Prefix())
+		if err != nil {
+			return err
+		}
+		var refRequests []types.WriteRequest
+		for _, comment := range comments {
+			cId, err := keys.ParseSorted(comment)
+			if err != nil {
+				return err
+			}
+			refRequests = append(refRequests, types.WriteRequest{
+				DeleteRequest: &types.DeleteRequest{
+					Key: map[string]types.AttributeValue{
+						"pk": &types.AttributeValueMemberS{Value: keys.Comment.Key(cId)},
+						"sk": &types.AttributeValueMemberS{Value: "ref"},
+					},
+				},
+			})
+		}
+		err = batchWrite(ctx, repo.Db, repo.TableName, refRequests)
+		if err != nil {
+			return err
+		}
+		countRequests := []types.WriteRequest{countsDelete(keys.Post.Key(pId)), {
+			DeleteRequest: &types.DeleteRequest{
+				Key: map[string]types.AttributeValue{
+					"pk": &types.AttributeValueMemberS{Value: keys.Post.Key(pId)},
+					"sk": &types.AttributeValueMemberS{Value: postOwnerSK},
+				},
+			},
+		}}
+		for _, pk := range pks {
+			countRequests = append(countRequests, countsDelete(pk), questionRefDelete(pk))
+		}
+		err = batchWrite(ctx, repo.Db, repo.TableName, countRequests)
+		if err != nil {
+			return err
+		}
+		err = deleteReactions(ctx, repo.Db, repo.TableName, config.PostContent, pId)
+		if err != nil {
+			return err
+		}
+		for _, pk := range pks {
+			qId, err := keys.Question.Parse(pk)
+			if err != nil {
+				return err
+			}
+			err = deleteReactions(ctx, repo.Db, repo.TableName, config.QuestionContent, qId)
+			if err != nil {
+				return err
+			}
+		}
+		for _, reply := range replies {
+			rId, err := keys.Reply.Parse(reply)
+			if err != nil {
+				return err
+			}
+			err = deleteReactions(ctx, repo.Db, repo.TableName, config.AnswerContent, rId)
+			if err != nil {
+				return err
+			}
+		}
+		err = deleteBookmarks(ctx, repo.Db, repo.TableName, pId)
+		if err != nil {
+			return err
+		}
 	}
 	_, err := repo.Db.DeleteItem(ctx, input2)
 	return err
@@ -346,8 +441,8 @@ func (repo *PostRepository) GetPostsByUId(ctx context.Context, uId string) ([]*m
 		TableName:              aws.String(repo.TableName),
 		KeyConditionExpression: aws.String("pk = :pk AND begins_with(sk, :sk) "),
 		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":pk": &types.AttributeValueMemberS{Value: "user:" + uId},
-			":sk": &types.AttributeValueMemberS{Value: "post:"},
+			":pk": &types.AttributeValueMemberS{Value: keys.User.Key(uId)},
+			":sk": &types.AttributeValueMemberS{Value: keys.Post.Prefix()},
 		},
 		ScanIndexForward: aws.Bool(false),
 	}
@@ -357,173 +452,362 @@ func (repo *PostRepository) GetPostsByUId(ctx context.Context, uId string) ([]*m
 	}
 	posts := make([]*models.Post, 0)
 	for _, item := range result.Items {
-		var postDB models.Post
-		err := attributevalue.UnmarshalMap(item, &postDB)
+		post, err := unmarshalUserPost(item)
 		if err != nil {
 			return nil, err
 		}
-		dtoPostId := strings.Split(postDB.PostId, ":")
-		dtoUserId := strings.Split(postDB.UId, ":")
-		post := &models.Post{
-			Title:     postDB.Title,
-			Content:   postDB.Content,
-			Likes:     postDB.Likes,
-			CreatedAt: postDB.CreatedAt,
-			UId:       dtoUserId[1],
-			PostId:    dtoPostId[1],
-			Type:      postDB.Type,
-		}
 		posts = append(posts, post)
 	}
 	return posts, nil
 }
 
-func (repo *PostRepository) UpdatePost(ctx context.Context, uId string, post *models.Post) error {
-	input1 := &dynamodb.UpdateItemInput{
-		TableName:           aws.String(repo.TableName),
-		ConditionExpression: aws.String("attribute_exists(pk) AND attribute_exists(sk) AND user_id = :userId"),
-		Key: map[string]types.AttributeValue{
-			"pk": &types.AttributeValueMemberS{Value: "posts"},
-			"sk": &types.AttributeValueMemberS{Value: fmt.Sprintf("post:%s:%s:%s", post.Type, post.CreatedAt.Format(time.RFC3339), post.PostId)},
-		},
-		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":title":   &types.AttributeValueMemberS{Value: post.Title},
-			":content": &types.AttributeValueMemberS{Value: post.Content},
-			":userId":  &types.AttributeValueMemberS{Value: uId},
-		},
-		UpdateExpression: aws.String("SET title =:title, content =:content"),
+// GetUserPostsBefore returns up to limit posts of uId created at or before the given time,
+// newest first. It reads the user partition through the created_at index so only the page is
+// loaded, a zero time starts at the newest post.
+func (repo *PostRepository) GetUserPostsBefore(ctx context.Context, uId string, before time.Time, limit int) ([]*models.Post, error) {
+	keyCondition := "pk = :pk"
+	values := map[string]types.AttributeValue{
+		":pk":    &types.AttributeValueMemberS{Value: keys.User.Key(uId)},
+		":sk":    &types.AttributeValueMemberS{Value: keys.Post.Prefix()},
+		":false": &types.AttributeValueMemberBOOL{Value: false},
+	}
+	if !before.IsZero() {
+		createdAt, err := attributevalue.Marshal(before)
+		if err != nil {
+			return nil, err
+		}
+		keyCondition += " AND created_at <= :before"
+		values[":before"] = createdAt
 	}
-	input2 := &dynamodb.UpdateItemInput{
-		TableName:           aws.String(repo.TableName),
-		ConditionExpression: aws.String("attribute_exists(pk) AND attribute_exists(sk)"),
+	input := &dynamodb.QueryInput{
+		TableName:                 aws.String(repo.TableName),
+		IndexName:                 aws.String(repo.IndexName),
+		KeyConditionExpression:    aws.String(keyCondition),
+		FilterExpression:          aws.String("begins_with(sk, :sk) AND (attribute_not_exists(hidden) OR hidden = :false)"),
+		ExpressionAttributeValues: values,
+		ScanIndexForward:          aws.Bool(false),
+		Limit:                     aws.Int32(int32(limit)),
+	}
+	posts := make([]*models.Post, 0, limit)
+	// the filter drops the other items of the partition after the limit is applied, so pages
+	// are read until enough posts are found or the partition ends
+	for len(posts) < limit {
+		result, err := repo.Db.Query(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range result.Items {
+			post, err := unmarshalUserPost(item)
+			if err != nil {
+				return nil, err
+			}
+			posts = append(posts, post)
+		}
+		if result.LastEvaluatedKey == nil {
+			break
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+	if len(posts) > limit {
+		posts = posts[:limit]
+	}
+	return posts, nil
+}
+
+// unmarshalUserPost reads the copy of a post kept in its author's partition
+func unmarshalUserPost(item map[string]types.AttributeValue) (*models.Post, error) {
+	var postDB models.Post
+	err := attributevalue.UnmarshalMap(item, &postDB)
+	if err != nil {
+		return nil, err
+	}
+	dtoPostId, err := keys.Post.Parse(postDB.PostId)
+	if err != nil {
+		return nil, err
+	}
+	dtoUserId, err := keys.User.Parse(postDB.UId)
+	if err != nil {
+		return nil, err
+	}
+	return &models.Post{
+		Title:     postDB.Title,
+		Content:   postDB.Content,
+		Likes:     postDB.Likes,
+		CreatedAt: postDB.CreatedAt,
+		UId:       dtoUserId,
+		PostId:    dtoPostId,
+		Type:      postDB.Type,
+		EditedAt:  postDB.EditedAt,
+		Hidden:    postDB.Hidden,
+		Version:   postDB.Version,
+	}, nil
+}
+
+// GetPostOwner returns the author of a post. Posts created before owner items existed are
+// found through the posts partition and get their owner item written on the way.
+func (repo *PostRepository) GetPostOwner(ctx context.Context, pId string) (string, error) {
+	result, err := repo.Db.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(repo.TableName),
 		Key: map[string]types.AttributeValue{
-			"pk": &types.AttributeValueMemberS{Value: "user:" + uId},
-			"sk": &types.AttributeValueMemberS{Value: "post:" + post.PostId},
+			"pk": &types.AttributeValueMemberS{Value: keys.Post.Key(pId)},
+			"sk": &types.AttributeValueMemberS{Value: postOwnerSK},
 		},
+	})
+	if err != nil {
+		return "", err
+	}
+	if result.Item != nil {
+		var owner models.PostOwner
+		if err := attributevalue.UnmarshalMap(result.Item, &owner); err != nil {
+			return "", err
+		}
+		return owner.UId, nil
+	}
+	queryInput := &dynamodb.QueryInput{
+		TableName:              aws.String(repo.TableName),
+		KeyConditionExpression: aws.String("pk = :pk"),
+		FilterExpression:       aws.String("contains(sk, :pId)"),
 		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":title":   &types.AttributeValueMemberS{Value: post.Title},
-			":content": &types.AttributeValueMemberS{Value: post.Content},
+			":pk":  &types.AttributeValueMemberS{Value: keys.Posts},
+			":pId": &types.AttributeValueMemberS{Value: ":" + keys.Escape(pId)},
 		},
-		UpdateExpression: aws.String("SET title =:title, content =:content"),
-	}
-	var wg sync.WaitGroup
-	var mu sync.Mutex
-	var err error
-
-	update := func(input *dynamodb.UpdateItemInput) {
-		defer wg.Done()
-		_, updateErr := repo.Db.UpdateItem(ctx, input)
-		if updateErr != nil {
-			mu.Lock()
-			if err == nil {
-				err = updateErr
+		ProjectionExpression: aws.String("sk, user_id"),
+	}
+	for {
+		queryOutput, err := repo.Db.Query(ctx, queryInput)
+		if err != nil {
+			return "", err
+		}
+		for _, item := range queryOutput.Items {
+			var post models.PostSKFilter
+			if err := attributevalue.UnmarshalMap(item, &post); err != nil {
+				return "", err
+			}
+			listing, err := keys.ParseListing(post.SK)
+			if err != nil {
+				return "", err
 			}
-			mu.Unlock()
+			if listing.PostId != pId {
+				continue
+			}
+			ownerAv, err := attributevalue.MarshalMap(&models.PostOwner{
+				PostId: keys.Post.Key(pId),
+				SK:     postOwnerSK,
+				UId:    post.UId,
+			})
+			if err != nil {
+				return "", err
+			}
+			_, err = repo.Db.PutItem(ctx, &dynamodb.PutItemInput{
+				TableName: aws.String(repo.TableName),
+				Item:      ownerAv,
+			})
+			return post.UId, err
+		}
+		if queryOutput.LastEvaluatedKey == nil {
+			return "", utils.NoPost
 		}
+		queryInput.ExclusiveStartKey = queryOutput.LastEvaluatedKey
 	}
-	wg.Add(2)
-	go update(input1)
-	go update(input2)
-	wg.Wait()
-	return err
 }
 
-func (repo *PostRepository) ToggleLike(ctx context.Context, postUId, uId, filter, pId string, createdAt time.Time) (config.LikeStatus, error) {
-	var wg sync.WaitGroup
-	var mu sync.Mutex
-	var err error
-	update := func(input *dynamodb.UpdateItemInput) {
-		defer wg.Done()
-		_, updateErr := repo.Db.UpdateItem(ctx, input)
-		if updateErr != nil {
-			mu.Lock()
-			if err == nil {
-				err = updateErr
+func (repo *PostRepository) GetUserPost(ctx context.Context, uId, pId string) (*models.Post, error) {
+	result, err := repo.Db.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(repo.TableName),
+		Key: map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{Value: keys.User.Key(uId)},
+			"sk": &types.AttributeValueMemberS{Value: keys.Post.Key(pId)},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.Item == nil {
+		return nil, utils.NotYourPost
+	}
+	var post models.Post
+	if err := attributevalue.UnmarshalMap(result.Item, &post); err != nil {
+		return nil, err
+	}
+	post.UId = uId
+	post.PostId = pId
+	return &post, nil
+}
+
+// GetPostsByOwners loads posts given a map of post id to author id, keyed by post id.
+// Posts that no longer exist are absent from the result.
+func (repo *PostRepository) GetPostsByOwners(ctx context.Context, owners map[string]string) (map[string]*models.Post, error) {
+	posts := make(map[string]*models.Post)
+	itemKeys := make([]map[string]types.AttributeValue, 0, len(owners))
+	for pId, uId := range owners {
+		itemKeys = append(itemKeys, map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{Value: keys.User.Key(uId)},
+			"sk": &types.AttributeValueMemberS{Value: keys.Post.Key(pId)},
+		})
+	}
+	// BatchGetItem accepts at most 100 keys per call
+	for start := 0; start < len(itemKeys); start += 100 {
+		end := start + 100
+		if end > len(itemKeys) {
+			end = len(itemKeys)
+		}
+		requestItems := map[string]types.KeysAndAttributes{
+			repo.TableName: {Keys: itemKeys[start:end]},
+		}
+		for len(requestItems) > 0 {
+			result, err := repo.Db.BatchGetItem(ctx, &dynamodb.BatchGetItemInput{
+				RequestItems: requestItems,
+			})
+			if err != nil {
+				return nil, err
 			}
-			mu.Unlock()
+			for _, item := range result.Responses[repo.TableName] {
+				var post models.Post
+				if err := attributevalue.UnmarshalMap(item, &post); err != nil {
+					return nil, err
+				}
+				if post.UId, err = keys.User.Parse(post.UId); err != nil {
+					return nil, err
+				}
+				if post.PostId, err = keys.Post.Parse(post.PostId); err != nil {
+					return nil, err
+				}
+				posts[post.PostId] = &post
+			}
+			requestItems = result.UnprocessedKeys
 		}
 	}
-	hasLiked, err := repo.HasUserLikedAPost(ctx, uId, pId)
+	return posts, nil
+}
+
+// UpdatePost edits both copies of a post if it is still at post.Version and returns the new version
+func (repo *PostRepository) UpdatePost(ctx context.Context, uId string, post *models.Post) (int, error) {
+	previous, err := repo.GetUserPost(ctx, uId, post.PostId)
 	if err != nil {
-		return "0", err
-	}
-	if hasLiked {
-		input1 := &dynamodb.UpdateItemInput{
-			TableName: aws.String(repo.TableName),
-			Key: map[string]types.AttributeValue{
-				"pk": &types.AttributeValueMemberS{Value: "user:" + postUId},
-				"sk": &types.AttributeValueMemberS{Value: "post:" + pId},
-			},
-			ExpressionAttributeValues: map[string]types.AttributeValue{
-				":likes": &types.AttributeValueMemberN{Value: "1"},
-			},
-			UpdateExpression: aws.String("SET likes = likes - :likes"),
-		}
-		input2 := &dynamodb.UpdateItemInput{
-			TableName: aws.String(repo.TableName),
-			Key: map[string]types.AttributeValue{
-				"pk": &types.AttributeValueMemberS{Value: "posts"},
-				"sk": &types.AttributeValueMemberS{Value: fmt.Sprintf("post:%s:%s:%s", filter, createdAt.Format(time.RFC3339), pId)},
-			},
-			ExpressionAttributeValues: map[string]types.AttributeValue{
-				":likes": &types.AttributeValueMemberN{Value: "1"},
-			},
-			UpdateExpression: aws.String("SET likes = likes - :likes"),
-		}
-		wg.Add(2)
-		go update(input1)
-		go update(input2)
-		err = repo.deleteLikeEntry(ctx, uId, pId)
-		wg.Wait()
-		return config.NotLiked, err
+		return 0, err
 	}
-	input1 := &dynamodb.UpdateItemInput{
-		TableName: aws.String(repo.TableName),
+	if previous.Version != post.Version {
+		return 0, utils.VersionMismatch
+	}
+	editedAt := time.Now()
+	revision, err := revisionPut(repo.TableName, config.PostContent, post.PostId, uId, previous.Title, previous.Content, editedAt)
+	if err != nil {
+		return 0, err
+	}
+	listingValues := map[string]types.AttributeValue{
+		":title":    &types.AttributeValueMemberS{Value: post.Title},
+		":content":  &types.AttributeValueMemberS{Value: post.Content},
+		":userId":   &types.AttributeValueMemberS{Value: uId},
+		":editedAt": &types.AttributeValueMemberS{Value: editedAt.Format(time.RFC3339)},
+	}
+	// both copies are checked against the version the client read, listings report the
+	// version of the listing copy so it has to move in step with the author's copy
+	listingBump, listingCondition := versioned(post.Version, listingValues)
+	input1 := &types.Update{
+		TableName:           aws.String(repo.TableName),
+		ConditionExpression: aws.String("attribute_exists(pk) AND attribute_exists(sk) AND user_id = :userId AND " + listingCondition),
 		Key: map[string]types.AttributeValue{
-			"pk": &types.AttributeValueMemberS{Value: "user:" + postUId},
-			"sk": &types.AttributeValueMemberS{Value: "post:" + pId},
+			"pk": &types.AttributeValueMemberS{Value: keys.Posts},
+			"sk": &types.AttributeValueMemberS{Value: keys.ListingKey(post.Type, post.CreatedAt, post.PostId)},
 		},
-		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":likes": &types.AttributeValueMemberN{Value: "1"},
+		ExpressionAttributeValues: listingValues,
+		UpdateExpression:          aws.String("SET title =:title, content =:content, edited_at =:editedAt, " + listingBump),
+	}
+	values := map[string]types.AttributeValue{
+		":title":    &types.AttributeValueMemberS{Value: post.Title},
+		":content":  &types.AttributeValueMemberS{Value: post.Content},
+		":editedAt": &types.AttributeValueMemberS{Value: editedAt.Format(time.RFC3339)},
+	}
+	bump, condition := versioned(post.Version, values)
+	input2 := &types.Update{
+		TableName:           aws.String(repo.TableName),
+		ConditionExpression: aws.String("attribute_exists(pk) AND attribute_exists(sk) AND " + condition),
+		Key: map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{Value: keys.User.Key(uId)},
+			"sk": &types.AttributeValueMemberS{Value: keys.Post.Key(post.PostId)},
+		},
+		ExpressionAttributeValues: values,
+		UpdateExpression:          aws.String("SET title =:title, content =:content, edited_at =:editedAt, " + bump),
+	}
+	_, err = repo.Db.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{{Update: input1}, {Update: input2}, revision},
+	})
+	if conditionFailedAt(err, 0) || conditionFailedAt(err, 1) {
+		return 0, utils.VersionMismatch
+	}
+	if utils.IsConditionFailed(err) {
+		return 0, utils.EditConflict
+	}
+	if err != nil {
+		return 0, err
+	}
+	return post.Version + 1, nil
+}
+
+// SetPostHidden hides or restores both copies of a post
+func (repo *PostRepository) SetPostHidden(ctx context.Context, uId, pId string, hidden bool) error {
+	post, err := repo.GetUserPost(ctx, uId, pId)
+	if err != nil {
+		return err
+	}
+	err = setHidden(ctx, repo.Db, repo.TableName, []map[string]types.AttributeValue{
+		{
+			"pk": &types.AttributeValueMemberS{Value: keys.User.Key(uId)},
+			"sk": &types.AttributeValueMemberS{Value: keys.Post.Key(pId)},
+		},
+		{
+			"pk": &types.AttributeValueMemberS{Value: keys.Posts},
+			"sk": &types.AttributeValueMemberS{Value: keys.ListingKey(post.Type, post.CreatedAt, pId)},
 		},
-		UpdateExpression: aws.String("SET likes = likes + :likes"),
+	}, hidden)
+	if utils.IsConditionFailed(err) {
+		return utils.NotYourPost
 	}
-	input2 := &dynamodb.UpdateItemInput{
+	return err
+}
+
+// UpdateLikeCount keeps the likes attribute of both post copies in step with LIKE reactions
+func (repo *PostRepository) UpdateLikeCount(ctx context.Context, postUId, filter, pId string, createdAt time.Time, delta int) error {
+	input1 := &types.Update{
 		TableName: aws.String(repo.TableName),
 		Key: map[string]types.AttributeValue{
-			"pk": &types.AttributeValueMemberS{Value: "posts"},
-			"sk": &types.AttributeValueMemberS{Value: fmt.Sprintf("post:%s:%s:%s", filter, createdAt.Format(time.RFC3339), pId)},
+			"pk": &types.AttributeValueMemberS{Value: keys.User.Key(postUId)},
+			"sk": &types.AttributeValueMemberS{Value: keys.Post.Key(pId)},
 		},
 		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":likes": &types.AttributeValueMemberN{Value: "1"},
+			":likes": &types.AttributeValueMemberN{Value: strconv.Itoa(delta)},
 		},
-		UpdateExpression: aws.String("SET likes = likes + :likes"),
-	}
-	wg.Add(2)
-	go update(input1)
-	go update(input2)
-	err = repo.enterLikeEntry(ctx, uId, pId)
-	wg.Wait()
-	return config.Liked, err
-}
-
-func (repo *PostRepository) deleteLikeEntry(ctx context.Context, uId, pId string) error {
-	_, err := repo.Db.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		UpdateExpression:    aws.String("SET likes = likes + :likes"),
+		ConditionExpression: aws.String("attribute_exists(pk)"),
+	}
+	input2 := &types.Update{
 		TableName: aws.String(repo.TableName),
 		Key: map[string]types.AttributeValue{
-			"pk": &types.AttributeValueMemberS{Value: "like:" + pId},
-			"sk": &types.AttributeValueMemberS{Value: "user:" + uId},
+			"pk": &types.AttributeValueMemberS{Value: keys.Posts},
+			"sk": &types.AttributeValueMemberS{Value: keys.ListingKey(config.Filter(filter), createdAt, pId)},
 		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":likes": &types.AttributeValueMemberN{Value: strconv.Itoa(delta)},
+		},
+		UpdateExpression:    aws.String("SET likes = likes + :likes"),
+		ConditionExpression: aws.String("attribute_exists(pk)"),
+	}
+	_, err := repo.Db.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{{Update: input1}, {Update: input2}},
 	})
+	if utils.IsConditionFailed(err) {
+		return utils.NoPost
+	}
 	return err
 }
 
-func (repo *PostRepository) enterLikeEntry(ctx context.Context, uId, pId string) error {
-	_, err := repo.Db.PutItem(ctx, &dynamodb.PutItemInput{
+// DeleteLikeEntry removes a like recorded before likes became reactions
+func (repo *PostRepository) DeleteLikeEntry(ctx context.Context, uId, pId string) error {
+	_, err := repo.Db.DeleteItem(ctx, &dynamodb.DeleteItemInput{
 		TableName: aws.String(repo.TableName),
-		Item: map[string]types.AttributeValue{
-			"pk": &types.AttributeValueMemberS{Value: "like:" + pId},
-			"sk": &types.AttributeValueMemberS{Value: "user:" + uId},
+		Key: map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{Value: keys.Like.Key(pId)},
+			"sk": &types.AttributeValueMemberS{Value: keys.User.Key(uId)},
 		},
 	})
 	return err
@@ -534,8 +818,8 @@ func (repo *PostRepository) HasUserLikedAPost(ctx context.Context, uId, pId stri
 		TableName:              aws.String(repo.TableName),
 		KeyConditionExpression: aws.String("pk = :pk AND sk = :sk"),
 		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":pk": &types.AttributeValueMemberS{Value: "like:" + pId},
-			":sk": &types.AttributeValueMemberS{Value: "user:" + uId},
+			":pk": &types.AttributeValueMemberS{Value: keys.Like.Key(pId)},
+			":sk": &types.AttributeValueMemberS{Value: keys.User.Key(uId)},
 		},
 	}
 	result, err := repo.Db.Query(ctx, input)
@@ -544,3 +828,92 @@ func (repo *PostRepository) HasUserLikedAPost(ctx context.Context, uId, pId stri
 	}
 	return len(result.Items) > 0, err
 }
+
+func (repo *PostRepository) IsPostOwner(ctx context.Context, uId, pId string) (bool, error) {
+	result, err := repo.Db.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(repo.TableName),
+		Key: map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{Value: keys.User.Key(uId)},
+			"sk": &types.AttributeValueMemberS{Value: keys.Post.Key(pId)},
+		},
+		ProjectionExpression: aws.String("pk"),
+	})
+	if err != nil {
+		return false, err
+	}
+	return result.Item != nil, nil
+}
+
+func (repo *PostRepository) GetPostCounts(ctx context.Context, pIds []string) (map[string]*models.Counts, error) {
+	pks := make([]string, 0, len(pIds))
+	for _, pId := range pIds {
+		pks = append(pks, keys.Post.Key(pId))
+	}
+	counts, err := getCounts(ctx, repo.Db, repo.TableName, pks)
+	if err != nil {
+		return nil, err
+	}
+	postCounts := make(map[string]*models.Counts)
+	for pk, count := range counts {
+		pId, err := keys.Post.Parse(pk)
+		if err != nil {
+			return nil, err
+		}
+		postCounts[pId] = count
+	}
+	return postCounts, nil
+}
+
+// BackfillOwners writes the owner item of every post that does not have one yet and returns
+// how many were written
+func (repo *PostRepository) BackfillOwners(ctx context.Context) (int, error) {
+	written := 0
+	queryInput := &dynamodb.QueryInput{
+		TableName:              aws.String(repo.TableName),
+		KeyConditionExpression: aws.String("pk = :pk"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": &types.AttributeValueMemberS{Value: keys.Posts},
+		},
+		ProjectionExpression: aws.String("sk, user_id"),
+	}
+	for {
+		queryOutput, err := repo.Db.Query(ctx, queryInput)
+		if err != nil {
+			return written, err
+		}
+		for _, item := range queryOutput.Items {
+			var post models.PostSKFilter
+			if err := attributevalue.UnmarshalMap(item, &post); err != nil {
+				return written, err
+			}
+			listing, err := keys.ParseListing(post.SK)
+			if err != nil {
+				return written, err
+			}
+			ownerAv, err := attributevalue.MarshalMap(&models.PostOwner{
+				PostId: keys.Post.Key(listing.PostId),
+				SK:     postOwnerSK,
+				UId:    post.UId,
+			})
+			if err != nil {
+				return written, err
+			}
+			_, err = repo.Db.PutItem(ctx, &dynamodb.PutItemInput{
+				TableName:           aws.String(repo.TableName),
+				Item:                ownerAv,
+				ConditionExpression: aws.String("attribute_not_exists(pk)"),
+			})
+			if utils.IsConditionFailed(err) {
+				continue
+			}
+			if err != nil {
+				return written, err
+			}
+			written++
+		}
+		if queryOutput.LastEvaluatedKey == nil {
+			return written, nil
+		}
+		queryInput.ExclusiveStartKey = queryOutput.LastEvaluatedKey
+	}
+}
@@ -0,0 +1,86 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"localeyes/internal/models"
+	"localeyes/utils"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUpdatePost(t *testing.T) {
+	// reasons builds the cancellation reasons of a transaction whose item at failed failed
+	reasons := func(failed int) []any {
+		list := []any{map[string]any{"Code": "None"}, map[string]any{"Code": "None"}, map[string]any{"Code": "None"}}
+		list[failed] = map[string]any{"Code": "ConditionalCheckFailed"}
+		return list
+	}
+	canceled := func(failed int) dynamoResponse {
+		return dynamoResponse{Error: "TransactionCanceledException", Body: map[string]any{
+			"message":             "Transaction cancelled",
+			"CancellationReasons": reasons(failed),
+		}}
+	}
+	tests := []struct {
+		name     string
+		read     int
+		response dynamoResponse
+		version  int
+		err      error
+		written  bool
+	}{
+		{"updated", 3, dynamoResponse{}, 4, nil, true},
+		{"read version is outdated", 2, dynamoResponse{}, 0, utils.VersionMismatch, false},
+		{"listing copy moved on", 3, canceled(0), 0, utils.VersionMismatch, true},
+		{"author's copy moved on", 3, canceled(1), 0, utils.VersionMismatch, true},
+		{"revision already written", 3, canceled(2), 0, utils.EditConflict, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client, calls := fakeDynamo(t, func(call dynamoCall) dynamoResponse {
+				switch call.Operation {
+				case "GetItem":
+					return dynamoResponse{Body: map[string]any{"Item": map[string]any{
+						"pk":      map[string]any{"S": "user:u1"},
+						"sk":      map[string]any{"S": "post:p1"},
+						"title":   map[string]any{"S": "old"},
+						"content": map[string]any{"S": "old"},
+						"version": map[string]any{"N": "3"},
+					}}}
+				case "TransactWriteItems":
+					return test.response
+				}
+				return dynamoResponse{}
+			})
+			repo := &PostRepository{Db: client, TableName: "localeyes"}
+			post := &models.Post{PostId: "p1", Title: "new", Content: "new", Type: "food", CreatedAt: time.Now(), Version: test.read}
+			version, err := repo.UpdatePost(context.Background(), "u1", post)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("error = %v, want %v", err, test.err)
+			}
+			if version != test.version {
+				t.Fatalf("version = %d, want %d", version, test.version)
+			}
+			written := false
+			for _, call := range *calls {
+				if call.Operation != "TransactWriteItems" {
+					continue
+				}
+				written = true
+				items := call.Input["TransactItems"].([]any)
+				for _, i := range []int{0, 1} {
+					update := items[i].(map[string]any)["Update"].(map[string]any)
+					condition := update["ConditionExpression"].(string)
+					if !strings.Contains(condition, "version = :version") {
+						t.Fatalf("copy %d is not conditioned on the version: %q", i, condition)
+					}
+				}
+			}
+			if written != test.written {
+				t.Fatalf("written = %v, want %v", written, test.written)
+			}
+		})
+	}
+}
@@ -6,11 +6,29 @@ import (
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"localeyes/config"
+	"localeyes/internal/keys"
 	"localeyes/internal/models"
+	"localeyes/utils"
 	"os"
-	"strings"
+	"sort"
+	"time"
 )
 
+// questionRefSK is the sort key of the item in a question partition pointing back to its post
+const questionRefSK = "ref"
+
+func questionRefDelete(pk string) types.WriteRequest {
+	return types.WriteRequest{
+		DeleteRequest: &types.DeleteRequest{
+			Key: map[string]types.AttributeValue{
+				"pk": &types.AttributeValueMemberS{Value: pk},
+				"sk": &types.AttributeValueMemberS{Value: questionRefSK},
+			},
+		},
+	}
+}
+
 type QuestionRepository struct {
 	Db        *dynamodb.Client
 	TableName string
@@ -27,110 +45,290 @@ func NewQuestionRepository(db *dynamodb.Client) *QuestionRepository {
 
 func (repo *QuestionRepository) Create(ctx context.Context, question *models.Question) error {
 	questionNew := &models.Question{
-		QId:    "question:" + question.QId,
-		PostId: "post:" + question.PostId,
-		Text:   question.Text,
-		UserId: question.UserId,
+		QId:       keys.Question.Key(question.QId),
+		PostId:    keys.Post.Key(question.PostId),
+		Text:      question.Text,
+		UserId:    question.UserId,
+		CreatedAt: question.CreatedAt,
+		UpdatedAt: question.UpdatedAt,
 	}
 	questionNewAv, err := attributevalue.MarshalMap(questionNew)
 	if err != nil {
 		return err
 	}
-	_, err = repo.Db.PutItem(ctx, &dynamodb.PutItemInput{
-		TableName: aws.String(repo.TableName),
-		Item:      questionNewAv,
+	refAv, err := attributevalue.MarshalMap(&models.QuestionRef{
+		QId:    keys.Question.Key(question.QId),
+		SK:     questionRefSK,
+		PostId: question.PostId,
+		UserId: question.UserId,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = repo.Db.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{
+			{
+				Put: &types.Put{
+					TableName: aws.String(repo.TableName),
+					Item:      questionNewAv,
+				},
+			},
+			{
+				Put: &types.Put{
+					TableName: aws.String(repo.TableName),
+					Item:      refAv,
+				},
+			},
+			countUpdate(repo.TableName, keys.Post.Key(question.PostId), "question_count", 1),
+		},
 	})
 	return err
 }
 
 func (repo *QuestionRepository) DeleteByQId(ctx context.Context, qId, pId, uId string) error {
-	result, err := repo.Db.DeleteItem(ctx, &dynamodb.DeleteItemInput{
-		TableName: aws.String(repo.TableName),
-		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":userId": &types.AttributeValueMemberS{Value: uId},
-		},
-		Key: map[string]types.AttributeValue{
-			"pk": &types.AttributeValueMemberS{Value: "post:" + pId},
-			"sk": &types.AttributeValueMemberS{Value: "question:" + qId},
+	_, err := repo.Db.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{
+			{
+				Delete: &types.Delete{
+					TableName: aws.String(repo.TableName),
+					ExpressionAttributeValues: map[string]types.AttributeValue{
+						":userId": &types.AttributeValueMemberS{Value: uId},
+					},
+					Key: map[string]types.AttributeValue{
+						"pk": &types.AttributeValueMemberS{Value: keys.Post.Key(pId)},
+						"sk": &types.AttributeValueMemberS{Value: keys.Question.Key(qId)},
+					},
+					ConditionExpression: aws.String("q_user_id = :userId"),
+				},
+			},
+			countUpdate(repo.TableName, keys.Post.Key(pId), "question_count", -1),
 		},
-		ConditionExpression: aws.String("q_user_id = :userId"),
-		ReturnValues:        types.ReturnValueAllOld,
 	})
 	if err != nil {
+		if utils.IsConditionFailed(err) {
+			return utils.NotYourQuestion
+		}
 		return err
 	}
-	if result.Attributes != nil {
-		var writeRequests []types.WriteRequest
-		var queryOutput *dynamodb.QueryOutput
-		var err error
-		queryInput := &dynamodb.QueryInput{
-			TableName:              aws.String(repo.TableName),
-			KeyConditionExpression: aws.String("pk = :pk AND begins_with(sk, :sk)"),
-			ExpressionAttributeValues: map[string]types.AttributeValue{
-				":pk": &types.AttributeValueMemberS{Value: "question:" + qId},
-				":sk": &types.AttributeValueMemberS{Value: "reply:"},
-			},
+	replies, err := deleteByPrefix(ctx, repo.Db, repo.TableName, keys.Question.Key(qId), keys.Reply.Prefix())
+	if err != nil {
+		return err
+	}
+	for _, reply := range replies {
+		rId, err := keys.Reply.Parse(reply)
+		if err != nil {
+			return err
 		}
-		for {
-			queryOutput, err = repo.Db.Query(ctx, queryInput)
-			if err != nil {
-				return err
-			}
-			if len(queryOutput.Items) == 0 {
-				break
-			}
-			for _, item := range queryOutput.Items {
-				writeRequests = append(writeRequests, types.WriteRequest{
-					DeleteRequest: &types.DeleteRequest{
-						Key: map[string]types.AttributeValue{
-							"pk": &types.AttributeValueMemberS{Value: item["pk"].(*types.AttributeValueMemberS).Value},
-							"sk": &types.AttributeValueMemberS{Value: item["sk"].(*types.AttributeValueMemberS).Value},
-						},
-					},
-				})
-			}
-			_, err := repo.Db.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{
-				RequestItems: map[string][]types.WriteRequest{
-					repo.TableName: writeRequests,
-				},
-			})
-			if err != nil {
-				return err
-			}
-			if queryOutput.LastEvaluatedKey == nil {
-				break
-			}
-			queryInput.ExclusiveStartKey = queryOutput.LastEvaluatedKey
-			writeRequests = nil
+		_, err = deleteByPrefix(ctx, repo.Db, repo.TableName, keys.Vote.Key(rId), keys.User.Prefix())
+		if err != nil {
+			return err
+		}
+		err = deleteReactions(ctx, repo.Db, repo.TableName, config.AnswerContent, rId)
+		if err != nil {
+			return err
 		}
 	}
-	return err
+	err = deleteReactions(ctx, repo.Db, repo.TableName, config.QuestionContent, qId)
+	if err != nil {
+		return err
+	}
+	return batchWrite(ctx, repo.Db, repo.TableName, []types.WriteRequest{countsDelete(keys.Question.Key(qId)), questionRefDelete(keys.Question.Key(qId))})
 }
 
 func (repo *QuestionRepository) GetAllQuestionsByPId(ctx context.Context, pId string) ([]*models.Question, error) {
-	result, err := repo.Db.Query(ctx, &dynamodb.QueryInput{
+	queryInput := &dynamodb.QueryInput{
 		TableName:              aws.String(repo.TableName),
 		KeyConditionExpression: aws.String("pk = :pk AND begins_with(sk, :sk)"),
 		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":pk": &types.AttributeValueMemberS{Value: "post:" + pId},
-			":sk": &types.AttributeValueMemberS{Value: "question:"},
+			":pk": &types.AttributeValueMemberS{Value: keys.Post.Key(pId)},
+			":sk": &types.AttributeValueMemberS{Value: keys.Question.Prefix()},
 		},
-	})
-	if err != nil {
-		return nil, err
 	}
 	var questions []*models.Question
-	for _, v := range result.Items {
-		var question models.Question
-		err := attributevalue.UnmarshalMap(v, &question)
+	for {
+		result, err := repo.Db.Query(ctx, queryInput)
 		if err != nil {
 			return nil, err
 		}
-		dtoQId := strings.Split(question.QId, ":")[1]
-		dtoPId := strings.Split(question.PostId, ":")[1]
-		question.PostId = dtoPId
-		question.QId = dtoQId
-		questions = append(questions, &question)
+		for _, v := range result.Items {
+			var question models.Question
+			err := attributevalue.UnmarshalMap(v, &question)
+			if err != nil {
+				return nil, err
+			}
+			if question.QId, err = keys.Question.Parse(question.QId); err != nil {
+				return nil, err
+			}
+			if question.PostId, err = keys.Post.Parse(question.PostId); err != nil {
+				return nil, err
+			}
+			questions = append(questions, &question)
+		}
+		if result.LastEvaluatedKey == nil {
+			break
+		}
+		queryInput.ExclusiveStartKey = result.LastEvaluatedKey
 	}
+	// question ids are random so the sort key gives no ordering, oldest first by creation time
+	sort.SliceStable(questions, func(i, j int) bool {
+		return questions[i].CreatedAt.Before(questions[j].CreatedAt)
+	})
 	return questions, nil
 }
+
+// GetQuestionRef finds the post and author of a question from its id alone, questions
+// asked before refs were written return NoQuestion
+func (repo *QuestionRepository) GetQuestionRef(ctx context.Context, qId string) (*models.QuestionRef, error) {
+	result, err := repo.Db.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(repo.TableName),
+		Key: map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{Value: keys.Question.Key(qId)},
+			"sk": &types.AttributeValueMemberS{Value: questionRefSK},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.Item == nil {
+		return nil, utils.NoQuestion
+	}
+	var ref models.QuestionRef
+	if err := attributevalue.UnmarshalMap(result.Item, &ref); err != nil {
+		return nil, err
+	}
+	ref.QId = qId
+	return &ref, nil
+}
+
+func (repo *QuestionRepository) GetQuestion(ctx context.Context, pId, qId string) (*models.Question, error) {
+	result, err := repo.Db.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(repo.TableName),
+		Key: map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{Value: keys.Post.Key(pId)},
+			"sk": &types.AttributeValueMemberS{Value: keys.Question.Key(qId)},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.Item == nil {
+		return nil, utils.NoQuestion
+	}
+	var question models.Question
+	if err := attributevalue.UnmarshalMap(result.Item, &question); err != nil {
+		return nil, err
+	}
+	question.PostId = pId
+	question.QId = qId
+	return &question, nil
+}
+
+func (repo *QuestionRepository) UpdateQuestion(ctx context.Context, pId, qId, uId, text string, editedAt time.Time) error {
+	question, err := repo.GetQuestion(ctx, pId, qId)
+	if err != nil {
+		return err
+	}
+	if question.UserId != uId {
+		return utils.NotYourQuestion
+	}
+	revision, err := revisionPut(repo.TableName, config.QuestionContent, qId, uId, "", question.Text, editedAt)
+	if err != nil {
+		return err
+	}
+	questionUpdate := types.TransactWriteItem{
+		Update: &types.Update{
+			TableName: aws.String(repo.TableName),
+			Key: map[string]types.AttributeValue{
+				"pk": &types.AttributeValueMemberS{Value: keys.Post.Key(pId)},
+				"sk": &types.AttributeValueMemberS{Value: keys.Question.Key(qId)},
+			},
+			UpdateExpression:    aws.String("SET #text = :text, edited_at = :editedAt, updated_at = :editedAt"),
+			ConditionExpression: aws.String("q_user_id = :userId AND #text = :previous"),
+			ExpressionAttributeNames: map[string]string{
+				"#text": "text",
+			},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":text":     &types.AttributeValueMemberS{Value: text},
+				":editedAt": &types.AttributeValueMemberS{Value: editedAt.Format(time.RFC3339)},
+				":userId":   &types.AttributeValueMemberS{Value: uId},
+				":previous": &types.AttributeValueMemberS{Value: question.Text},
+			},
+		},
+	}
+	_, err = repo.Db.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{questionUpdate, revision},
+	})
+	if utils.IsConditionFailed(err) {
+		return utils.EditConflict
+	}
+	return err
+}
+
+func (repo *QuestionRepository) SetQuestionHidden(ctx context.Context, pId, qId string, hidden bool) error {
+	err := setHidden(ctx, repo.Db, repo.TableName, []map[string]types.AttributeValue{
+		{
+			"pk": &types.AttributeValueMemberS{Value: keys.Post.Key(pId)},
+			"sk": &types.AttributeValueMemberS{Value: keys.Question.Key(qId)},
+		},
+	}, hidden)
+	if utils.IsConditionFailed(err) {
+		return utils.NoQuestion
+	}
+	return err
+}
+
+// BackfillRefs writes the ref item of every question asked before refs existed and returns how
+// many were written. Questions live in the partitions of their posts, so this scans the table.
+func (repo *QuestionRepository) BackfillRefs(ctx context.Context) (int, error) {
+	written := 0
+	scanInput := &dynamodb.ScanInput{
+		TableName:        aws.String(repo.TableName),
+		FilterExpression: aws.String("begins_with(pk, :pk) AND begins_with(sk, :sk)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": &types.AttributeValueMemberS{Value: keys.Post.Prefix()},
+			":sk": &types.AttributeValueMemberS{Value: keys.Question.Prefix()},
+		},
+		ProjectionExpression: aws.String("pk, sk, q_user_id"),
+	}
+	for {
+		scanOutput, err := repo.Db.Scan(ctx, scanInput)
+		if err != nil {
+			return written, err
+		}
+		for _, item := range scanOutput.Items {
+			var question models.Question
+			if err := attributevalue.UnmarshalMap(item, &question); err != nil {
+				return written, err
+			}
+			pId, err := keys.Post.Parse(question.PostId)
+			if err != nil {
+				return written, err
+			}
+			refAv, err := attributevalue.MarshalMap(&models.QuestionRef{
+				QId:    question.QId,
+				SK:     questionRefSK,
+				PostId: pId,
+				UserId: question.UserId,
+			})
+			if err != nil {
+				return written, err
+			}
+			_, err = repo.Db.PutItem(ctx, &dynamodb.PutItemInput{
+				TableName:           aws.String(repo.TableName),
+				Item:                refAv,
+				ConditionExpression: aws.String("attribute_not_exists(pk)"),
+			})
+			if utils.IsConditionFailed(err) {
+				continue
+			}
+			if err != nil {
+				return written, err
+			}
+			written++
+		}
+		if scanOutput.LastEvaluatedKey == nil {
+			return written, nil
+		}
+		scanInput.ExclusiveStartKey = scanOutput.LastEvaluatedKey
+	}
+}
@@ -0,0 +1,95 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"localeyes/config"
+	"localeyes/internal/keys"
+	"localeyes/internal/models"
+	"localeyes/internal/ratelimit"
+	"localeyes/utils"
+	"os"
+	"strconv"
+	"time"
+)
+
+const rateLimitSK = "bucket"
+
+// maxBucketAttempts bounds how often a bucket update is retried when concurrent requests race on it
+const maxBucketAttempts = 5
+
+var errBucketContended = errors.New("rate limit bucket kept changing during the update")
+
+// RateLimitRepository keeps one token bucket per class and subject under
+// "ratelimit:<class>:<subject>" so every Lambda instance shares the same limits
+type RateLimitRepository struct {
+	Db        *dynamodb.Client
+	TableName string
+}
+
+func NewRateLimitRepository(db *dynamodb.Client) *RateLimitRepository {
+	return &RateLimitRepository{
+		db,
+		os.Getenv("TABLE_NAME"),
+	}
+}
+
+// Allow takes a request from the bucket of subject. The bucket is read, refilled and written back
+// on the condition that nobody else wrote it in between, a lost race is retried with a fresh read.
+func (repo *RateLimitRepository) Allow(ctx context.Context, subject string, policy config.RateLimitPolicy) (*models.RateLimitDecision, error) {
+	key := map[string]types.AttributeValue{
+		"pk": &types.AttributeValueMemberS{Value: keys.RateLimit.Key(string(policy.Class), subject)},
+		"sk": &types.AttributeValueMemberS{Value: rateLimitSK},
+	}
+	for attempt := 0; attempt < maxBucketAttempts; attempt++ {
+		result, err := repo.Db.GetItem(ctx, &dynamodb.GetItemInput{
+			TableName:      aws.String(repo.TableName),
+			Key:            key,
+			ConsistentRead: aws.Bool(true),
+		})
+		if err != nil {
+			return nil, err
+		}
+		now := time.Now()
+		var bucket *models.RateLimitBucket
+		condition := "attribute_not_exists(pk)"
+		values := map[string]types.AttributeValue{}
+		if result.Item != nil {
+			var stored struct {
+				Tokens    float64 `dynamodbav:"tokens"`
+				UpdatedAt int64   `dynamodbav:"updated_at"`
+			}
+			if err := attributevalue.UnmarshalMap(result.Item, &stored); err != nil {
+				return nil, err
+			}
+			bucket = &models.RateLimitBucket{Tokens: stored.Tokens, UpdatedAt: time.UnixMicro(stored.UpdatedAt)}
+			condition = "updated_at = :previous"
+			values[":previous"] = &types.AttributeValueMemberN{Value: strconv.FormatInt(stored.UpdatedAt, 10)}
+		}
+		bucket, decision := ratelimit.Take(bucket, policy, now)
+		values[":tokens"] = &types.AttributeValueMemberN{Value: strconv.FormatFloat(bucket.Tokens, 'f', -1, 64)}
+		values[":updatedAt"] = &types.AttributeValueMemberN{Value: strconv.FormatInt(bucket.UpdatedAt.UnixMicro(), 10)}
+		// a bucket left alone for a window is full again, the same as one that does not exist
+		values[":ttl"] = &types.AttributeValueMemberN{Value: strconv.FormatInt(now.Add(policy.Window).Unix(), 10)}
+		_, err = repo.Db.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+			TableName:                 aws.String(repo.TableName),
+			Key:                       key,
+			UpdateExpression:          aws.String("SET tokens = :tokens, updated_at = :updatedAt, #ttl = :ttl"),
+			ConditionExpression:       aws.String(condition),
+			ExpressionAttributeNames:  map[string]string{"#ttl": "ttl"},
+			ExpressionAttributeValues: values,
+		})
+		if utils.IsConditionFailed(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return decision, nil
+	}
+	return nil, errBucketContended
+}
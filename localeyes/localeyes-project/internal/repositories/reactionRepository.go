@@ -0,0 +1,205 @@
+package repositories
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"localeyes/config"
+	"localeyes/internal/keys"
+	"localeyes/internal/models"
+	"os"
+	"strconv"
+	"strings"
+)
+
+type ReactionRepository struct {
+	Db        *dynamodb.Client
+	TableName string
+}
+
+func NewReactionRepository(db *dynamodb.Client) *ReactionRepository {
+	return &ReactionRepository{
+		db,
+		os.Getenv("TABLE_NAME"),
+	}
+}
+
+// Reactions on a target share the partition "reaction:<type>:<id>": one "user:<id>" item per
+// reacting user and a "counts" item holding one counter attribute per reaction type.
+func reactionPK(contentType config.ContentType, id string) string {
+	return keys.Reaction.Key(string(contentType), id)
+}
+
+func reactionCounter(reaction config.ReactionType) string {
+	return strings.ToLower(string(reaction))
+}
+
+func (repo *ReactionRepository) GetUserReaction(ctx context.Context, contentType config.ContentType, id, uId string) (config.ReactionType, error) {
+	result, err := repo.Db.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(repo.TableName),
+		Key: map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{Value: reactionPK(contentType, id)},
+			"sk": &types.AttributeValueMemberS{Value: keys.User.Key(uId)},
+		},
+	})
+	if err != nil {
+		return config.NoReaction, err
+	}
+	if result.Item == nil {
+		return config.NoReaction, nil
+	}
+	var reaction models.Reaction
+	if err := attributevalue.UnmarshalMap(result.Item, &reaction); err != nil {
+		return config.NoReaction, err
+	}
+	return reaction.Reaction, nil
+}
+
+// React replaces the user's reaction on the target, NoReaction removes it. The previous
+// reaction is returned so callers can keep denormalized counters in step.
+func (repo *ReactionRepository) React(ctx context.Context, contentType config.ContentType, id, uId string, reaction config.ReactionType) (config.ReactionType, error) {
+	previous, err := repo.GetUserReaction(ctx, contentType, id, uId)
+	if err != nil {
+		return config.NoReaction, err
+	}
+	if previous == reaction {
+		return previous, nil
+	}
+	pk := reactionPK(contentType, id)
+	reactionKey := map[string]types.AttributeValue{
+		"pk": &types.AttributeValueMemberS{Value: pk},
+		"sk": &types.AttributeValueMemberS{Value: keys.User.Key(uId)},
+	}
+	var reactionWrite types.TransactWriteItem
+	switch {
+	case reaction == config.NoReaction:
+		reactionWrite.Delete = &types.Delete{
+			TableName:           aws.String(repo.TableName),
+			Key:                 reactionKey,
+			ConditionExpression: aws.String("reaction = :previous"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":previous": &types.AttributeValueMemberS{Value: string(previous)},
+			},
+		}
+	case previous == config.NoReaction:
+		reactionWrite.Put = &types.Put{
+			TableName: aws.String(repo.TableName),
+			Item: map[string]types.AttributeValue{
+				"pk":       reactionKey["pk"],
+				"sk":       reactionKey["sk"],
+				"reaction": &types.AttributeValueMemberS{Value: string(reaction)},
+			},
+			ConditionExpression: aws.String("attribute_not_exists(pk)"),
+		}
+	default:
+		reactionWrite.Update = &types.Update{
+			TableName:           aws.String(repo.TableName),
+			Key:                 reactionKey,
+			UpdateExpression:    aws.String("SET reaction = :reaction"),
+			ConditionExpression: aws.String("reaction = :previous"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":reaction": &types.AttributeValueMemberS{Value: string(reaction)},
+				":previous": &types.AttributeValueMemberS{Value: string(previous)},
+			},
+		}
+	}
+
+	var additions []string
+	names := map[string]string{}
+	values := map[string]types.AttributeValue{}
+	if previous != config.NoReaction {
+		additions = append(additions, "#previous :minus")
+		names["#previous"] = reactionCounter(previous)
+		values[":minus"] = &types.AttributeValueMemberN{Value: "-1"}
+	}
+	if reaction != config.NoReaction {
+		additions = append(additions, "#reaction :plus")
+		names["#reaction"] = reactionCounter(reaction)
+		values[":plus"] = &types.AttributeValueMemberN{Value: "1"}
+	}
+	countsWrite := types.TransactWriteItem{
+		Update: &types.Update{
+			TableName: aws.String(repo.TableName),
+			Key: map[string]types.AttributeValue{
+				"pk": &types.AttributeValueMemberS{Value: pk},
+				"sk": &types.AttributeValueMemberS{Value: countsSK},
+			},
+			UpdateExpression:          aws.String("ADD " + strings.Join(additions, ", ")),
+			ExpressionAttributeNames:  names,
+			ExpressionAttributeValues: values,
+		},
+	}
+	_, err = repo.Db.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{reactionWrite, countsWrite},
+	})
+	return previous, err
+}
+
+func (repo *ReactionRepository) GetReactionSummaries(ctx context.Context, contentType config.ContentType, ids []string) (map[string]models.ReactionSummary, error) {
+	summaries := make(map[string]models.ReactionSummary)
+	var itemKeys []map[string]types.AttributeValue
+	seen := make(map[string]bool)
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		itemKeys = append(itemKeys, map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{Value: reactionPK(contentType, id)},
+			"sk": &types.AttributeValueMemberS{Value: countsSK},
+		})
+	}
+	// BatchGetItem accepts at most 100 keys per call
+	for start := 0; start < len(itemKeys); start += 100 {
+		end := start + 100
+		if end > len(itemKeys) {
+			end = len(itemKeys)
+		}
+		requestItems := map[string]types.KeysAndAttributes{
+			repo.TableName: {Keys: itemKeys[start:end]},
+		}
+		for len(requestItems) > 0 {
+			result, err := repo.Db.BatchGetItem(ctx, &dynamodb.BatchGetItemInput{
+				RequestItems: requestItems,
+			})
+			if err != nil {
+				return nil, err
+			}
+			for _, item := range result.Responses[repo.TableName] {
+				parts, err := keys.Reaction.ParseParts(item["pk"].(*types.AttributeValueMemberS).Value, 2)
+				if err != nil {
+					return nil, err
+				}
+				summary := make(models.ReactionSummary)
+				for _, reactionType := range config.ReactionTypes {
+					counter, ok := item[reactionCounter(reactionType)].(*types.AttributeValueMemberN)
+					if !ok {
+						continue
+					}
+					count, err := strconv.Atoi(counter.Value)
+					if err != nil {
+						return nil, err
+					}
+					if count > 0 {
+						summary[reactionType] = count
+					}
+				}
+				summaries[parts[1]] = summary
+			}
+			requestItems = result.UnprocessedKeys
+		}
+	}
+	return summaries, nil
+}
+
+// deleteReactions removes every reaction and the counter item of a target that is being deleted
+func deleteReactions(ctx context.Context, db *dynamodb.Client, tableName string, contentType config.ContentType, id string) error {
+	pk := reactionPK(contentType, id)
+	_, err := deleteByPrefix(ctx, db, tableName, pk, keys.User.Prefix())
+	if err != nil {
+		return err
+	}
+	return batchWrite(ctx, db, tableName, []types.WriteRequest{countsDelete(pk)})
+}
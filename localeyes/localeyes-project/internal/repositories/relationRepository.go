@@ -0,0 +1,221 @@
+package repositories
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"localeyes/internal/keys"
+	"localeyes/internal/models"
+	"localeyes/utils"
+	"os"
+	"time"
+)
+
+type RelationRepository struct {
+	Db        *dynamodb.Client
+	TableName string
+}
+
+func NewRelationRepository(db *dynamodb.Client) *RelationRepository {
+	return &RelationRepository{
+		db,
+		os.Getenv("TABLE_NAME"),
+	}
+}
+
+// A block is kept under "block:<blocker>" and mirrored under "blockedby:<blocked>" so both
+// sides of the mutual invisibility can be read with one query each. Mutes only matter to
+// the muting user and live under "mute:<uid>".
+func (repo *RelationRepository) Block(ctx context.Context, uId, blockedId string, createdAt time.Time) error {
+	block, err := attributevalue.MarshalMap(&models.Relation{
+		PK:        keys.Block.Key(uId),
+		UId:       keys.User.Key(blockedId),
+		CreatedAt: createdAt,
+	})
+	if err != nil {
+		return err
+	}
+	blockedBy, err := attributevalue.MarshalMap(&models.Relation{
+		PK:        keys.BlockedBy.Key(blockedId),
+		UId:       keys.User.Key(uId),
+		CreatedAt: createdAt,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = repo.Db.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{
+			{
+				Put: &types.Put{
+					TableName:           aws.String(repo.TableName),
+					Item:                block,
+					ConditionExpression: aws.String("attribute_not_exists(pk)"),
+				},
+			},
+			{
+				Put: &types.Put{
+					TableName: aws.String(repo.TableName),
+					Item:      blockedBy,
+				},
+			},
+		},
+	})
+	if utils.IsConditionFailed(err) {
+		return nil
+	}
+	return err
+}
+
+func (repo *RelationRepository) Unblock(ctx context.Context, uId, blockedId string) error {
+	_, err := repo.Db.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{
+			{
+				Delete: &types.Delete{
+					TableName:           aws.String(repo.TableName),
+					Key:                 edgeKey(keys.Block.Key(uId), blockedId),
+					ConditionExpression: aws.String("attribute_exists(pk)"),
+				},
+			},
+			{
+				Delete: &types.Delete{
+					TableName: aws.String(repo.TableName),
+					Key:       edgeKey(keys.BlockedBy.Key(blockedId), uId),
+				},
+			},
+		},
+	})
+	if utils.IsConditionFailed(err) {
+		return utils.NotBlocked
+	}
+	return err
+}
+
+func (repo *RelationRepository) Mute(ctx context.Context, uId, mutedId string, createdAt time.Time) error {
+	mute, err := attributevalue.MarshalMap(&models.Relation{
+		PK:        keys.Mute.Key(uId),
+		UId:       keys.User.Key(mutedId),
+		CreatedAt: createdAt,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = repo.Db.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           aws.String(repo.TableName),
+		Item:                mute,
+		ConditionExpression: aws.String("attribute_not_exists(pk)"),
+	})
+	if utils.IsConditionFailed(err) {
+		return nil
+	}
+	return err
+}
+
+func (repo *RelationRepository) Unmute(ctx context.Context, uId, mutedId string) error {
+	_, err := repo.Db.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName:           aws.String(repo.TableName),
+		Key:                 edgeKey(keys.Mute.Key(uId), mutedId),
+		ConditionExpression: aws.String("attribute_exists(pk)"),
+	})
+	if utils.IsConditionFailed(err) {
+		return utils.NotMuted
+	}
+	return err
+}
+
+func (repo *RelationRepository) GetBlocked(ctx context.Context, uId string) ([]*models.Relation, error) {
+	return repo.getRelations(ctx, keys.Block.Key(uId))
+}
+
+func (repo *RelationRepository) GetMuted(ctx context.Context, uId string) ([]*models.Relation, error) {
+	return repo.getRelations(ctx, keys.Mute.Key(uId))
+}
+
+func (repo *RelationRepository) getRelations(ctx context.Context, pk string) ([]*models.Relation, error) {
+	relations := make([]*models.Relation, 0)
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(repo.TableName),
+		KeyConditionExpression: aws.String("pk = :pk"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": &types.AttributeValueMemberS{Value: pk},
+		},
+	}
+	for {
+		result, err := repo.Db.Query(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range result.Items {
+			var relation models.Relation
+			if err := attributevalue.UnmarshalMap(item, &relation); err != nil {
+				return nil, err
+			}
+			if relation.UId, err = keys.User.Parse(relation.UId); err != nil {
+				return nil, err
+			}
+			relations = append(relations, &relation)
+		}
+		if result.LastEvaluatedKey == nil {
+			return relations, nil
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+}
+
+// GetHiddenUsers returns the users whose content uId must not see: everyone uId blocked,
+// everyone who blocked uId and, when includeMuted is set, everyone uId muted
+func (repo *RelationRepository) GetHiddenUsers(ctx context.Context, uId string, includeMuted bool) (map[string]bool, error) {
+	pks := []string{keys.Block.Key(uId), keys.BlockedBy.Key(uId)}
+	if includeMuted {
+		pks = append(pks, keys.Mute.Key(uId))
+	}
+	hidden := make(map[string]bool)
+	for _, pk := range pks {
+		relations, err := repo.getRelations(ctx, pk)
+		if err != nil {
+			return nil, err
+		}
+		for _, relation := range relations {
+			hidden[relation.UId] = true
+		}
+	}
+	return hidden, nil
+}
+
+// IsBlocked reports whether either user has blocked the other
+func (repo *RelationRepository) IsBlocked(ctx context.Context, uId, otherId string) (bool, error) {
+	result, err := repo.Db.BatchGetItem(ctx, &dynamodb.BatchGetItemInput{
+		RequestItems: map[string]types.KeysAndAttributes{
+			repo.TableName: {
+				Keys: []map[string]types.AttributeValue{
+					edgeKey(keys.Block.Key(uId), otherId),
+					edgeKey(keys.Block.Key(otherId), uId),
+				},
+				ProjectionExpression: aws.String("pk"),
+			},
+		},
+	})
+	if err != nil {
+		return false, err
+	}
+	if len(result.Responses[repo.TableName]) > 0 {
+		return true, nil
+	}
+	// both keys fit in one request so anything unprocessed is retried by a plain read
+	for _, unprocessed := range result.UnprocessedKeys {
+		for _, key := range unprocessed.Keys {
+			item, err := repo.Db.GetItem(ctx, &dynamodb.GetItemInput{
+				TableName: aws.String(repo.TableName),
+				Key:       key,
+			})
+			if err != nil {
+				return false, err
+			}
+			if item.Item != nil {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,258 @@
+package repositories
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"localeyes/config"
+	"localeyes/internal/keys"
+	"localeyes/internal/models"
+	"localeyes/utils"
+	"os"
+	"time"
+)
+
+// every case lives in the single "reports" partition so the moderation queue is one query
+const reportsPK = "reports"
+
+type ReportRepository struct {
+	Db        *dynamodb.Client
+	TableName string
+}
+
+func NewReportRepository(db *dynamodb.Client) *ReportRepository {
+	return &ReportRepository{
+		db,
+		os.Getenv("TABLE_NAME"),
+	}
+}
+
+func reportPK(targetType config.ContentType, targetId string) string {
+	return keys.Report.Key(string(targetType), targetId)
+}
+
+func caseKey(targetType config.ContentType, targetId string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"pk": &types.AttributeValueMemberS{Value: reportsPK},
+		"sk": &types.AttributeValueMemberS{Value: keys.Case.Key(string(targetType), targetId)},
+	}
+}
+
+// Report stores the user's report and bumps the case for the target in one transaction.
+// A case that was already resolved is reopened by a new report.
+func (repo *ReportRepository) Report(ctx context.Context, reportCase *models.ReportCase, report *models.Report) (*models.ReportCase, error) {
+	report.Target = reportPK(reportCase.TargetType, reportCase.TargetId)
+	report.UId = keys.User.Key(report.UId)
+	item, err := attributevalue.MarshalMap(report)
+	if err != nil {
+		return nil, err
+	}
+	values := map[string]types.AttributeValue{
+		":open":        &types.AttributeValueMemberS{Value: string(config.ReportOpen)},
+		":target_type": &types.AttributeValueMemberS{Value: string(reportCase.TargetType)},
+		":target_id":   &types.AttributeValueMemberS{Value: reportCase.TargetId},
+		":owner_id":    &types.AttributeValueMemberS{Value: reportCase.OwnerId},
+		":reported_at": &types.AttributeValueMemberS{Value: report.CreatedAt.Format(time.RFC3339Nano)},
+		":one":         &types.AttributeValueMemberN{Value: "1"},
+		":false":       &types.AttributeValueMemberBOOL{Value: false},
+	}
+	update := "SET #status = :open, target_type = :target_type, target_id = :target_id, owner_id = :owner_id, " +
+		"first_reported_at = if_not_exists(first_reported_at, :reported_at), last_reported_at = :reported_at, " +
+		"hidden = if_not_exists(hidden, :false)"
+	if reportCase.PostId != "" {
+		update += ", post_id = :post_id"
+		values[":post_id"] = &types.AttributeValueMemberS{Value: reportCase.PostId}
+	}
+	if reportCase.QuestionId != "" {
+		update += ", question_id = :question_id"
+		values[":question_id"] = &types.AttributeValueMemberS{Value: reportCase.QuestionId}
+	}
+	update += " REMOVE resolved_by, resolved_at ADD report_count :one"
+	_, err = repo.Db.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{
+			{
+				Put: &types.Put{
+					TableName:           aws.String(repo.TableName),
+					Item:                item,
+					ConditionExpression: aws.String("attribute_not_exists(pk)"),
+				},
+			},
+			{
+				Update: &types.Update{
+					TableName:                 aws.String(repo.TableName),
+					Key:                       caseKey(reportCase.TargetType, reportCase.TargetId),
+					UpdateExpression:          aws.String(update),
+					ExpressionAttributeNames:  map[string]string{"#status": "status"},
+					ExpressionAttributeValues: values,
+				},
+			},
+		},
+	})
+	if utils.IsConditionFailed(err) {
+		return nil, utils.AlreadyReported
+	}
+	if err != nil {
+		return nil, err
+	}
+	return repo.GetCase(ctx, reportCase.TargetType, reportCase.TargetId)
+}
+
+func (repo *ReportRepository) GetCase(ctx context.Context, targetType config.ContentType, targetId string) (*models.ReportCase, error) {
+	result, err := repo.Db.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(repo.TableName),
+		Key:            caseKey(targetType, targetId),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.Item == nil {
+		return nil, utils.NoReport
+	}
+	var reportCase models.ReportCase
+	if err := attributevalue.UnmarshalMap(result.Item, &reportCase); err != nil {
+		return nil, err
+	}
+	return &reportCase, nil
+}
+
+func (repo *ReportRepository) GetReports(ctx context.Context, targetType config.ContentType, targetId string) ([]*models.Report, error) {
+	reports := make([]*models.Report, 0)
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(repo.TableName),
+		KeyConditionExpression: aws.String("pk = :pk"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": &types.AttributeValueMemberS{Value: reportPK(targetType, targetId)},
+		},
+	}
+	for {
+		result, err := repo.Db.Query(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range result.Items {
+			var report models.Report
+			if err := attributevalue.UnmarshalMap(item, &report); err != nil {
+				return nil, err
+			}
+			if report.UId, err = keys.User.Parse(report.UId); err != nil {
+				return nil, err
+			}
+			reports = append(reports, &report)
+		}
+		if result.LastEvaluatedKey == nil {
+			return reports, nil
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+}
+
+// ListCases pages through the queue, an empty status lists every case. The status filter is
+// applied after the read so a page can come back short while a cursor is still returned.
+func (repo *ReportRepository) ListCases(ctx context.Context, status config.ReportStatus, limit int, cursor string) ([]*models.ReportCase, string, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(repo.TableName),
+		KeyConditionExpression: aws.String("pk = :pk AND begins_with(sk, :sk)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": &types.AttributeValueMemberS{Value: reportsPK},
+			":sk": &types.AttributeValueMemberS{Value: keys.Case.Prefix()},
+		},
+		Limit: aws.Int32(int32(limit)),
+	}
+	if status != "" {
+		input.FilterExpression = aws.String("#status = :status")
+		input.ExpressionAttributeNames = map[string]string{"#status": "status"}
+		input.ExpressionAttributeValues[":status"] = &types.AttributeValueMemberS{Value: string(status)}
+	}
+	if cursor != "" {
+		sk, err := utils.DecodeCursor(cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		input.ExclusiveStartKey = map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{Value: reportsPK},
+			"sk": &types.AttributeValueMemberS{Value: sk},
+		}
+	}
+	result, err := repo.Db.Query(ctx, input)
+	if err != nil {
+		return nil, "", err
+	}
+	cases := make([]*models.ReportCase, 0, len(result.Items))
+	for _, item := range result.Items {
+		var reportCase models.ReportCase
+		if err := attributevalue.UnmarshalMap(item, &reportCase); err != nil {
+			return nil, "", err
+		}
+		cases = append(cases, &reportCase)
+	}
+	next := ""
+	if result.LastEvaluatedKey != nil {
+		next = utils.EncodeCursor(result.LastEvaluatedKey["sk"].(*types.AttributeValueMemberS).Value)
+	}
+	return cases, next, nil
+}
+
+func (repo *ReportRepository) SetCaseHidden(ctx context.Context, targetType config.ContentType, targetId string, hidden bool) error {
+	_, err := repo.Db.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:        aws.String(repo.TableName),
+		Key:              caseKey(targetType, targetId),
+		UpdateExpression: aws.String("SET hidden = :hidden"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":hidden": &types.AttributeValueMemberBOOL{Value: hidden},
+		},
+		ConditionExpression: aws.String("attribute_exists(pk)"),
+	})
+	if utils.IsConditionFailed(err) {
+		return utils.NoReport
+	}
+	return err
+}
+
+// Resolve closes an open case, resolving a case twice is rejected so two admins cannot act on it at once
+func (repo *ReportRepository) Resolve(ctx context.Context, targetType config.ContentType, targetId string, status config.ReportStatus, adminId string, resolvedAt time.Time) error {
+	_, err := repo.Db.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:        aws.String(repo.TableName),
+		Key:              caseKey(targetType, targetId),
+		UpdateExpression: aws.String("SET #status = :status, resolved_by = :admin, resolved_at = :at"),
+		ExpressionAttributeNames: map[string]string{
+			"#status": "status",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":status": &types.AttributeValueMemberS{Value: string(status)},
+			":admin":  &types.AttributeValueMemberS{Value: adminId},
+			":at":     &types.AttributeValueMemberS{Value: resolvedAt.Format(time.RFC3339Nano)},
+			":open":   &types.AttributeValueMemberS{Value: string(config.ReportOpen)},
+		},
+		ConditionExpression: aws.String("#status = :open"),
+	})
+	if utils.IsConditionFailed(err) {
+		return utils.ReportNotOpen
+	}
+	return err
+}
+
+// setHidden flags or unflags content as hidden on every copy of it, the condition makes a
+// missing item fail instead of creating a stub
+func setHidden(ctx context.Context, db *dynamodb.Client, table string, keys []map[string]types.AttributeValue, hidden bool) error {
+	items := make([]types.TransactWriteItem, 0, len(keys))
+	for _, key := range keys {
+		update := &types.Update{
+			TableName:           aws.String(table),
+			Key:                 key,
+			UpdateExpression:    aws.String("REMOVE hidden"),
+			ConditionExpression: aws.String("attribute_exists(pk)"),
+		}
+		if hidden {
+			update.UpdateExpression = aws.String("SET hidden = :hidden")
+			update.ExpressionAttributeValues = map[string]types.AttributeValue{
+				":hidden": &types.AttributeValueMemberBOOL{Value: true},
+			}
+		}
+		items = append(items, types.TransactWriteItem{Update: update})
+	}
+	_, err := db.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: items})
+	return err
+}
@@ -0,0 +1,85 @@
+package repositories
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"localeyes/config"
+	"localeyes/internal/keys"
+	"localeyes/internal/models"
+	"os"
+	"time"
+)
+
+type RevisionRepository struct {
+	Db        *dynamodb.Client
+	TableName string
+}
+
+func NewRevisionRepository(db *dynamodb.Client) *RevisionRepository {
+	return &RevisionRepository{
+		db,
+		os.Getenv("TABLE_NAME"),
+	}
+}
+
+func (repo *RevisionRepository) GetRevisions(ctx context.Context, contentType config.ContentType, id string) ([]*models.Revision, error) {
+	queryInput := &dynamodb.QueryInput{
+		TableName:              aws.String(repo.TableName),
+		KeyConditionExpression: aws.String("pk = :pk"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": &types.AttributeValueMemberS{Value: revisionPK(contentType, id)},
+		},
+		ScanIndexForward: aws.Bool(false),
+	}
+	revisions := make([]*models.Revision, 0)
+	for {
+		result, err := repo.Db.Query(ctx, queryInput)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range result.Items {
+			var revision models.Revision
+			if err := attributevalue.UnmarshalMap(item, &revision); err != nil {
+				return nil, err
+			}
+			revisions = append(revisions, &revision)
+		}
+		if result.LastEvaluatedKey == nil {
+			break
+		}
+		queryInput.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+	return revisions, nil
+}
+
+func revisionPK(contentType config.ContentType, id string) string {
+	return keys.Revision.Key(string(contentType), id)
+}
+
+// revisionPut builds the write that stores the content being replaced so it can be
+// committed in the same transaction as the edit itself
+func revisionPut(tableName string, contentType config.ContentType, id, editorId, title, text string, editedAt time.Time) (types.TransactWriteItem, error) {
+	revision := &models.Revision{
+		PK:       revisionPK(contentType, id),
+		SK:       editedAt.UTC().Format(config.SortableTime),
+		Type:     contentType,
+		EditorId: editorId,
+		Title:    title,
+		Text:     text,
+		EditedAt: editedAt,
+	}
+	revisionAv, err := attributevalue.MarshalMap(revision)
+	if err != nil {
+		return types.TransactWriteItem{}, err
+	}
+	return types.TransactWriteItem{
+		Put: &types.Put{
+			TableName:           aws.String(tableName),
+			Item:                revisionAv,
+			ConditionExpression: aws.String("attribute_not_exists(pk)"),
+		},
+	}, nil
+}
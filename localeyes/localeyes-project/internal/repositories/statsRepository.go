@@ -0,0 +1,210 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"localeyes/config"
+	"localeyes/internal/keys"
+	"localeyes/internal/models"
+	"localeyes/utils"
+	"math/rand/v2"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Counters are kept under "stats:day" and "stats:week" with one item per bucket, and under
+// "stats" for all time spread over the items "totals#0" to "totals#<StatsTotalShards-1>", so
+// every statistic is read with a query instead of a scan
+
+type StatsRepository struct {
+	Db        *dynamodb.Client
+	TableName string
+}
+
+func NewStatsRepository(db *dynamodb.Client) *StatsRepository {
+	return &StatsRepository{
+		db,
+		os.Getenv("TABLE_NAME"),
+	}
+}
+
+func statsPeriod(granularity config.StatsGranularity, at time.Time) string {
+	at = at.UTC()
+	if granularity == config.StatsWeek {
+		year, week := at.ISOWeek()
+		return fmt.Sprintf(config.StatsWeekLayout, year, week)
+	}
+	return at.Format(config.StatsDayLayout)
+}
+
+func statsKey(pk, sk string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"pk": &types.AttributeValueMemberS{Value: pk},
+		"sk": &types.AttributeValueMemberS{Value: sk},
+	}
+}
+
+// totalsShard picks the all time counter item a write adds to
+func totalsShard() string {
+	return fmt.Sprintf("totals#%d", rand.IntN(config.StatsTotalShards))
+}
+
+func statsUpdate(tableName string, key map[string]types.AttributeValue, counts map[config.StatsMetric]int) *types.Update {
+	var additions []string
+	names := map[string]string{}
+	values := map[string]types.AttributeValue{}
+	i := 0
+	for metric, delta := range counts {
+		additions = append(additions, fmt.Sprintf("#m%d :d%d", i, i))
+		names[fmt.Sprintf("#m%d", i)] = string(metric)
+		values[fmt.Sprintf(":d%d", i)] = &types.AttributeValueMemberN{Value: strconv.Itoa(delta)}
+		i++
+	}
+	return &types.Update{
+		TableName:                 aws.String(tableName),
+		Key:                       key,
+		UpdateExpression:          aws.String("ADD " + strings.Join(additions, ", ")),
+		ExpressionAttributeNames:  names,
+		ExpressionAttributeValues: values,
+	}
+}
+
+// Increment adds counts to the day and week buckets of at and to a shard of the totals. Each
+// item is updated on its own, ADD needs no read and the counters are only statistics, so a
+// failed update costs one bucket its counts instead of conflicting with every other write to
+// the same items the way a transaction over all three does under load.
+func (repo *StatsRepository) Increment(ctx context.Context, at time.Time, counts map[config.StatsMetric]int) error {
+	if len(counts) == 0 {
+		return nil
+	}
+	updates := []*types.Update{
+		statsUpdate(repo.TableName, statsKey(keys.Stats.Key(string(config.StatsDay)), statsPeriod(config.StatsDay, at)), counts),
+		statsUpdate(repo.TableName, statsKey(keys.Stats.Key(string(config.StatsWeek)), statsPeriod(config.StatsWeek, at)), counts),
+		statsUpdate(repo.TableName, statsKey(string(keys.Stats), totalsShard()), counts),
+	}
+	var errs []error
+	for _, update := range updates {
+		_, err := repo.Db.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+			TableName:                 update.TableName,
+			Key:                       update.Key,
+			UpdateExpression:          update.UpdateExpression,
+			ExpressionAttributeNames:  update.ExpressionAttributeNames,
+			ExpressionAttributeValues: update.ExpressionAttributeValues,
+		})
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// MarkActive counts the user as active in the day and week of at, once per bucket. A marker
+// item per user and bucket makes the count distinct, it expires once the bucket is over.
+func (repo *StatsRepository) MarkActive(ctx context.Context, uId string, at time.Time) error {
+	ttl := strconv.FormatInt(at.Add(config.ActiveMarkerTTL).Unix(), 10)
+	for _, granularity := range []config.StatsGranularity{config.StatsDay, config.StatsWeek} {
+		period := statsPeriod(granularity, at)
+		_, err := repo.Db.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+			TransactItems: []types.TransactWriteItem{
+				{
+					Put: &types.Put{
+						TableName: aws.String(repo.TableName),
+						Item: map[string]types.AttributeValue{
+							"pk":  &types.AttributeValueMemberS{Value: keys.Active.Key(period)},
+							"sk":  &types.AttributeValueMemberS{Value: keys.User.Key(uId)},
+							"ttl": &types.AttributeValueMemberN{Value: ttl},
+						},
+						ConditionExpression: aws.String("attribute_not_exists(pk)"),
+					},
+				},
+				{Update: statsUpdate(repo.TableName, statsKey(keys.Stats.Key(string(granularity)), period), map[config.StatsMetric]int{config.ActiveUsersMetric: 1})},
+			},
+		})
+		if err != nil && !utils.IsConditionFailed(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetSeries returns the buckets between from and to, oldest first. Buckets without any
+// activity have no item and are left out.
+func (repo *StatsRepository) GetSeries(ctx context.Context, granularity config.StatsGranularity, from, to time.Time) ([]*models.StatsBucket, error) {
+	buckets := make([]*models.StatsBucket, 0)
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(repo.TableName),
+		KeyConditionExpression: aws.String("pk = :pk AND sk BETWEEN :from AND :to"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk":   &types.AttributeValueMemberS{Value: keys.Stats.Key(string(granularity))},
+			":from": &types.AttributeValueMemberS{Value: statsPeriod(granularity, from)},
+			":to":   &types.AttributeValueMemberS{Value: statsPeriod(granularity, to)},
+		},
+	}
+	for {
+		result, err := repo.Db.Query(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range result.Items {
+			buckets = append(buckets, &models.StatsBucket{
+				Period: item["sk"].(*types.AttributeValueMemberS).Value,
+				Counts: statsCounts(item),
+			})
+		}
+		if result.LastEvaluatedKey == nil {
+			return buckets, nil
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+}
+
+// GetTotals sums the shards of the all time counters. The item "totals" that held them before
+// they were sharded starts with the same prefix and is added in as well.
+func (repo *StatsRepository) GetTotals(ctx context.Context) (map[config.StatsMetric]int, error) {
+	totals := make(map[config.StatsMetric]int)
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(repo.TableName),
+		KeyConditionExpression: aws.String("pk = :pk AND begins_with(sk, :sk)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": &types.AttributeValueMemberS{Value: string(keys.Stats)},
+			":sk": &types.AttributeValueMemberS{Value: "totals"},
+		},
+	}
+	for {
+		result, err := repo.Db.Query(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range result.Items {
+			for metric, count := range statsCounts(item) {
+				totals[metric] += count
+			}
+		}
+		if result.LastEvaluatedKey == nil {
+			return totals, nil
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+}
+
+// statsCounts reads every numeric attribute of a counter item as a metric
+func statsCounts(item map[string]types.AttributeValue) map[config.StatsMetric]int {
+	counts := make(map[config.StatsMetric]int)
+	for name, value := range item {
+		number, ok := value.(*types.AttributeValueMemberN)
+		if !ok {
+			continue
+		}
+		count, err := strconv.Atoi(number.Value)
+		if err == nil {
+			counts[config.StatsMetric(name)] = count
+		}
+	}
+	return counts
+}
@@ -0,0 +1,168 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"localeyes/config"
+	"localeyes/internal/keys"
+	"localeyes/utils"
+	"os"
+	"time"
+)
+
+// TableRepository reads and writes raw items for operators, it knows which keys belong
+// together but nothing about what the items mean
+type TableRepository struct {
+	Db        *dynamodb.Client
+	TableName string
+}
+
+func NewTableRepository(db *dynamodb.Client) *TableRepository {
+	return &TableRepository{
+		db,
+		os.Getenv("TABLE_NAME"),
+	}
+}
+
+// Partition returns every item stored under pk
+func (repo *TableRepository) Partition(ctx context.Context, pk string) ([]map[string]types.AttributeValue, error) {
+	var items []map[string]types.AttributeValue
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(repo.TableName),
+		KeyConditionExpression: aws.String("pk = :pk"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": &types.AttributeValueMemberS{Value: pk},
+		},
+	}
+	for {
+		result, err := repo.Db.Query(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, result.Items...)
+		if result.LastEvaluatedKey == nil {
+			return items, nil
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+}
+
+// ScanPrefix returns every item whose partition key starts with prefix. It reads the whole table.
+func (repo *TableRepository) ScanPrefix(ctx context.Context, prefix string) ([]map[string]types.AttributeValue, error) {
+	var items []map[string]types.AttributeValue
+	input := &dynamodb.ScanInput{
+		TableName:        aws.String(repo.TableName),
+		FilterExpression: aws.String("begins_with(pk, :prefix)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":prefix": &types.AttributeValueMemberS{Value: prefix},
+		},
+	}
+	for {
+		result, err := repo.Db.Scan(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, result.Items...)
+		if result.LastEvaluatedKey == nil {
+			return items, nil
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+}
+
+func (repo *TableRepository) getItem(ctx context.Context, pk, sk string) (map[string]types.AttributeValue, error) {
+	result, err := repo.Db.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(repo.TableName),
+		Key: map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{Value: pk},
+			"sk": &types.AttributeValueMemberS{Value: sk},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.Item, nil
+}
+
+// PostItems returns every item that makes up a post: its own partition with the owner,
+// questions, comments and counters, the copies kept under its owner, the posts feed and the
+// notifications, and the partitions of its likes, reactions, bookmarks, revisions and reports
+func (repo *TableRepository) PostItems(ctx context.Context, pId string) ([]map[string]types.AttributeValue, error) {
+	items, err := repo.Partition(ctx, keys.Post.Key(pId))
+	if err != nil {
+		return nil, err
+	}
+	var ownerId string
+	for _, item := range items {
+		sk, _ := item["sk"].(*types.AttributeValueMemberS)
+		owner, ok := item["user_id"].(*types.AttributeValueMemberS)
+		if sk != nil && sk.Value == postOwnerSK && ok {
+			ownerId = owner.Value
+		}
+	}
+	if ownerId == "" {
+		return nil, utils.NoPost
+	}
+	post, err := repo.getItem(ctx, keys.User.Key(ownerId), keys.Post.Key(pId))
+	if err != nil {
+		return nil, err
+	}
+	if post != nil {
+		items = append(items, post)
+		postType, _ := post["type"].(*types.AttributeValueMemberS)
+		createdAt, _ := post["created_at"].(*types.AttributeValueMemberS)
+		if postType != nil && createdAt != nil {
+			at, err := time.Parse(time.RFC3339, createdAt.Value)
+			if err == nil {
+				feed, err := repo.getItem(ctx, keys.Posts, keys.ListingKey(config.Filter(postType.Value), at, pId))
+				if err != nil {
+					return nil, err
+				}
+				if feed != nil {
+					items = append(items, feed)
+				}
+			}
+		}
+	}
+	for _, key := range [][2]string{{keys.Notifications, keys.Post.Key(pId)}, {reportsPK, keys.Case.Key(string(config.PostContent), pId)}} {
+		item, err := repo.getItem(ctx, key[0], key[1])
+		if err != nil {
+			return nil, err
+		}
+		if item != nil {
+			items = append(items, item)
+		}
+	}
+	for _, pk := range []string{
+		keys.Like.Key(pId),
+		keys.Bookmark.Key(pId),
+		reactionPK(config.PostContent, pId),
+		revisionPK(config.PostContent, pId),
+		reportPK(config.PostContent, pId),
+	} {
+		partition, err := repo.Partition(ctx, pk)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, partition...)
+	}
+	return items, nil
+}
+
+// PutItems writes the items as they are, replacing items with the same keys
+func (repo *TableRepository) PutItems(ctx context.Context, items []map[string]types.AttributeValue) error {
+	writeRequests := make([]types.WriteRequest, 0, len(items))
+	for i, item := range items {
+		if item["pk"] == nil || item["sk"] == nil {
+			return fmt.Errorf("item %d has no pk or sk", i+1)
+		}
+		writeRequests = append(writeRequests, types.WriteRequest{
+			PutRequest: &types.PutRequest{Item: item},
+		})
+	}
+	return batchWrite(ctx, repo.Db, repo.TableName, writeRequests)
+}
@@ -0,0 +1,412 @@
+package repositories
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"localeyes/config"
+	"localeyes/internal/keys"
+	"localeyes/internal/models"
+	"localeyes/utils"
+	"strings"
+	"time"
+)
+
+// The admin user search reads index partitions instead of filtering the user items. Every user
+// has an item under "uindex:all", "uindex:status:<status>", "uindex:city:<city>" and
+// "uindex:tag:<tag>" sorted by signup time, and one under "uindex:username" sorted by username.
+// The number of users in each partition is kept on "count:<partition>".
+
+// userIndexEntries returns the index items of a user, keyed by partition and sort key
+func userIndexEntries(user *models.UserIndexEntry) map[string]*models.UserIndexEntry {
+	var createdAt time.Time
+	if user.CreatedAt != nil {
+		createdAt = *user.CreatedAt
+	}
+	bySignup := keys.Sorted(createdAt, user.UId)
+	itemKeys := [][2]string{
+		{keys.Index.Key("all"), bySignup},
+		{keys.Index.Key("status", string(user.Status)), bySignup},
+		{keys.Index.Key("city", strings.ToLower(user.City)), bySignup},
+		{keys.Index.Key("username"), keys.SortedBy(strings.ToLower(user.Username), user.UId)},
+	}
+	if user.Tag != "" {
+		itemKeys = append(itemKeys, [2]string{keys.Index.Key("tag", user.Tag), bySignup})
+	}
+	entries := make(map[string]*models.UserIndexEntry, len(itemKeys))
+	for _, key := range itemKeys {
+		entry := *user
+		entry.PK = key[0]
+		entry.SK = key[1]
+		entries[key[0]+"/"+key[1]] = &entry
+	}
+	return entries
+}
+
+func userIndexEntry(uId, username, email, city, tag string, isActive bool, createdAt *time.Time, state models.AccountState) *models.UserIndexEntry {
+	return &models.UserIndexEntry{
+		UId:       uId,
+		Username:  username,
+		Email:     email,
+		City:      city,
+		Tag:       tag,
+		Status:    state.CurrentStatus(isActive),
+		CreatedAt: createdAt,
+	}
+}
+
+// syncUserIndex moves the index items of a user from before to after, either may be nil.
+// Items are only counted when they are created or removed, so syncing twice is harmless.
+func (repo *UserRepository) syncUserIndex(ctx context.Context, before, after *models.UserIndexEntry) error {
+	old := make(map[string]*models.UserIndexEntry)
+	if before != nil {
+		old = userIndexEntries(before)
+	}
+	current := make(map[string]*models.UserIndexEntry)
+	if after != nil {
+		current = userIndexEntries(after)
+	}
+	for key, entry := range current {
+		item, err := attributevalue.MarshalMap(entry)
+		if err != nil {
+			return err
+		}
+		if _, ok := old[key]; !ok {
+			_, err = repo.Db.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+				TransactItems: []types.TransactWriteItem{
+					{
+						Put: &types.Put{
+							TableName:           aws.String(repo.TableName),
+							Item:                item,
+							ConditionExpression: aws.String("attribute_not_exists(pk)"),
+						},
+					},
+					countUpdate(repo.TableName, keys.CountKey(entry.PK), "user_count", 1),
+				},
+			})
+			if !utils.IsConditionFailed(err) {
+				if err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		// the item already exists, only its summary is refreshed
+		_, err = repo.Db.PutItem(ctx, &dynamodb.PutItemInput{
+			TableName: aws.String(repo.TableName),
+			Item:      item,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	for key, entry := range old {
+		if _, ok := current[key]; ok {
+			continue
+		}
+		_, err := repo.Db.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+			TransactItems: []types.TransactWriteItem{
+				{
+					Delete: &types.Delete{
+						TableName: aws.String(repo.TableName),
+						Key: map[string]types.AttributeValue{
+							"pk": &types.AttributeValueMemberS{Value: entry.PK},
+							"sk": &types.AttributeValueMemberS{Value: entry.SK},
+						},
+						ConditionExpression: aws.String("attribute_exists(pk)"),
+					},
+				},
+				countUpdate(repo.TableName, keys.CountKey(entry.PK), "user_count", -1),
+			},
+		})
+		if err != nil && !utils.IsConditionFailed(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// indexedUser reads the index summary of a user from their email item, nil if there is none
+func (repo *UserRepository) indexedUser(ctx context.Context, email string) (*models.UserIndexEntry, error) {
+	result, err := repo.Db.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(repo.TableName),
+		Key: map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{Value: keys.Users},
+			"sk": &types.AttributeValueMemberS{Value: keys.Email.Key(email)},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil || result.Item == nil {
+		return nil, err
+	}
+	var user models.UserSKEmail
+	if err := attributevalue.UnmarshalMap(result.Item, &user); err != nil {
+		return nil, err
+	}
+	return userIndexEntry(user.UId, user.Username, email, user.City, user.Tag, user.IsActive, user.CreatedAt, user.AccountState), nil
+}
+
+// signupPartitions returns the index partition of every filter of search that has one, keyed
+// by the filter's name
+func signupPartitions(search *models.UserSearch) map[string]string {
+	partitions := make(map[string]string)
+	if search.Status != "" {
+		partitions["status"] = keys.Index.Key("status", string(search.Status))
+	}
+	if search.City != "" {
+		partitions["city"] = keys.Index.Key("city", strings.ToLower(search.City))
+	}
+	if search.Tag != "" {
+		partitions["tag"] = keys.Index.Key("tag", search.Tag)
+	}
+	return partitions
+}
+
+// searchFilters counts the filters of search, a date range counts as one
+func searchFilters(search *models.UserSearch) int {
+	filters := 0
+	for _, set := range []bool{
+		search.UsernamePrefix != "",
+		search.City != "",
+		search.Tag != "",
+		search.Status != "",
+		!search.From.IsZero() || !search.To.IsZero(),
+	} {
+		if set {
+			filters++
+		}
+	}
+	return filters
+}
+
+// SearchUsers returns one page of index entries matching search. Of the filters that have a
+// partition of their own the one whose partition holds the fewest users is read, the cursor
+// names it so every page reads the same one, and the remaining filters are checked against the
+// summaries kept on the index items. Partitions are read until the page is full or they end.
+// The count of the partition is only an estimate of the matches when it has no other filters
+// to apply, otherwise no estimate is returned.
+func (repo *UserRepository) SearchUsers(ctx context.Context, search *models.UserSearch) ([]*models.UserIndexEntry, string, *int, error) {
+	entries := make([]*models.UserIndexEntry, 0, search.Limit)
+	if search.Email != "" {
+		entry, err := repo.indexedUser(ctx, search.Email)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		if entry != nil && matchesUserSearch(entry, search) {
+			entries = append(entries, entry)
+		}
+		total := len(entries)
+		return entries, "", &total, nil
+	}
+
+	var partition, startSK string
+	if search.Cursor != "" {
+		position, err := utils.DecodeCursor(search.Cursor)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		var ok bool
+		partition, startSK, ok = strings.Cut(position, "|")
+		if !ok {
+			return nil, "", nil, utils.InvalidCursor
+		}
+	}
+
+	var pk, condition string
+	// the filters the partition applies by itself
+	applied := 0
+	var counts map[string]*models.Counts
+	values := map[string]types.AttributeValue{}
+	if search.Sort == config.SortByUsername {
+		partition = "username"
+		pk = keys.Index.Key("username")
+		condition = "pk = :pk"
+		if search.UsernamePrefix != "" {
+			condition += " AND begins_with(sk, :prefix)"
+			values[":prefix"] = &types.AttributeValueMemberS{Value: strings.ToLower(search.UsernamePrefix)}
+		}
+	} else {
+		partitions := signupPartitions(search)
+		countKeys := make([]string, 0, len(partitions)+1)
+		for _, candidate := range partitions {
+			countKeys = append(countKeys, keys.CountKey(candidate))
+		}
+		countKeys = append(countKeys, keys.CountKey(keys.Index.Key("all")))
+		var err error
+		counts, err = getCounts(ctx, repo.Db, repo.TableName, countKeys)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		if partition == "" {
+			partition = "all"
+			fewest := -1
+			for _, name := range []string{"status", "city", "tag"} {
+				candidate, ok := partitions[name]
+				if !ok {
+					continue
+				}
+				// a partition without a count holds no users yet
+				users := 0
+				if count, ok := counts[keys.CountKey(candidate)]; ok {
+					users = count.UserCount
+				}
+				if fewest < 0 || users < fewest {
+					partition, fewest = name, users
+				}
+			}
+		}
+		if partition == "all" {
+			pk = keys.Index.Key("all")
+		} else {
+			var ok bool
+			if pk, ok = partitions[partition]; !ok {
+				return nil, "", nil, utils.InvalidCursor
+			}
+			applied = 1
+		}
+		// "0" sorts before and "~" after every timestamp prefix
+		from, to := "0", "~"
+		if !search.From.IsZero() {
+			from = search.From.UTC().Format(config.SortableTime)
+		}
+		if !search.To.IsZero() {
+			to = keys.SortedUntil(search.To)
+		}
+		condition = "pk = :pk AND sk BETWEEN :from AND :to"
+		values[":from"] = &types.AttributeValueMemberS{Value: from}
+		values[":to"] = &types.AttributeValueMemberS{Value: to}
+	}
+	exact := searchFilters(search) == applied
+	values[":pk"] = &types.AttributeValueMemberS{Value: pk}
+	// items the partition does not filter by itself are skipped, so it is read in bigger pages
+	pageSize := search.Limit
+	if !exact {
+		pageSize = config.MaxPageSize
+	}
+	input := &dynamodb.QueryInput{
+		TableName:                 aws.String(repo.TableName),
+		KeyConditionExpression:    aws.String(condition),
+		ExpressionAttributeValues: values,
+		ScanIndexForward:          aws.Bool(!search.Desc),
+		Limit:                     aws.Int32(int32(pageSize)),
+	}
+	if startSK != "" {
+		input.ExclusiveStartKey = map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{Value: pk},
+			"sk": &types.AttributeValueMemberS{Value: startSK},
+		}
+	}
+	next := ""
+	for len(entries) < search.Limit {
+		result, err := repo.Db.Query(ctx, input)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		for i, item := range result.Items {
+			var entry models.UserIndexEntry
+			if err := attributevalue.UnmarshalMap(item, &entry); err != nil {
+				return nil, "", nil, err
+			}
+			if !matchesUserSearch(&entry, search) {
+				continue
+			}
+			entries = append(entries, &entry)
+			if len(entries) == search.Limit {
+				if i < len(result.Items)-1 || result.LastEvaluatedKey != nil {
+					next = utils.EncodeCursor(partition + "|" + entry.SK)
+				}
+				break
+			}
+		}
+		if result.LastEvaluatedKey == nil {
+			break
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+
+	if !exact {
+		return entries, next, nil, nil
+	}
+	if counts == nil {
+		var err error
+		counts, err = getCounts(ctx, repo.Db, repo.TableName, []string{keys.CountKey(pk)})
+		if err != nil {
+			return nil, "", nil, err
+		}
+	}
+	total := 0
+	if count, ok := counts[keys.CountKey(pk)]; ok {
+		total = count.UserCount
+	}
+	return entries, next, &total, nil
+}
+
+// matchesUserSearch checks the filters that the searched partition does not already apply
+func matchesUserSearch(entry *models.UserIndexEntry, search *models.UserSearch) bool {
+	if search.UsernamePrefix != "" && !strings.HasPrefix(strings.ToLower(entry.Username), strings.ToLower(search.UsernamePrefix)) {
+		return false
+	}
+	if search.City != "" && !strings.EqualFold(entry.City, search.City) {
+		return false
+	}
+	if search.Tag != "" && entry.Tag != search.Tag {
+		return false
+	}
+	if search.Status != "" && entry.Status != search.Status {
+		return false
+	}
+	if !search.From.IsZero() || !search.To.IsZero() {
+		if entry.CreatedAt == nil {
+			return false
+		}
+		if !search.From.IsZero() && entry.CreatedAt.Before(search.From) {
+			return false
+		}
+		if !search.To.IsZero() && entry.CreatedAt.After(search.To) {
+			return false
+		}
+	}
+	return true
+}
+
+// BackfillSearchIndex indexes every user, users that are already indexed only get their
+// summaries refreshed. It returns how many users were read.
+func (repo *UserRepository) BackfillSearchIndex(ctx context.Context) (int, error) {
+	read := 0
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(repo.TableName),
+		KeyConditionExpression: aws.String("pk = :pk AND begins_with(sk, :sk)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": &types.AttributeValueMemberS{Value: keys.Users},
+			":sk": &types.AttributeValueMemberS{Value: keys.Email.Prefix()},
+		},
+	}
+	for {
+		result, err := repo.Db.Query(ctx, input)
+		if err != nil {
+			return read, err
+		}
+		for _, item := range result.Items {
+			var user models.UserSKEmail
+			if err := attributevalue.UnmarshalMap(item, &user); err != nil {
+				return read, err
+			}
+			email, err := keys.Email.Parse(user.Email)
+			if err != nil {
+				// written before keys were escaped, RekeyLookups indexes it once it is moved
+				continue
+			}
+			entry := userIndexEntry(user.UId, user.Username, email, user.City, user.Tag, user.IsActive, user.CreatedAt, user.AccountState)
+			if err := repo.syncUserIndex(ctx, nil, entry); err != nil {
+				return read, err
+			}
+			read++
+		}
+		if result.LastEvaluatedKey == nil {
+			return read, nil
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+}
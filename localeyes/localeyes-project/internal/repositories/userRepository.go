@@ -2,7 +2,10 @@ package repositories
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"localeyes/config"
+	"localeyes/internal/keys"
 	"localeyes/internal/models"
 	"localeyes/utils"
 	"os"
@@ -32,35 +35,41 @@ func NewNoSQLUserRepository(db *dynamodb.Client) *UserRepository {
 func (repo *UserRepository) CreateUser(ctx context.Context, user *models.User) error {
 	users := make([]map[string]types.AttributeValue, 0, 3)
 	userSKEmail := &models.UserSKEmail{
-		PK:          "users",
-		UId:         user.UId,
-		Username:    user.Username,
-		Password:    user.Password,
-		Email:       "email:" + user.Email,
-		Tag:         user.Tag,
-		City:        user.City,
-		IsActive:    user.IsActive,
-		DwellingAge: user.DwellingAge,
+		PK:           keys.Users,
+		UId:          user.UId,
+		Username:     user.Username,
+		Password:     user.Password,
+		Email:        keys.Email.Key(user.Email),
+		Tag:          user.Tag,
+		City:         user.City,
+		IsActive:     user.IsActive,
+		DwellingAge:  user.DwellingAge,
+		CreatedAt:    user.CreatedAt,
+		AccountState: user.AccountState,
 	}
 	userSKUsername := &models.UserSKUsername{
-		PK:          "users",
-		UId:         user.UId,
-		Username:    "username:" + user.Username,
-		Password:    user.Password,
-		Email:       user.Email,
-		Tag:         user.Tag,
-		City:        user.City,
-		IsActive:    user.IsActive,
-		DwellingAge: user.DwellingAge,
+		PK:           keys.Users,
+		UId:          user.UId,
+		Username:     keys.Username.Key(user.Username),
+		Password:     user.Password,
+		Email:        user.Email,
+		Tag:          user.Tag,
+		City:         user.City,
+		IsActive:     user.IsActive,
+		DwellingAge:  user.DwellingAge,
+		CreatedAt:    user.CreatedAt,
+		AccountState: user.AccountState,
 	}
 	userPKId := &models.User{
-		UId:         "user:" + user.UId,
-		Username:    user.Username,
-		Password:    user.Password,
-		Email:       user.Email,
-		Tag:         user.Tag,
-		City:        user.City,
-		DwellingAge: user.DwellingAge,
+		UId:          keys.User.Key(user.UId),
+		Username:     user.Username,
+		Password:     user.Password,
+		Email:        user.Email,
+		Tag:          user.Tag,
+		City:         user.City,
+		DwellingAge:  user.DwellingAge,
+		CreatedAt:    user.CreatedAt,
+		AccountState: user.AccountState,
 	}
 	userSKEmailAv, err := attributevalue.MarshalMap(userSKEmail)
 	//userSKEmailAv["dwelling_age"] = &types.AttributeValueMemberN{Value: strconv.FormatFloat(user.DwellingAge, 'f', -1, 64)}
@@ -92,16 +101,18 @@ func (repo *UserRepository) CreateUser(ctx context.Context, user *models.User) e
 			repo.TableName: writeRequests,
 		},
 	})
-
-	return err
+	if err != nil {
+		return err
+	}
+	return repo.syncUserIndex(ctx, nil, userIndexEntry(user.UId, user.Username, user.Email, user.City, user.Tag, user.IsActive, user.CreatedAt, user.AccountState))
 }
 
 func (repo *UserRepository) FetchUserByEmail(ctx context.Context, email string) (*models.UserSKEmail, error) {
 	input := &dynamodb.GetItemInput{
 		TableName: aws.String(repo.TableName),
 		Key: map[string]types.AttributeValue{
-			"pk": &types.AttributeValueMemberS{Value: "users"},
-			"sk": &types.AttributeValueMemberS{Value: fmt.Sprintf("email:%s", email)},
+			"pk": &types.AttributeValueMemberS{Value: keys.Users},
+			"sk": &types.AttributeValueMemberS{Value: keys.Email.Key(email)},
 		},
 	}
 
@@ -118,8 +129,9 @@ func (repo *UserRepository) FetchUserByEmail(ctx context.Context, email string)
 	if err := attributevalue.UnmarshalMap(result.Item, &dbUser); err != nil {
 		return &models.UserSKEmail{}, err
 	}
-	dtoEmail := strings.Split(dbUser.Email, ":")
-	dbUser.Email = dtoEmail[1]
+	if dbUser.Email, err = keys.Email.Parse(dbUser.Email); err != nil {
+		return &models.UserSKEmail{}, err
+	}
 	return &dbUser, nil
 }
 
@@ -127,8 +139,8 @@ func (repo *UserRepository) FetchUserByUsername(ctx context.Context, username st
 	input := &dynamodb.GetItemInput{
 		TableName: aws.String(repo.TableName),
 		Key: map[string]types.AttributeValue{
-			"pk": &types.AttributeValueMemberS{Value: "users"},
-			"sk": &types.AttributeValueMemberS{Value: fmt.Sprintf("username:%s", username)},
+			"pk": &types.AttributeValueMemberS{Value: keys.Users},
+			"sk": &types.AttributeValueMemberS{Value: keys.Username.Key(username)},
 		},
 	}
 
@@ -145,8 +157,9 @@ func (repo *UserRepository) FetchUserByUsername(ctx context.Context, username st
 	if err := attributevalue.UnmarshalMap(result.Item, &dbUser); err != nil {
 		return &models.UserSKUsername{}, err
 	}
-	dtoUsername := strings.Split(dbUser.Username, ":")
-	dbUser.Username = dtoUsername[1]
+	if dbUser.Username, err = keys.Username.Parse(dbUser.Username); err != nil {
+		return &models.UserSKUsername{}, err
+	}
 	return &dbUser, nil
 }
 
@@ -155,14 +168,14 @@ func (repo *UserRepository) FetchUserById(ctx context.Context, uid string, isUse
 	input = &dynamodb.GetItemInput{
 		TableName: aws.String(repo.TableName),
 		Key: map[string]types.AttributeValue{
-			"pk": &types.AttributeValueMemberS{Value: fmt.Sprintf("user:%s", uid)},
+			"pk": &types.AttributeValueMemberS{Value: keys.User.Key(uid)},
 			"sk": &types.AttributeValueMemberS{Value: "true"},
 		},
 	}
 
 	if !isUserActive {
 		key := map[string]types.AttributeValue{
-			"pk": &types.AttributeValueMemberS{Value: fmt.Sprintf("user:%s", uid)},
+			"pk": &types.AttributeValueMemberS{Value: keys.User.Key(uid)},
 			"sk": &types.AttributeValueMemberS{Value: "false"},
 		}
 		input.Key = key
@@ -182,16 +195,20 @@ func (repo *UserRepository) FetchUserById(ctx context.Context, uid string, isUse
 		return &models.User{}, err
 	}
 	var user = &models.User{
-		Username:    dbUser.Username,
-		UId:         dbUser.UId,
-		City:        dbUser.City,
-		DwellingAge: dbUser.DwellingAge,
-		Password:    dbUser.Password,
-		Email:       dbUser.Email,
-		Tag:         dbUser.Tag,
-	}
-	dtoId := strings.Split(dbUser.UId, ":")
-	user.UId = dtoId[1]
+		Username:     dbUser.Username,
+		UId:          dbUser.UId,
+		City:         dbUser.City,
+		DwellingAge:  dbUser.DwellingAge,
+		Password:     dbUser.Password,
+		Email:        dbUser.Email,
+		Tag:          dbUser.Tag,
+		CreatedAt:    dbUser.CreatedAt,
+		AccountState: dbUser.AccountState,
+		Version:      dbUser.Version,
+	}
+	if user.UId, err = keys.User.Parse(dbUser.UId); err != nil {
+		return &models.User{}, err
+	}
 	if dbUser.IsActive == "true" {
 		user.IsActive = true
 	} else {
@@ -200,47 +217,79 @@ func (repo *UserRepository) FetchUserById(ctx context.Context, uid string, isUse
 	return user, nil
 }
 
-func (repo *UserRepository) UpdateUserById(ctx context.Context, user *models.User) error {
-	input1 := &dynamodb.UpdateItemInput{
+// UpdateUserById updates the profile of a user if their item is still at expected, which may be
+// config.AnyVersion, and returns the new version. A missing user fails with NoUser. The version
+// is kept on the user item only, the email and username lookups follow once it has been written.
+func (repo *UserRepository) UpdateUserById(ctx context.Context, user *models.User, expected int) (int, error) {
+	before, err := repo.indexedUser(ctx, user.Email)
+	if err != nil {
+		return 0, err
+	}
+	values := map[string]types.AttributeValue{
+		":city":         &types.AttributeValueMemberS{Value: user.City},
+		":password":     &types.AttributeValueMemberS{Value: user.Password},
+		":dwelling_age": &types.AttributeValueMemberN{Value: strconv.FormatFloat(user.DwellingAge, 'f', -1, 64)},
+		":tag":          &types.AttributeValueMemberS{Value: user.Tag},
+	}
+	bump, condition := versioned(expected, values)
+	if condition != "" {
+		condition = " AND " + condition
+	}
+	result, err := repo.Db.UpdateItem(ctx, &dynamodb.UpdateItemInput{
 		TableName: aws.String(repo.TableName),
 		Key: map[string]types.AttributeValue{
-			"pk": &types.AttributeValueMemberS{Value: fmt.Sprintf("user:%s", user.UId)},
+			"pk": &types.AttributeValueMemberS{Value: keys.User.Key(user.UId)},
 			"sk": &types.AttributeValueMemberS{Value: "true"},
 		},
-		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":city":         &types.AttributeValueMemberS{Value: user.City},
-			":password":     &types.AttributeValueMemberS{Value: user.Password},
-			":dwelling_age": &types.AttributeValueMemberN{Value: strconv.FormatFloat(user.DwellingAge, 'f', -1, 64)},
-		},
-		UpdateExpression:    aws.String(fmt.Sprintf("SET city =:city, password =:password , dwelling_age =:dwelling_age")),
-		ConditionExpression: aws.String("attribute_exists(pk) AND attribute_exists(sk)"),
+		ExpressionAttributeValues: values,
+		UpdateExpression:          aws.String("SET city =:city, password =:password , dwelling_age =:dwelling_age, tag =:tag, " + bump),
+		ConditionExpression:       aws.String("attribute_exists(pk) AND attribute_exists(sk)" + condition),
+		ReturnValues:              types.ReturnValueUpdatedNew,
+		// the item that failed the condition tells a missing user from an outdated version
+		ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+	})
+	var conditionErr *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionErr) {
+		if conditionErr.Item == nil {
+			return 0, utils.NoUser
+		}
+		return 0, utils.VersionMismatch
+	}
+	if err != nil {
+		return 0, err
+	}
+	var updated models.User
+	if err := attributevalue.UnmarshalMap(result.Attributes, &updated); err != nil {
+		return 0, err
 	}
 	input2 := &dynamodb.UpdateItemInput{
 		TableName: aws.String(repo.TableName),
 		Key: map[string]types.AttributeValue{
-			"pk": &types.AttributeValueMemberS{Value: "users"},
-			"sk": &types.AttributeValueMemberS{Value: fmt.Sprintf("email:%s", user.Email)},
+			"pk": &types.AttributeValueMemberS{Value: keys.Users},
+			"sk": &types.AttributeValueMemberS{Value: keys.Email.Key(user.Email)},
 		},
 		ExpressionAttributeValues: map[string]types.AttributeValue{
 			":city":         &types.AttributeValueMemberS{Value: user.City},
 			":password":     &types.AttributeValueMemberS{Value: user.Password},
 			":dwelling_age": &types.AttributeValueMemberN{Value: strconv.FormatFloat(user.DwellingAge, 'f', -1, 64)},
+			":tag":          &types.AttributeValueMemberS{Value: user.Tag},
 		},
-		UpdateExpression:    aws.String(fmt.Sprintf("SET city =:city, password =:password , dwelling_age =:dwelling_age")),
+		UpdateExpression:    aws.String(fmt.Sprintf("SET city =:city, password =:password , dwelling_age =:dwelling_age, tag =:tag")),
 		ConditionExpression: aws.String("attribute_exists(pk) AND attribute_exists(sk)"),
 	}
 	input3 := &dynamodb.UpdateItemInput{
 		TableName: aws.String(repo.TableName),
 		Key: map[string]types.AttributeValue{
-			"pk": &types.AttributeValueMemberS{Value: "users"},
-			"sk": &types.AttributeValueMemberS{Value: fmt.Sprintf("username:%s", user.Username)},
+			"pk": &types.AttributeValueMemberS{Value: keys.Users},
+			"sk": &types.AttributeValueMemberS{Value: keys.Username.Key(user.Username)},
 		},
 		ExpressionAttributeValues: map[string]types.AttributeValue{
 			":city":         &types.AttributeValueMemberS{Value: user.City},
 			":password":     &types.AttributeValueMemberS{Value: user.Password},
 			":dwelling_age": &types.AttributeValueMemberN{Value: strconv.FormatFloat(user.DwellingAge, 'f', -1, 64)},
+			":tag":          &types.AttributeValueMemberS{Value: user.Tag},
 		},
-		UpdateExpression:    aws.String(fmt.Sprintf("SET city =:city, password =:password , dwelling_age =:dwelling_age")),
+		UpdateExpression:    aws.String(fmt.Sprintf("SET city =:city, password =:password , dwelling_age =:dwelling_age, tag =:tag")),
 		ConditionExpression: aws.String("attribute_exists(pk) AND attribute_exists(sk)"),
 	}
 
@@ -259,121 +308,116 @@ func (repo *UserRepository) UpdateUserById(ctx context.Context, user *models.Use
 			mu.Unlock()
 		}
 	}
-	wg.Add(3)
-	go update(input1)
+	wg.Add(2)
 	go update(input2)
 	go update(input3)
 	wg.Wait()
-	return upErr
+	if upErr != nil || before == nil {
+		return updated.Version, upErr
+	}
+	after := *before
+	after.City = user.City
+	after.Tag = user.Tag
+	return updated.Version, repo.syncUserIndex(ctx, before, &after)
 }
 
-func (repo *UserRepository) ToggleUserActiveStatus(ctx context.Context, user *models.User) error {
-	var activeStatus string
-	if user.IsActive {
-		activeStatus = "true"
-	} else {
-		activeStatus = "false"
-	}
-	var input1 *types.DeleteRequest
+// accountStateAttributes are the attributes of models.AccountState as stored on every user item
+var accountStateAttributes = []string{"account_status", "status_reason", "status_actor", "suspended_until", "status_changed_at"}
 
-	if ctx.Value("Role").(string) == "admin" {
-		input1 = &types.DeleteRequest{
-			Key: map[string]types.AttributeValue{
-				"pk": &types.AttributeValueMemberS{Value: fmt.Sprintf("user:%s", user.UId)},
-				"sk": &types.AttributeValueMemberS{Value: "false"},
-			},
-		}
-	} else {
-		input1 = &types.DeleteRequest{
-			Key: map[string]types.AttributeValue{
-				"pk": &types.AttributeValueMemberS{Value: fmt.Sprintf("user:%s", user.UId)},
-				"sk": &types.AttributeValueMemberS{Value: "true"},
-			},
-		}
-	}
-	userPKId := &models.User{
-		UId:         "user:" + user.UId,
-		Username:    user.Username,
-		Password:    user.Password,
-		Email:       user.Email,
-		Tag:         user.Tag,
-		City:        user.City,
-		DwellingAge: user.DwellingAge,
-	}
-	userAv, err := attributevalue.MarshalMap(userPKId)
+// SetAccountState writes the account state onto all three user items. Only the active state
+// keeps the "user:<id>" item under sk "true", so any other state moves it to "false".
+func (repo *UserRepository) SetAccountState(ctx context.Context, user *models.User, state *models.AccountState) error {
+	oldSK := strconv.FormatBool(user.IsActive)
+	newSK := strconv.FormatBool(state.Status == config.AccountActive)
+	result, err := repo.Db.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(repo.TableName),
+		Key: map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{Value: keys.User.Key(user.UId)},
+			"sk": &types.AttributeValueMemberS{Value: oldSK},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
 	if err != nil {
 		return err
 	}
-	userAv["sk"] = &types.AttributeValueMemberS{Value: activeStatus}
-	input2 := &types.PutRequest{
-		Item: userAv,
-	}
-	writeRequests := []types.WriteRequest{
-		{
-			DeleteRequest: input1,
-		},
-		{
-			PutRequest: input2,
-		},
+	if result.Item == nil {
+		return utils.NoUser
 	}
-	_, err = repo.Db.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{
-		RequestItems: map[string][]types.WriteRequest{
-			repo.TableName: writeRequests,
-		},
-	})
+	stateAv, err := attributevalue.MarshalMap(state)
 	if err != nil {
 		return err
 	}
-	var wg sync.WaitGroup
-	var mu sync.Mutex
-	var upErr error
-
-	update := func(input *dynamodb.UpdateItemInput) {
-		defer wg.Done()
-		_, updateErr := repo.Db.UpdateItem(ctx, input)
-		if updateErr != nil {
-			mu.Lock()
-			if upErr == nil {
-				upErr = updateErr
-			}
-			mu.Unlock()
-		}
+	item := result.Item
+	for _, attribute := range accountStateAttributes {
+		delete(item, attribute)
+	}
+	for attribute, value := range stateAv {
+		item[attribute] = value
+	}
+	item["sk"] = &types.AttributeValueMemberS{Value: newSK}
+	transactItems := make([]types.TransactWriteItem, 0, 4)
+	if oldSK != newSK {
+		transactItems = append(transactItems, types.TransactWriteItem{
+			Delete: &types.Delete{
+				TableName: aws.String(repo.TableName),
+				Key: map[string]types.AttributeValue{
+					"pk": &types.AttributeValueMemberS{Value: keys.User.Key(user.UId)},
+					"sk": &types.AttributeValueMemberS{Value: oldSK},
+				},
+				ConditionExpression: aws.String("attribute_exists(pk)"),
+			},
+		})
 	}
-	input3 := &dynamodb.UpdateItemInput{
-		TableName: aws.String(repo.TableName),
-		Key: map[string]types.AttributeValue{
-			"pk": &types.AttributeValueMemberS{Value: "users"},
-			"sk": &types.AttributeValueMemberS{Value: fmt.Sprintf("email:%s", user.Email)},
-		},
-		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":city":         &types.AttributeValueMemberS{Value: user.City},
-			":password":     &types.AttributeValueMemberS{Value: user.Password},
-			":dwelling_age": &types.AttributeValueMemberN{Value: strconv.FormatFloat(user.DwellingAge, 'f', -1, 64)},
-			":active":       &types.AttributeValueMemberBOOL{Value: user.IsActive},
+	transactItems = append(transactItems, types.TransactWriteItem{
+		Put: &types.Put{
+			TableName: aws.String(repo.TableName),
+			Item:      item,
 		},
-		UpdateExpression:    aws.String(fmt.Sprintf("SET city =:city, password =:password , dwelling_age =:dwelling_age, is_active = :active")),
-		ConditionExpression: aws.String("attribute_exists(pk) AND attribute_exists(sk)"),
+	})
+	set := []string{"is_active = :is_active"}
+	var remove []string
+	values := map[string]types.AttributeValue{
+		":is_active": &types.AttributeValueMemberBOOL{Value: state.Status == config.AccountActive},
+	}
+	for _, attribute := range accountStateAttributes {
+		if value, ok := stateAv[attribute]; ok {
+			set = append(set, attribute+" = :"+attribute)
+			values[":"+attribute] = value
+		} else {
+			remove = append(remove, attribute)
+		}
 	}
-	input4 := &dynamodb.UpdateItemInput{
-		TableName: aws.String(repo.TableName),
-		Key: map[string]types.AttributeValue{
-			"pk": &types.AttributeValueMemberS{Value: "users"},
-			"sk": &types.AttributeValueMemberS{Value: fmt.Sprintf("username:%s", user.Username)},
-		},
-		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":city":         &types.AttributeValueMemberS{Value: user.City},
-			":password":     &types.AttributeValueMemberS{Value: user.Password},
-			":dwelling_age": &types.AttributeValueMemberN{Value: strconv.FormatFloat(user.DwellingAge, 'f', -1, 64)},
-			":active":       &types.AttributeValueMemberBOOL{Value: user.IsActive},
-		},
-		UpdateExpression:    aws.String(fmt.Sprintf("SET city =:city, password =:password , dwelling_age =:dwelling_age, is_active = :active")),
-		ConditionExpression: aws.String("attribute_exists(pk) AND attribute_exists(sk)"),
+	update := "SET " + strings.Join(set, ", ")
+	if len(remove) > 0 {
+		update += " REMOVE " + strings.Join(remove, ", ")
+	}
+	for _, sk := range []string{keys.Email.Key(user.Email), keys.Username.Key(user.Username)} {
+		transactItems = append(transactItems, types.TransactWriteItem{
+			Update: &types.Update{
+				TableName: aws.String(repo.TableName),
+				Key: map[string]types.AttributeValue{
+					"pk": &types.AttributeValueMemberS{Value: keys.Users},
+					"sk": &types.AttributeValueMemberS{Value: sk},
+				},
+				UpdateExpression:          aws.String(update),
+				ExpressionAttributeValues: values,
+				ConditionExpression:       aws.String("attribute_exists(pk)"),
+			},
+		})
 	}
-	wg.Add(2)
-	go update(input3)
-	go update(input4)
-	wg.Wait()
-	return upErr
+	_, err = repo.Db.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: transactItems,
+	})
+	if utils.IsConditionFailed(err) {
+		return utils.NoUser
+	}
+	if err != nil {
+		return err
+	}
+	before := userIndexEntry(user.UId, user.Username, user.Email, user.City, user.Tag, user.IsActive, user.CreatedAt, user.AccountState)
+	after := *before
+	after.Status = state.Status
+	return repo.syncUserIndex(ctx, before, &after)
 }
 
 func (repo *UserRepository) FetchNotifications(ctx context.Context, uId string) ([]*models.Notification, error) {
@@ -382,7 +426,7 @@ func (repo *UserRepository) FetchNotifications(ctx context.Context, uId string)
 		FilterExpression:       aws.String("user_id <> :userId"),
 		KeyConditionExpression: aws.String("pk = :pk"),
 		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":pk":     &types.AttributeValueMemberS{Value: "notifications"},
+			":pk":     &types.AttributeValueMemberS{Value: keys.Notifications},
 			":userId": &types.AttributeValueMemberS{Value: uId},
 		},
 	})
@@ -409,8 +453,8 @@ func (repo *UserRepository) FetchNotifications(ctx context.Context, uId string)
 //		TableName:              aws.String(repo.TableName),
 //		KeyConditionExpression: aws.String("pk = :pk AND begins_with(sk, :sk)"),
 //		ExpressionAttributeValues: map[string]types.AttributeValue{
-//			":pk": &types.AttributeValueMemberS{Value: "users"},
-//			":sk": &types.AttributeValueMemberS{Value: "username:"},
+//			":pk": &types.AttributeValueMemberS{Value: keys.Users},
+//			":sk": &types.AttributeValueMemberS{Value: keys.Username.Prefix()},
 //		},
 //	})
 //	if err != nil {
@@ -447,8 +491,8 @@ func (repo *UserRepository) GetAllUsers(ctx context.Context, params models.GetUs
 		TableName:              aws.String(repo.TableName),
 		KeyConditionExpression: aws.String("pk = :pk AND begins_with(sk, :sk)"),
 		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":pk": &types.AttributeValueMemberS{Value: "users"},
-			":sk": &types.AttributeValueMemberS{Value: "email:"},
+			":pk": &types.AttributeValueMemberS{Value: keys.Users},
+			":sk": &types.AttributeValueMemberS{Value: keys.Email.Prefix()},
 		},
 		Limit: aws.Int32(params.Limit),
 	}
@@ -490,15 +534,21 @@ func (repo *UserRepository) GetAllUsers(ctx context.Context, params models.GetUs
 			return nil, err
 		}
 
+		email, err := keys.Email.Parse(userModel.Email)
+		if err != nil {
+			return nil, err
+		}
 		userNew := &models.User{
-			Username:    userModel.Username,
-			UId:         userModel.UId,
-			Email:       strings.Split(userModel.Email, ":")[1],
-			DwellingAge: userModel.DwellingAge,
-			Password:    userModel.Password,
-			City:        userModel.City,
-			IsActive:    userModel.IsActive,
-			Tag:         userModel.Tag,
+			Username:     userModel.Username,
+			UId:          userModel.UId,
+			Email:        email,
+			DwellingAge:  userModel.DwellingAge,
+			Password:     userModel.Password,
+			City:         userModel.City,
+			IsActive:     userModel.IsActive,
+			Tag:          userModel.Tag,
+			CreatedAt:    userModel.CreatedAt,
+			AccountState: userModel.AccountState,
 		}
 		users = append(users, userNew)
 	}
@@ -506,26 +556,30 @@ func (repo *UserRepository) GetAllUsers(ctx context.Context, params models.GetUs
 }
 
 func (repo *UserRepository) DeleteUser(ctx context.Context, uId, username, email string) error {
+	indexed, err := repo.indexedUser(ctx, email)
+	if err != nil {
+		return err
+	}
 	input1 := types.WriteRequest{
 		DeleteRequest: &types.DeleteRequest{
 			Key: map[string]types.AttributeValue{
-				"pk": &types.AttributeValueMemberS{Value: "users"},
-				"sk": &types.AttributeValueMemberS{Value: fmt.Sprintf("username:%s", username)},
+				"pk": &types.AttributeValueMemberS{Value: keys.Users},
+				"sk": &types.AttributeValueMemberS{Value: keys.Username.Key(username)},
 			},
 		},
 	}
 	input2 := types.WriteRequest{
 		DeleteRequest: &types.DeleteRequest{
 			Key: map[string]types.AttributeValue{
-				"pk": &types.AttributeValueMemberS{Value: "users"},
-				"sk": &types.AttributeValueMemberS{Value: fmt.Sprintf("email:%s", email)},
+				"pk": &types.AttributeValueMemberS{Value: keys.Users},
+				"sk": &types.AttributeValueMemberS{Value: keys.Email.Key(email)},
 			},
 		},
 	}
 	input3 := types.WriteRequest{
 		DeleteRequest: &types.DeleteRequest{
 			Key: map[string]types.AttributeValue{
-				"pk": &types.AttributeValueMemberS{Value: "user:" + uId},
+				"pk": &types.AttributeValueMemberS{Value: keys.User.Key(uId)},
 				"sk": &types.AttributeValueMemberS{Value: "true"},
 			},
 		},
@@ -533,16 +587,314 @@ func (repo *UserRepository) DeleteUser(ctx context.Context, uId, username, email
 	input4 := types.WriteRequest{
 		DeleteRequest: &types.DeleteRequest{
 			Key: map[string]types.AttributeValue{
-				"pk": &types.AttributeValueMemberS{Value: "user:" + uId},
+				"pk": &types.AttributeValueMemberS{Value: keys.User.Key(uId)},
 				"sk": &types.AttributeValueMemberS{Value: "false"},
 			},
 		},
 	}
-	writeRequests := []types.WriteRequest{input1, input2, input3, input4}
-	_, err := repo.Db.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{
-		RequestItems: map[string][]types.WriteRequest{
-			repo.TableName: writeRequests,
+	// the user's own edge partitions are only ever read by the user, whatever is still in them
+	// once the mirrored edges have been removed goes with the account
+	for _, kind := range []keys.Kind{keys.Following, keys.Followers, keys.Block, keys.BlockedBy, keys.Mute, keys.Saved} {
+		_, err := deleteByPrefix(ctx, repo.Db, repo.TableName, kind.Key(uId), "")
+		if err != nil {
+			return err
+		}
+	}
+	// the index goes first, it is found through the email item so a retry still reaches it
+	if err := repo.syncUserIndex(ctx, indexed, nil); err != nil {
+		return err
+	}
+	writeRequests := []types.WriteRequest{input1, input2, input3, input4, countsDelete(keys.Follow.Key(uId))}
+	return batchWrite(ctx, repo.Db, repo.TableName, writeRequests)
+}
+
+// FindOwnedContent collects everything the user wrote or holds outside their own partitions.
+// Questions, answers, comments and the edges pointing at the user are not indexed by user,
+// so this reads the whole table and is only meant for background work such as account deletion.
+func (repo *UserRepository) FindOwnedContent(ctx context.Context, uId string) (*models.OwnedContent, error) {
+	content := &models.OwnedContent{}
+	edge := keys.User.Key(uId)
+	input := &dynamodb.ScanInput{
+		TableName:        aws.String(repo.TableName),
+		FilterExpression: aws.String("user_id = :userId OR q_user_id = :userId OR r_user_id = :userId OR sk = :edge"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":userId": &types.AttributeValueMemberS{Value: uId},
+			":edge":   &types.AttributeValueMemberS{Value: edge},
 		},
-	})
-	return err
+		ProjectionExpression:     aws.String("pk, sk, created_at, reaction, #deleted"),
+		ExpressionAttributeNames: map[string]string{"#deleted": "deleted"},
+	}
+	for {
+		result, err := repo.Db.Scan(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range result.Items {
+			var owned struct {
+				PK        string              `dynamodbav:"pk"`
+				SK        string              `dynamodbav:"sk"`
+				CreatedAt time.Time           `dynamodbav:"created_at"`
+				Reaction  config.ReactionType `dynamodbav:"reaction"`
+				Deleted   bool                `dynamodbav:"deleted"`
+			}
+			if err := attributevalue.UnmarshalMap(item, &owned); err != nil {
+				return nil, err
+			}
+			if err := collectOwned(content, uId, owned.PK, owned.SK, owned.CreatedAt, owned.Reaction, owned.Deleted); err != nil {
+				return nil, err
+			}
+		}
+		if result.LastEvaluatedKey == nil {
+			return content, nil
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+}
+
+// collectOwned adds the item under pk and sk to the content of the user when it is theirs
+func collectOwned(content *models.OwnedContent, uId, pk, sk string, createdAt time.Time, reaction config.ReactionType, deleted bool) error {
+	switch {
+	case pk == keys.Posts:
+		listing, err := keys.ParseListing(sk)
+		if err != nil {
+			return err
+		}
+		content.Posts = append(content.Posts, &models.Post{
+			PostId:    listing.PostId,
+			UId:       uId,
+			Type:      listing.Type,
+			CreatedAt: createdAt,
+		})
+	case pk == keys.Notifications:
+		pId, err := keys.Post.Parse(sk)
+		if err != nil {
+			return err
+		}
+		content.Notifications = append(content.Notifications, pId)
+	case keys.Post.Is(pk) && keys.Question.Is(sk):
+		pId, err := keys.Post.Parse(pk)
+		if err != nil {
+			return err
+		}
+		qId, err := keys.Question.Parse(sk)
+		if err != nil {
+			return err
+		}
+		content.Questions = append(content.Questions, &models.QuestionRef{QId: qId, PostId: pId, UserId: uId})
+	case keys.Post.Is(pk) && keys.Comment.Is(sk):
+		pId, err := keys.Post.Parse(pk)
+		if err != nil {
+			return err
+		}
+		path, err := keys.ParseComment(sk)
+		if err != nil {
+			return err
+		}
+		if !deleted {
+			content.Comments = append(content.Comments, &models.Comment{PostId: pId, Path: path, UserId: uId})
+		}
+	case keys.Question.Is(pk) && keys.Reply.Is(sk):
+		qId, err := keys.Question.Parse(pk)
+		if err != nil {
+			return err
+		}
+		rId, err := keys.Reply.Parse(sk)
+		if err != nil {
+			return err
+		}
+		content.Answers = append(content.Answers, &models.Reply{QId: qId, RId: rId, UserId: uId})
+	case sk == keys.User.Key(uId):
+		kind, parts, err := keys.Split(pk)
+		if err != nil || len(parts) == 0 {
+			return err
+		}
+		id := parts[len(parts)-1]
+		switch kind {
+		case keys.Like:
+			content.Likes = append(content.Likes, id)
+		case keys.Reaction:
+			content.Reactions = append(content.Reactions, &models.ContentRef{
+				Type:     config.ContentType(parts[0]),
+				Id:       id,
+				Reaction: reaction,
+			})
+		case keys.Bookmark:
+			content.Saved = append(content.Saved, id)
+		case keys.Followers:
+			content.Following = append(content.Following, id)
+		case keys.Following:
+			content.Followers = append(content.Followers, id)
+		case keys.BlockedBy:
+			content.Blocking = append(content.Blocking, id)
+		case keys.Block:
+			content.BlockedBy = append(content.BlockedBy, id)
+		case keys.Mute:
+			content.MutedBy = append(content.MutedBy, id)
+		}
+	}
+	return nil
+}
+
+// DeleteNotifications removes the new post notifications of the given posts
+func (repo *UserRepository) DeleteNotifications(ctx context.Context, pIds []string) error {
+	writeRequests := make([]types.WriteRequest, 0, len(pIds))
+	for _, pId := range pIds {
+		writeRequests = append(writeRequests, types.WriteRequest{
+			DeleteRequest: &types.DeleteRequest{
+				Key: map[string]types.AttributeValue{
+					"pk": &types.AttributeValueMemberS{Value: keys.Notifications},
+					"sk": &types.AttributeValueMemberS{Value: keys.Post.Key(pId)},
+				},
+			},
+		})
+	}
+	return batchWrite(ctx, repo.Db, repo.TableName, writeRequests)
+}
+
+// FetchUsersByIds looks up users regardless of their active status, keyed by user id
+func (repo *UserRepository) FetchUsersByIds(ctx context.Context, uIds []string) (map[string]*models.User, error) {
+	users := make(map[string]*models.User)
+	seen := make(map[string]bool)
+	var itemKeys []map[string]types.AttributeValue
+	for _, uId := range uIds {
+		if seen[uId] {
+			continue
+		}
+		seen[uId] = true
+		for _, status := range []string{"true", "false"} {
+			itemKeys = append(itemKeys, map[string]types.AttributeValue{
+				"pk": &types.AttributeValueMemberS{Value: keys.User.Key(uId)},
+				"sk": &types.AttributeValueMemberS{Value: status},
+			})
+		}
+	}
+	// BatchGetItem accepts at most 100 keys per call
+	for start := 0; start < len(itemKeys); start += 100 {
+		end := start + 100
+		if end > len(itemKeys) {
+			end = len(itemKeys)
+		}
+		requestItems := map[string]types.KeysAndAttributes{
+			repo.TableName: {Keys: itemKeys[start:end]},
+		}
+		for len(requestItems) > 0 {
+			result, err := repo.Db.BatchGetItem(ctx, &dynamodb.BatchGetItemInput{
+				RequestItems: requestItems,
+			})
+			if err != nil {
+				return nil, err
+			}
+			for _, item := range result.Responses[repo.TableName] {
+				var dbUser models.UserWithStringStatus
+				if err := attributevalue.UnmarshalMap(item, &dbUser); err != nil {
+					return nil, err
+				}
+				uId, err := keys.User.Parse(dbUser.UId)
+				if err != nil {
+					return nil, err
+				}
+				user := &models.User{
+					Username:     dbUser.Username,
+					UId:          uId,
+					City:         dbUser.City,
+					DwellingAge:  dbUser.DwellingAge,
+					Email:        dbUser.Email,
+					Tag:          dbUser.Tag,
+					IsActive:     dbUser.IsActive == "true",
+					CreatedAt:    dbUser.CreatedAt,
+					AccountState: dbUser.AccountState,
+				}
+				users[user.UId] = user
+			}
+			requestItems = result.UnprocessedKeys
+		}
+	}
+	return users, nil
+}
+
+// RekeyLookups moves the email and username items written before keys were escaped to their
+// escaped keys, and indexes the users moved. Only emails and usernames holding ":" or "%"
+// are affected. It returns how many items were moved.
+func (repo *UserRepository) RekeyLookups(ctx context.Context) (int, error) {
+	moved := 0
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(repo.TableName),
+		KeyConditionExpression: aws.String("pk = :pk"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": &types.AttributeValueMemberS{Value: keys.Users},
+		},
+	}
+	for {
+		result, err := repo.Db.Query(ctx, input)
+		if err != nil {
+			return moved, err
+		}
+		for _, item := range result.Items {
+			var lookup struct {
+				SK  string `dynamodbav:"sk"`
+				UId string `dynamodbav:"uid"`
+			}
+			if err := attributevalue.UnmarshalMap(item, &lookup); err != nil {
+				return moved, err
+			}
+			_, part, _ := strings.Cut(lookup.SK, ":")
+			if !strings.ContainsAny(part, ":%") {
+				continue
+			}
+			// the user item keeps the email and username as they were entered
+			users, err := repo.FetchUsersByIds(ctx, []string{lookup.UId})
+			if err != nil {
+				return moved, err
+			}
+			user, ok := users[lookup.UId]
+			if !ok {
+				continue
+			}
+			var sk string
+			switch {
+			case keys.Email.Is(lookup.SK):
+				sk = keys.Email.Key(user.Email)
+			case keys.Username.Is(lookup.SK):
+				sk = keys.Username.Key(user.Username)
+			}
+			if sk == "" || sk == lookup.SK {
+				continue
+			}
+			item["sk"] = &types.AttributeValueMemberS{Value: sk}
+			_, err = repo.Db.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+				TransactItems: []types.TransactWriteItem{
+					{
+						Put: &types.Put{
+							TableName:           aws.String(repo.TableName),
+							Item:                item,
+							ConditionExpression: aws.String("attribute_not_exists(pk)"),
+						},
+					},
+					{
+						Delete: &types.Delete{
+							TableName: aws.String(repo.TableName),
+							Key: map[string]types.AttributeValue{
+								"pk": &types.AttributeValueMemberS{Value: keys.Users},
+								"sk": &types.AttributeValueMemberS{Value: lookup.SK},
+							},
+						},
+					},
+				},
+			})
+			if err != nil {
+				return moved, err
+			}
+			moved++
+			if keys.Email.Is(sk) {
+				entry := userIndexEntry(user.UId, user.Username, user.Email, user.City, user.Tag, user.IsActive, user.CreatedAt, user.AccountState)
+				if err := repo.syncUserIndex(ctx, nil, entry); err != nil {
+					return moved, err
+				}
+			}
+		}
+		if result.LastEvaluatedKey == nil {
+			return moved, nil
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
 }
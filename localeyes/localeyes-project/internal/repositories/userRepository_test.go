@@ -0,0 +1,108 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"localeyes/internal/models"
+	"localeyes/utils"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUpdateUserById(t *testing.T) {
+	tests := []struct {
+		name     string
+		response dynamoResponse
+		version  int
+		err      error
+	}{
+		{
+			name:     "updated",
+			response: dynamoResponse{Body: map[string]any{"Attributes": map[string]any{"version": map[string]any{"N": "4"}}}},
+			version:  4,
+		},
+		{
+			name: "outdated version",
+			response: dynamoResponse{Error: "ConditionalCheckFailedException", Body: map[string]any{
+				"message": "The conditional request failed",
+				"Item":    map[string]any{"pk": map[string]any{"S": "user:u1"}, "version": map[string]any{"N": "5"}},
+			}},
+			err: utils.VersionMismatch,
+		},
+		{
+			name:     "missing user",
+			response: dynamoResponse{Error: "ConditionalCheckFailedException", Body: map[string]any{"message": "The conditional request failed"}},
+			err:      utils.NoUser,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client, calls := fakeDynamo(t, func(call dynamoCall) dynamoResponse {
+				if call.Operation == "UpdateItem" && call.Input["Key"].(map[string]any)["pk"].(map[string]any)["S"] == "user:u1" {
+					return test.response
+				}
+				return dynamoResponse{}
+			})
+			repo := &UserRepository{Db: client, TableName: "localeyes"}
+			user := &models.User{UId: "u1", Email: "a@x.com", Username: "a", City: "Pune"}
+			version, err := repo.UpdateUserById(context.Background(), user, 3)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("error = %v, want %v", err, test.err)
+			}
+			if version != test.version {
+				t.Fatalf("version = %d, want %d", version, test.version)
+			}
+			for _, call := range *calls {
+				if call.Operation != "UpdateItem" || call.Input["Key"].(map[string]any)["pk"].(map[string]any)["S"] != "user:u1" {
+					continue
+				}
+				if call.Input["ReturnValuesOnConditionCheckFailure"] != "ALL_OLD" {
+					t.Fatalf("the user update does not ask for the item that failed its condition")
+				}
+				condition, _ := call.Input["ConditionExpression"].(string)
+				expected := call.Input["ExpressionAttributeValues"].(map[string]any)[":version"]
+				if !strings.Contains(condition, "version = :version") || !reflect.DeepEqual(expected, map[string]any{"N": "3"}) {
+					t.Fatalf("the user update is not conditioned on version 3: %q %v", condition, expected)
+				}
+			}
+		})
+	}
+}
+
+func TestFetchUserByEmail(t *testing.T) {
+	tests := []struct {
+		name  string
+		email string
+		sk    string
+	}{
+		{"plain address", "me@example.com", "email:me@example.com"},
+		{"separator in address", "me:you@example.com", "email:me%3Ayou@example.com"},
+		{"escape in address", "50%off@example.com", "email:50%25off@example.com"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client, calls := fakeDynamo(t, func(call dynamoCall) dynamoResponse {
+				return dynamoResponse{Body: map[string]any{"Item": map[string]any{
+					"pk":  map[string]any{"S": "users"},
+					"sk":  call.Input["Key"].(map[string]any)["sk"],
+					"uid": map[string]any{"S": "u1"},
+				}}}
+			})
+			repo := &UserRepository{Db: client, TableName: "localeyes"}
+			user, err := repo.FetchUserByEmail(context.Background(), test.email)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(*calls) != 1 || (*calls)[0].Operation != "GetItem" {
+				t.Fatalf("calls = %+v, want a single GetItem", *calls)
+			}
+			if sk := (*calls)[0].Input["Key"].(map[string]any)["sk"]; !reflect.DeepEqual(sk, map[string]any{"S": test.sk}) {
+				t.Fatalf("looked up %v, want %q", sk, test.sk)
+			}
+			if user.Email != test.email || user.UId != "u1" {
+				t.Fatalf("user = %+v, want %s with address %q", user, "u1", test.email)
+			}
+		})
+	}
+}
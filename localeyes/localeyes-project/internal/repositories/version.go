@@ -0,0 +1,27 @@
+package repositories
+
+import (
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"localeyes/config"
+	"strconv"
+)
+
+// Mutable items carry a "version" attribute that every edit bumps. Clients send back the
+// version they read and the write only goes through if it is still current. Items written
+// before versions were kept have no attribute and count as version 0.
+
+// versioned returns the SET clause bumping the version and the condition checking that the
+// item is at expected, which is empty for config.AnyVersion. Their values are added to values.
+func versioned(expected int, values map[string]types.AttributeValue) (string, string) {
+	values[":zero"] = &types.AttributeValueMemberN{Value: "0"}
+	values[":one"] = &types.AttributeValueMemberN{Value: "1"}
+	bump := "version = if_not_exists(version, :zero) + :one"
+	switch expected {
+	case config.AnyVersion:
+		return bump, ""
+	case 0:
+		return bump, "(attribute_not_exists(version) OR version = :zero)"
+	}
+	values[":version"] = &types.AttributeValueMemberN{Value: strconv.Itoa(expected)}
+	return bump, "version = :version"
+}
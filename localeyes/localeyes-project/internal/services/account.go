@@ -0,0 +1,87 @@
+package services
+
+import (
+	"context"
+	"localeyes/config"
+	"localeyes/internal/interfaces"
+	"localeyes/internal/models"
+	"localeyes/utils"
+	"slices"
+	"time"
+)
+
+type accountTransition struct {
+	from []config.AccountStatus
+	to   config.AccountStatus
+}
+
+// accountTransitions is the account state machine, each action may only be applied to an
+// account in one of its from states
+var accountTransitions = map[config.AuditAction]accountTransition{
+	config.AuditDeactivate: {
+		from: []config.AccountStatus{config.AccountActive},
+		to:   config.AccountDeactivated,
+	},
+	config.AuditReactivateUser: {
+		from: []config.AccountStatus{config.AccountDeactivated, config.AccountPendingVerification},
+		to:   config.AccountActive,
+	},
+	config.AuditSuspendUser: {
+		from: []config.AccountStatus{config.AccountActive, config.AccountDeactivated, config.AccountSuspended},
+		to:   config.AccountSuspended,
+	},
+	config.AuditBanUser: {
+		from: []config.AccountStatus{config.AccountActive, config.AccountDeactivated, config.AccountSuspended, config.AccountPendingVerification},
+		to:   config.AccountBanned,
+	},
+	config.AuditUnbanUser: {
+		from: []config.AccountStatus{config.AccountSuspended, config.AccountBanned},
+		to:   config.AccountActive,
+	},
+	config.AuditReinstateUser: {
+		from: []config.AccountStatus{config.AccountSuspended},
+		to:   config.AccountActive,
+	},
+}
+
+// changeAccountState applies action to the user's account and records it in the audit log.
+// state carries the reason, actor and, for suspensions, the end of the suspension.
+func changeAccountState(ctx context.Context, userRepo interfaces.UserRepository, auditRepo interfaces.AuditRepoInterface, uId string, action config.AuditAction, state models.AccountState) error {
+	users, err := userRepo.FetchUsersByIds(ctx, []string{uId})
+	if err != nil {
+		return err
+	}
+	user, ok := users[uId]
+	if !ok {
+		return utils.NoUser
+	}
+	transition := accountTransitions[action]
+	if !slices.Contains(transition.from, user.CurrentStatus(user.IsActive)) {
+		return utils.InvalidAccountTransition
+	}
+	now := time.Now()
+	state.Status = transition.to
+	state.StatusChangedAt = &now
+	if state.Status != config.AccountSuspended {
+		state.SuspendedUntil = nil
+	}
+	before := auditUser(user)
+	err = userRepo.SetAccountState(ctx, user, &state)
+	if err != nil {
+		return err
+	}
+	recordAudit(ctx, auditRepo, &models.AuditEntry{
+		ActorId:    state.StatusActor,
+		Action:     action,
+		TargetType: config.UserContent,
+		TargetId:   uId,
+		Reason:     state.StatusReason,
+	}, before)
+	return nil
+}
+
+// contextActor is the id of the authenticated caller
+func contextActor(ctx context.Context) string {
+	actor, _ := ctx.Value("Id").(string)
+	return actor
+}
@@ -0,0 +1,156 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"localeyes/config"
+	"localeyes/internal/models"
+	"localeyes/utils"
+	"time"
+)
+
+// deleteAccount removes everything the user left behind and then the account itself. Posts,
+// questions and answers are deleted with everything hanging off them, comments are blanked so
+// the replies of others stay in their thread, reactions and likes are taken back so counters
+// stay right, and follows, blocks and saved posts are undone on both sides. Answer votes are
+// kept as anonymous tallies so the scores of other users' answers do not shift.
+// Content that is already gone counts as removed, so every slice of the job scans what is left
+// and carries on from there, and a failed job can simply be run again. The account is kept
+// until all of its content is gone so a failed job leaves it visible.
+func (s *AdminService) deleteAccount(ctx context.Context, slice *jobSlice) error {
+	job := slice.job
+	uId := job.TargetId
+	// items that failed in an earlier slice are still there and are tried again
+	job.Processed -= job.Failed
+	job.Failed = 0
+	job.Stage = "scanning"
+	saveJob(ctx, s.JobRepo, job)
+	content, err := s.UserRepo.FindOwnedContent(ctx, uId)
+	if err != nil {
+		return err
+	}
+	job.Total = job.Processed + content.Count() + 1
+	job.Stage = "content"
+	saveJob(ctx, s.JobRepo, job)
+
+	removals := make([]func() error, 0, content.Count())
+	for _, post := range content.Posts {
+		removals = append(removals, func() error {
+			return s.PostRepo.DeletePost(ctx, post.Type, post.CreatedAt, uId, post.PostId)
+		})
+	}
+	for _, question := range content.Questions {
+		removals = append(removals, func() error {
+			return ignoreGone(s.QuesRepo.DeleteByQId(ctx, question.QId, question.PostId, uId), utils.NotYourQuestion)
+		})
+	}
+	for _, answer := range content.Answers {
+		removals = append(removals, func() error {
+			return ignoreGone(s.AnsRepo.DeleteAnswer(ctx, answer.QId, answer.RId, uId), utils.NotYourAnswer)
+		})
+	}
+	now := time.Now()
+	for _, comment := range content.Comments {
+		removals = append(removals, func() error {
+			return ignoreGone(s.CommRepo.SoftDeleteComment(ctx, comment.PostId, comment.Path, uId, now), utils.NotYourComment)
+		})
+	}
+	for _, target := range content.Reactions {
+		removals = append(removals, func() error {
+			return s.removeReaction(ctx, uId, target)
+		})
+	}
+	for _, pId := range content.Likes {
+		removals = append(removals, func() error {
+			if err := s.PostRepo.DeleteLikeEntry(ctx, uId, pId); err != nil {
+				return err
+			}
+			return s.removePostLike(ctx, pId)
+		})
+	}
+	for _, pId := range content.Saved {
+		removals = append(removals, func() error {
+			return ignoreGone(s.BookRepo.UnsavePost(ctx, uId, pId), utils.NotSaved)
+		})
+	}
+	for _, followeeId := range content.Following {
+		removals = append(removals, func() error {
+			return ignoreGone(s.FollRepo.Unfollow(ctx, uId, followeeId), utils.NotFollowing)
+		})
+	}
+	for _, followerId := range content.Followers {
+		removals = append(removals, func() error {
+			return ignoreGone(s.FollRepo.Unfollow(ctx, followerId, uId), utils.NotFollowing)
+		})
+	}
+	for _, blockedId := range content.Blocking {
+		removals = append(removals, func() error {
+			return ignoreGone(s.RelRepo.Unblock(ctx, uId, blockedId), utils.NotBlocked)
+		})
+	}
+	for _, blockerId := range content.BlockedBy {
+		removals = append(removals, func() error {
+			return ignoreGone(s.RelRepo.Unblock(ctx, blockerId, uId), utils.NotBlocked)
+		})
+	}
+	for _, muterId := range content.MutedBy {
+		removals = append(removals, func() error {
+			return ignoreGone(s.RelRepo.Unmute(ctx, muterId, uId), utils.NotMuted)
+		})
+	}
+	for _, remove := range removals {
+		if slice.spent() {
+			return errSliceSpent
+		}
+		slice.step(ctx, remove())
+	}
+	if slice.spent() {
+		return errSliceSpent
+	}
+	err = s.UserRepo.DeleteNotifications(ctx, content.Notifications)
+	for range content.Notifications {
+		slice.step(ctx, err)
+	}
+
+	if job.Failed > 0 {
+		return fmt.Errorf("%d of %d items could not be removed, delete the user again to retry", job.Failed, job.Total-1)
+	}
+	job.Stage = "account"
+	err = s.UserRepo.DeleteUser(ctx, uId, job.Params["username"], job.Params["email"])
+	slice.step(ctx, err)
+	return err
+}
+
+// removeReaction takes back the user's reaction, a LIKE on a post also comes off its likes
+func (s *AdminService) removeReaction(ctx context.Context, uId string, target *models.ContentRef) error {
+	previous, err := s.ReacRepo.React(ctx, target.Type, target.Id, uId, config.NoReaction)
+	if err != nil {
+		return err
+	}
+	if target.Type == config.PostContent && previous == config.Like {
+		return s.removePostLike(ctx, target.Id)
+	}
+	return nil
+}
+
+// removePostLike decrements the likes of a post, a post that is already gone has nothing to adjust
+func (s *AdminService) removePostLike(ctx context.Context, pId string) error {
+	ownerId, err := s.PostRepo.GetPostOwner(ctx, pId)
+	if err != nil {
+		return ignoreGone(err, utils.NoPost)
+	}
+	post, err := s.PostRepo.GetUserPost(ctx, ownerId, pId)
+	if err != nil {
+		return ignoreGone(err, utils.NotYourPost)
+	}
+	return ignoreGone(s.PostRepo.UpdateLikeCount(ctx, ownerId, string(post.Type), pId, post.CreatedAt, -1), utils.NoPost)
+}
+
+// ignoreGone treats gone, the error a repository returns for a missing item, as success
+func ignoreGone(err, gone error) error {
+	if errors.Is(err, gone) {
+		return nil
+	}
+	return err
+}
@@ -2,23 +2,65 @@ package services
 
 import (
 	"context"
+	"errors"
+	"localeyes/config"
 	"localeyes/internal/interfaces"
 	"localeyes/internal/models"
+	"localeyes/utils"
+	"time"
 )
 
 type AdminService struct {
-	UserRepo interfaces.UserRepository
-	PostRepo interfaces.PostRepository
-	QuesRepo interfaces.QuestionRepoInterface
-	AnsRepo  interfaces.AnswerRepoInterface
+	UserRepo   interfaces.UserRepository
+	PostRepo   interfaces.PostRepository
+	QuesRepo   interfaces.QuestionRepoInterface
+	AnsRepo    interfaces.AnswerRepoInterface
+	CommRepo   interfaces.CommentRepoInterface
+	ReacRepo   interfaces.ReactionRepoInterface
+	BookRepo   interfaces.BookmarkRepoInterface
+	FollRepo   interfaces.FollowRepoInterface
+	RelRepo    interfaces.RelationRepoInterface
+	ReportRepo interfaces.ReportRepoInterface
+	AuditRepo  interfaces.AuditRepoInterface
+	JobRepo    interfaces.JobRepoInterface
+	JobQueue   interfaces.JobQueueInterface
+	StatsRepo  interfaces.StatsRepoInterface
+	Exports    *ExportBuilder
 }
 
-func NewAdminService(userRepo interfaces.UserRepository, postRepo interfaces.PostRepository, quesRepo interfaces.QuestionRepoInterface, ansRepo interfaces.AnswerRepoInterface) *AdminService {
+func NewAdminService(
+	userRepo interfaces.UserRepository,
+	postRepo interfaces.PostRepository,
+	quesRepo interfaces.QuestionRepoInterface,
+	ansRepo interfaces.AnswerRepoInterface,
+	commRepo interfaces.CommentRepoInterface,
+	reacRepo interfaces.ReactionRepoInterface,
+	bookRepo interfaces.BookmarkRepoInterface,
+	follRepo interfaces.FollowRepoInterface,
+	relRepo interfaces.RelationRepoInterface,
+	reportRepo interfaces.ReportRepoInterface,
+	auditRepo interfaces.AuditRepoInterface,
+	jobRepo interfaces.JobRepoInterface,
+	jobQueue interfaces.JobQueueInterface,
+	statsRepo interfaces.StatsRepoInterface,
+	exports *ExportBuilder,
+) *AdminService {
 	return &AdminService{
-		UserRepo: userRepo,
-		PostRepo: postRepo,
-		QuesRepo: quesRepo,
-		AnsRepo:  ansRepo,
+		UserRepo:   userRepo,
+		PostRepo:   postRepo,
+		QuesRepo:   quesRepo,
+		AnsRepo:    ansRepo,
+		CommRepo:   commRepo,
+		ReacRepo:   reacRepo,
+		BookRepo:   bookRepo,
+		FollRepo:   follRepo,
+		RelRepo:    relRepo,
+		ReportRepo: reportRepo,
+		AuditRepo:  auditRepo,
+		JobRepo:    jobRepo,
+		JobQueue:   jobQueue,
+		StatsRepo:  statsRepo,
+		Exports:    exports,
 	}
 }
 
@@ -30,57 +72,286 @@ func (s *AdminService) GetAllUsers(ctx context.Context, params models.GetUsersPa
 	var userResults []*models.ResponseUser
 	for _, user := range users {
 		userResult := &models.ResponseUser{
-			UId:          user.UId,
-			Username:     user.Username,
-			Email:        user.Email,
-			City:         user.City,
-			LivingSince:  user.DwellingAge,
-			Tag:          user.Tag,
-			ActiveStatus: user.IsActive,
+			UId:            user.UId,
+			Username:       user.Username,
+			Email:          user.Email,
+			City:           user.City,
+			LivingSince:    user.DwellingAge,
+			Tag:            user.Tag,
+			ActiveStatus:   user.IsActive,
+			Status:         user.CurrentStatus(user.IsActive),
+			StatusReason:   user.StatusReason,
+			SuspendedUntil: user.SuspendedUntil,
 		}
 		userResults = append(userResults, userResult)
 	}
 	return userResults, nil
 }
 
-func (s *AdminService) ReactivateUser(ctx context.Context, uId string) error {
-	user, err := s.UserRepo.FetchUserById(ctx, uId, false)
+// SearchUsers finds users through the search index and loads their current profiles, users
+// deleted since the index was read are left out
+func (s *AdminService) SearchUsers(ctx context.Context, search *models.UserSearch) (*models.ResponseUserSearch, error) {
+	entries, next, total, err := s.UserRepo.SearchUsers(ctx, search)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	user.IsActive = true
-	err = s.UserRepo.ToggleUserActiveStatus(ctx, user)
-	return err
+	uIds := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		uIds = append(uIds, entry.UId)
+	}
+	users, err := s.UserRepo.FetchUsersByIds(ctx, uIds)
+	if err != nil {
+		return nil, err
+	}
+	result := &models.ResponseUserSearch{
+		Users:         make([]*models.ResponseUser, 0, len(entries)),
+		NextCursor:    next,
+		TotalEstimate: total,
+	}
+	for _, uId := range uIds {
+		user, ok := users[uId]
+		if !ok {
+			continue
+		}
+		result.Users = append(result.Users, &models.ResponseUser{
+			UId:            user.UId,
+			Username:       user.Username,
+			Email:          user.Email,
+			City:           user.City,
+			LivingSince:    user.DwellingAge,
+			Tag:            user.Tag,
+			ActiveStatus:   user.IsActive,
+			Status:         user.CurrentStatus(user.IsActive),
+			StatusReason:   user.StatusReason,
+			SuspendedUntil: user.SuspendedUntil,
+			CreatedAt:      user.CreatedAt,
+		})
+	}
+	return result, nil
 }
 
-func (s *AdminService) DeleteUser(ctx context.Context, user *models.DeleteUser) error {
-	err := s.UserRepo.DeleteUser(ctx, user.UId, user.Username, user.Email)
+func (s *AdminService) ReactivateUser(ctx context.Context, uId, reason string) error {
+	return changeAccountState(ctx, s.UserRepo, s.AuditRepo, uId, config.AuditReactivateUser, models.AccountState{
+		StatusActor:  contextActor(ctx),
+		StatusReason: reason,
+	})
+}
+
+func (s *AdminService) SuspendUser(ctx context.Context, uId string, until time.Time, reason string) error {
+	return changeAccountState(ctx, s.UserRepo, s.AuditRepo, uId, config.AuditSuspendUser, models.AccountState{
+		StatusActor:    contextActor(ctx),
+		StatusReason:   reason,
+		SuspendedUntil: &until,
+	})
+}
+
+func (s *AdminService) BanUser(ctx context.Context, uId, reason string) error {
+	return changeAccountState(ctx, s.UserRepo, s.AuditRepo, uId, config.AuditBanUser, models.AccountState{
+		StatusActor:  contextActor(ctx),
+		StatusReason: reason,
+	})
+}
+
+// UnbanUser lifts a ban or a suspension before it runs out
+func (s *AdminService) UnbanUser(ctx context.Context, uId, reason string) error {
+	return changeAccountState(ctx, s.UserRepo, s.AuditRepo, uId, config.AuditUnbanUser, models.AccountState{
+		StatusActor:  contextActor(ctx),
+		StatusReason: reason,
+	})
+}
+
+// DeleteUser queues the job that removes the user and everything they left behind, see
+// deleteAccount. The audit entry is written when the job is queued.
+func (s *AdminService) DeleteUser(ctx context.Context, user *models.DeleteUser, reason string) (*models.Job, error) {
+	users, err := s.UserRepo.FetchUsersByIds(ctx, []string{user.UId})
+	if err != nil {
+		return nil, err
+	}
+	var before *models.ResponseUser
+	if existing, ok := users[user.UId]; ok {
+		before = auditUser(existing)
+	}
+	job, err := enqueueJob(ctx, s.JobRepo, s.JobQueue, &models.Job{
+		Kind:     config.DeleteAccountJob,
+		TargetId: user.UId,
+		Params:   map[string]string{"username": user.Username, "email": user.Email},
+	})
+	if err != nil {
+		return nil, err
+	}
+	recordAudit(ctx, s.AuditRepo, &models.AuditEntry{
+		Action:     config.AuditDeleteUser,
+		TargetType: config.UserContent,
+		TargetId:   user.UId,
+		TargetKeys: map[string]string{"username": user.Username, "email": user.Email, "job_id": job.Id},
+		Reason:     reason,
+	}, before)
+	return job, nil
+}
+
+// ExportUser builds the personal data export of a user for a support request
+func (s *AdminService) ExportUser(ctx context.Context, uId string, format config.ExportFormat) (*models.Job, error) {
+	return s.Exports.Start(ctx, uId, format)
+}
+
+func (s *AdminService) GetJob(ctx context.Context, id string) (*models.Job, error) {
+	return s.JobRepo.GetJob(ctx, id)
+}
+
+func (s *AdminService) DeletePost(ctx context.Context, uId, pId string, post *models.DeletePost, reason string) error {
+	before, err := s.PostRepo.GetUserPost(ctx, uId, pId)
 	if err != nil {
 		return err
 	}
+	err = s.PostRepo.DeletePost(ctx, post.Type, post.CreatedAt, uId, pId)
+	if err != nil {
+		return err
+	}
+	recordAudit(ctx, s.AuditRepo, &models.AuditEntry{
+		Action:     config.AuditDeletePost,
+		TargetType: config.PostContent,
+		TargetId:   pId,
+		TargetKeys: map[string]string{"user_id": uId, "type": string(post.Type), "created_at": post.CreatedAt.Format(time.RFC3339)},
+		Reason:     reason,
+	}, before)
 	return nil
 }
 
-func (s *AdminService) DeletePost(ctx context.Context, uId, pId string, post *models.DeletePost) error {
-	err := s.PostRepo.DeletePost(ctx, post.Type, post.CreatedAt, uId, pId)
+func (s *AdminService) DeleteQuestion(ctx context.Context, pId, qId, uId, reason string) error {
+	before, err := s.QuesRepo.GetQuestion(ctx, pId, qId)
 	if err != nil {
 		return err
 	}
+	err = s.QuesRepo.DeleteByQId(ctx, qId, pId, uId)
+	if err != nil {
+		return err
+	}
+	recordAudit(ctx, s.AuditRepo, &models.AuditEntry{
+		Action:     config.AuditDeleteQuestion,
+		TargetType: config.QuestionContent,
+		TargetId:   qId,
+		TargetKeys: map[string]string{"post_id": pId, "user_id": uId},
+		Reason:     reason,
+	}, before)
 	return nil
 }
 
-func (s *AdminService) DeleteQuestion(ctx context.Context, pId, qId, uId string) error {
-	err := s.QuesRepo.DeleteByQId(ctx, qId, pId, uId)
+func (s *AdminService) DeleteAnswer(ctx context.Context, rId, qId, uId, reason string) error {
+	before, err := s.AnsRepo.GetAnswer(ctx, qId, rId)
+	if err != nil {
+		return err
+	}
+	err = s.AnsRepo.DeleteAnswer(ctx, qId, rId, uId)
 	if err != nil {
 		return err
 	}
+	recordAudit(ctx, s.AuditRepo, &models.AuditEntry{
+		Action:     config.AuditDeleteAnswer,
+		TargetType: config.AnswerContent,
+		TargetId:   rId,
+		TargetKeys: map[string]string{"question_id": qId, "user_id": uId},
+		Reason:     reason,
+	}, before)
 	return nil
 }
 
-func (s *AdminService) DeleteAnswer(ctx context.Context, rId, qId, uId string) error {
-	err := s.AnsRepo.DeleteAnswer(ctx, qId, rId, uId)
+// GetStats returns the all time totals and the buckets of the chosen granularity between from and to
+func (s *AdminService) GetStats(ctx context.Context, granularity config.StatsGranularity, from, to time.Time) (*models.ResponseStats, error) {
+	totals, err := s.StatsRepo.GetTotals(ctx)
+	if err != nil {
+		return nil, err
+	}
+	series, err := s.StatsRepo.GetSeries(ctx, granularity, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return &models.ResponseStats{Granularity: granularity, Totals: totals, Series: series}, nil
+}
+
+func (s *AdminService) GetAuditLog(ctx context.Context, query *models.AuditQuery) ([]*models.AuditEntry, string, error) {
+	return s.AuditRepo.Query(ctx, query)
+}
+
+//report related services
+
+func (s *AdminService) ListReports(ctx context.Context, status config.ReportStatus, limit int, cursor string) ([]*models.ReportCase, string, error) {
+	return s.ReportRepo.ListCases(ctx, status, limit, cursor)
+}
+
+func (s *AdminService) GetReport(ctx context.Context, targetType config.ContentType, targetId string) (*models.ResponseReportCase, error) {
+	reportCase, err := s.ReportRepo.GetCase(ctx, targetType, targetId)
+	if err != nil {
+		return nil, err
+	}
+	reports, err := s.ReportRepo.GetReports(ctx, targetType, targetId)
+	if err != nil {
+		return nil, err
+	}
+	return &models.ResponseReportCase{Case: reportCase, Reports: reports}, nil
+}
+
+// ResolveReport closes an open case. Actioning it removes the target through the regular
+// delete methods, dismissing it restores content that was hidden automatically.
+func (s *AdminService) ResolveReport(ctx context.Context, adminId string, targetType config.ContentType, targetId string, status config.ReportStatus, reason string) error {
+	reportCase, err := s.ReportRepo.GetCase(ctx, targetType, targetId)
+	if err != nil {
+		return err
+	}
+	if reportCase.Status != config.ReportOpen {
+		return utils.ReportNotOpen
+	}
+	if status == config.ReportActioned {
+		err = s.removeReported(ctx, reportCase, reason)
+	} else if reportCase.Hidden {
+		err = setReportedHidden(ctx, s.PostRepo, s.QuesRepo, s.AnsRepo, s.ReportRepo, reportCase, false)
+	}
+	if err != nil {
+		return err
+	}
+	err = s.ReportRepo.Resolve(ctx, targetType, targetId, status, adminId, time.Now())
 	if err != nil {
 		return err
 	}
+	recordAudit(ctx, s.AuditRepo, &models.AuditEntry{
+		ActorId:    adminId,
+		Action:     config.AuditResolveReport,
+		TargetType: targetType,
+		TargetId:   targetId,
+		TargetKeys: map[string]string{"status": string(status)},
+		Reason:     reason,
+	}, reportCase)
 	return nil
 }
+
+// removeReported deletes the reported target, a target that is already gone counts as removed
+func (s *AdminService) removeReported(ctx context.Context, reportCase *models.ReportCase, reason string) error {
+	var err error
+	switch reportCase.TargetType {
+	case config.PostContent:
+		var post *models.Post
+		post, err = s.PostRepo.GetUserPost(ctx, reportCase.OwnerId, reportCase.TargetId)
+		if err == nil {
+			err = s.DeletePost(ctx, reportCase.OwnerId, reportCase.TargetId, &models.DeletePost{CreatedAt: post.CreatedAt, Type: post.Type}, reason)
+		}
+		if errors.Is(err, utils.NotYourPost) {
+			return nil
+		}
+	case config.QuestionContent:
+		err = s.DeleteQuestion(ctx, reportCase.PostId, reportCase.TargetId, reportCase.OwnerId, reason)
+		if errors.Is(err, utils.NoQuestion) || errors.Is(err, utils.NotYourQuestion) {
+			return nil
+		}
+	case config.AnswerContent:
+		err = s.DeleteAnswer(ctx, reportCase.TargetId, reportCase.QuestionId, reportCase.OwnerId, reason)
+		if errors.Is(err, utils.NoAnswer) || errors.Is(err, utils.NotYourAnswer) {
+			return nil
+		}
+	case config.UserContent:
+		var users map[string]*models.User
+		users, err = s.UserRepo.FetchUsersByIds(ctx, []string{reportCase.OwnerId})
+		if user, ok := users[reportCase.OwnerId]; err == nil && ok {
+			_, err = s.DeleteUser(ctx, &models.DeleteUser{Username: user.Username, Email: user.Email, UId: user.UId}, reason)
+		}
+	}
+	return err
+}
@@ -0,0 +1,53 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"go.uber.org/zap"
+	"localeyes/internal/interfaces"
+	"localeyes/internal/models"
+	"localeyes/utils"
+	"time"
+)
+
+// recordAudit appends an entry for an action that has already been applied. Unless the caller
+// names the actor, the acting user, their role and the request id are taken from the request
+// context. A failed write is logged instead of returned so an applied action is not reported
+// as failed.
+func recordAudit(ctx context.Context, repo interfaces.AuditRepoInterface, entry *models.AuditEntry, before any) {
+	if entry.ActorId == "" {
+		entry.ActorId = contextActor(ctx)
+	}
+	if entry.ActorRole == "" {
+		entry.ActorRole, _ = ctx.Value("Role").(string)
+	}
+	entry.RequestId, _ = ctx.Value("RequestId").(string)
+	entry.Id = utils.GenerateRandomId()
+	entry.CreatedAt = time.Now()
+	if before != nil {
+		snapshot, err := json.Marshal(before)
+		if err == nil {
+			entry.Before = snapshot
+		}
+	}
+	if err := repo.Record(ctx, entry); err != nil {
+		utils.LoggerFrom(ctx).Error("failed to record audit entry", zap.String("action", string(entry.Action)), zap.String("target_id", entry.TargetId), zap.Error(err))
+	}
+}
+
+// auditUser is the snapshot kept for user targets, it leaves out the password hash
+func auditUser(user *models.User) *models.ResponseUser {
+	return &models.ResponseUser{
+		UId:            user.UId,
+		Username:       user.Username,
+		Email:          user.Email,
+		City:           user.City,
+		LivingSince:    user.DwellingAge,
+		Tag:            user.Tag,
+		ActiveStatus:   user.IsActive,
+		Status:         user.CurrentStatus(user.IsActive),
+		StatusReason:   user.StatusReason,
+		SuspendedUntil: user.SuspendedUntil,
+		CreatedAt:      user.CreatedAt,
+	}
+}
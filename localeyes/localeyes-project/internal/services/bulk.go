@@ -0,0 +1,195 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"go.uber.org/zap"
+	"localeyes/config"
+	"localeyes/internal/models"
+	"localeyes/utils"
+	"path"
+	"time"
+)
+
+// runBulkJob collects the items of a bulk job once and keeps them on the job, then applies
+// them one at a time and saves the outcome of each as soon as it is known, so a resumed job
+// skips every item that is already done. A dry run stops once the items are collected so they
+// show what a real run would touch. Every applied change is audited on its own.
+func (s *AdminService) runBulkJob(ctx context.Context, slice *jobSlice) error {
+	job := slice.job
+	if job.Stage != "applying" {
+		job.Stage = "collecting"
+		saveJob(ctx, s.JobRepo, job)
+		items, err := s.collectBulkItems(ctx, job)
+		if err != nil {
+			return err
+		}
+		if len(items) > config.MaxBulkItems {
+			return fmt.Errorf("%d items match, narrow the selection down to at most %d", len(items), config.MaxBulkItems)
+		}
+		for _, item := range items {
+			item.Status = config.ItemPlanned
+		}
+		job.Total = len(items)
+		job.Items = items
+		if job.DryRun {
+			job.Stage = "preview"
+			return nil
+		}
+		// the collected items are the checkpoint every later slice works from
+		job.Stage = "applying"
+		job.UpdatedAt = time.Now()
+		if err := s.JobRepo.SaveJob(ctx, job); err != nil {
+			return err
+		}
+	}
+	for i, item := range job.Items {
+		if item.Status != config.ItemPlanned {
+			continue
+		}
+		if slice.spent() {
+			return errSliceSpent
+		}
+		err := s.applyBulkItem(ctx, job, item)
+		job.Processed++
+		item.Status = config.ItemDone
+		if err != nil {
+			job.Failed++
+			item.Status = config.ItemFailed
+			item.Error = err.Error()
+			utils.LoggerFrom(ctx).Error("job step failed", zap.String("stage", job.Stage), zap.String("item_id", item.Id), zap.Error(err))
+		}
+		// an item whose outcome could not be saved is applied again by a resumed job, which
+		// finds it already changed
+		job.UpdatedAt = time.Now()
+		if err := s.JobRepo.SaveJobItem(ctx, job, i); err != nil {
+			utils.LoggerFrom(ctx).Error("failed to save job item", zap.String("item_id", item.Id), zap.Error(err))
+		}
+	}
+	if job.Failed > 0 {
+		return fmt.Errorf("%d of %d items failed", job.Failed, job.Total)
+	}
+	return nil
+}
+
+// collectBulkItems lists what a bulk job changes, each item carries what is needed to apply it
+func (s *AdminService) collectBulkItems(ctx context.Context, job *models.Job) ([]*models.JobItem, error) {
+	switch job.Kind {
+	case config.BulkDeleteContentJob:
+		uId := job.TargetId
+		content, err := s.UserRepo.FindOwnedContent(ctx, uId)
+		if err != nil {
+			return nil, err
+		}
+		items := postItems(content.Posts)
+		for _, question := range content.Questions {
+			items = append(items, &models.JobItem{Type: config.QuestionContent, Id: question.QId, ParentId: question.PostId, OwnerId: uId})
+		}
+		for _, answer := range content.Answers {
+			items = append(items, &models.JobItem{Type: config.AnswerContent, Id: answer.RId, ParentId: answer.QId, OwnerId: uId})
+		}
+		for _, comment := range content.Comments {
+			items = append(items, &models.JobItem{Type: config.CommentContent, Id: path.Base(comment.Path), ParentId: comment.PostId, OwnerId: uId, Path: comment.Path})
+		}
+		return items, nil
+	case config.BulkDeletePostsJob:
+		search := job.Params["search"]
+		filter := job.Params["type"]
+		posts, err := s.PostRepo.GetAllPostsWithFilter(ctx, nil, nil, &search, &filter, nil)
+		if err != nil {
+			return nil, err
+		}
+		matched := make([]*models.Post, 0, len(posts))
+		for _, post := range posts {
+			if job.TargetId == "" || post.UId == job.TargetId {
+				matched = append(matched, post)
+			}
+		}
+		return postItems(matched), nil
+	case config.BulkAccountJob:
+		// the listed users are the items, they are known when the job is queued
+		return job.Items, nil
+	}
+	return nil, fmt.Errorf("%s is not a bulk job", job.Kind)
+}
+
+func postItems(posts []*models.Post) []*models.JobItem {
+	items := make([]*models.JobItem, 0, len(posts))
+	for _, post := range posts {
+		items = append(items, &models.JobItem{Type: config.PostContent, Id: post.PostId, OwnerId: post.UId})
+	}
+	return items
+}
+
+// applyBulkItem makes the change of a bulk job to one item. Content that is already gone counts
+// as deleted so an item applied again by a resumed job does not fail.
+func (s *AdminService) applyBulkItem(ctx context.Context, job *models.Job, item *models.JobItem) error {
+	reason := job.Params["reason"]
+	switch item.Type {
+	case config.PostContent:
+		post, err := s.PostRepo.GetUserPost(ctx, item.OwnerId, item.Id)
+		if err != nil {
+			return ignoreGone(err, utils.NotYourPost)
+		}
+		err = s.DeletePost(ctx, item.OwnerId, item.Id, &models.DeletePost{Type: post.Type, CreatedAt: post.CreatedAt}, reason)
+		return ignoreGone(err, utils.NotYourPost)
+	case config.QuestionContent:
+		return ignoreGone(s.DeleteQuestion(ctx, item.ParentId, item.Id, item.OwnerId, reason), utils.NoQuestion)
+	case config.AnswerContent:
+		return ignoreGone(s.DeleteAnswer(ctx, item.Id, item.ParentId, item.OwnerId, reason), utils.NoAnswer)
+	case config.CommentContent:
+		return ignoreGone(s.CommRepo.SoftDeleteComment(ctx, item.ParentId, item.Path, item.OwnerId, time.Now()), utils.NotYourComment)
+	case config.UserContent:
+		if config.BulkAction(job.Params["action"]) == config.BulkSuspend {
+			until, err := time.Parse(time.RFC3339, job.Params["until"])
+			if err != nil {
+				return err
+			}
+			return s.SuspendUser(ctx, item.Id, until, reason)
+		}
+		return s.ReactivateUser(ctx, item.Id, reason)
+	}
+	return fmt.Errorf("%s items cannot be changed in bulk", item.Type)
+}
+
+// BulkDeleteUserContent deletes the posts, questions and answers of a user and blanks their
+// comments, the account itself is kept
+func (s *AdminService) BulkDeleteUserContent(ctx context.Context, uId string, dryRun bool, reason string) (*models.Job, error) {
+	return enqueueJob(ctx, s.JobRepo, s.JobQueue, &models.Job{
+		Kind:     config.BulkDeleteContentJob,
+		TargetId: uId,
+		DryRun:   dryRun,
+		Params:   map[string]string{"reason": reason},
+	})
+}
+
+// BulkDeletePosts deletes the posts that the feed search finds for request, optionally only
+// those of one user
+func (s *AdminService) BulkDeletePosts(ctx context.Context, request *models.BulkDeletePosts, dryRun bool) (*models.Job, error) {
+	return enqueueJob(ctx, s.JobRepo, s.JobQueue, &models.Job{
+		Kind:     config.BulkDeletePostsJob,
+		TargetId: request.UserId,
+		DryRun:   dryRun,
+		Params:   map[string]string{"reason": request.Reason, "search": request.Search, "type": string(request.Type)},
+	})
+}
+
+// BulkChangeAccounts reactivates or suspends every listed user, users whose account cannot make
+// the change are reported as failed items
+func (s *AdminService) BulkChangeAccounts(ctx context.Context, action config.BulkAction, request *models.BulkAccountChange, dryRun bool) (*models.Job, error) {
+	params := map[string]string{"reason": request.Reason, "action": string(action)}
+	if action == config.BulkSuspend {
+		params["until"] = request.Until.Format(time.RFC3339)
+	}
+	items := make([]*models.JobItem, 0, len(request.UserIds))
+	for _, uId := range request.UserIds {
+		items = append(items, &models.JobItem{Type: config.UserContent, Id: uId, Status: config.ItemPlanned})
+	}
+	return enqueueJob(ctx, s.JobRepo, s.JobQueue, &models.Job{
+		Kind:   config.BulkAccountJob,
+		DryRun: dryRun,
+		Result: map[string]string{"action": string(action)},
+		Items:  items,
+		Params: params,
+	})
+}
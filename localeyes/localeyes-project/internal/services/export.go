@@ -0,0 +1,226 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"localeyes/config"
+	"localeyes/internal/interfaces"
+	"localeyes/internal/models"
+	"localeyes/utils"
+	"strconv"
+	"time"
+)
+
+// ExportBuilder assembles the personal data archive of a user. Users request their own export
+// and admins use the same builder to answer support requests.
+type ExportBuilder struct {
+	UserRepo   interfaces.UserRepository
+	PostRepo   interfaces.PostRepository
+	QuesRepo   interfaces.QuestionRepoInterface
+	AnsRepo    interfaces.AnswerRepoInterface
+	ExportRepo interfaces.ExportRepoInterface
+	JobRepo    interfaces.JobRepoInterface
+	JobQueue   interfaces.JobQueueInterface
+}
+
+func NewExportBuilder(userRepo interfaces.UserRepository, postRepo interfaces.PostRepository, quesRepo interfaces.QuestionRepoInterface, ansRepo interfaces.AnswerRepoInterface, exportRepo interfaces.ExportRepoInterface, jobRepo interfaces.JobRepoInterface, jobQueue interfaces.JobQueueInterface) *ExportBuilder {
+	return &ExportBuilder{
+		UserRepo:   userRepo,
+		PostRepo:   postRepo,
+		QuesRepo:   quesRepo,
+		AnsRepo:    ansRepo,
+		ExportRepo: exportRepo,
+		JobRepo:    jobRepo,
+		JobQueue:   jobQueue,
+	}
+}
+
+// Start queues the job that builds the export of uId. Once the job succeeds its result holds
+// the download token and when it expires.
+func (b *ExportBuilder) Start(ctx context.Context, uId string, format config.ExportFormat) (*models.Job, error) {
+	return enqueueJob(ctx, b.JobRepo, b.JobQueue, &models.Job{
+		Kind:     config.ExportJob,
+		TargetId: uId,
+		Params:   map[string]string{"format": string(format)},
+	})
+}
+
+// build collects and stores the export in one step. It only writes the archive at the very
+// end, a run that is cut off leaves nothing behind and the next one starts over.
+func (b *ExportBuilder) build(ctx context.Context, slice *jobSlice) error {
+	job := slice.job
+	uId := job.TargetId
+	format := config.ExportFormat(job.Params["format"])
+	job.Stage = "collecting"
+	export, err := b.Collect(ctx, uId)
+	if err != nil {
+		return err
+	}
+	job.Stage = "packaging"
+	archive, err := encodeExport(export, format)
+	if err != nil {
+		return err
+	}
+	token, err := utils.GenerateSecret()
+	if err != nil {
+		return err
+	}
+	meta := &models.Export{
+		UId:       uId,
+		JobId:     job.Id,
+		Format:    format,
+		FileName:  "localeyes-export-" + uId + "." + string(format),
+		ExpiresAt: time.Now().Add(config.ExportLinkTTL),
+	}
+	meta.ContentType = "application/json"
+	if format == config.ZipExport {
+		meta.ContentType = "application/zip"
+	}
+	err = b.ExportRepo.SaveArchive(ctx, token, meta, archive)
+	if err != nil {
+		return err
+	}
+	job.Result = map[string]string{
+		"download_token": token,
+		"expires_at":     meta.ExpiresAt.Format(time.RFC3339),
+	}
+	return nil
+}
+
+// Collect gathers the profile, posts, questions, answers, likes and notifications of a user
+func (b *ExportBuilder) Collect(ctx context.Context, uId string) (*models.UserExport, error) {
+	users, err := b.UserRepo.FetchUsersByIds(ctx, []string{uId})
+	if err != nil {
+		return nil, err
+	}
+	user, ok := users[uId]
+	if !ok {
+		return nil, utils.NoUser
+	}
+	export := &models.UserExport{
+		GeneratedAt:   time.Now(),
+		Profile:       auditUser(user),
+		Questions:     make([]*models.Question, 0),
+		Answers:       make([]*models.Reply, 0),
+		Likes:         make([]string, 0),
+		Notifications: make([]*models.Notification, 0),
+	}
+	export.Posts, err = b.PostRepo.GetPostsByUId(ctx, uId)
+	if err != nil {
+		return nil, err
+	}
+	content, err := b.UserRepo.FindOwnedContent(ctx, uId)
+	if err != nil {
+		return nil, err
+	}
+	for _, ref := range content.Questions {
+		question, err := b.QuesRepo.GetQuestion(ctx, ref.PostId, ref.QId)
+		if errors.Is(err, utils.NoQuestion) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		export.Questions = append(export.Questions, question)
+	}
+	for _, ref := range content.Answers {
+		answer, err := b.AnsRepo.GetAnswer(ctx, ref.QId, ref.RId)
+		if errors.Is(err, utils.NoAnswer) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		export.Answers = append(export.Answers, answer)
+	}
+	export.Likes = append(export.Likes, content.Likes...)
+	for _, target := range content.Reactions {
+		if target.Type == config.PostContent && target.Reaction == config.Like {
+			export.Likes = append(export.Likes, target.Id)
+		}
+	}
+	notifications, err := b.UserRepo.FetchNotifications(ctx, uId)
+	if err != nil {
+		return nil, err
+	}
+	export.Notifications = append(export.Notifications, notifications...)
+	return export, nil
+}
+
+// Status returns an export job of uId, jobs of other users or other kinds are reported missing
+func (b *ExportBuilder) Status(ctx context.Context, uId, jobId string) (*models.Job, error) {
+	job, err := b.JobRepo.GetJob(ctx, jobId)
+	if err != nil {
+		return nil, err
+	}
+	if job.Kind != config.ExportJob || job.TargetId != uId {
+		return nil, utils.NoJob
+	}
+	return job, nil
+}
+
+// Download returns a finished export given its download token
+func (b *ExportBuilder) Download(ctx context.Context, token string) (*models.Export, []byte, error) {
+	return b.ExportRepo.GetArchive(ctx, token)
+}
+
+// encodeExport renders the export as one JSON document or as a zip holding a CSV file per section
+func encodeExport(export *models.UserExport, format config.ExportFormat) ([]byte, error) {
+	if format != config.ZipExport {
+		return json.MarshalIndent(export, "", "  ")
+	}
+	profile := export.Profile
+	posts := [][]string{{"post_id", "type", "title", "content", "likes", "created_at"}}
+	for _, post := range export.Posts {
+		posts = append(posts, []string{post.PostId, string(post.Type), post.Title, post.Content, strconv.Itoa(post.Likes), post.CreatedAt.Format(time.RFC3339)})
+	}
+	questions := [][]string{{"question_id", "post_id", "text", "created_at"}}
+	for _, question := range export.Questions {
+		questions = append(questions, []string{question.QId, question.PostId, question.Text, question.CreatedAt.Format(time.RFC3339)})
+	}
+	answers := [][]string{{"answer_id", "question_id", "answer", "score", "accepted", "created_at"}}
+	for _, answer := range export.Answers {
+		answers = append(answers, []string{answer.RId, answer.QId, answer.Answer, strconv.Itoa(answer.Score), strconv.FormatBool(answer.Accepted), answer.CreatedAt.Format(time.RFC3339)})
+	}
+	likes := [][]string{{"post_id"}}
+	for _, pId := range export.Likes {
+		likes = append(likes, []string{pId})
+	}
+	notifications := [][]string{{"post_id", "user_id", "type", "title", "created_at"}}
+	for _, notification := range export.Notifications {
+		notifications = append(notifications, []string{notification.PostId, notification.UId, string(notification.Type), notification.Title, notification.CreatedAt.Format(time.RFC3339)})
+	}
+	files := []struct {
+		name string
+		rows [][]string
+	}{
+		{"profile.csv", [][]string{
+			{"user_id", "username", "email", "city", "living_since", "tag", "status"},
+			{profile.UId, profile.Username, profile.Email, profile.City, strconv.FormatFloat(profile.LivingSince, 'f', -1, 64), profile.Tag, string(profile.Status)},
+		}},
+		{"posts.csv", posts},
+		{"questions.csv", questions},
+		{"answers.csv", answers},
+		{"likes.csv", likes},
+		{"notifications.csv", notifications},
+	}
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for _, file := range files {
+		w, err := archive.Create(file.name)
+		if err != nil {
+			return nil, err
+		}
+		if err := csv.NewWriter(w).WriteAll(file.rows); err != nil {
+			return nil, err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,179 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"localeyes/config"
+	"localeyes/internal/interfaces"
+	"localeyes/internal/models"
+	"localeyes/utils"
+	"time"
+)
+
+// errSliceSpent is returned by job work that stopped because its slice ran out of time, the job
+// is saved and queued again to carry on from its checkpoint
+var errSliceSpent = errors.New("job slice spent")
+
+// jobWork does one slice of a job. It picks up where the checkpoint saved on the job left off,
+// calls slice.step for every item it handles and returns errSliceSpent once the slice is spent.
+type jobWork func(ctx context.Context, slice *jobSlice) error
+
+// jobSlice is the part of a job one worker run gets through before its deadline
+type jobSlice struct {
+	job      *models.Job
+	repo     interfaces.JobRepoInterface
+	deadline time.Time
+}
+
+// step counts a handled item and saves the job's progress every JobProgressInterval items
+func (slice *jobSlice) step(ctx context.Context, err error) {
+	job := slice.job
+	job.Processed++
+	if err != nil {
+		job.Failed++
+		utils.LoggerFrom(ctx).Error("job step failed", zap.String("stage", job.Stage), zap.Error(err))
+	}
+	if job.Processed%config.JobProgressInterval == 0 {
+		saveJob(ctx, slice.repo, job)
+	}
+}
+
+// spent reports whether the slice has to stop so its worker is not cut off mid item
+func (slice *jobSlice) spent() bool {
+	return !slice.deadline.IsZero() && time.Now().After(slice.deadline)
+}
+
+// enqueueJob saves job and queues it for the job worker, the returned job is as it was saved.
+// The acting user, their role and the request id go along so the worker records the changes
+// of the job like the request that started it would have.
+func enqueueJob(ctx context.Context, repo interfaces.JobRepoInterface, queue interfaces.JobQueueInterface, job *models.Job) (*models.Job, error) {
+	now := time.Now()
+	job.Id = utils.GenerateRandomId()
+	job.Status = config.JobPending
+	job.CreatedAt = now
+	job.UpdatedAt = now
+	if job.ActorId == "" {
+		job.ActorId = contextActor(ctx)
+	}
+	if job.Params == nil {
+		job.Params = make(map[string]string)
+	}
+	job.Params["actor_role"], _ = ctx.Value("Role").(string)
+	job.Params["request_id"], _ = ctx.Value("RequestId").(string)
+	if err := repo.SaveJob(ctx, job); err != nil {
+		return nil, err
+	}
+	if err := queue.Enqueue(ctx, job.Id); err != nil {
+		finishJob(ctx, repo, job, fmt.Errorf("job could not be queued: %w", err))
+		return nil, err
+	}
+	return job, nil
+}
+
+// JobWorker runs queued jobs. A run claims the lease of the job, works on it until shortly
+// before the deadline of its context and then either finishes the job or saves where it got to
+// and queues it again. A worker that is killed mid slice leaves its lease to run out, the queue
+// hands the job out again and the next worker resumes it from the last checkpoint.
+type JobWorker struct {
+	JobRepo interfaces.JobRepoInterface
+	Queue   interfaces.JobQueueInterface
+	Admin   *AdminService
+}
+
+func NewJobWorker(jobRepo interfaces.JobRepoInterface, queue interfaces.JobQueueInterface, admin *AdminService) *JobWorker {
+	return &JobWorker{
+		JobRepo: jobRepo,
+		Queue:   queue,
+		Admin:   admin,
+	}
+}
+
+// Run runs a slice of the job. An error leaves the job to the queue to hand out again, jobs
+// that no longer exist or have already finished are dropped.
+func (w *JobWorker) Run(ctx context.Context, jobId string) error {
+	lease := time.Now().Add(config.JobLease)
+	var deadline time.Time
+	if end, ok := ctx.Deadline(); ok {
+		lease = end
+		deadline = end.Add(-config.JobSliceMargin)
+	}
+	job, err := w.JobRepo.ClaimJob(ctx, jobId, lease)
+	if errors.Is(err, utils.NoJob) {
+		utils.LoggerFrom(ctx).Warn("queued job does not exist", zap.String("job_id", jobId))
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	ctx = jobContext(ctx, job)
+	if job.Status == config.JobSucceeded || job.Status == config.JobFailed {
+		job.LeaseUntil = 0
+		saveJob(ctx, w.JobRepo, job)
+		return nil
+	}
+	work := w.work(job)
+	if work == nil {
+		finishJob(ctx, w.JobRepo, job, fmt.Errorf("no worker runs %s jobs", job.Kind))
+		return nil
+	}
+	job.Status = config.JobRunning
+	saveJob(ctx, w.JobRepo, job)
+	err = work(ctx, &jobSlice{job: job, repo: w.JobRepo, deadline: deadline})
+	if errors.Is(err, errSliceSpent) {
+		job.LeaseUntil = 0
+		saveJob(ctx, w.JobRepo, job)
+		utils.LoggerFrom(ctx).Info("job slice spent, queueing the rest", zap.Int("processed", job.Processed), zap.Int("total", job.Total))
+		return w.Queue.Enqueue(ctx, job.Id)
+	}
+	finishJob(ctx, w.JobRepo, job, err)
+	return nil
+}
+
+func (w *JobWorker) work(job *models.Job) jobWork {
+	switch job.Kind {
+	case config.DeleteAccountJob:
+		return w.Admin.deleteAccount
+	case config.ExportJob:
+		return w.Admin.Exports.build
+	case config.BulkDeleteContentJob, config.BulkDeletePostsJob, config.BulkAccountJob:
+		return w.Admin.runBulkJob
+	}
+	return nil
+}
+
+// jobContext carries the actor of the job and the request that started it, so what the job
+// changes is recorded the same way as if that request had changed it
+func jobContext(ctx context.Context, job *models.Job) context.Context {
+	ctx = context.WithValue(ctx, "Id", job.ActorId)
+	ctx = context.WithValue(ctx, "Role", job.Params["actor_role"])
+	ctx = context.WithValue(ctx, "RequestId", job.Params["request_id"])
+	return utils.WithLogger(ctx, utils.Logger.With(
+		zap.String("job_id", job.Id),
+		zap.String("kind", string(job.Kind)),
+		zap.String("request_id", job.Params["request_id"]),
+	))
+}
+
+// finishJob records the outcome of a job and gives up its lease
+func finishJob(ctx context.Context, repo interfaces.JobRepoInterface, job *models.Job, err error) {
+	finishedAt := time.Now()
+	job.FinishedAt = &finishedAt
+	job.LeaseUntil = 0
+	job.Status = config.JobSucceeded
+	if err != nil {
+		job.Status = config.JobFailed
+		job.Error = err.Error()
+	}
+	saveJob(ctx, repo, job)
+	utils.LoggerFrom(ctx).Info("job finished", zap.String("job_id", job.Id), zap.String("kind", string(job.Kind)), zap.String("status", string(job.Status)))
+}
+
+// saveJob stores the job's progress, a failed save only costs progress reporting so it is logged
+func saveJob(ctx context.Context, repo interfaces.JobRepoInterface, job *models.Job) {
+	job.UpdatedAt = time.Now()
+	if err := repo.SaveJob(ctx, job); err != nil {
+		utils.LoggerFrom(ctx).Error("failed to save job", zap.String("job_id", job.Id), zap.Error(err))
+	}
+}
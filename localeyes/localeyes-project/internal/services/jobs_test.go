@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"localeyes/config"
+	"localeyes/internal/interfaces"
+	"localeyes/internal/jobqueue"
+	"localeyes/internal/models"
+	"localeyes/utils"
+	"testing"
+)
+
+// memoryJobs keeps the last saved state of every job. Methods a test does not use are left to
+// the embedded interface and panic when called.
+type memoryJobs struct {
+	interfaces.JobRepoInterface
+	jobs map[string]models.Job
+}
+
+func (repo *memoryJobs) SaveJob(ctx context.Context, job *models.Job) error {
+	repo.jobs[job.Id] = *job
+	return nil
+}
+
+// recordingQueue remembers the jobs queued on it
+type recordingQueue struct {
+	queued []string
+}
+
+func (queue *recordingQueue) Enqueue(ctx context.Context, jobId string) error {
+	queue.queued = append(queue.queued, jobId)
+	return nil
+}
+
+func TestEnqueueJob(t *testing.T) {
+	tests := []struct {
+		name   string
+		queue  interfaces.JobQueueInterface
+		err    error
+		status config.JobStatus
+	}{
+		{"queued", &recordingQueue{}, nil, config.JobPending},
+		{"no queue configured", jobqueue.NewDisabledQueue(), utils.JobsUnavailable, config.JobFailed},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repo := &memoryJobs{jobs: make(map[string]models.Job)}
+			ctx := context.WithValue(context.Background(), "Id", "admin1")
+			ctx = context.WithValue(ctx, "Role", "admin")
+			job, err := enqueueJob(ctx, repo, test.queue, &models.Job{Kind: config.ExportJob, TargetId: "u1"})
+			if !errors.Is(err, test.err) {
+				t.Fatalf("enqueueJob error = %v, want %v", err, test.err)
+			}
+			if len(repo.jobs) != 1 {
+				t.Fatalf("%d jobs saved, want 1", len(repo.jobs))
+			}
+			for id, saved := range repo.jobs {
+				if saved.Status != test.status {
+					t.Fatalf("job saved as %s, want %s", saved.Status, test.status)
+				}
+				if saved.ActorId != "admin1" || saved.Params["actor_role"] != "admin" {
+					t.Fatalf("job saved with actor %q and role %q", saved.ActorId, saved.Params["actor_role"])
+				}
+				if queue, ok := test.queue.(*recordingQueue); ok {
+					if len(queue.queued) != 1 || queue.queued[0] != id || job.Id != id {
+						t.Fatalf("queued %q, want job %q", queue.queued, id)
+					}
+				}
+			}
+		})
+	}
+}
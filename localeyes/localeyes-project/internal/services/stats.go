@@ -0,0 +1,24 @@
+package services
+
+import (
+	"context"
+	"go.uber.org/zap"
+	"localeyes/config"
+	"localeyes/internal/interfaces"
+	"localeyes/utils"
+	"time"
+)
+
+// recordStats adds counts to the admin statistics for a write that has already happened. Like
+// audit entries, a failed update is logged instead of failing the write it describes.
+func recordStats(ctx context.Context, repo interfaces.StatsRepoInterface, counts map[config.StatsMetric]int) {
+	if err := repo.Increment(ctx, time.Now(), counts); err != nil {
+		utils.LoggerFrom(ctx).Error("failed to record stats", zap.Any("counts", counts), zap.Error(err))
+	}
+}
+
+func recordActive(ctx context.Context, repo interfaces.StatsRepoInterface, uId string) {
+	if err := repo.MarkActive(ctx, uId, time.Now()); err != nil {
+		utils.LoggerFrom(ctx).Error("failed to record active user", zap.String("user_id", uId), zap.Error(err))
+	}
+}
@@ -14,16 +14,29 @@ import (
 	"localeyes/utils"
 	"math"
 	"os"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
 type UserService struct {
-	UserRepo interfaces.UserRepository
-	PostRepo interfaces.PostRepository
-	QuesRepo interfaces.QuestionRepoInterface
-	AnsRepo  interfaces.AnswerRepoInterface
-	OTPRepo  interfaces.OTPRepoInterface
+	UserRepo   interfaces.UserRepository
+	PostRepo   interfaces.PostRepository
+	QuesRepo   interfaces.QuestionRepoInterface
+	AnsRepo    interfaces.AnswerRepoInterface
+	OTPRepo    interfaces.OTPRepoInterface
+	RevRepo    interfaces.RevisionRepoInterface
+	CommRepo   interfaces.CommentRepoInterface
+	ReacRepo   interfaces.ReactionRepoInterface
+	BookRepo   interfaces.BookmarkRepoInterface
+	FollRepo   interfaces.FollowRepoInterface
+	RelRepo    interfaces.RelationRepoInterface
+	ReportRepo interfaces.ReportRepoInterface
+	AuditRepo  interfaces.AuditRepoInterface
+	StatsRepo  interfaces.StatsRepoInterface
+	Exports    *ExportBuilder
 }
 
 func NewUserService(
@@ -32,13 +45,33 @@ func NewUserService(
 	quesRepo interfaces.QuestionRepoInterface,
 	ansRepo interfaces.AnswerRepoInterface,
 	otpRepo interfaces.OTPRepoInterface,
+	revRepo interfaces.RevisionRepoInterface,
+	commRepo interfaces.CommentRepoInterface,
+	reacRepo interfaces.ReactionRepoInterface,
+	bookRepo interfaces.BookmarkRepoInterface,
+	follRepo interfaces.FollowRepoInterface,
+	relRepo interfaces.RelationRepoInterface,
+	reportRepo interfaces.ReportRepoInterface,
+	auditRepo interfaces.AuditRepoInterface,
+	statsRepo interfaces.StatsRepoInterface,
+	exports *ExportBuilder,
 ) *UserService {
 	return &UserService{
-		UserRepo: userRepo,
-		PostRepo: postRepo,
-		QuesRepo: quesRepo,
-		AnsRepo:  ansRepo,
-		OTPRepo:  otpRepo,
+		UserRepo:   userRepo,
+		PostRepo:   postRepo,
+		QuesRepo:   quesRepo,
+		AnsRepo:    ansRepo,
+		OTPRepo:    otpRepo,
+		RevRepo:    revRepo,
+		CommRepo:   commRepo,
+		ReacRepo:   reacRepo,
+		BookRepo:   bookRepo,
+		FollRepo:   follRepo,
+		RelRepo:    relRepo,
+		ReportRepo: reportRepo,
+		AuditRepo:  auditRepo,
+		StatsRepo:  statsRepo,
+		Exports:    exports,
 	}
 }
 
@@ -55,42 +88,113 @@ func (s *UserService) Signup(ctx context.Context, username, password, email stri
 	}
 	hashedPassword := hashPassword(password)
 	tag := utils.SetTag(dwellingAge)
+	now := time.Now()
 	user := &models.User{
-		UId:         uid.String(),
-		Username:    username,
-		Password:    hashedPassword,
-		City:        "delhi",
-		IsActive:    true,
-		DwellingAge: math.Round(dwellingAge*100) / 100,
-		Tag:         tag,
-		Email:       email,
+		UId:          uid.String(),
+		Username:     username,
+		Password:     hashedPassword,
+		City:         "delhi",
+		IsActive:     true,
+		DwellingAge:  math.Round(dwellingAge*100) / 100,
+		Tag:          tag,
+		Email:        email,
+		CreatedAt:    &now,
+		AccountState: models.AccountState{Status: config.AccountActive},
 	}
 	err = s.UserRepo.CreateUser(ctx, user)
-	return err
+	if err != nil {
+		return err
+	}
+	recordStats(ctx, s.StatsRepo, map[config.StatsMetric]int{config.SignupsMetric: 1})
+	return nil
+}
+
+// CheckAccess fails unless the account of uId may still use the API, so a suspension, ban or
+// deactivation also stops the tokens issued before it. A suspension that has run out does not
+// block, it is lifted on the next login.
+func (s *UserService) CheckAccess(ctx context.Context, uId string) error {
+	users, err := s.UserRepo.FetchUsersByIds(ctx, []string{uId})
+	if err != nil {
+		return err
+	}
+	user, ok := users[uId]
+	if !ok {
+		return utils.InvalidToken
+	}
+	if user.SuspensionExpired(time.Now()) {
+		return nil
+	}
+	return accountStateError(user.AccountState, user.IsActive)
+}
+
+// accountStateError is why the account cannot be used, or nil when it is active
+func accountStateError(state models.AccountState, isActive bool) error {
+	switch state.CurrentStatus(isActive) {
+	case config.AccountDeactivated:
+		return utils.InactiveUser
+	case config.AccountSuspended:
+		if state.SuspendedUntil != nil {
+			return fmt.Errorf("%w until %s: %s", utils.AccountSuspended, state.SuspendedUntil.Format(time.RFC3339), state.StatusReason)
+		}
+		return fmt.Errorf("%w: %s", utils.AccountSuspended, state.StatusReason)
+	case config.AccountBanned:
+		return fmt.Errorf("%w: %s", utils.AccountBanned, state.StatusReason)
+	case config.AccountPendingVerification:
+		return utils.AccountPendingVerification
+	}
+	return nil
 }
 
 func (s *UserService) Login(ctx context.Context, username, password string) (*models.User, error) {
 	hashedPassword := hashPassword(password)
 	dbUser, err := s.UserRepo.FetchUserByUsername(ctx, username)
-	if err != nil {
-		return nil, errors.New(err.Error())
-	} else if errors.Is(err, utils.NoUser) {
+	if errors.Is(err, utils.NoUser) {
 		return nil, utils.InvalidAccountCredentials
-	} else if dbUser.IsActive == false {
-		return nil, utils.InactiveUser
+	} else if err != nil {
+		return nil, err
 	} else if dbUser.Password != hashedPassword {
+		recordAudit(ctx, s.AuditRepo, &models.AuditEntry{
+			Action:     config.AuditLoginFailed,
+			TargetType: config.UserContent,
+			TargetId:   dbUser.UId,
+		}, nil)
 		return nil, utils.InvalidAccountCredentials
 	}
+	// an expired suspension is lifted on the next login attempt
+	if dbUser.SuspensionExpired(time.Now()) {
+		err = changeAccountState(ctx, s.UserRepo, s.AuditRepo, dbUser.UId, config.AuditReinstateUser, models.AccountState{
+			StatusActor:  "system",
+			StatusReason: "suspension expired",
+		})
+		if err != nil {
+			return nil, err
+		}
+		dbUser.AccountState = models.AccountState{Status: config.AccountActive}
+		dbUser.IsActive = true
+	}
+	err = accountStateError(dbUser.AccountState, dbUser.IsActive)
+	if err != nil {
+		return nil, err
+	}
 	user := &models.User{
-		Username:    dbUser.Username,
-		UId:         dbUser.UId,
-		City:        dbUser.City,
-		DwellingAge: dbUser.DwellingAge,
-		Password:    dbUser.Password,
-		Email:       dbUser.Email,
-		Tag:         dbUser.Tag,
-		IsActive:    dbUser.IsActive,
+		Username:     dbUser.Username,
+		UId:          dbUser.UId,
+		City:         dbUser.City,
+		DwellingAge:  dbUser.DwellingAge,
+		Password:     dbUser.Password,
+		Email:        dbUser.Email,
+		Tag:          dbUser.Tag,
+		IsActive:     dbUser.IsActive,
+		AccountState: dbUser.AccountState,
 	}
+	recordAudit(ctx, s.AuditRepo, &models.AuditEntry{
+		ActorId:    user.UId,
+		ActorRole:  "user",
+		Action:     config.AuditLogin,
+		TargetType: config.UserContent,
+		TargetId:   user.UId,
+	}, nil)
+	recordActive(ctx, s.StatsRepo, user.UId)
 	return user, nil
 }
 
@@ -99,15 +203,308 @@ func (s *UserService) FetchProfile(ctx context.Context, uid string) (*models.Use
 	if err != nil {
 		return nil, err
 	}
+	counts, err := s.FollRepo.GetFollowCounts(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	user.Followers = counts.FollowerCount
+	user.Following = counts.FollowingCount
 	return user, nil
 }
 
+func (s *UserService) StartExport(ctx context.Context, uId string, format config.ExportFormat) (*models.Job, error) {
+	return s.Exports.Start(ctx, uId, format)
+}
+
+func (s *UserService) GetExport(ctx context.Context, uId, jobId string) (*models.Job, error) {
+	return s.Exports.Status(ctx, uId, jobId)
+}
+
+func (s *UserService) DownloadExport(ctx context.Context, token string) (*models.Export, []byte, error) {
+	return s.Exports.Download(ctx, token)
+}
+
 func (s *UserService) DeActivate(ctx context.Context, uid string) error {
-	user, err := s.UserRepo.FetchUserById(ctx, uid, true)
-	user.IsActive = false
-	err = s.UserRepo.ToggleUserActiveStatus(ctx, user)
+	err := changeAccountState(ctx, s.UserRepo, s.AuditRepo, uid, config.AuditDeactivate, models.AccountState{
+		StatusActor:  uid,
+		StatusReason: "deactivated by user",
+	})
+	if err != nil {
+		return err
+	}
+	recordStats(ctx, s.StatsRepo, map[config.StatsMetric]int{config.DeactivationsMetric: 1})
+	return nil
+}
 
-	return err
+//follow related services
+
+func (s *UserService) Follow(ctx context.Context, uId, followeeId string) error {
+	if uId == followeeId {
+		return utils.CannotFollowSelf
+	}
+	_, err := s.UserRepo.FetchUserById(ctx, followeeId, true)
+	if err != nil {
+		return err
+	}
+	err = s.checkNotBlocked(ctx, uId, followeeId)
+	if err != nil {
+		return err
+	}
+	return s.FollRepo.Follow(ctx, uId, followeeId, time.Now())
+}
+
+func (s *UserService) Unfollow(ctx context.Context, uId, followeeId string) error {
+	return s.FollRepo.Unfollow(ctx, uId, followeeId)
+}
+
+func (s *UserService) GetFollowers(ctx context.Context, uId string, limit int, cursor string) ([]*models.ResponseFollow, string, error) {
+	follows, next, err := s.FollRepo.GetFollowers(ctx, uId, limit, cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	responseFollows, err := s.toResponseFollows(ctx, follows)
+	return responseFollows, next, err
+}
+
+func (s *UserService) GetFollowing(ctx context.Context, uId string, limit int, cursor string) ([]*models.ResponseFollow, string, error) {
+	follows, next, err := s.FollRepo.GetFollowing(ctx, uId, limit, cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	responseFollows, err := s.toResponseFollows(ctx, follows)
+	return responseFollows, next, err
+}
+
+// toResponseFollows adds user details and leaves out deactivated users
+func (s *UserService) toResponseFollows(ctx context.Context, follows []*models.Follow) ([]*models.ResponseFollow, error) {
+	uIds := make([]string, 0, len(follows))
+	for _, follow := range follows {
+		uIds = append(uIds, follow.UId)
+	}
+	users, err := s.UserRepo.FetchUsersByIds(ctx, uIds)
+	if err != nil {
+		return nil, err
+	}
+	responseFollows := make([]*models.ResponseFollow, 0, len(follows))
+	for _, follow := range follows {
+		user, ok := users[follow.UId]
+		if !ok || !user.IsActive {
+			continue
+		}
+		responseFollows = append(responseFollows, &models.ResponseFollow{
+			UId:        follow.UId,
+			Username:   user.Username,
+			Tag:        user.Tag,
+			FollowedAt: follow.FollowedAt,
+		})
+	}
+	return responseFollows, nil
+}
+
+// GetFollowingFeed merges the posts of the active users uId follows, newest first. The
+// cursor carries the creation time and id of the last post on the previous page, and every
+// followee is only read for one page of posts below it.
+func (s *UserService) GetFollowingFeed(ctx context.Context, uId string, limit int, cursor string) ([]*models.Post, string, error) {
+	var afterTime time.Time
+	var afterId string
+	if cursor != "" {
+		position, err := utils.DecodeCursor(cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		createdAt, pId, found := strings.Cut(position, "|")
+		afterTime, err = time.Parse(time.RFC3339Nano, createdAt)
+		if !found || err != nil {
+			return nil, "", utils.InvalidCursor
+		}
+		afterId = pId
+	}
+	followees, err := s.FollRepo.GetAllFollowing(ctx, uId)
+	if err != nil {
+		return nil, "", err
+	}
+	users, err := s.UserRepo.FetchUsersByIds(ctx, followees)
+	if err != nil {
+		return nil, "", err
+	}
+	hidden, err := s.RelRepo.GetHiddenUsers(ctx, uId, true)
+	if err != nil {
+		return nil, "", err
+	}
+	newer := func(a *models.Post, createdAt time.Time, pId string) bool {
+		if !a.CreatedAt.Equal(createdAt) {
+			return a.CreatedAt.After(createdAt)
+		}
+		return a.PostId > pId
+	}
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		fetchErr error
+		posts    []*models.Post
+	)
+	slots := make(chan struct{}, config.FeedFetchConcurrency)
+	for _, followee := range followees {
+		if user, ok := users[followee]; !ok || !user.IsActive || hidden[followee] {
+			continue
+		}
+		wg.Add(1)
+		go func(followee string) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			// one more than a page covers the post the cursor points at, which is read again
+			userPosts, err := s.PostRepo.GetUserPostsBefore(ctx, followee, afterTime, limit+1)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if fetchErr == nil {
+					fetchErr = err
+				}
+				return
+			}
+			for _, post := range userPosts {
+				if cursor == "" || newer(&models.Post{CreatedAt: afterTime, PostId: afterId}, post.CreatedAt, post.PostId) {
+					posts = append(posts, post)
+				}
+			}
+		}(followee)
+	}
+	wg.Wait()
+	if fetchErr != nil {
+		return nil, "", fetchErr
+	}
+	// every followee contributed its newest posts below the cursor, so the newest limit of all
+	// of them are the page
+	sort.Slice(posts, func(i, j int) bool {
+		return newer(posts[i], posts[j].CreatedAt, posts[j].PostId)
+	})
+	end := limit
+	next := ""
+	if end < len(posts) {
+		last := posts[end-1]
+		next = utils.EncodeCursor(last.CreatedAt.Format(time.RFC3339Nano) + "|" + last.PostId)
+	} else {
+		end = len(posts)
+	}
+	page := posts[:end]
+	err = s.setPostCounts(ctx, page)
+	if err != nil {
+		return nil, "", err
+	}
+	err = s.setSavedState(ctx, uId, page)
+	if err != nil {
+		return nil, "", err
+	}
+	return page, next, nil
+}
+
+//block and mute related services
+
+// checkNotBlocked fails with BlockedInteraction when uId and any of the owners have blocked each other
+func (s *UserService) checkNotBlocked(ctx context.Context, uId string, ownerIds ...string) error {
+	for _, ownerId := range ownerIds {
+		if ownerId == uId {
+			continue
+		}
+		blocked, err := s.RelRepo.IsBlocked(ctx, uId, ownerId)
+		if err != nil {
+			return err
+		}
+		if blocked {
+			return utils.BlockedInteraction
+		}
+	}
+	return nil
+}
+
+// Block hides both users from each other and drops any follows between them
+func (s *UserService) Block(ctx context.Context, uId, blockedId string) error {
+	if uId == blockedId {
+		return utils.CannotBlockSelf
+	}
+	users, err := s.UserRepo.FetchUsersByIds(ctx, []string{blockedId})
+	if err != nil {
+		return err
+	}
+	if _, ok := users[blockedId]; !ok {
+		return utils.NoUser
+	}
+	err = s.RelRepo.Block(ctx, uId, blockedId, time.Now())
+	if err != nil {
+		return err
+	}
+	err = s.FollRepo.Unfollow(ctx, uId, blockedId)
+	if err != nil && !errors.Is(err, utils.NotFollowing) {
+		return err
+	}
+	err = s.FollRepo.Unfollow(ctx, blockedId, uId)
+	if err != nil && !errors.Is(err, utils.NotFollowing) {
+		return err
+	}
+	return nil
+}
+
+func (s *UserService) Unblock(ctx context.Context, uId, blockedId string) error {
+	return s.RelRepo.Unblock(ctx, uId, blockedId)
+}
+
+func (s *UserService) Mute(ctx context.Context, uId, mutedId string) error {
+	if uId == mutedId {
+		return utils.CannotBlockSelf
+	}
+	users, err := s.UserRepo.FetchUsersByIds(ctx, []string{mutedId})
+	if err != nil {
+		return err
+	}
+	if _, ok := users[mutedId]; !ok {
+		return utils.NoUser
+	}
+	return s.RelRepo.Mute(ctx, uId, mutedId, time.Now())
+}
+
+func (s *UserService) Unmute(ctx context.Context, uId, mutedId string) error {
+	return s.RelRepo.Unmute(ctx, uId, mutedId)
+}
+
+func (s *UserService) GetBlockedUsers(ctx context.Context, uId string) ([]*models.ResponseRelation, error) {
+	relations, err := s.RelRepo.GetBlocked(ctx, uId)
+	if err != nil {
+		return nil, err
+	}
+	return s.toResponseRelations(ctx, relations)
+}
+
+func (s *UserService) GetMutedUsers(ctx context.Context, uId string) ([]*models.ResponseRelation, error) {
+	relations, err := s.RelRepo.GetMuted(ctx, uId)
+	if err != nil {
+		return nil, err
+	}
+	return s.toResponseRelations(ctx, relations)
+}
+
+func (s *UserService) toResponseRelations(ctx context.Context, relations []*models.Relation) ([]*models.ResponseRelation, error) {
+	uIds := make([]string, 0, len(relations))
+	for _, relation := range relations {
+		uIds = append(uIds, relation.UId)
+	}
+	users, err := s.UserRepo.FetchUsersByIds(ctx, uIds)
+	if err != nil {
+		return nil, err
+	}
+	responseRelations := make([]*models.ResponseRelation, 0, len(relations))
+	for _, relation := range relations {
+		responseRelation := &models.ResponseRelation{
+			UId:       relation.UId,
+			CreatedAt: relation.CreatedAt,
+		}
+		if user, ok := users[relation.UId]; ok {
+			responseRelation.Username = user.Username
+			responseRelation.Tag = user.Tag
+		}
+		responseRelations = append(responseRelations, responseRelation)
+	}
+	return responseRelations, nil
 }
 
 func hashPassword(password string) string {
@@ -121,7 +518,17 @@ func (s *UserService) GetNotifications(ctx context.Context, uid string) ([]*mode
 	if err != nil {
 		return nil, err
 	}
-	return notifications, nil
+	hidden, err := s.RelRepo.GetHiddenUsers(ctx, uid, false)
+	if err != nil {
+		return nil, err
+	}
+	visible := make([]*models.Notification, 0, len(notifications))
+	for _, notification := range notifications {
+		if !hidden[notification.UId] {
+			visible = append(visible, notification)
+		}
+	}
+	return visible, nil
 }
 
 func (s *UserService) validateUsername(ctx context.Context, username string) bool {
@@ -146,22 +553,24 @@ func (s *UserService) validateEmail(ctx context.Context, email string) bool {
 	return false
 }
 
-func (s *UserService) UpdateUser(ctx context.Context, uId string, requestUser *models.UpdateClient) error {
+// UpdateUser applies the profile changes if the user is still at version and returns the new version
+func (s *UserService) UpdateUser(ctx context.Context, uId string, requestUser *models.UpdateClient, version int) (int, error) {
 	var dwellingAge = (requestUser.LivingSince.Days / 365.0) + (requestUser.LivingSince.Years) + (requestUser.LivingSince.Months / 12.0)
 	var hashedPassword = hashPassword(requestUser.Password)
 	var tag = utils.SetTag(dwellingAge)
 	user, err := s.UserRepo.FetchUserById(ctx, uId, true)
 	if err != nil {
-		return err
+		return 0, err
+	}
+	if user.Version != version {
+		return 0, utils.VersionMismatch
 	}
 	user.Tag = tag
 	user.DwellingAge = dwellingAge
 	user.Password = hashedPassword
 	user.City = requestUser.City
 
-	err = s.UserRepo.UpdateUserById(ctx, user)
-
-	return err
+	return s.UserRepo.UpdateUserById(ctx, user, version)
 }
 
 //Post related functionality
@@ -177,10 +586,14 @@ func (s *UserService) CreatePost(ctx context.Context, userId string, title, cont
 		Likes:     0,
 	}
 	err := s.PostRepo.Create(ctx, post)
-	return err
+	if err != nil {
+		return err
+	}
+	recordStats(ctx, s.StatsRepo, map[config.StatsMetric]int{config.PostMetrics[postType]: 1})
+	return nil
 }
 
-func (s *UserService) UpdatePost(ctx context.Context, post *models.UpdatePost) error {
+func (s *UserService) UpdatePost(ctx context.Context, post *models.UpdatePost) (int, error) {
 	postNew := &models.Post{
 		PostId:    post.PostId,
 		Title:     post.Title,
@@ -188,13 +601,25 @@ func (s *UserService) UpdatePost(ctx context.Context, post *models.UpdatePost) e
 		Type:      post.Type,
 		UId:       post.UId,
 		CreatedAt: post.CreatedAt,
+		Version:   post.Version,
 	}
-	err := s.PostRepo.UpdatePost(ctx, post.UId, postNew)
-	return err
+	return s.PostRepo.UpdatePost(ctx, post.UId, postNew)
 }
 
-func (s *UserService) GiveAllPosts(ctx context.Context, limit, offset *int, search, filter *string) ([]*models.Post, error) {
-	posts, err := s.PostRepo.GetAllPostsWithFilter(ctx, limit, offset, search, filter)
+func (s *UserService) GiveAllPosts(ctx context.Context, uId string, limit, offset *int, search, filter *string) ([]*models.Post, error) {
+	hidden, err := s.RelRepo.GetHiddenUsers(ctx, uId, true)
+	if err != nil {
+		return nil, err
+	}
+	posts, err := s.PostRepo.GetAllPostsWithFilter(ctx, limit, offset, search, filter, hidden)
+	if err != nil {
+		return nil, err
+	}
+	err = s.setPostCounts(ctx, posts)
+	if err != nil {
+		return nil, err
+	}
+	err = s.setSavedState(ctx, uId, posts)
 	if err != nil {
 		return nil, err
 	}
@@ -206,9 +631,143 @@ func (s *UserService) GiveUserPosts(ctx context.Context, uId string) ([]*models.
 	if err != nil {
 		return nil, err
 	}
+	err = s.setPostCounts(ctx, posts)
+	if err != nil {
+		return nil, err
+	}
+	err = s.setSavedState(ctx, uId, posts)
+	if err != nil {
+		return nil, err
+	}
 	return posts, nil
 }
 
+// GetPost returns a post as its author's copy holds it, the copy edits are checked against, so
+// its version is the one to send back in If-Match. Hidden posts are only shown to their author
+// and posts of users blocked either way are reported as missing.
+func (s *UserService) GetPost(ctx context.Context, uId, pId string) (*models.Post, error) {
+	ownerId, err := s.PostRepo.GetPostOwner(ctx, pId)
+	if err != nil {
+		return nil, err
+	}
+	post, err := s.PostRepo.GetUserPost(ctx, ownerId, pId)
+	if errors.Is(err, utils.NotYourPost) {
+		return nil, utils.NoPost
+	}
+	if err != nil {
+		return nil, err
+	}
+	if post.Hidden && post.UId != uId {
+		return nil, utils.NoPost
+	}
+	hidden, err := s.RelRepo.GetHiddenUsers(ctx, uId, false)
+	if err != nil {
+		return nil, err
+	}
+	if hidden[post.UId] {
+		return nil, utils.NoPost
+	}
+	posts := []*models.Post{post}
+	err = s.setPostCounts(ctx, posts)
+	if err != nil {
+		return nil, err
+	}
+	err = s.setSavedState(ctx, uId, posts)
+	if err != nil {
+		return nil, err
+	}
+	return post, nil
+}
+
+func (s *UserService) setPostCounts(ctx context.Context, posts []*models.Post) error {
+	pIds := make([]string, 0, len(posts))
+	for _, post := range posts {
+		pIds = append(pIds, post.PostId)
+	}
+	counts, err := s.PostRepo.GetPostCounts(ctx, pIds)
+	if err != nil {
+		return err
+	}
+	reactions, err := s.ReacRepo.GetReactionSummaries(ctx, config.PostContent, pIds)
+	if err != nil {
+		return err
+	}
+	for _, post := range posts {
+		if count, ok := counts[post.PostId]; ok {
+			post.Questions = count.QuestionCount
+			post.Comments = count.CommentCount
+		}
+		post.Reactions = reactions[post.PostId]
+	}
+	return nil
+}
+
+func (s *UserService) setSavedState(ctx context.Context, uId string, posts []*models.Post) error {
+	pIds := make([]string, 0, len(posts))
+	for _, post := range posts {
+		pIds = append(pIds, post.PostId)
+	}
+	saved, err := s.BookRepo.GetSavedPostIds(ctx, uId, pIds)
+	if err != nil {
+		return err
+	}
+	for _, post := range posts {
+		post.Saved = saved[post.PostId]
+	}
+	return nil
+}
+
+func (s *UserService) SavePost(ctx context.Context, uId, pId string, post *models.SavePost) error {
+	exists, err := s.PostRepo.IsPostOwner(ctx, post.UId, pId)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return utils.NoPost
+	}
+	err = s.checkNotBlocked(ctx, uId, post.UId)
+	if err != nil {
+		return err
+	}
+	return s.BookRepo.SavePost(ctx, uId, pId, post.UId, time.Now())
+}
+
+func (s *UserService) UnsavePost(ctx context.Context, uId, pId string) error {
+	return s.BookRepo.UnsavePost(ctx, uId, pId)
+}
+
+// GetSavedPosts returns one page of the user's saved posts, most recently saved first
+func (s *UserService) GetSavedPosts(ctx context.Context, uId string, limit int, cursor string) ([]*models.Post, string, error) {
+	bookmarks, next, err := s.BookRepo.GetSavedPosts(ctx, uId, limit, cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	owners := make(map[string]string, len(bookmarks))
+	for _, bookmark := range bookmarks {
+		owners[bookmark.PostId] = bookmark.PostUserId
+	}
+	found, err := s.PostRepo.GetPostsByOwners(ctx, owners)
+	if err != nil {
+		return nil, "", err
+	}
+	hidden, err := s.RelRepo.GetHiddenUsers(ctx, uId, false)
+	if err != nil {
+		return nil, "", err
+	}
+	posts := make([]*models.Post, 0, len(bookmarks))
+	for _, bookmark := range bookmarks {
+		if post, ok := found[bookmark.PostId]; ok && !post.Hidden && !hidden[post.UId] {
+			post.Saved = true
+			posts = append(posts, post)
+		}
+	}
+	err = s.setPostCounts(ctx, posts)
+	if err != nil {
+		return nil, "", err
+	}
+	return posts, next, nil
+}
+
 func (s *UserService) DeleteUserPost(ctx context.Context, uId, pId string, post *models.DeletePost) error {
 	err := s.PostRepo.DeletePost(ctx, post.Type, post.CreatedAt, uId, pId)
 	if err != nil {
@@ -217,15 +776,43 @@ func (s *UserService) DeleteUserPost(ctx context.Context, uId, pId string, post
 	return nil
 }
 
+// Like toggles a LIKE reaction on the post, kept for clients of the like endpoint
 func (s *UserService) Like(ctx context.Context, uId, pId string, post *models.LikePost) (config.LikeStatus, error) {
-	status, err := s.PostRepo.ToggleLike(ctx, post.UId, uId, string(post.Type), pId, post.CreatedAt)
+	err := s.checkNotBlocked(ctx, uId, post.UId)
+	if err != nil {
+		return "0", err
+	}
+	status, err := s.GetLikeStatus(ctx, uId, pId)
 	if err != nil {
 		return "0", err
 	}
-	return status, nil
+	reaction, newStatus := config.Like, config.Liked
+	if status == config.Liked {
+		reaction, newStatus = config.NoReaction, config.NotLiked
+	}
+	err = s.ReactToPost(ctx, uId, pId, &models.ReactPost{
+		Reaction:  reaction,
+		UId:       post.UId,
+		CreatedAt: post.CreatedAt,
+		Type:      post.Type,
+	})
+	if err != nil {
+		return "0", err
+	}
+	return newStatus, nil
 }
 
 func (s *UserService) GetLikeStatus(ctx context.Context, uId, pId string) (config.LikeStatus, error) {
+	reaction, err := s.ReacRepo.GetUserReaction(ctx, config.PostContent, pId, uId)
+	if err != nil {
+		return "0", err
+	}
+	if reaction == config.Like {
+		return config.Liked, nil
+	}
+	if reaction != config.NoReaction {
+		return config.NotLiked, nil
+	}
 	status, err := s.PostRepo.HasUserLikedAPost(ctx, uId, pId)
 	if err != nil {
 		return "0", err
@@ -236,6 +823,52 @@ func (s *UserService) GetLikeStatus(ctx context.Context, uId, pId string) (confi
 	return config.NotLiked, nil
 }
 
+// ReactToPost sets the user's reaction on a post. The likes attribute of the post keeps
+// counting LIKE reactions, including likes recorded before reactions existed.
+func (s *UserService) ReactToPost(ctx context.Context, uId, pId string, post *models.ReactPost) error {
+	exists, err := s.PostRepo.IsPostOwner(ctx, post.UId, pId)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return utils.NoPost
+	}
+	err = s.checkNotBlocked(ctx, uId, post.UId)
+	if err != nil {
+		return err
+	}
+	legacyLike, err := s.PostRepo.HasUserLikedAPost(ctx, uId, pId)
+	if err != nil {
+		return err
+	}
+	previous, err := s.ReacRepo.React(ctx, config.PostContent, pId, uId, post.Reaction)
+	if err != nil {
+		return err
+	}
+	if legacyLike {
+		err = s.PostRepo.DeleteLikeEntry(ctx, uId, pId)
+		if err != nil {
+			return err
+		}
+	}
+	wasLiked := legacyLike || previous == config.Like
+	delta := 0
+	if wasLiked && post.Reaction != config.Like {
+		delta = -1
+	} else if !wasLiked && post.Reaction == config.Like {
+		delta = 1
+	}
+	if delta == 0 {
+		return nil
+	}
+	err = s.PostRepo.UpdateLikeCount(ctx, post.UId, string(post.Type), pId, post.CreatedAt, delta)
+	if err != nil {
+		return err
+	}
+	recordStats(ctx, s.StatsRepo, map[config.StatsMetric]int{config.LikesMetric: delta})
+	return nil
+}
+
 //forget password
 
 func (s *UserService) SendOtp(ctx context.Context, email string) error {
@@ -292,8 +925,18 @@ func (s *UserService) PasswordReset(ctx context.Context, resetUser models.ResetP
 			Tag:         user.Tag,
 			Email:       user.Email,
 		}
-		err = s.UserRepo.UpdateUserById(ctx, &updatedUser)
-		return err
+		_, err = s.UserRepo.UpdateUserById(ctx, &updatedUser, config.AnyVersion)
+		if err != nil {
+			return err
+		}
+		recordAudit(ctx, s.AuditRepo, &models.AuditEntry{
+			ActorId:    user.UId,
+			ActorRole:  "user",
+			Action:     config.AuditPasswordReset,
+			TargetType: config.UserContent,
+			TargetId:   user.UId,
+		}, nil)
+		return nil
 	}
 	return utils.WrongOTP
 }
@@ -301,14 +944,29 @@ func (s *UserService) PasswordReset(ctx context.Context, resetUser models.ResetP
 //question related services
 
 func (s *UserService) AddQuestion(ctx context.Context, ques *models.RequestQuestion) error {
+	owner, err := s.PostRepo.GetPostOwner(ctx, ques.PostId)
+	if err != nil {
+		return err
+	}
+	err = s.checkNotBlocked(ctx, ques.UserId, owner)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
 	question := &models.Question{
-		QId:    utils.GenerateRandomId(),
-		PostId: ques.PostId,
-		Text:   ques.Text,
-		UserId: ques.UserId,
+		QId:       utils.GenerateRandomId(),
+		PostId:    ques.PostId,
+		Text:      ques.Text,
+		UserId:    ques.UserId,
+		CreatedAt: now,
+		UpdatedAt: now,
 	}
-	err := s.QuesRepo.Create(ctx, question)
-	return err
+	err = s.QuesRepo.Create(ctx, question)
+	if err != nil {
+		return err
+	}
+	recordStats(ctx, s.StatsRepo, map[config.StatsMetric]int{config.QuestionsMetric: 1})
+	return nil
 }
 
 func (s *UserService) DeleteQuestion(ctx context.Context, pId, qId, uId string) error {
@@ -316,25 +974,114 @@ func (s *UserService) DeleteQuestion(ctx context.Context, pId, qId, uId string)
 	return err
 }
 
-func (s *UserService) GetQuestionByPId(ctx context.Context, pId string) ([]*models.Question, error) {
-	questions, err := s.QuesRepo.GetAllQuestionsByPId(ctx, pId)
+func (s *UserService) GetQuestionByPId(ctx context.Context, pId, uId string) ([]*models.ResponseQuestion, error) {
+	allQuestions, err := s.QuesRepo.GetAllQuestionsByPId(ctx, pId)
+	if err != nil {
+		return nil, err
+	}
+	hidden, err := s.RelRepo.GetHiddenUsers(ctx, uId, false)
 	if err != nil {
 		return nil, err
 	}
-	return questions, nil
+	questions := make([]*models.Question, 0, len(allQuestions))
+	for _, question := range allQuestions {
+		// content hidden by moderation stays visible to its author
+		if !hidden[question.UserId] && (!question.Hidden || question.UserId == uId) {
+			questions = append(questions, question)
+		}
+	}
+	qIds := make([]string, 0, len(questions))
+	uIds := make([]string, 0, len(questions))
+	for _, question := range questions {
+		qIds = append(qIds, question.QId)
+		uIds = append(uIds, question.UserId)
+	}
+	answerCounts, err := s.AnsRepo.GetAnswerCounts(ctx, qIds)
+	if err != nil {
+		return nil, err
+	}
+	reactions, err := s.ReacRepo.GetReactionSummaries(ctx, config.QuestionContent, qIds)
+	if err != nil {
+		return nil, err
+	}
+	authors, err := s.UserRepo.FetchUsersByIds(ctx, uIds)
+	if err != nil {
+		return nil, err
+	}
+	responseQuestions := make([]*models.ResponseQuestion, 0, len(questions))
+	for _, question := range questions {
+		responseQuestion := &models.ResponseQuestion{
+			QId:              question.QId,
+			PostId:           question.PostId,
+			UserId:           question.UserId,
+			Text:             question.Text,
+			Replies:          answerCounts[question.QId],
+			Reactions:        reactions[question.QId],
+			AcceptedAnswerId: question.AcceptedAnswerId,
+			CreatedAt:        question.CreatedAt,
+			UpdatedAt:        question.UpdatedAt,
+			EditedAt:         question.EditedAt,
+		}
+		if author, ok := authors[question.UserId]; ok {
+			responseQuestion.Username = author.Username
+			responseQuestion.Tag = author.Tag
+		}
+		responseQuestions = append(responseQuestions, responseQuestion)
+	}
+	return responseQuestions, nil
+}
+
+func (s *UserService) UpdateQuestion(ctx context.Context, pId, qId, uId, text string) error {
+	err := s.QuesRepo.UpdateQuestion(ctx, pId, qId, uId, text, time.Now())
+	return err
+}
+
+func (s *UserService) ReactToQuestion(ctx context.Context, pId, qId, uId string, reaction config.ReactionType) error {
+	question, err := s.QuesRepo.GetQuestion(ctx, pId, qId)
+	if err != nil {
+		return err
+	}
+	err = s.checkNotBlocked(ctx, uId, question.UserId)
+	if err != nil {
+		return err
+	}
+	_, err = s.ReacRepo.React(ctx, config.QuestionContent, qId, uId, reaction)
+	return err
 }
 
 //answer related services
 
 func (s *UserService) AddAnswer(ctx context.Context, ans *models.RequestAnswer) error {
+	ref, err := s.QuesRepo.GetQuestionRef(ctx, ans.QId)
+	if err != nil && !errors.Is(err, utils.NoQuestion) {
+		return err
+	}
+	// questions asked before refs were kept cannot be traced to their authors
+	if ref != nil {
+		owner, err := s.PostRepo.GetPostOwner(ctx, ref.PostId)
+		if err != nil {
+			return err
+		}
+		err = s.checkNotBlocked(ctx, ans.UserId, ref.UserId, owner)
+		if err != nil {
+			return err
+		}
+	}
+	now := time.Now()
 	answer := &models.Reply{
-		RId:    utils.GenerateRandomId(),
-		Answer: ans.Answer,
-		UserId: ans.UserId,
-		QId:    ans.QId,
+		RId:       utils.GenerateRandomId(),
+		Answer:    ans.Answer,
+		UserId:    ans.UserId,
+		QId:       ans.QId,
+		CreatedAt: now,
+		UpdatedAt: now,
 	}
-	err := s.AnsRepo.AddAnswer(ctx, answer)
-	return err
+	err = s.AnsRepo.AddAnswer(ctx, answer)
+	if err != nil {
+		return err
+	}
+	recordStats(ctx, s.StatsRepo, map[config.StatsMetric]int{config.AnswersMetric: 1})
+	return nil
 }
 
 func (s *UserService) DeleteAnswer(ctx context.Context, qId, rId, uId string) error {
@@ -342,10 +1089,351 @@ func (s *UserService) DeleteAnswer(ctx context.Context, qId, rId, uId string) er
 	return err
 }
 
-func (s *UserService) GetAllAnswers(ctx context.Context, qId string) ([]*models.Reply, error) {
-	answers, err := s.AnsRepo.GetAllAnswersByQId(ctx, qId)
+func (s *UserService) GetAllAnswers(ctx context.Context, qId, uId string) ([]*models.ResponseAnswer, error) {
+	allAnswers, err := s.AnsRepo.GetAllAnswersByQId(ctx, qId)
+	if err != nil {
+		return nil, err
+	}
+	hidden, err := s.RelRepo.GetHiddenUsers(ctx, uId, false)
+	if err != nil {
+		return nil, err
+	}
+	answers := make([]*models.Reply, 0, len(allAnswers))
+	for _, answer := range allAnswers {
+		if !hidden[answer.UserId] && (!answer.Hidden || answer.UserId == uId) {
+			answers = append(answers, answer)
+		}
+	}
+	rIds := make([]string, 0, len(answers))
+	uIds := make([]string, 0, len(answers))
+	for _, answer := range answers {
+		rIds = append(rIds, answer.RId)
+		uIds = append(uIds, answer.UserId)
+	}
+	votes, err := s.AnsRepo.GetUserVotes(ctx, uId, rIds)
+	if err != nil {
+		return nil, err
+	}
+	reactions, err := s.ReacRepo.GetReactionSummaries(ctx, config.AnswerContent, rIds)
+	if err != nil {
+		return nil, err
+	}
+	authors, err := s.UserRepo.FetchUsersByIds(ctx, uIds)
+	if err != nil {
+		return nil, err
+	}
+	responseAnswers := make([]*models.ResponseAnswer, 0, len(answers))
+	for _, answer := range answers {
+		myVote, ok := votes[answer.RId]
+		if !ok {
+			myVote = config.NoVote
+		}
+		responseAnswer := &models.ResponseAnswer{
+			RId:       answer.RId,
+			QId:       answer.QId,
+			Answer:    answer.Answer,
+			UserId:    answer.UserId,
+			Upvotes:   answer.Upvotes,
+			Downvotes: answer.Downvotes,
+			Score:     answer.Score,
+			Accepted:  answer.Accepted,
+			MyVote:    myVote,
+			Reactions: reactions[answer.RId],
+			CreatedAt: answer.CreatedAt,
+			UpdatedAt: answer.UpdatedAt,
+			EditedAt:  answer.EditedAt,
+		}
+		if author, ok := authors[answer.UserId]; ok {
+			responseAnswer.Username = author.Username
+			responseAnswer.Tag = author.Tag
+		}
+		responseAnswers = append(responseAnswers, responseAnswer)
+	}
+	// accepted answer first, then highest score
+	sort.SliceStable(responseAnswers, func(i, j int) bool {
+		if responseAnswers[i].Accepted != responseAnswers[j].Accepted {
+			return responseAnswers[i].Accepted
+		}
+		return responseAnswers[i].Score > responseAnswers[j].Score
+	})
+	return responseAnswers, nil
+}
+
+func (s *UserService) ReactToAnswer(ctx context.Context, qId, rId, uId string, reaction config.ReactionType) error {
+	answer, err := s.AnsRepo.GetAnswer(ctx, qId, rId)
+	if err != nil {
+		return err
+	}
+	err = s.checkNotBlocked(ctx, uId, answer.UserId)
+	if err != nil {
+		return err
+	}
+	_, err = s.ReacRepo.React(ctx, config.AnswerContent, rId, uId, reaction)
+	return err
+}
+
+func (s *UserService) VoteAnswer(ctx context.Context, qId, rId, uId string, vote config.VoteType) error {
+	answer, err := s.AnsRepo.GetAnswer(ctx, qId, rId)
+	if err != nil {
+		return err
+	}
+	err = s.checkNotBlocked(ctx, uId, answer.UserId)
+	if err != nil {
+		return err
+	}
+	err = s.AnsRepo.VoteAnswer(ctx, qId, rId, uId, vote)
+	return err
+}
+
+func (s *UserService) AcceptAnswer(ctx context.Context, pId, qId, rId, uId string) (bool, error) {
+	question, err := s.QuesRepo.GetQuestion(ctx, pId, qId)
+	if err != nil {
+		return false, err
+	}
+	if question.UserId != uId {
+		isPostOwner, err := s.PostRepo.IsPostOwner(ctx, uId, pId)
+		if err != nil {
+			return false, err
+		}
+		if !isPostOwner {
+			return false, utils.NotAllowedToAccept
+		}
+	}
+	_, err = s.AnsRepo.GetAnswer(ctx, qId, rId)
+	if err != nil {
+		return false, err
+	}
+	if question.AcceptedAnswerId == rId {
+		err = s.AnsRepo.AcceptAnswer(ctx, pId, qId, "", rId)
+		return false, err
+	}
+	err = s.AnsRepo.AcceptAnswer(ctx, pId, qId, rId, question.AcceptedAnswerId)
+	return err == nil, err
+}
+
+func (s *UserService) UpdateAnswer(ctx context.Context, qId, rId, uId, text string) error {
+	err := s.AnsRepo.UpdateAnswer(ctx, qId, rId, uId, text, time.Now())
+	return err
+}
+
+//revision history
+
+func (s *UserService) GetRevisions(ctx context.Context, contentType config.ContentType, id string) ([]*models.Revision, error) {
+	revisions, err := s.RevRepo.GetRevisions(ctx, contentType, id)
+	if err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+//comment related services
+
+func (s *UserService) AddComment(ctx context.Context, uId, pId string, request *models.RequestComment) (*models.Comment, error) {
+	owner, err := s.PostRepo.GetPostOwner(ctx, pId)
+	if err != nil {
+		return nil, err
+	}
+	err = s.checkNotBlocked(ctx, uId, owner)
 	if err != nil {
 		return nil, err
 	}
-	return answers, nil
+	now := time.Now()
+	cId := utils.GenerateRandomId()
+	// fixed width segments keep siblings in creation order within the materialized path
+	segment := now.UTC().Format(config.SortableTime) + "#" + cId
+	comment := &models.Comment{
+		PostId:    pId,
+		Path:      segment,
+		CId:       cId,
+		UserId:    uId,
+		Content:   request.Content,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if request.ParentId != "" {
+		parent, err := s.CommRepo.GetCommentRef(ctx, request.ParentId)
+		if err != nil {
+			return nil, err
+		}
+		if parent.PostId != pId {
+			return nil, utils.NoComment
+		}
+		if parent.Depth+1 > config.MaxCommentDepth {
+			return nil, utils.CommentTooDeep
+		}
+		comment.ParentId = parent.CId
+		comment.Path = parent.Path + "/" + segment
+		comment.Depth = parent.Depth + 1
+	}
+	err = s.CommRepo.CreateComment(ctx, comment)
+	if err != nil {
+		return nil, err
+	}
+	return comment, nil
+}
+
+func (s *UserService) DeleteComment(ctx context.Context, uId, pId, cId string) error {
+	ref, err := s.CommRepo.GetCommentRef(ctx, cId)
+	if err != nil {
+		return err
+	}
+	if ref.PostId != pId {
+		return utils.NoComment
+	}
+	err = s.CommRepo.SoftDeleteComment(ctx, pId, ref.Path, uId, time.Now())
+	return err
+}
+
+// GetComments returns the comment thread of a post. Comments of users hidden from uId are left
+// out together with their replies, like the answers of a question that is left out.
+func (s *UserService) GetComments(ctx context.Context, pId, uId string) ([]*models.ResponseComment, error) {
+	allComments, err := s.CommRepo.GetCommentsByPId(ctx, pId)
+	if err != nil {
+		return nil, err
+	}
+	hidden, err := s.RelRepo.GetHiddenUsers(ctx, uId, false)
+	if err != nil {
+		return nil, err
+	}
+	left := make(map[string]bool)
+	comments := make([]*models.Comment, 0, len(allComments))
+	for _, comment := range allComments {
+		if left[comment.ParentId] || (!comment.Deleted && hidden[comment.UserId]) {
+			left[comment.CId] = true
+			continue
+		}
+		comments = append(comments, comment)
+	}
+	uIds := make([]string, 0, len(comments))
+	for _, comment := range comments {
+		if !comment.Deleted {
+			uIds = append(uIds, comment.UserId)
+		}
+	}
+	authors, err := s.UserRepo.FetchUsersByIds(ctx, uIds)
+	if err != nil {
+		return nil, err
+	}
+	// comments arrive in thread order so a parent is always seen before its replies
+	byId := make(map[string]*models.ResponseComment)
+	thread := make([]*models.ResponseComment, 0)
+	for _, comment := range comments {
+		responseComment := &models.ResponseComment{
+			CId:       comment.CId,
+			PostId:    comment.PostId,
+			ParentId:  comment.ParentId,
+			Content:   comment.Content,
+			Depth:     comment.Depth,
+			Deleted:   comment.Deleted,
+			CreatedAt: comment.CreatedAt,
+			Replies:   make([]*models.ResponseComment, 0),
+		}
+		if !comment.Deleted {
+			responseComment.UserId = comment.UserId
+			if author, ok := authors[comment.UserId]; ok {
+				responseComment.Username = author.Username
+				responseComment.Tag = author.Tag
+			}
+		}
+		byId[comment.CId] = responseComment
+		if parent, ok := byId[comment.ParentId]; ok {
+			parent.Replies = append(parent.Replies, responseComment)
+		} else {
+			thread = append(thread, responseComment)
+		}
+	}
+	return thread, nil
+}
+
+//report related services
+
+func (s *UserService) ReportPost(ctx context.Context, uId, pId string, request *models.RequestReport) error {
+	owner, err := s.PostRepo.GetPostOwner(ctx, pId)
+	if err != nil {
+		return err
+	}
+	return s.report(ctx, uId, &models.ReportCase{
+		TargetType: config.PostContent,
+		TargetId:   pId,
+		OwnerId:    owner,
+	}, request)
+}
+
+func (s *UserService) ReportQuestion(ctx context.Context, uId, pId, qId string, request *models.RequestReport) error {
+	question, err := s.QuesRepo.GetQuestion(ctx, pId, qId)
+	if err != nil {
+		return err
+	}
+	return s.report(ctx, uId, &models.ReportCase{
+		TargetType: config.QuestionContent,
+		TargetId:   qId,
+		OwnerId:    question.UserId,
+		PostId:     pId,
+	}, request)
+}
+
+func (s *UserService) ReportAnswer(ctx context.Context, uId, qId, rId string, request *models.RequestReport) error {
+	answer, err := s.AnsRepo.GetAnswer(ctx, qId, rId)
+	if err != nil {
+		return err
+	}
+	return s.report(ctx, uId, &models.ReportCase{
+		TargetType: config.AnswerContent,
+		TargetId:   rId,
+		OwnerId:    answer.UserId,
+		QuestionId: qId,
+	}, request)
+}
+
+func (s *UserService) ReportUser(ctx context.Context, uId, reportedId string, request *models.RequestReport) error {
+	_, err := s.UserRepo.FetchUserById(ctx, reportedId, true)
+	if err != nil {
+		return err
+	}
+	return s.report(ctx, uId, &models.ReportCase{
+		TargetType: config.UserContent,
+		TargetId:   reportedId,
+		OwnerId:    reportedId,
+	}, request)
+}
+
+// report files the report and hides the target once enough distinct users have reported it
+func (s *UserService) report(ctx context.Context, uId string, target *models.ReportCase, request *models.RequestReport) error {
+	if target.OwnerId == uId {
+		return utils.CannotReportOwn
+	}
+	reportCase, err := s.ReportRepo.Report(ctx, target, &models.Report{
+		UId:       uId,
+		Reason:    request.Reason,
+		Text:      request.Text,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	recordStats(ctx, s.StatsRepo, map[config.StatsMetric]int{config.ReportsMetric: 1})
+	if reportCase.Status != config.ReportOpen || reportCase.Hidden || reportCase.ReportCount < config.AutoHideReports {
+		return nil
+	}
+	return setReportedHidden(ctx, s.PostRepo, s.QuesRepo, s.AnsRepo, s.ReportRepo, reportCase, true)
+}
+
+// setReportedHidden hides or restores the content a case is about and records it on the case.
+// Reported users are never hidden here, suspending an account is left to an admin.
+func setReportedHidden(ctx context.Context, postRepo interfaces.PostRepository, quesRepo interfaces.QuestionRepoInterface, ansRepo interfaces.AnswerRepoInterface, reportRepo interfaces.ReportRepoInterface, reportCase *models.ReportCase, hidden bool) error {
+	var err error
+	switch reportCase.TargetType {
+	case config.PostContent:
+		err = postRepo.SetPostHidden(ctx, reportCase.OwnerId, reportCase.TargetId, hidden)
+	case config.QuestionContent:
+		err = quesRepo.SetQuestionHidden(ctx, reportCase.PostId, reportCase.TargetId, hidden)
+	case config.AnswerContent:
+		err = ansRepo.SetAnswerHidden(ctx, reportCase.QuestionId, reportCase.TargetId, hidden)
+	default:
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return reportRepo.SetCaseHidden(ctx, reportCase.TargetType, reportCase.TargetId, hidden)
 }
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"context"
+	"localeyes/config"
+	"localeyes/internal/interfaces"
+	"localeyes/internal/models"
+	"testing"
+	"time"
+)
+
+type memoryQuestions struct {
+	interfaces.QuestionRepoInterface
+	questions []*models.Question
+}
+
+func (r *memoryQuestions) GetAllQuestionsByPId(ctx context.Context, pId string) ([]*models.Question, error) {
+	var questions []*models.Question
+	for _, question := range r.questions {
+		if question.PostId == pId {
+			questions = append(questions, question)
+		}
+	}
+	return questions, nil
+}
+
+type memoryAnswers struct {
+	interfaces.AnswerRepoInterface
+	counts map[string]int
+}
+
+func (r *memoryAnswers) GetAnswerCounts(ctx context.Context, qIds []string) (map[string]int, error) {
+	counts := make(map[string]int)
+	for _, qId := range qIds {
+		if count, ok := r.counts[qId]; ok {
+			counts[qId] = count
+		}
+	}
+	return counts, nil
+}
+
+type memoryReactions struct {
+	interfaces.ReactionRepoInterface
+	summaries map[string]models.ReactionSummary
+}
+
+func (r *memoryReactions) GetReactionSummaries(ctx context.Context, contentType config.ContentType, ids []string) (map[string]models.ReactionSummary, error) {
+	summaries := make(map[string]models.ReactionSummary)
+	for _, id := range ids {
+		if summary, ok := r.summaries[string(contentType)+":"+id]; ok {
+			summaries[id] = summary
+		}
+	}
+	return summaries, nil
+}
+
+type memoryRelations struct {
+	interfaces.RelationRepoInterface
+	hidden map[string]map[string]bool
+}
+
+func (r *memoryRelations) GetHiddenUsers(ctx context.Context, uId string, includeMuted bool) (map[string]bool, error) {
+	return r.hidden[uId], nil
+}
+
+type memoryUsers struct {
+	interfaces.UserRepository
+	users map[string]*models.User
+}
+
+func (r *memoryUsers) FetchUsersByIds(ctx context.Context, uIds []string) (map[string]*models.User, error) {
+	users := make(map[string]*models.User)
+	for _, uId := range uIds {
+		if user, ok := r.users[uId]; ok {
+			users[uId] = user
+		}
+	}
+	return users, nil
+}
+
+func TestGetQuestionByPId(t *testing.T) {
+	createdAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	editedAt := createdAt.Add(time.Hour)
+	service := &UserService{
+		QuesRepo: &memoryQuestions{questions: []*models.Question{
+			{QId: "q1", PostId: "p1", UserId: "u1", Text: "first", CreatedAt: createdAt, UpdatedAt: editedAt, EditedAt: &editedAt},
+			{QId: "q2", PostId: "p1", UserId: "u2", Text: "second", CreatedAt: createdAt, UpdatedAt: createdAt},
+			{QId: "q4", PostId: "p1", UserId: "u3", Text: "blocked", CreatedAt: createdAt, UpdatedAt: createdAt},
+			{QId: "q5", PostId: "p1", UserId: "u1", Text: "reported", CreatedAt: createdAt, UpdatedAt: createdAt, Hidden: true},
+			{QId: "q3", PostId: "p2", UserId: "u1", Text: "elsewhere", CreatedAt: createdAt, UpdatedAt: createdAt},
+		}},
+		AnsRepo: &memoryAnswers{counts: map[string]int{"q1": 3, "q3": 1}},
+		ReacRepo: &memoryReactions{summaries: map[string]models.ReactionSummary{
+			string(config.QuestionContent) + ":q1": {config.Like: 2},
+			string(config.PostContent) + ":q2":     {config.Like: 1},
+		}},
+		// the viewer blocked u3
+		RelRepo: &memoryRelations{hidden: map[string]map[string]bool{
+			"viewer": {"u3": true},
+		}},
+		// u2 deleted their account, their question stays without author details
+		UserRepo: &memoryUsers{users: map[string]*models.User{
+			"u1": {UId: "u1", Username: "alice", Tag: "resident"},
+		}},
+	}
+	questions, err := service.GetQuestionByPId(context.Background(), "p1", "viewer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(questions) != 2 {
+		t.Fatalf("got %d questions, want 2", len(questions))
+	}
+	first, second := questions[0], questions[1]
+	if first.QId != "q1" || first.Username != "alice" || first.Tag != "resident" || first.Replies != 3 || first.Reactions[config.Like] != 2 {
+		t.Fatalf("first question = %+v", first)
+	}
+	if !first.CreatedAt.Equal(createdAt) || !first.UpdatedAt.Equal(editedAt) || first.EditedAt == nil || !first.EditedAt.Equal(editedAt) {
+		t.Fatalf("first question timestamps = %v %v %v", first.CreatedAt, first.UpdatedAt, first.EditedAt)
+	}
+	if second.QId != "q2" || second.Username != "" || second.Replies != 0 || len(second.Reactions) != 0 || second.EditedAt != nil {
+		t.Fatalf("second question = %+v", second)
+	}
+}
+
+func TestGetQuestionByPIdShowsHiddenQuestionsToTheirAuthor(t *testing.T) {
+	service := &UserService{
+		QuesRepo: &memoryQuestions{questions: []*models.Question{
+			{QId: "q1", PostId: "p1", UserId: "u1", Text: "reported", Hidden: true},
+		}},
+		AnsRepo:  &memoryAnswers{},
+		ReacRepo: &memoryReactions{},
+		RelRepo:  &memoryRelations{},
+		UserRepo: &memoryUsers{},
+	}
+	tests := []struct {
+		viewer string
+		count  int
+	}{
+		{"u1", 1},
+		{"u2", 0},
+	}
+	for _, test := range tests {
+		t.Run(test.viewer, func(t *testing.T) {
+			questions, err := service.GetQuestionByPId(context.Background(), "p1", test.viewer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(questions) != test.count {
+				t.Fatalf("%s sees %d questions, want %d", test.viewer, len(questions), test.count)
+			}
+		})
+	}
+}
@@ -2,24 +2,35 @@ package main
 
 import (
 	"context"
+	"encoding/base64"
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/go-playground/validator"
 	"github.com/gorilla/mux"
+	"go.uber.org/zap"
 	"localeyes/config"
+	"localeyes/internal/alerting"
 	"localeyes/internal/handlers"
+	"localeyes/internal/interfaces"
+	"localeyes/internal/jobqueue"
 	"localeyes/internal/middlewares"
+	"localeyes/internal/ratelimit"
 	"localeyes/internal/repositories"
 	"localeyes/internal/services"
 	"localeyes/utils"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"strings"
+	"time"
 )
 
 var client *dynamodb.Client
 var customValidator *validator.Validate
+var rateLimiter interfaces.RateLimiterInterface
+var rateLimits map[config.RateLimitClass]config.RateLimitPolicy
+var jobQueue interfaces.JobQueueInterface
 
 func init() {
 	client = config.GetDBClient()
@@ -27,24 +38,93 @@ func init() {
 	_ = customValidator.RegisterValidation("isValidFilter", utils.ValidateFilter)
 	_ = customValidator.RegisterValidation("isValidPassword", utils.ValidatePassword)
 	_ = customValidator.RegisterValidation("isValidTime", utils.ValidateTime)
+	_ = customValidator.RegisterValidation("isValidVote", utils.ValidateVote)
+	_ = customValidator.RegisterValidation("isValidReaction", utils.ValidateReaction)
+	_ = customValidator.RegisterValidation("isValidReportReason", utils.ValidateReportReason)
+	_ = customValidator.RegisterValidation("isValidResolution", utils.ValidateResolution)
+	rateLimiter = repositories.NewRateLimitRepository(client)
+	var err error
+	rateLimits, err = config.RateLimitPolicies()
+	if err != nil {
+		utils.Logger.Fatal(err.Error())
+	}
+	if os.Getenv("JOB_QUEUE_URL") != "" {
+		sqsClient, queueUrl, err := config.InitSQS()
+		if err != nil {
+			utils.Logger.Fatal(err.Error())
+		}
+		jobQueue = jobqueue.NewSQSQueue(sqsClient, queueUrl)
+	} else {
+		jobQueue = jobqueue.NewDisabledQueue()
+	}
+	// alerts are sent when they are flushed, at most once per fingerprint and window across
+	// every execution environment
+	alertRepo := repositories.NewAlertRepository(client)
+	if os.Getenv("SNS_TOPIC_ARN") == "" {
+		utils.Alerter = alerting.NewLogAlerter(config.AlertWindow, alertRepo)
+		return
+	}
+	snsClient, topicArn, err := config.InitSNS()
+	if err != nil {
+		utils.Logger.Fatal(err.Error())
+	}
+	utils.Alerter = alerting.NewSNSAlerter(snsClient, topicArn, config.AlertWindow, alertRepo)
 }
 
-func createRouter() *mux.Router {
-	router := mux.NewRouter()
-	router.Use(middlewares.AuthenticationMiddleware)
+// createServices builds the services shared by the API and the job worker
+func createServices() (*services.UserService, *services.AdminService, *services.JobWorker) {
+	exportBuilder := services.NewExportBuilder(
+		repositories.NewNoSQLUserRepository(client),
+		repositories.NewPostRepository(client),
+		repositories.NewQuestionRepository(client),
+		repositories.NewAnswerRepository(client),
+		repositories.NewExportRepository(client),
+		repositories.NewJobRepository(client),
+		jobQueue,
+	)
 	userService := services.NewUserService(
 		repositories.NewNoSQLUserRepository(client),
 		repositories.NewPostRepository(client),
 		repositories.NewQuestionRepository(client),
 		repositories.NewAnswerRepository(client),
 		repositories.NewOtpRepository(client),
+		repositories.NewRevisionRepository(client),
+		repositories.NewCommentRepository(client),
+		repositories.NewReactionRepository(client),
+		repositories.NewBookmarkRepository(client),
+		repositories.NewFollowRepository(client),
+		repositories.NewRelationRepository(client),
+		repositories.NewReportRepository(client),
+		repositories.NewAuditRepository(client),
+		repositories.NewStatsRepository(client),
+		exportBuilder,
 	)
 	adminService := services.NewAdminService(
 		repositories.NewNoSQLUserRepository(client),
 		repositories.NewPostRepository(client),
 		repositories.NewQuestionRepository(client),
 		repositories.NewAnswerRepository(client),
+		repositories.NewCommentRepository(client),
+		repositories.NewReactionRepository(client),
+		repositories.NewBookmarkRepository(client),
+		repositories.NewFollowRepository(client),
+		repositories.NewRelationRepository(client),
+		repositories.NewReportRepository(client),
+		repositories.NewAuditRepository(client),
+		repositories.NewJobRepository(client),
+		jobQueue,
+		repositories.NewStatsRepository(client),
+		exportBuilder,
 	)
+	return userService, adminService, services.NewJobWorker(repositories.NewJobRepository(client), jobQueue, adminService)
+}
+
+func createRouter() *mux.Router {
+	userService, adminService, _ := createServices()
+	router := mux.NewRouter()
+	router.Use(middlewares.AuthenticationMiddleware(userService))
+	router.Use(middlewares.RateLimitMiddleware(rateLimiter, rateLimits))
+	router.Use(middlewares.IdempotencyMiddleware(repositories.NewIdempotencyRepository(client)))
 	userHandler := handlers.NewUserHandler(userService, customValidator)
 	adminHandler := handlers.NewAdminHandler(adminService, customValidator)
 
@@ -58,37 +138,86 @@ func createRouter() *mux.Router {
 	router.HandleFunc("/user/profile", userHandler.ViewProfile).Methods("GET")
 	router.HandleFunc("/user/deactivate", userHandler.DeActivate).Methods("POST") //need to be checked
 	router.HandleFunc("/user/notifications", userHandler.ViewNotifications).Methods("GET")
+	router.HandleFunc("/user/saved", userHandler.GetSavedPosts).Methods("GET")
+	router.HandleFunc("/user/blocked", userHandler.GetBlockedUsers).Methods("GET")
+	router.HandleFunc("/user/muted", userHandler.GetMutedUsers).Methods("GET")
+	router.HandleFunc("/user/export", userHandler.ExportData).Methods("POST")
+	router.HandleFunc("/user/export/{job_id}", userHandler.GetExport).Methods("GET")
+	router.HandleFunc("/export/download/{token}", userHandler.DownloadExport).Methods("GET")
 	router.HandleFunc("/user/{user_id}", userHandler.GetUserById).Methods("GET")
+	router.HandleFunc("/user/{user_id}/follow", userHandler.FollowUser).Methods("POST")
+	router.HandleFunc("/user/{user_id}/follow", userHandler.UnfollowUser).Methods("DELETE")
+	router.HandleFunc("/user/{user_id}/followers", userHandler.GetFollowers).Methods("GET")
+	router.HandleFunc("/user/{user_id}/following", userHandler.GetFollowing).Methods("GET")
+	router.HandleFunc("/user/{user_id}/block", userHandler.BlockUser).Methods("POST")
+	router.HandleFunc("/user/{user_id}/block", userHandler.UnblockUser).Methods("DELETE")
+	router.HandleFunc("/user/{user_id}/mute", userHandler.MuteUser).Methods("POST")
+	router.HandleFunc("/user/{user_id}/mute", userHandler.UnmuteUser).Methods("DELETE")
+	router.HandleFunc("/user/{user_id}/report", userHandler.ReportUser).Methods("POST")
 	router.HandleFunc("/user/{user_id}", userHandler.UpdateUserById).Methods("PUT")
 	router.HandleFunc("/user/post", userHandler.CreatePost).Methods("POST")
 	router.HandleFunc("/posts/all", userHandler.DisplayPosts).Methods("GET") // error
+	router.HandleFunc("/feed/following", userHandler.GetFollowingFeed).Methods("GET")
 	router.HandleFunc("/post/{post_id}/like", userHandler.LikePost).Methods("POST")
+	router.HandleFunc("/post/{post_id}/react", userHandler.ReactToPost).Methods("POST")
+	router.HandleFunc("/post/{post_id}/save", userHandler.SavePost).Methods("POST")
+	router.HandleFunc("/post/{post_id}/save", userHandler.UnsavePost).Methods("DELETE")
+	router.HandleFunc("/post/{post_id}/report", userHandler.ReportPost).Methods("POST")
 	router.HandleFunc("/user/post/{post_id}", userHandler.UpdatePost).Methods("PUT")
 	router.HandleFunc("/user/post/{post_id}", userHandler.DeletePost).Methods("DELETE")
 	router.HandleFunc("/user/posts/all", userHandler.DisplayUserPosts).Methods("GET")
 	router.HandleFunc("/user/post/{post_id}", userHandler.GetLikeStatus).Methods("GET")
+	router.HandleFunc("/post/{post_id}", userHandler.GetPost).Methods("GET")
+	router.HandleFunc("/post/{post_id}/revisions", userHandler.GetPostRevisions).Methods("GET")
+	router.HandleFunc("/post/{post_id}/comments/all", userHandler.GetAllComments).Methods("GET")
+	router.HandleFunc("/post/{post_id}/comment", userHandler.CreateComment).Methods("POST")
+	router.HandleFunc("/post/{post_id}/comment/{comment_id}", userHandler.DeleteComment).Methods("DELETE")
 	router.HandleFunc("/post/{post_id}/questions/all", userHandler.GetAllQuestions).Methods("GET")
 	router.HandleFunc("/post/{post_id}/question", userHandler.CreateQuestion).Methods("POST")
 	router.HandleFunc("/post/{post_id}/question/{ques_id}", userHandler.DeleteQuestion).Methods("DELETE")
+	router.HandleFunc("/post/{post_id}/question/{ques_id}", userHandler.UpdateQuestion).Methods("PUT")
+	router.HandleFunc("/post/{post_id}/question/{ques_id}/revisions", userHandler.GetQuestionRevisions).Methods("GET")
+	router.HandleFunc("/post/{post_id}/question/{ques_id}/react", userHandler.ReactToQuestion).Methods("POST")
+	router.HandleFunc("/post/{post_id}/question/{ques_id}/report", userHandler.ReportQuestion).Methods("POST")
 	router.HandleFunc("/question/{ques_id}/answer", userHandler.AddAnswer).Methods("POST")
 	router.HandleFunc("/question/{ques_id}/answer/{answer_id}", userHandler.DeleteAnswer).Methods("DELETE")
+	router.HandleFunc("/question/{ques_id}/answer/{answer_id}", userHandler.UpdateAnswer).Methods("PUT")
+	router.HandleFunc("/question/{ques_id}/answer/{answer_id}/revisions", userHandler.GetAnswerRevisions).Methods("GET")
 	router.HandleFunc("/question/{ques_id}/answers/all", userHandler.GetAllAnswers).Methods("GET")
+	router.HandleFunc("/question/{ques_id}/answer/{answer_id}/vote", userHandler.VoteAnswer).Methods("POST")
+	router.HandleFunc("/question/{ques_id}/answer/{answer_id}/accept", userHandler.AcceptAnswer).Methods("POST")
+	router.HandleFunc("/question/{ques_id}/answer/{answer_id}/react", userHandler.ReactToAnswer).Methods("POST")
+	router.HandleFunc("/question/{ques_id}/answer/{answer_id}/report", userHandler.ReportAnswer).Methods("POST")
 
 	adminRouter := router.PathPrefix("/admin").Subrouter()
 	adminRouter.Use(middlewares.AdminAuthMiddleware)
 	adminRouter.HandleFunc("/user/{user_id}", adminHandler.DeleteUser).Methods("DELETE")
 	adminRouter.HandleFunc("/user/{user_id}/reactivate", adminHandler.ReActivateUser).Methods("POST")
+	adminRouter.HandleFunc("/user/{user_id}/suspend", adminHandler.SuspendUser).Methods("POST")
+	adminRouter.HandleFunc("/user/{user_id}/ban", adminHandler.BanUser).Methods("POST")
+	adminRouter.HandleFunc("/user/{user_id}/unban", adminHandler.UnbanUser).Methods("POST")
+	adminRouter.HandleFunc("/user/{user_id}/export", adminHandler.ExportUser).Methods("POST")
 	adminRouter.HandleFunc("/users/all", adminHandler.GetAllUsers).Methods("GET")
+	adminRouter.HandleFunc("/users/search", adminHandler.SearchUsers).Methods("GET")
 	adminRouter.HandleFunc("/user/{user_id}/post/{post_id}", adminHandler.DeletePost).Methods("DELETE")
 	adminRouter.HandleFunc("/post/{post_id}/user/{user_id}/question/{ques_id}", adminHandler.DeleteQuestion).Methods("DELETE")
 	adminRouter.HandleFunc("/question/{ques_id}/user/{user_id}/answer/{answer_id}", adminHandler.DeleteAnswer).Methods("DELETE")
+	adminRouter.HandleFunc("/audit", adminHandler.GetAuditLog).Methods("GET")
+	adminRouter.HandleFunc("/stats", adminHandler.GetStats).Methods("GET")
+	adminRouter.HandleFunc("/jobs/{job_id}", adminHandler.GetJob).Methods("GET")
+	adminRouter.HandleFunc("/bulk/user/{user_id}/content/delete", adminHandler.BulkDeleteUserContent).Methods("POST")
+	adminRouter.HandleFunc("/bulk/posts/delete", adminHandler.BulkDeletePosts).Methods("POST")
+	adminRouter.HandleFunc("/bulk/users/{action}", adminHandler.BulkChangeAccounts).Methods("POST")
+	adminRouter.HandleFunc("/reports", adminHandler.ListReports).Methods("GET")
+	adminRouter.HandleFunc("/reports/{target_type}/{target_id}", adminHandler.GetReport).Methods("GET")
+	adminRouter.HandleFunc("/reports/{target_type}/{target_id}/resolve", adminHandler.ResolveReport).Methods("POST")
 
 	return router
 }
 
 func lambdaHandler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
-	// Create the mux router
-	router := createRouter()
+	// Create the mux router, every request is given an id and logged
+	router := middlewares.RequestLogMiddleware(createRouter())
 
 	url := request.Path
 	if len(request.QueryStringParameters) > 0 {
@@ -99,6 +228,9 @@ func lambdaHandler(ctx context.Context, request events.APIGatewayProxyRequest) (
 		url += "?" + strings.Join(params, "&")
 	}
 
+	// Carry API Gateway's request id so it can be recorded alongside what the request did
+	ctx = context.WithValue(ctx, "RequestId", request.RequestContext.RequestID)
+
 	// Create the HTTP request from the API Gateway event
 	req, err := http.NewRequestWithContext(ctx, request.HTTPMethod, url, strings.NewReader(request.Body))
 	if err != nil {
@@ -109,6 +241,7 @@ func lambdaHandler(ctx context.Context, request events.APIGatewayProxyRequest) (
 	for key, value := range request.Headers {
 		req.Header.Add(key, value)
 	}
+	req.RemoteAddr = request.RequestContext.Identity.SourceIP
 
 	// Create an in-memory response recorder
 	rr := httptest.NewRecorder()
@@ -116,32 +249,100 @@ func lambdaHandler(ctx context.Context, request events.APIGatewayProxyRequest) (
 	// Serve the HTTP request using the mux router
 	router.ServeHTTP(rr, req)
 
+	// Send the alerts the request raised before the execution environment may be frozen, within
+	// what is left of the invocation
+	utils.Alerter.Flush(ctx)
+
 	if request.HTTPMethod == "OPTIONS" {
 		return events.APIGatewayProxyResponse{
 			StatusCode: 200,
 			Headers: map[string]string{
 				"Access-Control-Allow-Origin":      "*",
-				"Access-Control-Allow-Headers":     "Content-Type,X-Amz-Date,Authorization,X-Api-Key,X-Amz-Security-Token,X-Requested-With",
+				"Access-Control-Allow-Headers":     "Content-Type,X-Amz-Date,Authorization,X-Api-Key,X-Amz-Security-Token,X-Requested-With,If-Match,Idempotency-Key,X-Request-Id",
 				"Access-Control-Allow-Methods":     "POST,GET,OPTIONS,PUT,DELETE",
 				"Access-Control-Allow-Credentials": "true",
 			},
 		}, nil
 	}
+	headers := map[string]string{
+		"Access-Control-Allow-Origin":      "*",
+		"Access-Control-Allow-Headers":     "Content-Type,X-Amz-Date,Authorization,X-Api-Key,X-Amz-Security-Token,X-Requested-With,If-Match,Idempotency-Key,X-Request-Id",
+		"Access-Control-Allow-Methods":     "POST,GET,OPTIONS,PUT,DELETE",
+		"Access-Control-Allow-Credentials": "true",
+		"Access-Control-Expose-Headers":    "ETag,Idempotent-Replayed,Retry-After,RateLimit-Policy,RateLimit-Limit,RateLimit-Remaining,RateLimit-Reset,X-Request-Id",
+		"Content-Type":                     "application/json",
+	}
+	if disposition := rr.Header().Get("Content-Disposition"); disposition != "" {
+		headers["Content-Disposition"] = disposition
+	}
+	for _, header := range []string{"ETag", "Idempotent-Replayed", "Retry-After", "RateLimit-Policy", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "X-Request-Id"} {
+		if value := rr.Header().Get(header); value != "" {
+			headers[header] = value
+		}
+	}
+	// downloads set their own content type, binary ones go back base64 encoded for API Gateway
+	contentType := rr.Header().Get("Content-Type")
+	if contentType != "" && contentType != "application/json" {
+		headers["Content-Type"] = contentType
+		if contentType == "application/zip" {
+			return events.APIGatewayProxyResponse{
+				StatusCode:      rr.Code,
+				Body:            base64.StdEncoding.EncodeToString(rr.Body.Bytes()),
+				IsBase64Encoded: true,
+				Headers:         headers,
+			}, nil
+		}
+	}
 	// Return the API Gateway response
 	return events.APIGatewayProxyResponse{
 		StatusCode: rr.Code,
 		Body:       rr.Body.String(),
-		Headers: map[string]string{
-			"Access-Control-Allow-Origin":      "*",
-			"Access-Control-Allow-Headers":     "Content-Type,X-Amz-Date,Authorization,X-Api-Key,X-Amz-Security-Token,X-Requested-With",
-			"Access-Control-Allow-Methods":     "POST,GET,OPTIONS,PUT,DELETE",
-			"Access-Control-Allow-Credentials": "true",
-			"Content-Type":                     "application/json",
-		},
+		Headers:    headers,
 	}, nil
 }
 
+// workerHandler runs the jobs queued on the job queue. A job whose run fails is reported back
+// so its message is handed out again once its visibility timeout has passed, and the next run
+// resumes the job from its checkpoint.
+func workerHandler(ctx context.Context, event events.SQSEvent) (events.SQSEventResponse, error) {
+	_, _, worker := createServices()
+	var response events.SQSEventResponse
+	for _, message := range event.Records {
+		if err := worker.Run(ctx, message.Body); err != nil {
+			utils.Logger.Error("Error running job", zap.String("job_id", message.Body), zap.Error(err))
+			response.BatchItemFailures = append(response.BatchItemFailures, events.SQSBatchItemFailure{ItemIdentifier: message.MessageId})
+		}
+	}
+	return response, nil
+}
+
 func main() {
+	// SERVER_ADDR runs the API as a plain HTTP server, e.g. for local development. A single
+	// process sees every request so its rate limits are kept in memory, and it outlives the
+	// requests that start jobs so it runs them itself.
+	if addr := os.Getenv("SERVER_ADDR"); addr != "" {
+		rateLimiter = ratelimit.NewMemoryLimiter()
+		localQueue := jobqueue.NewLocalQueue()
+		jobQueue = localQueue
+		_, _, worker := createServices()
+		localQueue.Run = worker.Run
+		// the server is not frozen between requests, it sends its alerts once per window
+		go func() {
+			for range time.Tick(config.AlertWindow) {
+				utils.Alerter.Flush(context.Background())
+			}
+		}()
+		utils.Logger.Info("Listening on " + addr)
+		utils.Logger.Fatal(http.ListenAndServe(addr, middlewares.RequestLogMiddleware(createRouter())).Error())
+	}
+	if _, ok := jobQueue.(*jobqueue.DisabledQueue); ok {
+		utils.Logger.Warn("JOB_QUEUE_URL is not set, endpoints starting jobs are unavailable")
+	}
+	// JOB_WORKER runs the function as the job worker consuming the job queue instead of the API
+	if os.Getenv("JOB_WORKER") != "" {
+		lambda.Start(workerHandler)
+		return
+	}
 	// Start the Lambda function
 	lambda.Start(lambdaHandler)
 }
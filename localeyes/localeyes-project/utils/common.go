@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"crypto/rand"
 	"encoding/base64"
 	"github.com/google/uuid"
 	"strings"
@@ -15,3 +16,12 @@ func GenerateRandomId() string {
 	}
 	return idString
 }
+
+// GenerateSecret returns an unguessable url safe token for links that work without logging in
+func GenerateSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
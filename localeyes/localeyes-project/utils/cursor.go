@@ -0,0 +1,17 @@
+package utils
+
+import "encoding/base64"
+
+// EncodeCursor wraps a position in a listing so clients treat it as opaque and the
+// key layout behind it can change without breaking them
+func EncodeCursor(position string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(position))
+}
+
+func DecodeCursor(cursor string) (string, error) {
+	position, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", InvalidCursor
+	}
+	return string(position), nil
+}
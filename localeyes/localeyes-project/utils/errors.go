@@ -1,19 +1,64 @@
 package utils
 
-import "errors"
+// Every error a client can act on is an *Error, its Kind decides the HTTP status it is answered
+// with and its Code is the stable name clients match on. Anything else is answered as an
+// internal error without its message, which may come from DynamoDB or another dependency.
 
-var NotYourPost = errors.New("no post of yours exist with this id")
-var NotYourQuestion = errors.New("no question of yours exist with this id")
-var NoPost = errors.New("no post exist with this id")
-var NoQuestion = errors.New("no question exist with this id")
-var NoUser = errors.New("no user exist")
-var TitleMissing = errors.New("required field 'title' is missing")
-var ContentMissing = errors.New("required field 'content' is missing")
-var TypeMissing = errors.New("required field 'type' is missing")
-var InvalidPost = errors.New("invalid post type")
-var InvalidAnswer = errors.New("invalid answer")
-var InvalidAccountCredentials = errors.New("invalid account credentials")
-var InactiveUser = errors.New("inactive user")
-var WrongOTP = errors.New("wrong otp")
-var UserExistsEmail = errors.New("user exists with this email")
-var UserExistsName = errors.New("user exists with this username")
+var NotYourPost = NewError(NotFound, "post_not_found", "no post of yours exist with this id")
+var NotYourQuestion = NewError(NotFound, "question_not_found", "no question of yours exist with this id")
+var NoPost = NewError(NotFound, "post_not_found", "no post exist with this id")
+var NoQuestion = NewError(NotFound, "question_not_found", "no question exist with this id")
+var NoUser = NewError(NotFound, "user_not_found", "no user exist")
+var TitleMissing = NewError(Invalid, "title_missing", "required field 'title' is missing")
+var ContentMissing = NewError(Invalid, "content_missing", "required field 'content' is missing")
+var TypeMissing = NewError(Invalid, "type_missing", "required field 'type' is missing")
+var InvalidPost = NewError(Invalid, "invalid_post_type", "invalid post type")
+var InvalidAnswer = NewError(Invalid, "invalid_answer", "invalid answer")
+var InvalidAccountCredentials = NewError(Unauthorized, "invalid_credentials", "invalid account credentials")
+var InactiveUser = NewError(Forbidden, "account_inactive", "account has been deactivated, contact support to reactivate it")
+var WrongOTP = NewError(Invalid, "wrong_otp", "wrong otp")
+var UserExistsEmail = NewError(Conflict, "email_taken", "user exists with this email")
+var UserExistsName = NewError(Conflict, "username_taken", "user exists with this username")
+var NoAnswer = NewError(NotFound, "answer_not_found", "no answer exist with this id")
+var NotAllowedToAccept = NewError(Forbidden, "accept_not_allowed", "only the question asker or post author can accept an answer")
+var NotYourAnswer = NewError(NotFound, "answer_not_found", "no answer of yours exist with this id")
+var EditConflict = NewError(Conflict, "edit_conflict", "content was modified by another request, retry the edit")
+var NoComment = NewError(NotFound, "comment_not_found", "no comment exist with this id")
+var NotYourComment = NewError(NotFound, "comment_not_found", "no comment of yours exist with this id")
+var CommentTooDeep = NewError(Invalid, "comment_too_deep", "comment thread is nested too deeply")
+var NotSaved = NewError(NotFound, "post_not_saved", "post is not in your saved list")
+var InvalidCursor = NewError(Invalid, "invalid_cursor", "invalid cursor")
+var CannotFollowSelf = NewError(Invalid, "cannot_follow_self", "you cannot follow yourself")
+var NotFollowing = NewError(NotFound, "not_following", "you are not following this user")
+var CannotBlockSelf = NewError(Invalid, "cannot_block_self", "you cannot block or mute yourself")
+var NotBlocked = NewError(NotFound, "not_blocked", "you have not blocked this user")
+var NotMuted = NewError(NotFound, "not_muted", "you have not muted this user")
+var BlockedInteraction = NewError(Forbidden, "blocked", "you cannot interact with this user's content")
+var NotYourProfile = NewError(Forbidden, "not_your_profile", "you can only update your own profile")
+var CannotReportOwn = NewError(Forbidden, "cannot_report_own", "you cannot report your own content")
+var AlreadyReported = NewError(Conflict, "already_reported", "you have already reported this")
+var NoReport = NewError(NotFound, "report_not_found", "no report exist for this content")
+var ReportNotOpen = NewError(Conflict, "report_not_open", "report has already been resolved")
+var AccountSuspended = NewError(Forbidden, "account_suspended", "account is suspended")
+var AccountBanned = NewError(Forbidden, "account_banned", "account is banned")
+var AccountPendingVerification = NewError(Forbidden, "account_pending_verification", "account is pending verification")
+var InvalidAccountTransition = NewError(Conflict, "invalid_account_transition", "account cannot be moved to this state from its current state")
+var NoJob = NewError(NotFound, "job_not_found", "no job exist with this id")
+var JobLeased = NewError(Conflict, "job_leased", "another worker is running this job")
+var JobsUnavailable = NewError(Unavailable, "jobs_unavailable", "background jobs cannot be started right now, try again later")
+var NoExport = NewError(NotFound, "export_not_found", "export does not exist or its download link has expired")
+var MigrationLocked = NewError(Conflict, "migration_locked", "another migration run holds the lock, try again once it has finished")
+var MalformedKey = NewError(Internal, "malformed_key", "malformed key")
+var VersionMismatch = NewError(PreconditionFailed, "version_mismatch", "resource was modified since it was read, fetch it again and retry with its current ETag")
+var MissingIfMatch = NewError(PreconditionRequired, "if_match_required", "If-Match header with the ETag of the resource is required")
+var InvalidETag = NewError(Invalid, "invalid_etag", "If-Match header must hold a single ETag")
+var InvalidIdempotencyKey = NewError(Invalid, "invalid_idempotency_key", "Idempotency-Key header must be between 1 and 255 characters")
+var IdempotencyKeyReused = NewError(Unprocessable, "idempotency_key_reused", "Idempotency-Key was already used for a different request")
+var IdempotencyInProgress = NewError(Conflict, "idempotency_in_progress", "a request with this Idempotency-Key is still being processed, retry later")
+var TooManyRequests = NewError(RateLimited, "rate_limited", "too many requests, retry later")
+var InvalidBody = NewError(Invalid, "invalid_body", "request body is missing or is not valid JSON")
+var MissingToken = NewError(Unauthorized, "token_missing", "missing authentication token")
+var InvalidToken = NewError(Unauthorized, "token_invalid", "invalid or expired token")
+var NotAdmin = NewError(Forbidden, "not_admin", "only admins can do this")
+var ConditionFailed = NewError(Conflict, "condition_failed", "the item was changed by another request, fetch it again and retry")
+var NotAvailable = NewError(Unimplemented, "not_available", "this service is not available yet")
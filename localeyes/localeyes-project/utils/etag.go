@@ -0,0 +1,27 @@
+package utils
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ETag formats the version of an item as a strong entity tag
+func ETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// ParseIfMatch reads the version a conditional write expects from an If-Match header
+func ParseIfMatch(header string) (int, error) {
+	tag := strings.TrimSpace(header)
+	if tag == "" {
+		return 0, MissingIfMatch
+	}
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return 0, InvalidETag
+	}
+	version, err := strconv.Atoi(tag[1 : len(tag)-1])
+	if err != nil || version < 0 {
+		return 0, InvalidETag
+	}
+	return version, nil
+}
@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseIfMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		version int
+		err     error
+	}{
+		{"version", `"3"`, 3, nil},
+		{"zero", `"0"`, 0, nil},
+		{"surrounding space", ` "12" `, 12, nil},
+		{"missing", "", 0, MissingIfMatch},
+		{"blank", "   ", 0, MissingIfMatch},
+		{"unquoted", "3", 0, InvalidETag},
+		{"weak", `W/"3"`, 0, InvalidETag},
+		{"wildcard", "*", 0, InvalidETag},
+		{"several", `"3", "4"`, 0, InvalidETag},
+		{"not a number", `"abc"`, 0, InvalidETag},
+		{"negative", `"-1"`, 0, InvalidETag},
+		{"lone quote", `"`, 0, InvalidETag},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			version, err := ParseIfMatch(test.header)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("ParseIfMatch(%q) error = %v, want %v", test.header, err, test.err)
+			}
+			if version != test.version {
+				t.Fatalf("ParseIfMatch(%q) = %d, want %d", test.header, version, test.version)
+			}
+		})
+	}
+}
+
+func TestETagRoundTrip(t *testing.T) {
+	for _, version := range []int{0, 1, 42} {
+		parsed, err := ParseIfMatch(ETag(version))
+		if err != nil || parsed != version {
+			t.Fatalf("ParseIfMatch(ETag(%d)) = %d, %v", version, parsed, err)
+		}
+	}
+}
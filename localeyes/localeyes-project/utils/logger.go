@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"context"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"os"
@@ -27,5 +28,33 @@ func init() {
 		zapcore.AddSync(os.Stdout),
 		zap.NewAtomicLevelAt(zap.InfoLevel),
 	)
-	Logger = zap.New(core)
+	Logger = zap.New(redactingCore{core})
+	// packages that utils depends on log through the global logger
+	zap.ReplaceGlobals(Logger)
+}
+
+// requestLogger is the logger of one request. It is shared by pointer so fields added further
+// down the chain, like the user id once the token has been checked, reach the access log too.
+type requestLogger struct {
+	logger *zap.Logger
+}
+
+// WithLogger attaches the logger of a request to its context
+func WithLogger(ctx context.Context, logger *zap.Logger) context.Context {
+	return context.WithValue(ctx, "Logger", &requestLogger{logger})
+}
+
+// LoggerFrom returns the logger of the request ctx belongs to, or Logger outside of a request
+func LoggerFrom(ctx context.Context) *zap.Logger {
+	if holder, ok := ctx.Value("Logger").(*requestLogger); ok {
+		return holder.logger
+	}
+	return Logger
+}
+
+// AddLogFields adds fields to every later log line of the request ctx belongs to
+func AddLogFields(ctx context.Context, fields ...zap.Field) {
+	if holder, ok := ctx.Value("Logger").(*requestLogger); ok {
+		holder.logger = holder.logger.With(fields...)
+	}
 }
@@ -0,0 +1,90 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator"
+	"net/http"
+	"strings"
+)
+
+// Kind says what went wrong from the client's point of view and decides the HTTP status
+type Kind int
+
+const (
+	Internal Kind = iota
+	Invalid
+	Unauthorized
+	Forbidden
+	NotFound
+	Conflict
+	PreconditionFailed
+	Unprocessable
+	PreconditionRequired
+	RateLimited
+	Unimplemented
+	Unavailable
+)
+
+var kindStatus = map[Kind]int{
+	Internal:             http.StatusInternalServerError,
+	Invalid:              http.StatusBadRequest,
+	Unauthorized:         http.StatusUnauthorized,
+	Forbidden:            http.StatusForbidden,
+	NotFound:             http.StatusNotFound,
+	Conflict:             http.StatusConflict,
+	PreconditionFailed:   http.StatusPreconditionFailed,
+	Unprocessable:        http.StatusUnprocessableEntity,
+	PreconditionRequired: http.StatusPreconditionRequired,
+	RateLimited:          http.StatusTooManyRequests,
+	Unimplemented:        http.StatusNotImplemented,
+	Unavailable:          http.StatusServiceUnavailable,
+}
+
+// Error is an error the API answers with its own status, code and message
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	// Err is the underlying cause, it is logged but never shown to clients
+	Err error
+}
+
+func NewError(kind Kind, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (e *Error) Status() int {
+	return kindStatus[e.Kind]
+}
+
+var internalError = NewError(Internal, "internal_error", "something went wrong on our side, try again later")
+
+// InvalidInput reports a request that is well formed but carries values the API cannot accept
+func InvalidInput(format string, args ...any) *Error {
+	return NewError(Invalid, "invalid_input", fmt.Sprintf(format, args...))
+}
+
+// ValidationError describes the fields of a request body that failed validation
+func ValidationError(err error) *Error {
+	var fieldErrors validator.ValidationErrors
+	if !errors.As(err, &fieldErrors) {
+		return InvalidInput("request body is invalid")
+	}
+	fields := make([]string, 0, len(fieldErrors))
+	for _, fieldError := range fieldErrors {
+		fields = append(fields, fmt.Sprintf("%s (%s)", strings.ToLower(fieldError.Field()), fieldError.Tag()))
+	}
+	return InvalidInput("invalid value for %s", strings.Join(fields, ", "))
+}
@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"go.uber.org/zap/zapcore"
+	"net/url"
+	"strings"
+)
+
+// Redacted replaces the value of anything that must never reach the logs
+const Redacted = "[REDACTED]"
+
+var sensitiveNames = []string{"password", "otp", "token", "secret", "authorization"}
+
+// IsSensitive reports whether a field, parameter or header of this name holds a credential
+func IsSensitive(name string) bool {
+	name = strings.ToLower(name)
+	for _, sensitive := range sensitiveNames {
+		if strings.Contains(name, sensitive) {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactQuery encodes query parameters with the values of sensitive ones replaced
+func RedactQuery(values url.Values) string {
+	redacted := make(url.Values, len(values))
+	for name, value := range values {
+		if IsSensitive(name) {
+			value = []string{Redacted}
+		}
+		redacted[name] = value
+	}
+	return redacted.Encode()
+}
+
+// redactingCore masks the value of every log field with a sensitive name, so a password or
+// token passed to a log call by mistake is not written out
+type redactingCore struct {
+	zapcore.Core
+}
+
+func (core redactingCore) With(fields []zapcore.Field) zapcore.Core {
+	return redactingCore{core.Core.With(redactFields(fields))}
+}
+
+func (core redactingCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if core.Enabled(entry.Level) {
+		return checked.AddCore(entry, core)
+	}
+	return checked
+}
+
+func (core redactingCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	return core.Core.Write(entry, redactFields(fields))
+}
+
+func redactFields(fields []zapcore.Field) []zapcore.Field {
+	var redacted []zapcore.Field
+	for i, field := range fields {
+		if !IsSensitive(field.Key) {
+			continue
+		}
+		if redacted == nil {
+			redacted = append([]zapcore.Field(nil), fields...)
+		}
+		redacted[i] = zapcore.Field{Key: field.Key, Type: zapcore.StringType, String: Redacted}
+	}
+	if redacted == nil {
+		return fields
+	}
+	return redacted
+}
@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"net/url"
+	"testing"
+)
+
+func TestIsSensitive(t *testing.T) {
+	tests := []struct {
+		name      string
+		sensitive bool
+	}{
+		{"password", true},
+		{"new_password", true},
+		{"OTP", true},
+		{"token", true},
+		{"refresh_token", true},
+		{"client_secret", true},
+		{"Authorization", true},
+		{"email", false},
+		{"user_id", false},
+		{"cursor", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := IsSensitive(test.name); got != test.sensitive {
+				t.Fatalf("IsSensitive(%q) = %v, want %v", test.name, got, test.sensitive)
+			}
+		})
+	}
+}
+
+func TestRedactQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"nothing sensitive", "limit=10&cursor=abc", "cursor=abc&limit=10"},
+		{"token", "token=abc&limit=10", "limit=10&token=%5BREDACTED%5D"},
+		{"every value", "otp=1&otp=2", "otp=%5BREDACTED%5D"},
+		{"empty", "", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			values, err := url.ParseQuery(test.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := RedactQuery(values); got != test.want {
+				t.Fatalf("RedactQuery(%q) = %q, want %q", test.query, got, test.want)
+			}
+		})
+	}
+}
+
+func TestRedactingCore(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	logger := zap.New(redactingCore{core}).With(zap.String("token", "abc"), zap.String("user_id", "u1"))
+	logger.Info("login", zap.String("password", "hunter2"), zap.Int("attempt", 2))
+	logger.Debug("below the level", zap.String("password", "hunter2"))
+
+	entries := logs.AllUntimed()
+	if len(entries) != 1 {
+		t.Fatalf("%d entries logged, want 1", len(entries))
+	}
+	fields := entries[0].ContextMap()
+	want := map[string]any{"token": Redacted, "user_id": "u1", "password": Redacted, "attempt": int64(2)}
+	for key, value := range want {
+		if fields[key] != value {
+			t.Errorf("field %s = %v, want %v", key, fields[key], value)
+		}
+	}
+}
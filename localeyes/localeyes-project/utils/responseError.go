@@ -1,37 +1,102 @@
 package utils
 
 import (
+	"encoding/json"
+	"errors"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	"localeyes/internal/interfaces"
 	"localeyes/internal/models"
+	"net/http"
+	"time"
 )
 
-var DBError = 5500
-var AuthError = 3300
-var InvalidRequest = 4400
+// Alerter is told about every request failing with a server error, main sets it up once
+var Alerter interfaces.AlerterInterface
 
-func NewNotFoundError(message string) *models.Response {
-	return &models.Response{
-		Message: message,
-		Code:    InvalidRequest,
+// route names the route a request matched by its path template so requests for different
+// ids share it, or the path when no route matched
+func route(r *http.Request) string {
+	if current := mux.CurrentRoute(r); current != nil {
+		if template, err := current.GetPathTemplate(); err == nil {
+			return r.Method + " " + template
+		}
 	}
+	return r.Method + " " + r.URL.Path
 }
 
-func NewInternalServerError(message string) *models.Response {
-	return &models.Response{
-		Message: message,
-		Code:    DBError,
+// WriteError answers a request that failed with an application/problem+json body. Errors that
+// are not an *Error are logged and reported as internal errors without their message, except
+// failed DynamoDB conditions which mean the item changed or vanished under the request.
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	var apiErr *Error
+	var detail string
+	if errors.As(err, &apiErr) {
+		// context added by wrapping, like the reason of a suspension, is meant for the client
+		// but the cause an *Error carries is not
+		detail = err.Error()
+		if apiErr.Err != nil {
+			detail = apiErr.Message
+		}
+	} else {
+		apiErr = internalError
+		if IsConditionFailed(err) {
+			apiErr = ConditionFailed
+		}
+		detail = apiErr.Message
 	}
-}
-
-func NewBadRequestError(message string) *models.Response {
-	return &models.Response{
-		Message: message,
-		Code:    InvalidRequest,
+	if apiErr.Kind == Internal {
+		detail = internalError.Message
+	}
+	status := apiErr.Status()
+	requestId, _ := r.Context().Value("RequestId").(string)
+	if status >= http.StatusInternalServerError {
+		LoggerFrom(r.Context()).Error("Request failed", zap.Error(err))
+		if Alerter != nil {
+			userId, _ := r.Context().Value("Id").(string)
+			Alerter.Alert(&models.Alert{
+				Code:      apiErr.Code,
+				Status:    status,
+				Message:   err.Error(),
+				RequestId: requestId,
+				Route:     route(r),
+				UserId:    userId,
+				Time:      time.Now(),
+			})
+		}
+	}
+	problem := &models.Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    detail,
+		Code:      apiErr.Code,
+		Instance:  r.URL.Path,
+		RequestId: requestId,
+	}
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(problem); err != nil {
+		Logger.Error("Error encoding problem response", zap.Error(err))
 	}
 }
 
-func NewUnauthorizedError(message string) *models.Response {
-	return &models.Response{
-		Message: message,
-		Code:    AuthError,
+// IsConditionFailed reports whether err was caused by a failed condition expression,
+// either on a single write or on any item of a transaction
+func IsConditionFailed(err error) bool {
+	var conditionErr *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionErr) {
+		return true
+	}
+	var transactionErr *types.TransactionCanceledException
+	if errors.As(err, &transactionErr) {
+		for _, reason := range transactionErr.CancellationReasons {
+			if aws.ToString(reason.Code) == "ConditionalCheckFailed" {
+				return true
+			}
+		}
 	}
+	return false
 }
@@ -36,6 +36,19 @@ func ValidateFilter(fl validator.FieldLevel) bool {
 	}
 }
 
+func ValidateReaction(fl validator.FieldLevel) bool {
+	reaction := config.ReactionType(fl.Field().String())
+	if reaction == config.NoReaction {
+		return true
+	}
+	for _, reactionType := range config.ReactionTypes {
+		if reaction == reactionType {
+			return true
+		}
+	}
+	return false
+}
+
 func IsValidFilter(value string) bool {
 	switch config.Filter(value) {
 	case config.Food, config.Travel, config.Shopping:
@@ -51,3 +64,31 @@ func SetTag(value float64) string {
 	}
 	return "newbie"
 }
+
+func ValidateVote(fl validator.FieldLevel) bool {
+	switch config.VoteType(fl.Field().String()) {
+	case config.Upvote, config.Downvote, config.NoVote:
+		return true
+	default:
+		return false
+	}
+}
+
+func ValidateReportReason(fl validator.FieldLevel) bool {
+	reason := config.ReportReason(fl.Field().String())
+	for _, reportReason := range config.ReportReasons {
+		if reason == reportReason {
+			return true
+		}
+	}
+	return false
+}
+
+func ValidateResolution(fl validator.FieldLevel) bool {
+	switch config.ReportStatus(fl.Field().String()) {
+	case config.ReportActioned, config.ReportDismissed:
+		return true
+	default:
+		return false
+	}
+}